@@ -14,6 +14,9 @@ import (
 	"github.com/biwakonbu/agent-runner/internal/orchestrator"
 	"github.com/biwakonbu/agent-runner/internal/orchestrator/ipc"
 	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/biwakonbu/agent-runner/internal/ratelimit"
+	"github.com/biwakonbu/agent-runner/internal/usage"
+	"github.com/biwakonbu/agent-runner/internal/worker"
 	"github.com/google/uuid"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -30,6 +33,8 @@ type App struct {
 	currentWSID           string
 	executionOrchestrator *orchestrator.ExecutionOrchestrator
 	backlogStore          *orchestrator.BacklogStore
+	usageLedger           *orchestrator.UsageLedger
+	rateLimiter           *ratelimit.Limiter
 	eventEmitter          orchestrator.EventEmitter
 }
 
@@ -61,6 +66,24 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 }
 
+// configureChatUsage はチャットの Meta 呼び出しの使用量をワークスペースの使用量台帳に記録させる。
+// 価格表は agent-runner と同じく ~/.multiverse/config と <repo>/.multiverse の pricing.json で上書きする。
+func (a *App) configureChatUsage(projectRoot string) {
+	a.chatHandler.UsageLedger = a.usageLedger
+
+	var pricePaths []string
+	if home, err := os.UserHomeDir(); err == nil {
+		pricePaths = append(pricePaths, filepath.Join(home, ".multiverse", "config", usage.PricingFileName))
+	}
+	pricePaths = append(pricePaths, filepath.Join(projectRoot, ".multiverse", usage.PricingFileName))
+	prices, err := usage.LoadPriceTable(pricePaths...)
+	if err != nil {
+		runtime.LogWarningf(a.ctx, "Failed to load pricing table, using defaults: %v", err)
+		return
+	}
+	a.chatHandler.Prices = prices
+}
+
 // newMetaClientFromConfig は LLMConfigStore の設定に基づいて Meta クライアントを生成する
 // 優先度:
 // 1. LLMConfigStore の設定（codex-cli, mock 等）
@@ -77,7 +100,7 @@ func (a *App) newMetaClientFromConfig() *meta.Client {
 		kind = "openai-chat"
 	}
 
-	apiKey, apiKeyErr := a.llmConfigStore.GetAPIKey()
+	apiKey, apiKeyErr := a.llmConfigStore.GetAPIKeyForKind(kind)
 	if apiKeyErr != nil {
 		runtime.LogWarningf(a.ctx, "Failed to read API key: %v", apiKeyErr)
 		apiKey = os.Getenv("OPENAI_API_KEY")
@@ -93,19 +116,48 @@ func (a *App) newMetaClientFromConfig() *meta.Client {
 		}
 	}
 
+	var client *meta.Client
 	switch kind {
 	case "mock":
-		return meta.NewMockClient()
+		client = meta.NewMockClient()
 	case "codex-cli":
-		return meta.NewClient("codex-cli", "", config.Model, config.SystemPrompt)
+		client = meta.NewClient("codex-cli", "", config.Model, config.SystemPrompt)
 	case "openai-chat":
 		// 後方互換性のため残す（HTTP ベース）
-		return meta.NewClient("openai-chat", apiKey, config.Model, config.SystemPrompt)
+		client = meta.NewClient("openai-chat", apiKey, config.Model, config.SystemPrompt)
+	case meta.AnthropicKind:
+		if apiKey == "" {
+			runtime.LogWarningf(a.ctx, "ANTHROPIC_API_KEY is empty; Meta requests will fail")
+		}
+		client = meta.NewClient(meta.AnthropicKind, apiKey, config.Model, config.SystemPrompt)
 	default:
 		// 未知の種類の時も openai-chat にフォールバックする。
 		runtime.LogErrorf(a.ctx, "Unknown LLM kind '%s', falling back to openai-chat", kind)
-		return meta.NewClient("openai-chat", apiKey, config.Model, config.SystemPrompt)
+		client = meta.NewClient("openai-chat", apiKey, config.Model, config.SystemPrompt)
 	}
+	if len(config.Routes) > 0 {
+		routes := make(map[string]meta.Route, len(config.Routes))
+		for msgType, r := range config.Routes {
+			routes[msgType] = meta.Route{Kind: r.Kind, Model: r.Model}
+		}
+		apiKeyFor := func(kind string) string {
+			key, _ := a.llmConfigStore.GetAPIKeyForKind(kind)
+			return key
+		}
+		if err := client.SetRoutes(routes, apiKeyFor); err != nil {
+			runtime.LogErrorf(a.ctx, "Invalid Meta routes, using %s for all message types: %v", kind, err)
+		}
+	}
+	client.SetHTTPOptions(meta.HTTPOptions{
+		BaseURL:           config.BaseURL,
+		Headers:           config.Headers,
+		Timeout:           time.Duration(config.TimeoutSec) * time.Second,
+		StructuredOutputs: config.StructuredOutputs,
+		ToolCalls:         config.ToolCalls,
+	})
+	// agent-runner と同じ共有レート制限に参加する
+	client.SetLimiter(a.rateLimiter)
+	return client
 }
 
 // SelectWorkspace opens a directory selection dialog and loads the workspace.
@@ -178,6 +230,22 @@ func (a *App) SelectWorkspace() string {
 		a.backlogStore,
 		[]string{"default", "codegen", "test"},
 	)
+	a.usageLedger = orchestrator.NewUsageLedger(wsDir)
+	a.executionOrchestrator.UsageLedger = a.usageLedger
+	a.executionOrchestrator.WorkspaceID = id
+	if sandbox, err := worker.NewSandboxManager(); err == nil {
+		a.executionOrchestrator.Containers = sandbox
+	} else {
+		runtime.LogWarningf(a.ctx, "Docker unavailable, orphaned containers will not be cleaned up: %v", err)
+	}
+
+	// Shared rate limiter (workspace-level ratelimits.yaml)
+	limiter, err := ratelimit.LoadShared(ws.ProjectRoot)
+	if err != nil {
+		runtime.LogWarningf(a.ctx, "Failed to load rate limit config: %v", err)
+		limiter, _ = ratelimit.LoadShared("")
+	}
+	a.rateLimiter = limiter
 
 	// Initialize ChatHandler with Meta client from LLMConfigStore
 	sessionStore := chat.NewChatSessionStore(wsDir)
@@ -185,6 +253,7 @@ func (a *App) SelectWorkspace() string {
 	// ChatHandler の互換のため TaskStore を引き続き生成（design/state との同期は Handler 内で行う）
 	taskStore := orchestrator.NewTaskStore(wsDir)
 	a.chatHandler = chat.NewHandler(metaClient, taskStore, sessionStore, id, ws.ProjectRoot, a.repo, a.eventEmitter)
+	a.configureChatUsage(ws.ProjectRoot)
 
 	return id
 }
@@ -259,6 +328,22 @@ func (a *App) OpenWorkspaceByID(id string) string {
 		a.backlogStore,
 		[]string{"default", "codegen", "test"},
 	)
+	a.usageLedger = orchestrator.NewUsageLedger(wsDir)
+	a.executionOrchestrator.UsageLedger = a.usageLedger
+	a.executionOrchestrator.WorkspaceID = id
+	if sandbox, err := worker.NewSandboxManager(); err == nil {
+		a.executionOrchestrator.Containers = sandbox
+	} else {
+		runtime.LogWarningf(a.ctx, "Docker unavailable, orphaned containers will not be cleaned up: %v", err)
+	}
+
+	// Shared rate limiter (workspace-level ratelimits.yaml)
+	limiter, err := ratelimit.LoadShared(ws.ProjectRoot)
+	if err != nil {
+		runtime.LogWarningf(a.ctx, "Failed to load rate limit config: %v", err)
+		limiter, _ = ratelimit.LoadShared("")
+	}
+	a.rateLimiter = limiter
 
 	// Initialize ChatHandler with Meta client from LLMConfigStore
 	sessionStore := chat.NewChatSessionStore(wsDir)
@@ -266,6 +351,7 @@ func (a *App) OpenWorkspaceByID(id string) string {
 	// Temporary taskStore instance for ChatHandler
 	taskStore := orchestrator.NewTaskStore(wsDir)
 	a.chatHandler = chat.NewHandler(metaClient, taskStore, sessionStore, id, ws.ProjectRoot, a.repo, a.eventEmitter)
+	a.configureChatUsage(ws.ProjectRoot)
 
 	return id
 }
@@ -401,6 +487,15 @@ func (a *App) ListTasks() []orchestrator.Task {
 			UpdatedAt: ts.UpdatedAt,
 		}
 
+		if ts.Inputs != nil {
+			task.Schedule = orchestrator.TaskScheduleFromInputs(ts.Inputs)
+			if next, ok := ts.Inputs[orchestrator.InputKeyNextRunAt].(string); ok {
+				if t, err := time.Parse(time.RFC3339, next); err == nil {
+					task.NextRunAt = &t
+				}
+			}
+		}
+
 		// Best-effort enrich from NodeDesign.
 		if node != nil {
 			task.Description = node.Summary
@@ -482,6 +577,56 @@ func (a *App) RunTask(taskID string) error {
 	return a.scheduler.ScheduleTask(taskID)
 }
 
+// SetTaskSchedule schedules a task to run once at runAt (RFC3339) or
+// repeatedly per the cron expression, and returns the next run time (RFC3339).
+// runAt と cron はどちらか一方だけを指定する。
+func (a *App) SetTaskSchedule(taskID string, runAt string, cron string) (string, error) {
+	if a.scheduler == nil {
+		return "", fmt.Errorf("scheduler not initialized")
+	}
+	schedule := orchestrator.TaskSchedule{Cron: cron}
+	if runAt != "" {
+		t, err := time.Parse(time.RFC3339, runAt)
+		if err != nil {
+			return "", fmt.Errorf("invalid runAt %q: %w", runAt, err)
+		}
+		schedule.RunAt = &t
+	}
+	next, err := a.scheduler.SetTaskSchedule(taskID, schedule)
+	if err != nil {
+		return "", err
+	}
+	return next.Format(time.RFC3339), nil
+}
+
+// ClearTaskSchedule removes the schedule of a task.
+func (a *App) ClearTaskSchedule(taskID string) error {
+	if a.scheduler == nil {
+		return fmt.Errorf("scheduler not initialized")
+	}
+	return a.scheduler.ClearTaskSchedule(taskID)
+}
+
+// GetTaskRunHistory returns the run history of a scheduled task (oldest first).
+func (a *App) GetTaskRunHistory(taskID string) []orchestrator.ScheduleRun {
+	if a.repo == nil {
+		return []orchestrator.ScheduleRun{}
+	}
+	tasksState, err := a.repo.State().LoadTasks()
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to load tasks: %v", err)
+		return []orchestrator.ScheduleRun{}
+	}
+	for _, ts := range tasksState.Tasks {
+		if ts.TaskID == taskID && ts.Inputs != nil {
+			if runs := orchestrator.ScheduleRunHistory(ts.Inputs); runs != nil {
+				return runs
+			}
+		}
+	}
+	return []orchestrator.ScheduleRun{}
+}
+
 // ListAttempts returns all attempts for a given task.
 func (a *App) ListAttempts(taskID string) []orchestrator.Attempt {
 	// Not supported in new persistence yet. Return empty.
@@ -510,7 +655,9 @@ type ChatResponseDTO struct {
 	GeneratedTasks []orchestrator.Task      `json:"generatedTasks"`
 	Understanding  string                   `json:"understanding"`
 	Conflicts      []meta.PotentialConflict `json:"conflicts,omitempty"`
-	Error          string                   `json:"error,omitempty"`
+	// DependencyReport は依存グラフの検証で問題が見つかった場合のみ設定される
+	DependencyReport *orchestrator.DependencyReport `json:"dependencyReport,omitempty"`
+	Error            string                         `json:"error,omitempty"`
 }
 
 // CreateChatSession は新しいチャットセッションを作成する
@@ -538,9 +685,13 @@ func (a *App) SendChatMessage(sessionID string, message string) *ChatResponseDTO
 
 	resp, err := a.chatHandler.HandleMessage(a.ctx, sessionID, message)
 	if err != nil {
-		return &ChatResponseDTO{
+		dto := &ChatResponseDTO{
 			Error: err.Error(),
 		}
+		if resp != nil {
+			dto.DependencyReport = resp.DependencyReport
+		}
+		return dto
 	}
 
 	// Chat Autopilot: タスク生成成功時に自動実行を開始
@@ -572,10 +723,11 @@ func (a *App) SendChatMessage(sessionID string, message string) *ChatResponseDTO
 	}
 
 	return &ChatResponseDTO{
-		Message:        resp.Message,
-		GeneratedTasks: resp.GeneratedTasks,
-		Understanding:  resp.Understanding,
-		Conflicts:      resp.Conflicts,
+		Message:          resp.Message,
+		GeneratedTasks:   resp.GeneratedTasks,
+		Understanding:    resp.Understanding,
+		Conflicts:        resp.Conflicts,
+		DependencyReport: resp.DependencyReport,
 	}
 }
 
@@ -629,6 +781,66 @@ func (a *App) StopExecution() error {
 	return a.executionOrchestrator.Stop()
 }
 
+// CancelTask cancels a single task. A running task has only its job (agent-runner
+// process and container) canceled; other tasks become CANCELED immediately.
+func (a *App) CancelTask(taskID string) error {
+	if a.executionOrchestrator == nil {
+		return fmt.Errorf("execution orchestrator not initialized")
+	}
+	return a.executionOrchestrator.CancelTask(taskID)
+}
+
+// RetryTask immediately retries a FAILED, RETRY_WAIT or CANCELED task.
+// resetAttempts resets its attempt_count.
+func (a *App) RetryTask(taskID string, resetAttempts bool) error {
+	if a.executionOrchestrator == nil {
+		return fmt.Errorf("execution orchestrator not initialized")
+	}
+	return a.executionOrchestrator.RetryTask(taskID, resetAttempts)
+}
+
+// SkipTask skips a task so that its dependents treat it as satisfied.
+func (a *App) SkipTask(taskID string) error {
+	if a.executionOrchestrator == nil {
+		return fmt.Errorf("execution orchestrator not initialized")
+	}
+	return a.executionOrchestrator.SkipTask(taskID)
+}
+
+// ListDeadLetters returns the dead-lettered jobs of poolID (every pool when
+// poolID is empty).
+func (a *App) ListDeadLetters(poolID string) []ipc.DeadLetter {
+	if a.executionOrchestrator == nil {
+		return []ipc.DeadLetter{}
+	}
+	letters, err := a.executionOrchestrator.ListDeadLetters(poolID)
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to list dead letters: %v", err)
+		return []ipc.DeadLetter{}
+	}
+	result := make([]ipc.DeadLetter, 0, len(letters))
+	for _, dl := range letters {
+		result = append(result, *dl)
+	}
+	return result
+}
+
+// RequeueDeadLetter puts a dead-lettered job back into its pool's queue.
+func (a *App) RequeueDeadLetter(jobID, poolID string) error {
+	if a.executionOrchestrator == nil {
+		return fmt.Errorf("execution orchestrator not initialized")
+	}
+	return a.executionOrchestrator.RequeueDeadLetter(jobID, poolID)
+}
+
+// PurgeDeadLetter deletes a dead-lettered job.
+func (a *App) PurgeDeadLetter(jobID, poolID string) error {
+	if a.executionOrchestrator == nil {
+		return fmt.Errorf("execution orchestrator not initialized")
+	}
+	return a.executionOrchestrator.PurgeDeadLetter(jobID, poolID)
+}
+
 // GetExecutionState returns the current execution state.
 func (a *App) GetExecutionState() string {
 	if a.executionOrchestrator == nil {
@@ -685,6 +897,41 @@ func (a *App) DeleteBacklogItem(id string) error {
 	return a.backlogStore.Delete(id)
 }
 
+// ============================================================================
+// Usage API
+// ============================================================================
+
+// GetUsageReport returns token usage and estimated cost for the current workspace,
+// broken down per task and per attempt.
+func (a *App) GetUsageReport() (*orchestrator.UsageReport, error) {
+	if a.usageLedger == nil {
+		return nil, fmt.Errorf("usage ledger not initialized")
+	}
+	report, err := a.usageLedger.Report()
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to build usage report: %v", err)
+		return nil, err
+	}
+	return report, nil
+}
+
+// ============================================================================
+// Rate Limit API
+// ============================================================================
+
+// GetRateLimitState returns the shared rate limiter state per provider kind / Meta model.
+func (a *App) GetRateLimitState() ([]ratelimit.KeyState, error) {
+	if a.rateLimiter == nil {
+		return nil, fmt.Errorf("rate limiter not initialized")
+	}
+	states, err := a.rateLimiter.Snapshot()
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to read rate limit state: %v", err)
+		return nil, err
+	}
+	return states, nil
+}
+
 // ============================================================================
 // LLM Config API
 // ============================================================================
@@ -734,6 +981,7 @@ func (a *App) SetLLMConfig(dto LLMConfigDTO) error {
 		metaClient := a.newMetaClientFromConfig()
 		taskStore := orchestrator.NewTaskStore(wsDir) // Temp
 		a.chatHandler = chat.NewHandler(metaClient, taskStore, sessionStore, a.currentWSID, a.currentWS.ProjectRoot, a.repo, a.eventEmitter)
+		a.configureChatUsage(a.currentWS.ProjectRoot)
 	}
 
 	return nil
@@ -2,14 +2,23 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
+	"github.com/biwakonbu/agent-runner/internal/agenttools"
 	"github.com/biwakonbu/agent-runner/internal/cli"
 	"github.com/biwakonbu/agent-runner/internal/core"
 	"github.com/biwakonbu/agent-runner/internal/meta"
 	"github.com/biwakonbu/agent-runner/internal/note"
+	"github.com/biwakonbu/agent-runner/internal/prompt"
+	"github.com/biwakonbu/agent-runner/internal/ratelimit"
+	"github.com/biwakonbu/agent-runner/internal/usage"
 	"github.com/biwakonbu/agent-runner/internal/worker"
 	"github.com/biwakonbu/agent-runner/pkg/config"
 	"gopkg.in/yaml.v3"
@@ -23,7 +32,11 @@ func main() {
 	}))
 	slog.SetDefault(logger)
 
-	if err := Run(context.Background(), os.Stdin, os.Stdout, os.Stderr, logger); err != nil {
+	// Orchestrator からのキャンセル（SIGTERM）で ctx を終了させ、コンテナを停止してから終了する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := Run(ctx, os.Stdin, os.Stdout, os.Stderr, logger)
+	stop()
+	if err != nil {
 		slog.Error("application failed", "err", err)
 		os.Exit(1)
 	}
@@ -48,28 +61,100 @@ func Run(ctx context.Context, stdin io.Reader, _, _ io.Writer, logger *slog.Logg
 		return err
 	}
 
-	// 3. Initialize Components
-	apiKey := os.Getenv("OPENAI_API_KEY")
+	// 3. Load declarative agent tool providers (~/.multiverse and <repo>/.multiverse)
+	// 不正な定義や組み込みと衝突する kind は読み飛ばし、残りの定義で続行する
+	kinds, err := agenttools.LoadDeclarativeProviders(filepath.Join(cfg.Task.Repo, ".multiverse"))
+	if err != nil {
+		logger.Warn("skipped invalid declarative agent tool providers", "error", err)
+	}
+	if len(kinds) > 0 {
+		logger.Info("loaded declarative agent tool providers", "kinds", kinds)
+	}
+
+	// 4. Initialize Components
+	apiKeyEnv := meta.APIKeyEnv(cfg.Runner.Meta.Kind)
+	apiKey := os.Getenv(apiKeyEnv)
 	if apiKey == "" {
-		logger.Warn("OPENAI_API_KEY not set, using mock mode")
+		logger.Warn(apiKeyEnv + " not set, using mock mode")
 	}
 
 	// Resolve Meta Model ID
 	metaModel := cli.ResolveMetaModel(flags.MetaModel, cfg.Runner.Meta.Model)
+	if cfg.Runner.Meta.Kind == meta.AnthropicKind && flags.MetaModel == "" && cfg.Runner.Meta.Model == "" {
+		metaModel = agenttools.DefaultClaudeModel
+	}
 	logger.Info("resolved meta model", "model", metaModel)
 
 	metaClient := meta.NewClient(cfg.Runner.Meta.Kind, apiKey, metaModel, cfg.Runner.Meta.SystemPrompt)
+	if len(cfg.Runner.Meta.Routes) > 0 {
+		routes := make(map[string]meta.Route, len(cfg.Runner.Meta.Routes))
+		for msgType, r := range cfg.Runner.Meta.Routes {
+			routes[msgType] = meta.Route{Kind: r.Kind, Model: r.Model}
+		}
+		if err := metaClient.SetRoutes(routes, func(kind string) string { return os.Getenv(meta.APIKeyEnv(kind)) }); err != nil {
+			return err
+		}
+		logger.Info("meta routes configured", "routes", routes)
+	}
+	httpOpts := meta.HTTPOptions{
+		BaseURL:           cfg.Runner.Meta.BaseURL,
+		Headers:           cfg.Runner.Meta.Headers,
+		Timeout:           time.Duration(cfg.Runner.Meta.TimeoutSec) * time.Second,
+		StructuredOutputs: cfg.Runner.Meta.StructuredOutputs,
+		ToolCalls:         cfg.Runner.Meta.ToolCalls,
+	}
+	metaClient.SetHTTPOptions(httpOpts)
+	// 呼び出し種別ごとのシステムプロンプト（~/.multiverse/prompts と <repo>/.multiverse/prompts で上書き可能）
+	prompts, err := prompt.Load(cfg.Task.Repo)
+	if err != nil {
+		return err
+	}
+	metaClient.SetPrompts(prompts)
+	var cassette *meta.Cassette
+	if flags.MetaCassette != "" {
+		cassette, err = meta.OpenCassette(flags.MetaCassette, meta.CassetteMode(flags.MetaCassetteMode))
+		if err != nil {
+			return err
+		}
+		metaClient.SetCassette(cassette)
+		logger.Info("meta cassette enabled", "path", flags.MetaCassette, "mode", flags.MetaCassetteMode)
+	}
 
 	workerExecutor, err := worker.NewExecutor(cfg.Runner.Worker, cfg.Task.Repo)
 	if err != nil {
 		return err
 	}
+	workerExecutor.TaskID = cfg.Task.ID
+
+	// 共有レート制限（~/.multiverse/config と <repo>/.multiverse の ratelimits.yaml）
+	limiter, err := ratelimit.LoadShared(cfg.Task.Repo)
+	if err != nil {
+		return err
+	}
+	metaClient.SetLimiter(limiter)
+	workerExecutor.Limiter = limiter
 
 	noteWriter := note.NewWriter()
 
 	runner := core.NewRunner(&cfg, metaClient, workerExecutor, noteWriter)
+	if rc := cfg.Runner.Reviewer; rc != nil {
+		runner.Reviewer = newReviewer(&cfg, *rc, metaModel, httpOpts, limiter, cassette, workerExecutor)
+		logger.Info("independent reviewer enabled", "reviewer", runner.Reviewer.Name())
+	}
+
+	// 価格表: 組み込み値を ~/.multiverse/config と <repo>/.multiverse の pricing.json で上書き
+	var pricePaths []string
+	if home, err := os.UserHomeDir(); err == nil {
+		pricePaths = append(pricePaths, filepath.Join(home, ".multiverse", "config", usage.PricingFileName))
+	}
+	pricePaths = append(pricePaths, filepath.Join(cfg.Task.Repo, ".multiverse", usage.PricingFileName))
+	prices, err := usage.LoadPriceTable(pricePaths...)
+	if err != nil {
+		return err
+	}
+	runner.Prices = prices
 
-	// 4. Run
+	// 5. Run
 	logger.Info("starting task", "title", cfg.Task.Title, "id", cfg.Task.ID)
 
 	result, err := runner.Run(ctx)
@@ -77,6 +162,55 @@ func Run(ctx context.Context, stdin io.Reader, _, _ io.Writer, logger *slog.Logg
 		return err
 	}
 
-	logger.Info("task completed", "state", result.State)
-	return nil
+	logger.Info("task finished", "state", result.State)
+	return checkFinalState(result.State)
+}
+
+// checkFinalState returns an error unless the task reached COMPLETE, so that
+// agent-runner exits non-zero and the orchestrator records the attempt as failed.
+func checkFinalState(state core.TaskState) error {
+	if state == core.StateComplete {
+		return nil
+	}
+	return fmt.Errorf("task finished in state %s", state)
+}
+
+// newReviewer builds the independent reviewer from runner.reviewer. kind: worker
+// はサンドボックス内の読み取り専用 Worker、それ以外は別設定の Meta クライアントを使う。
+func newReviewer(cfg *config.TaskConfig, rc config.ReviewerConfig, metaModel string, httpOpts meta.HTTPOptions, limiter *ratelimit.Limiter, cassette *meta.Cassette, w core.WorkerExecutor) core.Reviewer {
+	if rc.Kind == config.ReviewerKindWorker {
+		workerType := rc.WorkerType
+		if workerType == "" {
+			workerType = cfg.Runner.Worker.Kind
+		}
+		return core.NewWorkerReviewer(w, workerType, cfg.Runner.Worker.Env, cfg.Task.Repo)
+	}
+
+	kind, model := rc.Kind, rc.Model
+	if kind == "" {
+		kind = cfg.Runner.Meta.Kind
+	}
+	if model == "" && kind == cfg.Runner.Meta.Kind {
+		model = metaModel
+	}
+	systemPrompt := rc.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = meta.ReviewerSystemPrompt
+	}
+
+	client := meta.NewClient(kind, os.Getenv(meta.APIKeyEnv(kind)), model, systemPrompt)
+	// エンドポイントとヘッダーは Meta と同じ kind の場合のみ引き継ぐ
+	if kind != cfg.Runner.Meta.Kind {
+		httpOpts.BaseURL, httpOpts.Headers = "", nil
+	}
+	client.SetHTTPOptions(httpOpts)
+	client.SetLimiter(limiter)
+	if cassette != nil {
+		client.SetCassette(cassette)
+	}
+	label := kind
+	if model != "" {
+		label += " / " + model
+	}
+	return core.NewMetaReviewer(client, label)
 }
@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"strings"
 	"testing"
+
+	"github.com/biwakonbu/agent-runner/internal/core"
 )
 
 // TestRun_InvalidYAML verifies that Run returns an error for invalid YAML input.
@@ -31,3 +33,16 @@ func TestRun_EmptyInput(t *testing.T) {
 		t.Error("Expected error for empty input, got nil")
 	}
 }
+
+// TestCheckFinalState verifies that only COMPLETE exits successfully.
+func TestCheckFinalState(t *testing.T) {
+	if err := checkFinalState(core.StateComplete); err != nil {
+		t.Errorf("COMPLETE should succeed, got %v", err)
+	}
+	for _, state := range []core.TaskState{core.StateFailed, core.StateRunning} {
+		err := checkFinalState(state)
+		if err == nil || !strings.Contains(err.Error(), string(state)) {
+			t.Errorf("expected error naming %s, got %v", state, err)
+		}
+	}
+}
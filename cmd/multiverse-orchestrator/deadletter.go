@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator"
+)
+
+const deadLetterUsage = `usage: multiverse-orchestrator [flags] deadletter <command>
+
+commands:
+  list [-pool <pool-id>]                 list dead-lettered jobs (all pools by default)
+  requeue [-pool <pool-id>] <job-id>     put a dead-lettered job back into the queue
+  purge [-pool <pool-id>] <job-id>|-all  delete dead-lettered jobs
+`
+
+// runDeadLetterCommand inspects, requeues or purges dead-lettered jobs.
+// 返り値はプロセスの終了コード。
+func runDeadLetterCommand(orch *orchestrator.ExecutionOrchestrator, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, deadLetterUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("deadletter "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	poolID := fs.String("pool", "", "Queue Pool ID (list: all pools when empty, others: default)")
+	all := fs.Bool("all", false, "purge: delete every dead letter of the pool (all pools when -pool is empty)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		letters, err := orch.ListDeadLetters(*poolID)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to list dead letters: %v\n", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "POOL\tJOB\tTASK\tDELIVERIES\tDEAD AT\tREASON")
+		for _, dl := range letters {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+				dl.PoolID, dl.JobID, dl.TaskID, dl.Deliveries, dl.DeadAt.Format(time.RFC3339), dl.Reason)
+		}
+		w.Flush()
+		return 0
+
+	case "requeue", "purge":
+		pool := *poolID
+		if args[0] == "purge" && *all {
+			purged, err := orch.PurgeDeadLetters(pool)
+			if err != nil {
+				fmt.Fprintf(stderr, "failed to purge dead letters: %v\n", err)
+				return 1
+			}
+			fmt.Fprintf(stdout, "purged %d dead letters\n", purged)
+			return 0
+		}
+		if fs.NArg() != 1 {
+			fmt.Fprint(stderr, deadLetterUsage)
+			return 2
+		}
+		if pool == "" {
+			pool = "default"
+		}
+		jobID := fs.Arg(0)
+		var err error
+		if args[0] == "requeue" {
+			err = orch.RequeueDeadLetter(jobID, pool)
+		} else {
+			err = orch.PurgeDeadLetter(jobID, pool)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to %s dead letter: %v\n", args[0], err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%sd %s\n", args[0], jobID)
+		return 0
+	}
+
+	fmt.Fprint(stderr, deadLetterUsage)
+	return 2
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator"
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/ipc"
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDeadLetterCommand(t *testing.T) {
+	dir := t.TempDir()
+	repo := persistence.NewWorkspaceRepository(dir)
+	require.NoError(t, repo.Init())
+	queue := ipc.NewFilesystemQueue(dir)
+	orch := orchestrator.NewExecutionOrchestrator(nil, nil, repo, queue, nil, nil, nil)
+
+	require.NoError(t, queue.Enqueue(&ipc.Job{ID: "job-1", TaskID: "task-1", PoolID: "default"}))
+	_, err := queue.Dequeue("default")
+	require.NoError(t, err)
+	require.NoError(t, queue.DeadLetter("job-1", "default", "poison job"))
+
+	run := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := runDeadLetterCommand(orch, args, &stdout, &stderr)
+		return code, stdout.String() + stderr.String()
+	}
+
+	code, out := run("list")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "job-1")
+	assert.Contains(t, out, "poison job")
+
+	code, out = run("requeue", "job-1")
+	assert.Equal(t, 0, code, out)
+	jobs, err := queue.ListJobs("default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"job-1"}, jobs)
+
+	code, _ = run("purge", "job-1")
+	assert.Equal(t, 1, code, "already requeued")
+
+	// purge -all もジョブごとに履歴を残す
+	_, err = queue.Dequeue("default")
+	require.NoError(t, err)
+	require.NoError(t, queue.DeadLetter("job-1", "default", "poison again"))
+	code, out = run("purge", "-all")
+	assert.Equal(t, 0, code, out)
+	assert.Contains(t, out, "purged 1 dead letters")
+	actions, err := repo.History().ListActions(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	purgedActions := 0
+	for _, a := range actions {
+		if a.Kind == orchestrator.ActionKindDeadLetterPurged && a.Payload["job_id"] == "job-1" {
+			purgedActions++
+		}
+	}
+	assert.Equal(t, 1, purgedActions)
+
+	code, _ = run("unknown")
+	assert.Equal(t, 2, code)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/biwakonbu/agent-runner/internal/orchestrator"
 	"github.com/biwakonbu/agent-runner/internal/orchestrator/ipc"
 	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/biwakonbu/agent-runner/internal/worker"
 )
 
 func main() {
@@ -41,7 +42,11 @@ func main() {
 	// Executor (Stateless)
 	executor := orchestrator.NewExecutor(*agentRunnerPath, *workspaceDir)
 
-	// RetryPolicy and Backlog configurable? Using defaults for now.
+	// リトライポリシーは失敗のたびに state/retry-policies.json から読み込む（無ければ既定のポリシー）。
+	// 設定の誤りは起動時に知らせる
+	if _, err := orchestrator.LoadRetryPolicies(repo); err != nil {
+		log.Printf("Invalid retry policies, using the default policy: %v", err)
+	}
 	backlogStore := orchestrator.NewBacklogStore(*workspaceDir)
 
 	// Create ExecutionOrchestrator
@@ -54,6 +59,17 @@ func main() {
 		backlogStore,
 		[]string{*poolID},
 	)
+	// サブコマンド: デッドレターの参照・再投入・削除（オーケストレーターは起動しない）
+	if flag.Arg(0) == "deadletter" {
+		os.Exit(runDeadLetterCommand(orch, flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
+	// 起動時のリカバリで、中断されたタスクのコンテナを停止する
+	if sandbox, err := worker.NewSandboxManager(); err == nil {
+		orch.Containers = sandbox
+	} else {
+		log.Printf("Docker unavailable, orphaned containers will not be cleaned up: %v", err)
+	}
 
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
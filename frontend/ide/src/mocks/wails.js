@@ -215,6 +215,54 @@ export function StopExecution() {
     return Promise.resolve();
 }
 
+// Task control
+export function CancelTask(taskId) {
+    console.log("[Mock] CancelTask called:", taskId);
+    return Promise.resolve();
+}
+
+export function RetryTask(taskId, resetAttempts) {
+    console.log("[Mock] RetryTask called:", taskId, resetAttempts);
+    return Promise.resolve();
+}
+
+export function SkipTask(taskId) {
+    console.log("[Mock] SkipTask called:", taskId);
+    return Promise.resolve();
+}
+
+// Scheduled tasks
+export function SetTaskSchedule(taskId, runAt, cron) {
+    console.log("[Mock] SetTaskSchedule called:", taskId, runAt, cron);
+    return Promise.resolve(runAt || new Date(Date.now() + 60 * 60 * 1000).toISOString());
+}
+
+export function ClearTaskSchedule(taskId) {
+    console.log("[Mock] ClearTaskSchedule called:", taskId);
+    return Promise.resolve();
+}
+
+export function GetTaskRunHistory(taskId) {
+    console.log("[Mock] GetTaskRunHistory called:", taskId);
+    return Promise.resolve([]);
+}
+
+// Dead letters
+export function ListDeadLetters(poolId) {
+    console.log("[Mock] ListDeadLetters called:", poolId);
+    return Promise.resolve([]);
+}
+
+export function RequeueDeadLetter(jobId, poolId) {
+    console.log("[Mock] RequeueDeadLetter called:", jobId, poolId);
+    return Promise.resolve();
+}
+
+export function PurgeDeadLetter(jobId, poolId) {
+    console.log("[Mock] PurgeDeadLetter called:", jobId, poolId);
+    return Promise.resolve();
+}
+
 export function GetExecutionState() {
     console.log("[Mock] GetExecutionState called");
     return Promise.resolve(executionState);
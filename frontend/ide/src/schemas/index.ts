@@ -18,6 +18,9 @@ export const TaskStatusSchema = z.enum([
   'CANCELED',
   'BLOCKED',
   'RETRY_WAIT',
+  'DEPENDENCY_FAILED',
+  'SKIPPED',
+  'SCHEDULED',
 ]);
 
 export type TaskStatus = z.infer<typeof TaskStatusSchema>;
@@ -71,6 +74,13 @@ export const TaskSchema = z.object({
   attemptCount: z.number().int().nonnegative().optional(),
   nextRetryAt: z.string().datetime({ offset: true }).or(z.string()).optional().nullable(),
 
+  // スケジュール実行（実行時刻 1 回、または cron 式による繰り返し）
+  schedule: z.object({
+    runAt: z.string().optional().nullable(),
+    cron: z.string().optional(),
+  }).optional().nullable(),
+  nextRunAt: z.string().datetime({ offset: true }).or(z.string()).optional().nullable(),
+
   // Phase 1: Data Model Enhancements
   suggestedImpl: z.object({
     language: z.string().optional(),
@@ -135,6 +145,9 @@ export const statusLabels: Record<TaskStatus, string> = {
   CANCELED: 'キャンセル',
   BLOCKED: 'ブロック',
   RETRY_WAIT: 'リトライ待機',
+  DEPENDENCY_FAILED: '依存失敗',
+  SKIPPED: 'スキップ',
+  SCHEDULED: '予約済み',
 };
 
 // AttemptStatus スキーマ
@@ -22,9 +22,23 @@ export interface ChatResponse {
         tasks: string[];
         warning: string;
     }>;
+    // 依存グラフの検証で問題が見つかった場合のみ設定される
+    dependencyReport?: DependencyReport;
     error?: string;
 }
 
+export interface DependencyIssue {
+    kind: 'cycle' | 'self_dependency' | 'dangling_reference';
+    nodeIds: string[];
+    dependency?: string;
+    message: string;
+}
+
+export interface DependencyReport {
+    valid: boolean;
+    issues?: DependencyIssue[];
+}
+
 // Chat Log Interface
 export interface ChatLogEntry {
     step: string;
@@ -53,6 +67,16 @@ function createChatLogStore() {
     addLog: (entry: ChatLogEntry) => {
       update((logs) => [...logs, entry]);
     },
+    // 直前のエントリが同じ step なら置き換える（ストリーミング進捗でログが埋まらないようにする）
+    upsertLog: (entry: ChatLogEntry) => {
+      update((logs) => {
+        const last = logs[logs.length - 1];
+        if (last && last.step === entry.step) {
+          return [...logs.slice(0, -1), entry];
+        }
+        return [...logs, entry];
+      });
+    },
     clear: () => update(() => []),
   };
 }
@@ -188,12 +212,17 @@ export { chatStore };
 
 // Wailsイベントリスナーの初期化
 export function initChatEvents() {
-    EventsOn('chat:progress', (event: { step: string; message: string; timestamp: string }) => {
-        console.log('Chat Progress:', event);
-        chatLog.addLog({
+    EventsOn('chat:progress', (event: { step: string; message: string; delta?: string; timestamp: string }) => {
+        const entry = {
             step: event.step,
             message: event.message,
             timestamp: event.timestamp
-        });
+        };
+        if (event.step === 'Streaming') {
+            chatLog.upsertLog(entry);
+            return;
+        }
+        console.log('Chat Progress:', event);
+        chatLog.addLog(entry);
     });
 }
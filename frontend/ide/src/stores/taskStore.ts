@@ -107,6 +107,9 @@ export const taskCountsByStatus = derived(tasks, ($tasks) => {
     CANCELED: 0,
     BLOCKED: 0,
     RETRY_WAIT: 0,
+    DEPENDENCY_FAILED: 0,
+    SKIPPED: 0,
+    SCHEDULED: 0,
   };
 
   for (const task of $tasks) {
@@ -4,6 +4,11 @@ import {chat} from '../models';
 import {orchestrator} from '../models';
 import {main} from '../models';
 import {ide} from '../models';
+import {ipc} from '../models';
+
+export function CancelTask(arg1:string):Promise<void>;
+
+export function ClearTaskSchedule(arg1:string):Promise<void>;
 
 export function CreateChatSession():Promise<chat.ChatSession>;
 
@@ -25,10 +30,14 @@ export function GetLLMConfig():Promise<main.LLMConfigDTO>;
 
 export function GetPoolSummaries():Promise<Array<orchestrator.PoolSummary>>;
 
+export function GetTaskRunHistory(arg1:string):Promise<Array<orchestrator.ScheduleRun>>;
+
 export function GetWorkspace(arg1:string):Promise<ide.Workspace>;
 
 export function ListAttempts(arg1:string):Promise<Array<orchestrator.Attempt>>;
 
+export function ListDeadLetters(arg1:string):Promise<Array<ipc.DeadLetter>>;
+
 export function ListRecentWorkspaces():Promise<Array<ide.WorkspaceSummary>>;
 
 export function ListTasks():Promise<Array<orchestrator.Task>>;
@@ -37,12 +46,18 @@ export function OpenWorkspaceByID(arg1:string):Promise<string>;
 
 export function PauseExecution():Promise<void>;
 
+export function PurgeDeadLetter(arg1:string,arg2:string):Promise<void>;
+
 export function RemoveWorkspace(arg1:string):Promise<void>;
 
+export function RequeueDeadLetter(arg1:string,arg2:string):Promise<void>;
+
 export function ResolveBacklogItem(arg1:string,arg2:string):Promise<void>;
 
 export function ResumeExecution():Promise<void>;
 
+export function RetryTask(arg1:string,arg2:boolean):Promise<void>;
+
 export function RunTask(arg1:string):Promise<void>;
 
 export function SelectWorkspace():Promise<string>;
@@ -51,6 +66,10 @@ export function SendChatMessage(arg1:string,arg2:string):Promise<main.ChatRespon
 
 export function SetLLMConfig(arg1:main.LLMConfigDTO):Promise<void>;
 
+export function SetTaskSchedule(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function SkipTask(arg1:string):Promise<void>;
+
 export function StartExecution():Promise<void>;
 
 export function StopExecution():Promise<void>;
@@ -2,6 +2,14 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CancelTask(arg1) {
+  return window['go']['main']['App']['CancelTask'](arg1);
+}
+
+export function ClearTaskSchedule(arg1) {
+  return window['go']['main']['App']['ClearTaskSchedule'](arg1);
+}
+
 export function CreateChatSession() {
   return window['go']['main']['App']['CreateChatSession']();
 }
@@ -42,6 +50,10 @@ export function GetPoolSummaries() {
   return window['go']['main']['App']['GetPoolSummaries']();
 }
 
+export function GetTaskRunHistory(arg1) {
+  return window['go']['main']['App']['GetTaskRunHistory'](arg1);
+}
+
 export function GetWorkspace(arg1) {
   return window['go']['main']['App']['GetWorkspace'](arg1);
 }
@@ -50,6 +62,10 @@ export function ListAttempts(arg1) {
   return window['go']['main']['App']['ListAttempts'](arg1);
 }
 
+export function ListDeadLetters(arg1) {
+  return window['go']['main']['App']['ListDeadLetters'](arg1);
+}
+
 export function ListRecentWorkspaces() {
   return window['go']['main']['App']['ListRecentWorkspaces']();
 }
@@ -66,10 +82,18 @@ export function PauseExecution() {
   return window['go']['main']['App']['PauseExecution']();
 }
 
+export function PurgeDeadLetter(arg1, arg2) {
+  return window['go']['main']['App']['PurgeDeadLetter'](arg1, arg2);
+}
+
 export function RemoveWorkspace(arg1) {
   return window['go']['main']['App']['RemoveWorkspace'](arg1);
 }
 
+export function RequeueDeadLetter(arg1, arg2) {
+  return window['go']['main']['App']['RequeueDeadLetter'](arg1, arg2);
+}
+
 export function ResolveBacklogItem(arg1, arg2) {
   return window['go']['main']['App']['ResolveBacklogItem'](arg1, arg2);
 }
@@ -78,6 +102,10 @@ export function ResumeExecution() {
   return window['go']['main']['App']['ResumeExecution']();
 }
 
+export function RetryTask(arg1, arg2) {
+  return window['go']['main']['App']['RetryTask'](arg1, arg2);
+}
+
 export function RunTask(arg1) {
   return window['go']['main']['App']['RunTask'](arg1);
 }
@@ -94,6 +122,14 @@ export function SetLLMConfig(arg1) {
   return window['go']['main']['App']['SetLLMConfig'](arg1);
 }
 
+export function SetTaskSchedule(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetTaskSchedule'](arg1, arg2, arg3);
+}
+
+export function SkipTask(arg1) {
+  return window['go']['main']['App']['SkipTask'](arg1);
+}
+
 export function StartExecution() {
   return window['go']['main']['App']['StartExecution']();
 }
@@ -164,6 +164,139 @@ export namespace ide {
 
 }
 
+export namespace ipc {
+	
+	export class DeadLetter {
+	    jobId: string;
+	    poolId: string;
+	    taskId?: string;
+	    reason: string;
+	    // Go type: time
+	    deadAt: any;
+	    deliveries?: number;
+	    job?: Job;
+	    raw?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeadLetter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.jobId = source["jobId"];
+	        this.poolId = source["poolId"];
+	        this.taskId = source["taskId"];
+	        this.reason = source["reason"];
+	        this.deadAt = this.convertValues(source["deadAt"], null);
+	        this.deliveries = source["deliveries"];
+	        this.job = this.convertValues(source["job"], Job);
+	        this.raw = source["raw"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Job {
+	    id: string;
+	    taskId: string;
+	    poolId: string;
+	    payload: any;
+	    priority?: number;
+	    dependents?: number;
+	    // Go type: time
+	    enqueuedAt?: any;
+	    deliveries?: number;
+	    lease?: Lease;
+	
+	    static createFrom(source: any = {}) {
+	        return new Job(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.taskId = source["taskId"];
+	        this.poolId = source["poolId"];
+	        this.payload = source["payload"];
+	        this.priority = source["priority"];
+	        this.dependents = source["dependents"];
+	        this.enqueuedAt = this.convertValues(source["enqueuedAt"], null);
+	        this.deliveries = source["deliveries"];
+	        this.lease = this.convertValues(source["lease"], Lease);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Lease {
+	    owner: string;
+	    // Go type: time
+	    claimedAt: any;
+	    // Go type: time
+	    expiresAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Lease(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.owner = source["owner"];
+	        this.claimedAt = this.convertValues(source["claimedAt"], null);
+	        this.expiresAt = this.convertValues(source["expiresAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace main {
 	
 	export class ChatResponseDTO {
@@ -397,6 +530,7 @@ export namespace orchestrator {
 	export class RunnerSpec {
 	    maxLoops?: number;
 	    workerKind?: string;
+	    reasoningEffort?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new RunnerSpec(source);
@@ -406,8 +540,51 @@ export namespace orchestrator {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.maxLoops = source["maxLoops"];
 	        this.workerKind = source["workerKind"];
+	        this.reasoningEffort = source["reasoningEffort"];
 	    }
 	}
+	export class ScheduleRun {
+	    run: number;
+	    // Go type: time
+	    triggeredAt: any;
+	    // Go type: time
+	    finishedAt?: any;
+	    status?: string;
+	    attempts?: number;
+	    lastError?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScheduleRun(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.run = source["run"];
+	        this.triggeredAt = this.convertValues(source["triggeredAt"], null);
+	        this.finishedAt = this.convertValues(source["finishedAt"], null);
+	        this.status = source["status"];
+	        this.attempts = source["attempts"];
+	        this.lastError = source["lastError"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class SuggestedImpl {
 	    language?: string;
 	    filePaths?: string[];
@@ -448,6 +625,10 @@ export namespace orchestrator {
 	    attemptCount?: number;
 	    // Go type: time
 	    nextRetryAt?: any;
+	    lastError?: string;
+	    schedule?: TaskSchedule;
+	    // Go type: time
+	    nextRunAt?: any;
 	    suggestedImpl?: SuggestedImpl;
 	    artifacts?: Artifacts;
 	    runner?: RunnerSpec;
@@ -476,6 +657,9 @@ export namespace orchestrator {
 	        this.acceptanceCriteria = source["acceptanceCriteria"];
 	        this.attemptCount = source["attemptCount"];
 	        this.nextRetryAt = this.convertValues(source["nextRetryAt"], null);
+	        this.lastError = source["lastError"];
+	        this.schedule = this.convertValues(source["schedule"], TaskSchedule);
+	        this.nextRunAt = this.convertValues(source["nextRunAt"], null);
 	        this.suggestedImpl = this.convertValues(source["suggestedImpl"], SuggestedImpl);
 	        this.artifacts = this.convertValues(source["artifacts"], Artifacts);
 	        this.runner = this.convertValues(source["runner"], RunnerSpec);
@@ -500,5 +684,38 @@ export namespace orchestrator {
 		}
 	}
 
+	export class TaskSchedule {
+	    // Go type: time
+	    runAt?: any;
+	    cron?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TaskSchedule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.runAt = this.convertValues(source["runAt"], null);
+	        this.cron = source["cron"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 }
 
@@ -0,0 +1,102 @@
+package agenttools
+
+import (
+	"strings"
+)
+
+// FailureClass categorizes why a provider invocation failed.
+type FailureClass string
+
+const (
+	FailureNone        FailureClass = ""            // 失敗ではない、または分類不能
+	FailureRateLimit   FailureClass = "rate_limit"  // レート制限・クォータ超過
+	FailureAuth        FailureClass = "auth"        // 認証エラー・未ログイン
+	FailureUnavailable FailureClass = "unavailable" // CLI が存在しない・起動できない
+)
+
+// Retryable reports whether another provider should be tried for this failure.
+func (c FailureClass) Retryable() bool {
+	return c == FailureRateLimit || c == FailureAuth || c == FailureUnavailable
+}
+
+// FailureClassifier is implemented by providers that can recognize their own
+// rate-limit / auth errors from the exit code and combined output.
+type FailureClassifier interface {
+	ClassifyFailure(exitCode int, output string) FailureClass
+}
+
+// failurePatterns holds lower-case substrings that identify a failure class.
+type failurePatterns struct {
+	rateLimit   []string
+	auth        []string
+	unavailable []string
+}
+
+var commonFailurePatterns = failurePatterns{
+	rateLimit: []string{
+		"rate limit",
+		"rate_limit",
+		"ratelimit",
+		"too many requests",
+		"quota exceeded",
+		"exceeded your current quota",
+		"usage limit",
+	},
+	auth: []string{
+		"unauthorized",
+		"unauthenticated",
+		"authentication failed",
+		"invalid api key",
+		"invalid_api_key",
+		"not logged in",
+		"please log in",
+		"please login",
+	},
+	unavailable: []string{
+		"command not found",
+		"executable file not found",
+	},
+}
+
+// classifyWith classifies using the common patterns plus provider-specific ones.
+// exit code 0 は常に成功扱い。127（command not found）は unavailable。
+func classifyWith(exitCode int, output string, extra failurePatterns) FailureClass {
+	if exitCode == 0 {
+		return FailureNone
+	}
+	if exitCode == 127 {
+		return FailureUnavailable
+	}
+	lower := strings.ToLower(output)
+	if containsAny(lower, commonFailurePatterns.rateLimit) || containsAny(lower, extra.rateLimit) {
+		return FailureRateLimit
+	}
+	if containsAny(lower, commonFailurePatterns.auth) || containsAny(lower, extra.auth) {
+		return FailureAuth
+	}
+	if exitCode == 126 || containsAny(lower, commonFailurePatterns.unavailable) || containsAny(lower, extra.unavailable) {
+		return FailureUnavailable
+	}
+	return FailureNone
+}
+
+func containsAny(s string, patterns []string) bool {
+	for _, p := range patterns {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyFailure classifies a failed invocation of the given provider kind.
+// Providers implementing FailureClassifier are consulted first; others fall
+// back to the common patterns.
+func ClassifyFailure(kind string, exitCode int, output string) FailureClass {
+	if p, err := New(kind, ProviderConfig{Kind: kind}); err == nil {
+		if c, ok := p.(FailureClassifier); ok {
+			return c.ClassifyFailure(exitCode, output)
+		}
+	}
+	return classifyWith(exitCode, output, failurePatterns{})
+}
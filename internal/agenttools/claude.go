@@ -2,7 +2,11 @@ package agenttools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+
+	"github.com/biwakonbu/agent-runner/internal/usage"
 )
 
 // DefaultClaudeModel defines the default model for Claude Code.
@@ -55,9 +59,9 @@ func (p *ClaudeProvider) Build(_ context.Context, req Request) (ExecPlan, error)
 	}
 
 	// claude-code usually handles conversation or single shot.
-	// We map 'exec' to single shot or piped input.
-	if mode != "exec" {
-		return ExecPlan{}, fmt.Errorf("%w: %s (only 'exec' is supported)", ErrUnsupportedMode, mode)
+	// We map 'exec' to single shot or piped input, and 'resume' to --resume / --continue.
+	if mode != "exec" && mode != ModeResume {
+		return ExecPlan{}, fmt.Errorf("%w: %s (only 'exec' and 'resume' are supported)", ErrUnsupportedMode, mode)
 	}
 
 	// CLI実行フラグ構築
@@ -69,6 +73,20 @@ func (p *ClaudeProvider) Build(_ context.Context, req Request) (ExecPlan, error)
 	model := nonEmpty(req.Model, p.model, DefaultClaudeModel)
 	args = append(args, "--model", model)
 
+	// Session continuation
+	if mode == ModeResume {
+		if req.SessionID != "" {
+			args = append(args, "--resume", req.SessionID)
+		} else {
+			args = append(args, "--continue")
+		}
+	}
+
+	// Structured output (session_id を取得するために必要)
+	if v, ok := req.ToolSpecific["json_output"].(bool); ok && v {
+		args = append(args, "--output-format", "json")
+	}
+
 	// Extra flags
 	args = append(args, p.flags...)
 	args = append(args, req.Flags...)
@@ -96,6 +114,49 @@ func (p *ClaudeProvider) Build(_ context.Context, req Request) (ExecPlan, error)
 	return plan, nil
 }
 
+// ExtractSessionID reads session_id from `--output-format json` output.
+func (p *ClaudeProvider) ExtractSessionID(output string) string {
+	return findSessionID(output, "session_id")
+}
+
+// ExtractUsage reads the usage block of `--output-format json` results.
+// Claude の input_tokens はキャッシュ分を含まないため、キャッシュ読み書き分を加算する。
+func (p *ClaudeProvider) ExtractUsage(output string) usage.Usage {
+	var total usage.Usage
+	add := func(obj map[string]interface{}) {
+		if obj["type"] != "result" {
+			return
+		}
+		u, ok := obj["usage"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		cacheRead := intField(u, "cache_read_input_tokens")
+		total = total.Add(usage.Usage{
+			InputTokens:       intField(u, "input_tokens") + intField(u, "cache_creation_input_tokens") + cacheRead,
+			CachedInputTokens: cacheRead,
+			OutputTokens:      intField(u, "output_tokens"),
+		})
+	}
+	jsonLines(output, add)
+	if total.IsZero() {
+		// 整形済み（複数行）の単一 JSON
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &obj); err == nil {
+			add(obj)
+		}
+	}
+	return total
+}
+
+// ClassifyFailure detects Claude Code usage-limit and login errors.
+func (p *ClaudeProvider) ClassifyFailure(exitCode int, output string) FailureClass {
+	return classifyWith(exitCode, output, failurePatterns{
+		rateLimit: []string{"usage limit reached", "overloaded_error", "rate_limit_error"},
+		auth:      []string{"/login", "authentication_error", "oauth token"},
+	})
+}
+
 func init() {
 	Register("claude-code", func(cfg ProviderConfig) (AgentToolProvider, error) {
 		return NewClaudeProvider(cfg), nil
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/biwakonbu/agent-runner/internal/usage"
 )
 
 // DefaultCodexModel は Codex CLI のデフォルトモデル（Worker 用）
@@ -45,7 +47,7 @@ func (p *CodexProvider) Capabilities() Capability {
 		Kind:          p.Kind(),
 		DefaultModel:  nonEmpty(p.model, DefaultCodexModel),
 		SupportsStdin: true,
-		Notes:         "Codex CLI 0.65.0. Docker 内実行専用。exec モードのみサポート。",
+		Notes:         "Codex CLI 0.65.0. Docker 内実行専用。exec / resume モードをサポート。",
 	}
 }
 
@@ -59,18 +61,21 @@ func (p *CodexProvider) Capabilities() Capability {
 // ToolSpecific オプション:
 //   - docker_mode: bool - true の場合、Docker 内実行用フラグを追加（デフォルト: true）
 //   - json_output: bool - true の場合、--json フラグを追加（デフォルト: true）
+//
+// mode=resume の場合は req.SessionID のセッションを継続する（空なら --last）。
 func (p *CodexProvider) Build(_ context.Context, req Request) (ExecPlan, error) {
 	if err := ensurePrompt(req.Prompt); err != nil {
 		return ExecPlan{}, err
 	}
 
-	// exec モードのみサポート（chat サブコマンドは Codex CLI に存在しない）
+	// exec / resume モードをサポート（chat サブコマンドは Codex CLI に存在しない）
+	// resume は `codex exec [OPTIONS] resume <SESSION_ID|--last> <PROMPT>` に対応
 	mode := req.Mode
 	if mode == "" {
 		mode = "exec"
 	}
-	if mode != "exec" {
-		return ExecPlan{}, fmt.Errorf("%w: %s (only 'exec' is supported)", ErrUnsupportedMode, mode)
+	if mode != "exec" && mode != ModeResume {
+		return ExecPlan{}, fmt.Errorf("%w: %s (only 'exec' and 'resume' are supported)", ErrUnsupportedMode, mode)
 	}
 
 	// Docker モードかどうか（デフォルト: true = Worker 実行用）
@@ -154,6 +159,15 @@ func (p *CodexProvider) Build(_ context.Context, req Request) (ExecPlan, error)
 		Timeout: req.Timeout,
 	}
 
+	// セッション継続
+	if mode == ModeResume {
+		if req.SessionID != "" {
+			plan.Args = append(plan.Args, "resume", req.SessionID)
+		} else {
+			plan.Args = append(plan.Args, "resume", "--last")
+		}
+	}
+
 	// プロンプト（stdin 使用時は "-" を指定）
 	if req.UseStdin {
 		plan.Args = append(plan.Args, "-")
@@ -166,6 +180,55 @@ func (p *CodexProvider) Build(_ context.Context, req Request) (ExecPlan, error)
 	return plan, nil
 }
 
+// ExtractSessionID は --json 出力の thread.started / session_configured イベントから
+// セッション ID を取り出す。
+func (p *CodexProvider) ExtractSessionID(output string) string {
+	return findSessionID(output, "thread_id", "session_id")
+}
+
+// ExtractUsage は --json 出力からトークン使用量を集計する。
+//   - turn.completed イベントの usage を合算
+//   - 旧形式（token_count イベント）の場合は最後の total_token_usage を採用
+func (p *CodexProvider) ExtractUsage(output string) usage.Usage {
+	var turns, lastTotal usage.Usage
+	jsonLines(output, func(obj map[string]interface{}) {
+		if obj["type"] == "turn.completed" {
+			if u, ok := obj["usage"].(map[string]interface{}); ok {
+				turns = turns.Add(usage.Usage{
+					InputTokens:       intField(u, "input_tokens"),
+					CachedInputTokens: intField(u, "cached_input_tokens"),
+					OutputTokens:      intField(u, "output_tokens"),
+				})
+			}
+			return
+		}
+		msg, ok := obj["msg"].(map[string]interface{})
+		if !ok || msg["type"] != "token_count" {
+			return
+		}
+		info, _ := msg["info"].(map[string]interface{})
+		if total, ok := info["total_token_usage"].(map[string]interface{}); ok {
+			lastTotal = usage.Usage{
+				InputTokens:       intField(total, "input_tokens"),
+				CachedInputTokens: intField(total, "cached_input_tokens"),
+				OutputTokens:      intField(total, "output_tokens"),
+			}
+		}
+	})
+	if !turns.IsZero() {
+		return turns
+	}
+	return lastTotal
+}
+
+// ClassifyFailure detects Codex CLI quota and login errors.
+func (p *CodexProvider) ClassifyFailure(exitCode int, output string) FailureClass {
+	return classifyWith(exitCode, output, failurePatterns{
+		rateLimit: []string{"you've hit your usage limit", "insufficient_quota"},
+		auth:      []string{"codex login", "token_expired", "refresh token"},
+	})
+}
+
 func init() {
 	Register("codex-cli", func(cfg ProviderConfig) (AgentToolProvider, error) {
 		return NewCodexProvider(cfg), nil
@@ -0,0 +1,316 @@
+package agenttools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrBuiltinKindConflict is returned when a declarative provider reuses a built-in kind.
+var ErrBuiltinKindConflict = errors.New("agent tool provider kind conflicts with built-in provider")
+
+// DeclarativeProvidersDir is the directory (relative to a workspace or ~/.multiverse)
+// that holds declarative provider definitions.
+const DeclarativeProvidersDir = "agent-tools"
+
+// CredentialMount describes a host path mounted into the worker container.
+type CredentialMount struct {
+	Source   string `yaml:"source"`   // ホスト側パス（~ 展開、相対パスはホーム基準）
+	Target   string `yaml:"target"`   // コンテナ内パス
+	ReadOnly bool   `yaml:"readonly"` // 読み取り専用でマウントするか
+}
+
+// DeclarativeSpec is the YAML definition of an agent tool provider.
+//
+// Args are text/template strings rendered with DeclarativeArgs.
+// An argument that renders to an empty string is dropped, so optional
+// flags can be written as `{{if .Model}}--model={{.Model}}{{end}}`.
+type DeclarativeSpec struct {
+	Kind             string            `yaml:"kind"`
+	Binary           string            `yaml:"binary"`
+	Args             []string          `yaml:"args"`
+	Modes            []string          `yaml:"modes,omitempty"`
+	DefaultModel     string            `yaml:"default_model,omitempty"`
+	Env              map[string]string `yaml:"env,omitempty"`
+	CredentialMounts []CredentialMount `yaml:"credential_mounts,omitempty"`
+	SupportsStdin    bool              `yaml:"supports_stdin,omitempty"`
+	Notes            string            `yaml:"notes,omitempty"`
+}
+
+// DeclarativeArgs are the placeholders available to argument templates.
+type DeclarativeArgs struct {
+	Model           string
+	Prompt          string // UseStdin 時は空文字
+	Workdir         string
+	Stdin           string // UseStdin 時は "-"、それ以外は空文字
+	Mode            string
+	ReasoningEffort string
+	SessionID       string // mode=resume 時に継続するセッション ID
+	UseStdin        bool
+}
+
+// Validate checks required fields and pre-parses the argument templates.
+func (s DeclarativeSpec) Validate() error {
+	if s.Kind == "" {
+		return fmt.Errorf("declarative provider: kind is required")
+	}
+	if s.Binary == "" {
+		return fmt.Errorf("declarative provider %s: binary is required", s.Kind)
+	}
+	for i, arg := range s.Args {
+		if _, err := template.New("arg").Option("missingkey=error").Parse(arg); err != nil {
+			return fmt.Errorf("declarative provider %s: invalid args[%d] template: %w", s.Kind, i, err)
+		}
+	}
+	for _, m := range s.CredentialMounts {
+		if m.Source == "" || m.Target == "" {
+			return fmt.Errorf("declarative provider %s: credential mount requires source and target", s.Kind)
+		}
+	}
+	return nil
+}
+
+// DeclarativeProvider builds ExecPlan from a DeclarativeSpec.
+type DeclarativeProvider struct {
+	spec    DeclarativeSpec
+	args    []*template.Template
+	cliPath string
+	model   string
+	env     map[string]string
+	flags   []string
+}
+
+// NewDeclarativeProvider constructs a provider from a spec and runtime config.
+func NewDeclarativeProvider(spec DeclarativeSpec, cfg ProviderConfig) (*DeclarativeProvider, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	tmpls := make([]*template.Template, 0, len(spec.Args))
+	for i, arg := range spec.Args {
+		t, err := template.New(fmt.Sprintf("%s-arg-%d", spec.Kind, i)).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("declarative provider %s: invalid args[%d] template: %w", spec.Kind, i, err)
+		}
+		tmpls = append(tmpls, t)
+	}
+	return &DeclarativeProvider{
+		spec:    spec,
+		args:    tmpls,
+		cliPath: nonEmpty(cfg.CLIPath, spec.Binary),
+		model:   cfg.Model,
+		env:     mergeEnv(spec.Env, cfg.ExtraEnv),
+		flags:   append([]string{}, cfg.Flags...),
+	}, nil
+}
+
+func (p *DeclarativeProvider) Kind() string {
+	return p.spec.Kind
+}
+
+func (p *DeclarativeProvider) Capabilities() Capability {
+	return Capability{
+		Kind:          p.spec.Kind,
+		DefaultModel:  nonEmpty(p.model, p.spec.DefaultModel),
+		SupportsStdin: p.spec.SupportsStdin,
+		Notes:         nonEmpty(p.spec.Notes, "Declarative provider loaded from YAML."),
+	}
+}
+
+// CredentialMounts returns host paths to be mounted into the worker container.
+func (p *DeclarativeProvider) CredentialMounts() []CredentialMount {
+	return append([]CredentialMount{}, p.spec.CredentialMounts...)
+}
+
+// Build renders the argument templates into an ExecPlan.
+func (p *DeclarativeProvider) Build(_ context.Context, req Request) (ExecPlan, error) {
+	if err := ensurePrompt(req.Prompt); err != nil {
+		return ExecPlan{}, err
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = "exec"
+	}
+	if !p.supportsMode(mode) {
+		return ExecPlan{}, fmt.Errorf("%w: %s (supported: %s)", ErrUnsupportedMode, mode, strings.Join(p.modes(), ", "))
+	}
+
+	useStdin := req.UseStdin && p.spec.SupportsStdin
+	data := DeclarativeArgs{
+		Model:           nonEmpty(req.Model, p.model, p.spec.DefaultModel),
+		Workdir:         req.Workdir,
+		Mode:            mode,
+		ReasoningEffort: req.ReasoningEffort,
+		SessionID:       req.SessionID,
+		UseStdin:        useStdin,
+	}
+	if useStdin {
+		data.Stdin = "-"
+	} else {
+		data.Prompt = req.Prompt
+	}
+
+	args := make([]string, 0, len(p.args)+len(p.flags)+len(req.Flags))
+	for _, t := range p.args {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return ExecPlan{}, fmt.Errorf("declarative provider %s: failed to render args: %w", p.spec.Kind, err)
+		}
+		if buf.Len() == 0 {
+			continue
+		}
+		args = append(args, buf.String())
+	}
+	args = append(args, p.flags...)
+	args = append(args, req.Flags...)
+
+	plan := ExecPlan{
+		Command: p.cliPath,
+		Args:    args,
+		Env:     mergeEnv(p.env, req.ExtraEnv),
+		Workdir: req.Workdir,
+		Timeout: req.Timeout,
+	}
+	if useStdin {
+		plan.Stdin = req.Prompt
+	}
+	return plan, nil
+}
+
+func (p *DeclarativeProvider) modes() []string {
+	if len(p.spec.Modes) == 0 {
+		return []string{"exec"}
+	}
+	return p.spec.Modes
+}
+
+func (p *DeclarativeProvider) supportsMode(mode string) bool {
+	for _, m := range p.modes() {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadDeclarativeSpecs reads every *.yaml / *.yml file in the given directories.
+// Missing directories are skipped. When the same kind appears more than once,
+// the definition from the later directory wins (e.g. workspace over ~/.multiverse).
+// 読み込み・解析・検証に失敗したファイルは読み飛ばし、正常な定義と共に
+// ファイルごとのエラーをまとめて返す。
+func LoadDeclarativeSpecs(dirs ...string) ([]DeclarativeSpec, error) {
+	byKind := make(map[string]DeclarativeSpec)
+	var errs []error
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to read provider dir %s: %w", dir, err))
+			continue
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read provider file %s: %w", path, err))
+				continue
+			}
+			var spec DeclarativeSpec
+			if err := yaml.Unmarshal(data, &spec); err != nil {
+				errs = append(errs, fmt.Errorf("failed to parse provider file %s: %w", path, err))
+				continue
+			}
+			if err := spec.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+				continue
+			}
+			byKind[spec.Kind] = spec
+		}
+	}
+
+	specs := make([]DeclarativeSpec, 0, len(byKind))
+	for _, spec := range byKind {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Kind < specs[j].Kind })
+	return specs, errors.Join(errs...)
+}
+
+// RegisterDeclarative registers (or replaces) a declarative provider.
+// Built-in kinds cannot be overridden.
+func RegisterDeclarative(spec DeclarativeSpec) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[spec.Kind]; exists && !declarativeKinds[spec.Kind] {
+		return fmt.Errorf("%w: %s", ErrBuiltinKindConflict, spec.Kind)
+	}
+	registry[spec.Kind] = func(cfg ProviderConfig) (AgentToolProvider, error) {
+		return NewDeclarativeProvider(spec, cfg)
+	}
+	declarativeKinds[spec.Kind] = true
+	return nil
+}
+
+// LoadDeclarativeProviders loads definitions from ~/.multiverse/agent-tools
+// followed by <root>/agent-tools for each given root (later roots win),
+// and registers them. It returns the registered kinds. Invalid files and
+// kinds that clash with a built-in provider are skipped; their errors are
+// returned together with the kinds that were registered.
+func LoadDeclarativeProviders(roots ...string) ([]string, error) {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".multiverse", DeclarativeProvidersDir))
+	}
+	for _, root := range roots {
+		if root != "" {
+			dirs = append(dirs, filepath.Join(root, DeclarativeProvidersDir))
+		}
+	}
+
+	specs, err := LoadDeclarativeSpecs(dirs...)
+	errs := []error{err}
+
+	var kinds []string
+	for _, spec := range specs {
+		if err := RegisterDeclarative(spec); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		kinds = append(kinds, spec.Kind)
+	}
+	return kinds, errors.Join(errs...)
+}
+
+// CredentialMountsFor returns the credential mounts declared by the provider of kind.
+// Built-in providers return nil.
+func CredentialMountsFor(kind string, cfg ProviderConfig) []CredentialMount {
+	p, err := New(kind, cfg)
+	if err != nil {
+		return nil
+	}
+	if m, ok := p.(interface{ CredentialMounts() []CredentialMount }); ok {
+		return m.CredentialMounts()
+	}
+	return nil
+}
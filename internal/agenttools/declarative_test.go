@@ -0,0 +1,166 @@
+package agenttools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeSpecFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeclarativeProvider_Build(t *testing.T) {
+	spec := DeclarativeSpec{
+		Kind:          "aider-test",
+		Binary:        "aider",
+		Args:          []string{"--yes", "{{if .Model}}--model={{.Model}}{{end}}", "--message", "{{if .UseStdin}}{{.Stdin}}{{else}}{{.Prompt}}{{end}}"},
+		DefaultModel:  "gpt-4o",
+		Env:           map[string]string{"AIDER_NO_AUTO_COMMITS": "1"},
+		SupportsStdin: true,
+	}
+	p, err := NewDeclarativeProvider(spec, ProviderConfig{Kind: spec.Kind})
+	if err != nil {
+		t.Fatalf("NewDeclarativeProvider failed: %v", err)
+	}
+
+	plan, err := p.Build(context.Background(), Request{Prompt: "fix bug"})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	want := []string{"--yes", "--model=gpt-4o", "--message", "fix bug"}
+	if !reflect.DeepEqual(plan.Args, want) {
+		t.Errorf("Args = %v, want %v", plan.Args, want)
+	}
+	if plan.Command != "aider" {
+		t.Errorf("Command = %q, want aider", plan.Command)
+	}
+	if plan.Env["AIDER_NO_AUTO_COMMITS"] != "1" {
+		t.Errorf("Env not propagated: %v", plan.Env)
+	}
+
+	// stdin 経由
+	plan, err = p.Build(context.Background(), Request{Prompt: "fix bug", UseStdin: true, Model: "o3"})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	want = []string{"--yes", "--model=o3", "--message", "-"}
+	if !reflect.DeepEqual(plan.Args, want) {
+		t.Errorf("Args = %v, want %v", plan.Args, want)
+	}
+	if plan.Stdin != "fix bug" {
+		t.Errorf("Stdin = %q, want prompt", plan.Stdin)
+	}
+
+	// 未対応モード
+	if _, err := p.Build(context.Background(), Request{Prompt: "x", Mode: "chat"}); !errors.Is(err, ErrUnsupportedMode) {
+		t.Errorf("expected ErrUnsupportedMode, got %v", err)
+	}
+}
+
+func TestLoadDeclarativeSpecs_LaterDirWins(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "home")
+	ws := filepath.Join(t.TempDir(), "ws")
+	writeSpecFile(t, home, "tool.yaml", "kind: my-tool\nbinary: home-bin\n")
+	writeSpecFile(t, ws, "tool.yml", "kind: my-tool\nbinary: ws-bin\n")
+	writeSpecFile(t, ws, "README.md", "ignored")
+
+	specs, err := LoadDeclarativeSpecs(home, ws, filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("LoadDeclarativeSpecs failed: %v", err)
+	}
+	if len(specs) != 1 || specs[0].Binary != "ws-bin" {
+		t.Errorf("expected workspace definition to win, got %+v", specs)
+	}
+}
+
+func TestLoadDeclarativeSpecs_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	writeSpecFile(t, dir, "bad.yaml", "kind: bad\nbinary: x\nargs: [\"{{.Prompt\"]\n")
+	writeSpecFile(t, dir, "good.yaml", "kind: good\nbinary: x\n")
+	specs, err := LoadDeclarativeSpecs(dir)
+	if err == nil {
+		t.Error("expected error for invalid template")
+	}
+	// 不正なファイルだけを読み飛ばす
+	if len(specs) != 1 || specs[0].Kind != "good" {
+		t.Errorf("expected the valid definition to be kept, got %+v", specs)
+	}
+}
+
+func TestLoadDeclarativeProviders_SkipsBadFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	dir := filepath.Join(root, DeclarativeProvidersDir)
+	writeSpecFile(t, dir, "good.yaml", "kind: decl-load-good\nbinary: good-bin\nargs: [\"{{.Prompt}}\"]\n")
+	writeSpecFile(t, dir, "broken.yaml", "kind: [not a string\n")
+	writeSpecFile(t, dir, "clash.yaml", "kind: codex-cli\nbinary: fake-codex\n")
+
+	kinds, err := LoadDeclarativeProviders(root)
+	if err == nil {
+		t.Fatal("expected errors for the broken and clashing files")
+	}
+	if !errors.Is(err, ErrBuiltinKindConflict) {
+		t.Errorf("expected ErrBuiltinKindConflict in %v", err)
+	}
+	if !reflect.DeepEqual(kinds, []string{"decl-load-good"}) {
+		t.Fatalf("kinds = %v, want only the good provider", kinds)
+	}
+
+	plan, err := Build(context.Background(), "decl-load-good", ProviderConfig{}, Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if plan.Command != "good-bin" {
+		t.Errorf("Command = %q, want good-bin", plan.Command)
+	}
+	// 組み込みプロバイダは上書きされない
+	if plan, err := Build(context.Background(), "codex-cli", ProviderConfig{}, Request{Prompt: "hi"}); err != nil || plan.Command == "fake-codex" {
+		t.Errorf("built-in codex-cli should be kept, got %+v, %v", plan, err)
+	}
+}
+
+func TestRegisterDeclarative(t *testing.T) {
+	err := RegisterDeclarative(DeclarativeSpec{Kind: "codex-cli", Binary: "codex"})
+	if !errors.Is(err, ErrBuiltinKindConflict) {
+		t.Fatalf("expected ErrBuiltinKindConflict, got %v", err)
+	}
+
+	spec := DeclarativeSpec{
+		Kind:             "decl-register-test",
+		Binary:           "tool",
+		Args:             []string{"{{.Prompt}}"},
+		CredentialMounts: []CredentialMount{{Source: "~/.tool", Target: "/root/.tool", ReadOnly: true}},
+	}
+	if err := RegisterDeclarative(spec); err != nil {
+		t.Fatalf("RegisterDeclarative failed: %v", err)
+	}
+	// 宣言的プロバイダ同士は再登録（上書き）可能
+	spec.Binary = "tool2"
+	if err := RegisterDeclarative(spec); err != nil {
+		t.Fatalf("re-register failed: %v", err)
+	}
+
+	plan, err := Build(context.Background(), spec.Kind, ProviderConfig{}, Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if plan.Command != "tool2" {
+		t.Errorf("Command = %q, want tool2", plan.Command)
+	}
+	if mounts := CredentialMountsFor(spec.Kind, ProviderConfig{}); len(mounts) != 1 || mounts[0].Target != "/root/.tool" {
+		t.Errorf("unexpected mounts: %+v", mounts)
+	}
+	if mounts := CredentialMountsFor("codex-cli", ProviderConfig{}); mounts != nil {
+		t.Errorf("built-in provider should have no mounts, got %+v", mounts)
+	}
+}
@@ -1,10 +1,12 @@
 package agenttools
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -22,6 +24,16 @@ const GracefulShutdownDelay = 5 * time.Second
 // Execute は ExecPlan を実行し、結果を返す
 // Docker コンテナ内ではなくホスト上で直接実行する場合に使用
 func Execute(ctx context.Context, plan ExecPlan) ExecResult {
+	return execute(ctx, plan, nil)
+}
+
+// ExecuteStream は Execute と同様に ExecPlan を実行し、stdout/stderr を受信するたびに onOutput へ渡す。
+// Execute と異なり、呼び出し元コンテキストがキャンセルされた場合もプロセスを停止する。
+func ExecuteStream(ctx context.Context, plan ExecPlan, onOutput func(chunk string)) ExecResult {
+	return execute(ctx, plan, onOutput)
+}
+
+func execute(ctx context.Context, plan ExecPlan, onOutput func(chunk string)) ExecResult {
 	// plan.Timeout が設定されている場合、親コンテキストから独立したコンテキストを作成
 	// これにより、親コンテキストのキャンセルに影響されずに実行できる
 	execCtx := ctx
@@ -29,6 +41,11 @@ func Execute(ctx context.Context, plan ExecPlan) ExecResult {
 		var cancel context.CancelFunc
 		execCtx, cancel = context.WithTimeout(context.Background(), plan.Timeout)
 		defer cancel()
+		// ストリーミング時は呼び出し元が待っているため、そのキャンセルに追従する
+		if onOutput != nil {
+			stop := context.AfterFunc(ctx, cancel)
+			defer stop()
+		}
 	}
 
 	cmd := exec.CommandContext(execCtx, plan.Command, plan.Args...)
@@ -59,7 +76,20 @@ func Execute(ctx context.Context, plan ExecPlan) ExecResult {
 		cmd.Stdin = strings.NewReader(plan.Stdin)
 	}
 
-	output, err := cmd.CombinedOutput()
+	var output []byte
+	var err error
+	if onOutput == nil {
+		output, err = cmd.CombinedOutput()
+	} else {
+		w := &streamWriter{onOutput: onOutput}
+		cmd.Stdout = w
+		cmd.Stderr = w
+		err = cmd.Run()
+		output = w.Bytes()
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+	}
 
 	result := ExecResult{
 		ExitCode: 0,
@@ -77,3 +107,24 @@ func Execute(ctx context.Context, plan ExecPlan) ExecResult {
 
 	return result
 }
+
+// streamWriter は出力を蓄積しつつ、書き込みごとに onOutput を呼ぶ
+type streamWriter struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	onOutput func(chunk string)
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	w.onOutput(string(p))
+	return len(p), nil
+}
+
+func (w *streamWriter) Bytes() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Bytes()
+}
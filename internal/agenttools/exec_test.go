@@ -0,0 +1,47 @@
+package agenttools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecuteStream_ForwardsOutput(t *testing.T) {
+	var mu sync.Mutex
+	var chunks []string
+	plan := ExecPlan{Command: "sh", Args: []string{"-c", "echo one; sleep 0.1; echo two >&2"}, Timeout: 10 * time.Second}
+
+	result := ExecuteStream(context.Background(), plan, func(chunk string) {
+		mu.Lock()
+		defer mu.Unlock()
+		chunks = append(chunks, chunk)
+	})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if result.Output != "one\ntwo\n" {
+		t.Errorf("Output = %q", result.Output)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(chunks) < 2 || strings.Join(chunks, "") != result.Output {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
+func TestExecuteStream_CancelledByCaller(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	plan := ExecPlan{Command: "sh", Args: []string{"-c", "echo start; sleep 30"}, Timeout: time.Minute}
+
+	start := time.Now()
+	result := ExecuteStream(ctx, plan, func(string) { cancel() })
+	if !errors.Is(result.Error, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", result.Error)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("process was not stopped promptly: %v", elapsed)
+	}
+}
@@ -118,6 +118,14 @@ func (p *GeminiProvider) Build(_ context.Context, req Request) (ExecPlan, error)
 	return plan, nil
 }
 
+// ClassifyFailure detects Gemini CLI quota and credential errors.
+func (p *GeminiProvider) ClassifyFailure(exitCode int, output string) FailureClass {
+	return classifyWith(exitCode, output, failurePatterns{
+		rateLimit: []string{"resource_exhausted"},
+		auth:      []string{"permission_denied", "api key not valid", "gemini_api_key"},
+	})
+}
+
 func init() {
 	Register("gemini-cli", func(cfg ProviderConfig) (AgentToolProvider, error) {
 		return NewGeminiProvider(cfg), nil
@@ -215,8 +215,8 @@ func TestCodexProvider_Build_ChatModeRejected(t *testing.T) {
 		t.Fatal("Build() should have failed for chat mode")
 	}
 
-	if !strings.Contains(err.Error(), "only 'exec' is supported") {
-		t.Errorf("Error should mention exec only, got: %v", err)
+	if !strings.Contains(err.Error(), "only 'exec' and 'resume' are supported") {
+		t.Errorf("Error should mention supported modes, got: %v", err)
 	}
 }
 
@@ -341,3 +341,117 @@ func TestGeminiProvider_Build_Options(t *testing.T) {
 		t.Errorf("Last arg should be '-', got: %s", plan.Args[len(plan.Args)-1])
 	}
 }
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		kind     string
+		exitCode int
+		output   string
+		want     FailureClass
+	}{
+		{"codex-cli", 0, "rate limit", FailureNone},
+		{"codex-cli", 1, "You've hit your usage limit", FailureRateLimit},
+		{"codex-cli", 1, "Please run `codex login`", FailureAuth},
+		{"claude-code", 1, "Invalid API key · Please run /login", FailureAuth},
+		{"claude-code", 1, "Claude AI usage limit reached|1700000000", FailureRateLimit},
+		{"gemini-cli", 1, "status: RESOURCE_EXHAUSTED", FailureRateLimit},
+		{"gemini-cli", 127, "", FailureUnavailable},
+		{"cursor-cli", 1, "429 Too Many Requests", FailureRateLimit},
+		{"codex-cli", 1, "compile error: undefined: foo", FailureNone},
+	}
+	for _, tt := range tests {
+		if got := ClassifyFailure(tt.kind, tt.exitCode, tt.output); got != tt.want {
+			t.Errorf("ClassifyFailure(%s, %d, %q) = %q, want %q", tt.kind, tt.exitCode, tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestCodexProvider_Build_Resume(t *testing.T) {
+	p := NewCodexProvider(ProviderConfig{Kind: "codex-cli"})
+
+	plan, err := p.Build(context.Background(), Request{Prompt: "continue", Mode: ModeResume, SessionID: "abc-123"})
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	args := strings.Join(plan.Args, " ")
+	if !strings.HasPrefix(args, "exec ") || !strings.HasSuffix(args, "resume abc-123 continue") {
+		t.Errorf("unexpected resume args: %s", args)
+	}
+
+	plan, err = p.Build(context.Background(), Request{Prompt: "continue", Mode: ModeResume, UseStdin: true})
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	if args := strings.Join(plan.Args, " "); !strings.HasSuffix(args, "resume --last -") {
+		t.Errorf("unexpected resume --last args: %s", args)
+	}
+}
+
+func TestClaudeProvider_Build_Resume(t *testing.T) {
+	p := NewClaudeProvider(ProviderConfig{Kind: "claude-code"})
+
+	plan, err := p.Build(context.Background(), Request{
+		Prompt:       "continue",
+		Mode:         ModeResume,
+		SessionID:    "sess-1",
+		ToolSpecific: map[string]interface{}{"json_output": true},
+	})
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	args := strings.Join(plan.Args, " ")
+	if !strings.Contains(args, "--resume sess-1") || !strings.Contains(args, "--output-format json") {
+		t.Errorf("unexpected resume args: %s", args)
+	}
+
+	plan, _ = p.Build(context.Background(), Request{Prompt: "continue", Mode: ModeResume})
+	if args := strings.Join(plan.Args, " "); !strings.Contains(args, "--continue") {
+		t.Errorf("expected --continue without session id: %s", args)
+	}
+}
+
+func TestExtractSessionID(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   string
+		output string
+		want   string
+	}{
+		{"codex thread.started", "codex-cli", "noise\n{\"type\":\"thread.started\",\"thread_id\":\"th-1\"}\n{\"type\":\"turn.completed\"}", "th-1"},
+		{"codex session_configured", "codex-cli", `{"id":"0","msg":{"type":"session_configured","session_id":"s-2"}}`, "s-2"},
+		{"claude json result", "claude-code", "{\n  \"type\": \"result\",\n  \"session_id\": \"c-3\"\n}", "c-3"},
+		{"plain text", "claude-code", "done", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractSessionID(tt.kind, tt.output); got != tt.want {
+				t.Errorf("ExtractSessionID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractUsage(t *testing.T) {
+	codexOut := `{"type":"thread.started","thread_id":"t"}
+{"type":"turn.completed","usage":{"input_tokens":100,"cached_input_tokens":40,"output_tokens":10}}
+{"type":"turn.completed","usage":{"input_tokens":50,"cached_input_tokens":0,"output_tokens":5}}`
+	u := ExtractUsage("codex-cli", "gpt-5.1-codex", codexOut)
+	if u.InputTokens != 150 || u.CachedInputTokens != 40 || u.OutputTokens != 15 || u.Model != "gpt-5.1-codex" {
+		t.Errorf("unexpected codex usage: %+v", u)
+	}
+
+	legacyOut := `{"id":"1","msg":{"type":"token_count","info":{"total_token_usage":{"input_tokens":10,"output_tokens":1}}}}
+{"id":"2","msg":{"type":"token_count","info":{"total_token_usage":{"input_tokens":20,"output_tokens":2}}}}`
+	if u := ExtractUsage("codex-cli", "m", legacyOut); u.InputTokens != 20 || u.OutputTokens != 2 {
+		t.Errorf("unexpected legacy codex usage: %+v", u)
+	}
+
+	claudeOut := `{"type":"result","session_id":"s","usage":{"input_tokens":10,"cache_creation_input_tokens":5,"cache_read_input_tokens":100,"output_tokens":20}}`
+	if u := ExtractUsage("claude-code", "claude-x", claudeOut); u.InputTokens != 115 || u.CachedInputTokens != 100 || u.OutputTokens != 20 {
+		t.Errorf("unexpected claude usage: %+v", u)
+	}
+
+	if u := ExtractUsage("claude-code", "claude-x", "plain text"); !u.IsZero() || u.Model != "" {
+		t.Errorf("expected zero usage for plain text, got %+v", u)
+	}
+}
@@ -3,6 +3,7 @@ package agenttools
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -12,6 +13,8 @@ type ProviderFactory func(cfg ProviderConfig) (AgentToolProvider, error)
 var (
 	registryMu sync.RWMutex
 	registry   = map[string]ProviderFactory{}
+	// declarativeKinds は YAML 定義から登録された kind（上書き可能）
+	declarativeKinds = map[string]bool{}
 )
 
 // Register attaches a provider factory by kind.
@@ -36,6 +39,18 @@ func New(kind string, cfg ProviderConfig) (AgentToolProvider, error) {
 	return factory(cfg)
 }
 
+// Kinds returns all registered provider kinds.
+func Kinds() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	kinds := make([]string, 0, len(registry))
+	for k := range registry {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
 // MustNew is a helper that panics on failure (useful for tests).
 func MustNew(kind string, cfg ProviderConfig) AgentToolProvider {
 	p, err := New(kind, cfg)
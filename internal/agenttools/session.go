@@ -0,0 +1,75 @@
+package agenttools
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+)
+
+// ModeResume は直前のセッションを継続して実行するモード。
+// Request.SessionID が空の場合は各 CLI の「最後のセッション」を再開する。
+const ModeResume = "resume"
+
+// SessionExtractor is implemented by providers that report a session ID in
+// their structured (JSON / JSONL) output.
+type SessionExtractor interface {
+	ExtractSessionID(output string) string
+}
+
+// ExtractSessionID returns the session ID reported by the provider of kind,
+// or an empty string when none was found.
+func ExtractSessionID(kind, output string) string {
+	if p, err := New(kind, ProviderConfig{Kind: kind}); err == nil {
+		if e, ok := p.(SessionExtractor); ok {
+			return e.ExtractSessionID(output)
+		}
+	}
+	return findSessionID(output, "session_id")
+}
+
+// findSessionID scans JSON objects (one per line, or a single document) for the
+// first non-empty string value under any of keys, searching nested objects.
+// 非 JSON 行は無視する。
+func findSessionID(output string, keys ...string) string {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			continue
+		}
+		if id := lookupString(obj, keys); id != "" {
+			return id
+		}
+	}
+
+	// 整形済み（複数行）の単一 JSON ドキュメント
+	trimmed := strings.TrimSpace(output)
+	if strings.HasPrefix(trimmed, "{") {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &obj); err == nil {
+			return lookupString(obj, keys)
+		}
+	}
+	return ""
+}
+
+func lookupString(obj map[string]interface{}, keys []string) string {
+	for _, k := range keys {
+		if v, ok := obj[k].(string); ok && v != "" {
+			return v
+		}
+	}
+	for _, v := range obj {
+		if nested, ok := v.(map[string]interface{}); ok {
+			if id := lookupString(nested, keys); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
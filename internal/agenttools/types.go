@@ -29,6 +29,7 @@ type Request struct {
 	Flags           []string               // Extra CLI flags to append
 	ToolSpecific    map[string]interface{} // Bag for tool-specific parameters
 	UseStdin        bool                   // If true, send prompt via stdin when supported
+	SessionID       string                 // Session to continue when Mode is "resume"
 }
 
 // ExecPlan is the resolved command plan produced by a provider.
@@ -0,0 +1,56 @@
+package agenttools
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+
+	"github.com/biwakonbu/agent-runner/internal/usage"
+)
+
+// UsageExtractor is implemented by providers whose structured output reports
+// token usage.
+type UsageExtractor interface {
+	ExtractUsage(output string) usage.Usage
+}
+
+// ExtractUsage returns the token usage reported in the output of the provider
+// of kind. model is attached to the result for pricing.
+// 構造化出力に使用量が含まれない場合はゼロ値を返す。
+func ExtractUsage(kind, model, output string) usage.Usage {
+	var u usage.Usage
+	if p, err := New(kind, ProviderConfig{Kind: kind}); err == nil {
+		if e, ok := p.(UsageExtractor); ok {
+			u = e.ExtractUsage(output)
+		}
+	}
+	if u.IsZero() {
+		return usage.Usage{}
+	}
+	u.Model = model
+	return u
+}
+
+// jsonLines calls fn for every line of output that parses as a JSON object.
+func jsonLines(output string, fn func(obj map[string]interface{})) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &obj); err == nil {
+			fn(obj)
+		}
+	}
+}
+
+// intField reads a numeric JSON field as int.
+func intField(obj map[string]interface{}, key string) int {
+	if v, ok := obj[key].(float64); ok {
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+package chat
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/logging"
+	"github.com/biwakonbu/agent-runner/internal/meta"
+	"github.com/biwakonbu/agent-runner/internal/orchestrator"
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+)
+
+// Dependency validation sources
+const (
+	dependencySourceDecompose = "decompose"
+	dependencySourcePlanPatch = "plan_patch"
+)
+
+// currentDependencyGraph returns the dependency graph of the active plan
+// (design/state when Repo is set, otherwise the TaskStore tasks).
+func (h *Handler) currentDependencyGraph(existingTasksByID map[string]orchestrator.Task) (map[string][]string, error) {
+	if h.Repo != nil {
+		if err := h.Repo.Init(); err != nil {
+			return nil, err
+		}
+		return orchestrator.LoadDependencyGraph(h.Repo)
+	}
+	graph := make(map[string][]string, len(existingTasksByID))
+	for id, t := range existingTasksByID {
+		graph[id] = append([]string(nil), t.Dependencies...)
+	}
+	return graph, nil
+}
+
+// checkDependencyGraph validates the dependency graph a plan change would
+// produce. 循環・自己依存は書き込み前に拒否し、未知ノードへの参照は報告のみとする。
+// 問題があればレポートをイベントで IDE に通知する。
+func (h *Handler) checkDependencyGraph(ctx context.Context, sessionID, source string, graph map[string][]string) (*orchestrator.DependencyReport, error) {
+	report := orchestrator.ValidateDependencyGraph(graph)
+	if report.Valid {
+		return report, nil
+	}
+
+	rejected := report.HasBlockingIssues()
+	logger := logging.WithTraceID(h.logger, ctx)
+	logger.Warn("dependency graph has issues",
+		slog.String("source", source),
+		slog.Bool("rejected", rejected),
+		slog.Int("issues", len(report.Issues)),
+	)
+	if h.events != nil {
+		h.events.Emit(orchestrator.EventDependencyValidation, orchestrator.DependencyValidationEvent{
+			SessionID: sessionID,
+			Source:    source,
+			Rejected:  rejected,
+			Report:    report,
+			Timestamp: time.Now(),
+		})
+	}
+	if rejected {
+		return report, &orchestrator.DependencyGraphError{Report: report}
+	}
+	return report, nil
+}
+
+// plannedPatchGraph applies the dependency changes of plan_patch operations
+// (updates and deletes; creates are already in graph) to graph.
+func plannedPatchGraph(graph map[string][]string, ops []meta.PlanOperation, tempToReal map[string]string, wbs *persistence.WBS) map[string][]string {
+	resolve := func(ref string) string {
+		id := strings.TrimSpace(ref)
+		if real, ok := tempToReal[id]; ok {
+			return real
+		}
+		return id
+	}
+
+	deleted := make(map[string]struct{})
+	for _, op := range ops {
+		switch op.Op {
+		case meta.PlanOpUpdate:
+			id := resolve(op.TaskID)
+			if _, ok := graph[id]; !ok || op.Dependencies == nil {
+				continue
+			}
+			deps := make([]string, 0, len(op.Dependencies))
+			for _, ref := range op.Dependencies {
+				if dep := resolve(ref); dep != "" {
+					deps = append(deps, dep)
+				}
+			}
+			graph[id] = deps
+		case meta.PlanOpDelete:
+			id := resolve(op.TaskID)
+			ids := []string{id}
+			if wbs != nil {
+				if collected, err := collectDeleteIDs(wbs, id, op.Cascade); err == nil {
+					ids = collected
+				}
+			}
+			for _, d := range ids {
+				deleted[d] = struct{}{}
+				delete(graph, d)
+			}
+		}
+	}
+
+	// 削除されたノードへの依存は cleanupDeletedDependencies で取り除かれる
+	if len(deleted) > 0 {
+		for id, deps := range graph {
+			next := make([]string, 0, len(deps))
+			for _, dep := range deps {
+				if _, ok := deleted[dep]; !ok {
+					next = append(next, dep)
+				}
+			}
+			graph[id] = next
+		}
+	}
+	return graph
+}
@@ -2,17 +2,20 @@ package chat
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/logging"
 	"github.com/biwakonbu/agent-runner/internal/meta"
 	"github.com/biwakonbu/agent-runner/internal/orchestrator"
 	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/biwakonbu/agent-runner/internal/usage"
 	"github.com/google/uuid"
 )
 
@@ -32,6 +35,8 @@ type ChatResponse struct {
 	GeneratedTasks []orchestrator.Task      `json:"generatedTasks"` // 生成されたタスク
 	Understanding  string                   `json:"understanding"`  // ユーザー意図の理解
 	Conflicts      []meta.PotentialConflict `json:"conflicts"`      // 潜在的なコンフリクト
+	// DependencyReport は依存グラフに問題がある場合の検証結果（循環などで拒否した場合も含む）
+	DependencyReport *orchestrator.DependencyReport `json:"dependencyReport,omitempty"`
 }
 
 // Handler はチャットメッセージを処理するハンドラ
@@ -42,11 +47,37 @@ type Handler struct {
 	SessionStore *ChatSessionStore
 	WorkspaceID  string
 	ProjectRoot  string
+	UsageLedger  *orchestrator.UsageLedger // nil の場合は Meta 呼び出しの使用量を記録しない
+	Prices       usage.PriceTable
 	logger       *slog.Logger
 	events       orchestrator.EventEmitter
 	metaTimeout  time.Duration
 }
 
+// streamProgressInterval は Meta 応答のストリーミング進捗を通知する最小間隔
+const streamProgressInterval = 500 * time.Millisecond
+
+// newStreamProgress は受信した差分をまとめ、streamProgressInterval ごとに emit へ渡す
+// meta.StreamHandler を返す（トークン単位でイベントを発行しないよう間引く）
+func newStreamProgress(emit func(delta string, received int)) meta.StreamHandler {
+	var (
+		mu      sync.Mutex
+		pending strings.Builder
+		last    time.Time
+	)
+	return func(chunk meta.StreamChunk) {
+		mu.Lock()
+		defer mu.Unlock()
+		pending.WriteString(chunk.Delta)
+		if time.Since(last) < streamProgressInterval {
+			return
+		}
+		last = time.Now()
+		emit(pending.String(), chunk.Received)
+		pending.Reset()
+	}
+}
+
 // NewHandler は新しい ChatHandler を作成する
 func NewHandler(
 	metaClient MetaClient,
@@ -64,6 +95,7 @@ func NewHandler(
 		SessionStore: sessionStore,
 		WorkspaceID:  workspaceID,
 		ProjectRoot:  projectRoot,
+		Prices:       usage.DefaultPriceTable(),
 		logger:       logging.WithComponent(slog.Default(), "chat-handler"),
 		events:       events,
 		metaTimeout:  DefaultChatMetaTimeout,
@@ -162,8 +194,23 @@ func (h *Handler) HandleMessage(ctx context.Context, sessionID, message string)
 	logger.Debug("calling meta-agent for plan_patch")
 	metaCtx, cancel := context.WithTimeout(ctx, h.metaTimeout)
 	defer cancel()
+	metaCtx = meta.WithStreamHandler(metaCtx, newStreamProgress(func(delta string, received int) {
+		if h.events != nil {
+			h.events.Emit(orchestrator.EventChatProgress, orchestrator.ChatProgressEvent{
+				SessionID: sessionID,
+				Step:      "Streaming",
+				Message:   fmt.Sprintf("Meta-agent が応答を生成中... (%d バイト受信)", received),
+				Delta:     delta,
+				Timestamp: time.Now(),
+			})
+		}
+	}))
+	usageRec := &usage.Recorder{}
+	metaCtx = usage.WithRecorder(metaCtx, usageRec)
 
 	patchResp, err := h.Meta.PlanPatch(metaCtx, planPatchReq)
+	// 失敗した呼び出しもトークンを消費しているため、結果に関わらず記録する
+	h.recordMetaUsage(sessionID, usageRec)
 	if err != nil {
 		emitFailed(fmt.Sprintf("計画更新に失敗しました: %v", err))
 		// エラー時もアシスタントメッセージを返す
@@ -187,6 +234,25 @@ func (h *Handler) HandleMessage(ctx context.Context, sessionID, message string)
 	applyRes, err := h.applyPlanPatch(ctx, sessionID, patchResp, existingTaskIDs, existingTasksByID)
 	if err != nil {
 		emitFailed(fmt.Sprintf("計画変更の保存に失敗しました: %v", err))
+		var graphErr *orchestrator.DependencyGraphError
+		if errors.As(err, &graphErr) {
+			// 依存グラフの問題はどのノードが原因かを応答として返す
+			rejectMsg := &ChatMessage{
+				ID:        uuid.New().String(),
+				SessionID: sessionID,
+				Role:      "assistant",
+				Content:   buildDependencyRejectionContent(graphErr.Report),
+				Timestamp: time.Now(),
+			}
+			if appendErr := h.SessionStore.AppendMessage(rejectMsg); appendErr != nil {
+				return nil, fmt.Errorf("failed to apply plan patch: %v (assistant message save failed: %w)", err, appendErr)
+			}
+			return &ChatResponse{
+				Message:          *rejectMsg,
+				Understanding:    patchResp.Understanding,
+				DependencyReport: graphErr.Report,
+			}, fmt.Errorf("failed to apply plan patch: %w", err)
+		}
 		return nil, fmt.Errorf("failed to apply plan patch: %w", err)
 	}
 
@@ -221,12 +287,47 @@ func (h *Handler) HandleMessage(ctx context.Context, sessionID, message string)
 		logging.LogDuration(start),
 	)
 
-	return &ChatResponse{
+	resp := &ChatResponse{
 		Message:        *assistantMsg,
 		GeneratedTasks: applyRes.CreatedTasks,
 		Understanding:  patchResp.Understanding,
 		Conflicts:      filteredConflicts,
-	}, nil
+	}
+	if applyRes.DependencyReport != nil && !applyRes.DependencyReport.Valid {
+		resp.DependencyReport = applyRes.DependencyReport
+	}
+	return resp, nil
+}
+
+// buildDependencyRejectionContent explains why a plan change was rejected.
+func buildDependencyRejectionContent(report *orchestrator.DependencyReport) string {
+	var b strings.Builder
+	b.WriteString("計画変更により依存関係が不正になるため、変更を適用しませんでした：\n\n")
+	for _, issue := range report.Issues {
+		fmt.Fprintf(&b, "- %s\n", issue.Message)
+	}
+	return b.String()
+}
+
+// recordMetaUsage はチャットの Meta 呼び出しで消費した使用量を台帳に追記する
+func (h *Handler) recordMetaUsage(sessionID string, rec *usage.Recorder) {
+	if h.UsageLedger == nil {
+		return
+	}
+	summary := usage.Summarize(rec.Usages(), h.Prices)
+	if summary.Calls == 0 {
+		return
+	}
+	if err := h.UsageLedger.Append(orchestrator.UsageRecord{
+		Source:    orchestrator.UsageSourceChat,
+		SessionID: sessionID,
+		Usage:     summary,
+	}); err != nil {
+		h.logger.Warn("failed to record chat usage",
+			slog.String("session_id", sessionID),
+			slog.Any("error", err),
+		)
+	}
 }
 
 // BuildDecomposeRequest は Meta-agent への分解リクエストを構築する
@@ -361,6 +462,17 @@ func (h *Handler) PersistTasks(
 		return nil, fmt.Errorf("unresolved dependencies: %s", strings.Join(unique, ", "))
 	}
 
+	graph, err := h.currentDependencyGraph(existingTasksByID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dependency graph: %w", err)
+	}
+	for _, t := range tasksToSave {
+		graph[t.ID] = append([]string(nil), t.Dependencies...)
+	}
+	if _, err := h.checkDependencyGraph(ctx, sessionID, dependencySourceDecompose, graph); err != nil {
+		return nil, err
+	}
+
 	if err := h.persistDesignAndState(ctx, sessionID, tasksToSave, existingTasksByID); err != nil {
 		logger.Error("failed to persist design/state", slog.Any("error", err))
 		return nil, fmt.Errorf("failed to persist design/state: %w", err)
@@ -2,12 +2,17 @@ package chat_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/biwakonbu/agent-runner/internal/chat"
 	"github.com/biwakonbu/agent-runner/internal/meta"
 	"github.com/biwakonbu/agent-runner/internal/orchestrator"
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/biwakonbu/agent-runner/internal/usage"
 )
 
 func TestHandleMessage_Mock(t *testing.T) {
@@ -183,3 +188,179 @@ func (r *recordingEmitter) Emit(eventName string, data any) {
 		r.events = append(r.events, ev)
 	}
 }
+
+func TestHandleMessage_ForwardsStreamingProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskStore := orchestrator.NewTaskStore(tmpDir)
+	sessionStore := chat.NewChatSessionStore(tmpDir)
+	recorder := &recordingEmitter{}
+	handler := chat.NewHandler(streamingMetaClient{chunks: []string{`{"type":`, `"plan_patch"}`}}, taskStore, sessionStore, "ws", tmpDir, nil, recorder)
+
+	ctx := context.Background()
+	session, err := handler.CreateSession(ctx)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if _, err := handler.HandleMessage(ctx, session.ID, "hi"); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	var streaming []orchestrator.ChatProgressEvent
+	for _, ev := range recorder.events {
+		if ev.Step == "Streaming" {
+			streaming = append(streaming, ev)
+		}
+	}
+	// 連続するチャンクは間引かれるため、最初のチャンクのみ即座に通知される
+	if len(streaming) != 1 {
+		t.Fatalf("expected 1 streaming event, got %d", len(streaming))
+	}
+	if streaming[0].Delta != `{"type":` || streaming[0].SessionID != session.ID {
+		t.Errorf("unexpected streaming event: %+v", streaming[0])
+	}
+}
+
+type streamingMetaClient struct {
+	chunks []string
+}
+
+func (s streamingMetaClient) Decompose(context.Context, *meta.DecomposeRequest) (*meta.DecomposeResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (s streamingMetaClient) PlanPatch(ctx context.Context, _ *meta.PlanPatchRequest) (*meta.PlanPatchResponse, error) {
+	h := meta.StreamHandlerFrom(ctx)
+	if h == nil {
+		return nil, fmt.Errorf("stream handler not set")
+	}
+	received := 0
+	for _, c := range s.chunks {
+		received += len(c)
+		h(meta.StreamChunk{Type: meta.MessageTypePlanPatch, Delta: c, Received: received})
+	}
+	return &meta.PlanPatchResponse{Understanding: "ok"}, nil
+}
+
+func TestHandleMessage_RejectsDependencyCycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskStore := orchestrator.NewTaskStore(tmpDir)
+	sessionStore := chat.NewChatSessionStore(tmpDir)
+	repo := persistence.NewWorkspaceRepository(filepath.Join(tmpDir, "ws"))
+	events := &validationRecorder{}
+
+	first, second := "Schema", "API"
+	patch := &meta.PlanPatchResponse{
+		Understanding: "cyclic plan",
+		Operations: []meta.PlanOperation{
+			{Op: meta.PlanOpCreate, TempID: "t1", Title: &first},
+			{Op: meta.PlanOpCreate, TempID: "t2", Title: &second, Dependencies: []string{"t1"}},
+			{Op: meta.PlanOpUpdate, TaskID: "t1", Dependencies: []string{"t2"}},
+		},
+	}
+	handler := chat.NewHandler(staticMetaClient{resp: patch}, taskStore, sessionStore, "ws", tmpDir, repo, events)
+
+	ctx := context.Background()
+	session, err := handler.CreateSession(ctx)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	resp, err := handler.HandleMessage(ctx, session.ID, "plan")
+	var graphErr *orchestrator.DependencyGraphError
+	if !errors.As(err, &graphErr) {
+		t.Fatalf("expected DependencyGraphError, got %v", err)
+	}
+	if resp == nil || resp.DependencyReport == nil || !resp.DependencyReport.HasBlockingIssues() {
+		t.Fatalf("expected dependency report in response, got %+v", resp)
+	}
+	issue := resp.DependencyReport.Issues[0]
+	if issue.Kind != orchestrator.DependencyIssueCycle || len(issue.NodeIDs) != 2 {
+		t.Errorf("unexpected issue: %+v", issue)
+	}
+	if !strings.Contains(resp.Message.Content, "dependency cycle") {
+		t.Errorf("assistant message should name the cycle: %q", resp.Message.Content)
+	}
+
+	// 書き込み前に拒否されるため、タスクは永続化されない
+	tasks, _ := taskStore.ListAllTasks()
+	state, _ := repo.State().LoadTasks()
+	if len(tasks) != 0 || len(state.Tasks) != 0 {
+		t.Errorf("expected nothing persisted, got %d tasks / %d task states", len(tasks), len(state.Tasks))
+	}
+	if len(events.validations) != 1 || !events.validations[0].Rejected || events.validations[0].Source != "plan_patch" {
+		t.Errorf("unexpected validation events: %+v", events.validations)
+	}
+}
+
+type validationRecorder struct {
+	validations []orchestrator.DependencyValidationEvent
+}
+
+func (r *validationRecorder) Emit(eventName string, data any) {
+	if ev, ok := data.(orchestrator.DependencyValidationEvent); ok && eventName == orchestrator.EventDependencyValidation {
+		r.validations = append(r.validations, ev)
+	}
+}
+
+func TestHandleMessage_RecordsChatUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskStore := orchestrator.NewTaskStore(tmpDir)
+	sessionStore := chat.NewChatSessionStore(tmpDir)
+	ledger := orchestrator.NewUsageLedger(tmpDir)
+
+	// 成功・失敗のどちらの呼び出しも使用量を台帳に残す
+	for _, fail := range []bool{false, true} {
+		handler := chat.NewHandler(usageMetaClient{fail: fail}, taskStore, sessionStore, "ws", tmpDir, nil, nil)
+		handler.UsageLedger = ledger
+
+		ctx := context.Background()
+		session, err := handler.CreateSession(ctx)
+		if err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+		_, err = handler.HandleMessage(ctx, session.ID, "hi")
+		if fail != (err != nil) {
+			t.Fatalf("HandleMessage(fail=%v) error = %v", fail, err)
+		}
+	}
+
+	records, err := ledger.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 chat usage records, got %+v", records)
+	}
+	for _, rec := range records {
+		if rec.Source != orchestrator.UsageSourceChat || rec.TaskID != "" || rec.SessionID == "" {
+			t.Errorf("unexpected record: %+v", rec)
+		}
+		if rec.Usage.InputTokens != 100 || rec.Usage.OutputTokens != 20 || rec.Usage.Calls != 1 {
+			t.Errorf("unexpected usage: %+v", rec.Usage)
+		}
+	}
+
+	report, err := ledger.Report()
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if len(report.Tasks) != 0 || report.Sources[orchestrator.UsageSourceChat].Calls != 2 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+type usageMetaClient struct {
+	fail bool
+}
+
+func (usageMetaClient) Decompose(context.Context, *meta.DecomposeRequest) (*meta.DecomposeResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (c usageMetaClient) PlanPatch(ctx context.Context, _ *meta.PlanPatchRequest) (*meta.PlanPatchResponse, error) {
+	usage.Record(ctx, usage.Usage{Model: "gpt-5.2", InputTokens: 100, OutputTokens: 20})
+	if c.fail {
+		return nil, fmt.Errorf("meta failure")
+	}
+	return &meta.PlanPatchResponse{Understanding: "ok"}, nil
+}
@@ -20,6 +20,8 @@ type PlanPatchApplyResult struct {
 	UpdatedTasks   []orchestrator.Task
 	DeletedTaskIDs []string
 	MovedTaskIDs   []string
+	// DependencyReport は変更後の依存グラフの検証結果（問題が無ければ Valid）
+	DependencyReport *orchestrator.DependencyReport
 }
 
 func (h *Handler) buildPlanPatchRequest(sessionID, message string, existingTasks []orchestrator.Task) *meta.PlanPatchRequest {
@@ -211,7 +213,24 @@ func (h *Handler) applyPlanPatch(
 		}
 	}
 
-	// 3) Persist created tasks into design/state and TaskStore.
+	// 3) Validate the resulting dependency graph before writing anything.
+	graph, err := h.currentDependencyGraph(existingTasksByID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dependency graph: %w", err)
+	}
+	for _, t := range tasksToCreate {
+		graph[t.ID] = append([]string(nil), t.Dependencies...)
+	}
+	var currentWBS *persistence.WBS
+	if h.Repo != nil {
+		currentWBS, _ = h.Repo.Design().LoadWBS()
+	}
+	report, err := h.checkDependencyGraph(ctx, sessionID, dependencySourcePlanPatch, plannedPatchGraph(graph, resp.Operations, tempToReal, currentWBS))
+	if err != nil {
+		return nil, err
+	}
+
+	// 4) Persist created tasks into design/state and TaskStore.
 	if len(tasksToCreate) > 0 {
 		if err := h.persistDesignAndState(ctx, sessionID, tasksToCreate, existingTasksByID); err != nil {
 			logger.Error("failed to persist design/state for created tasks", slog.Any("error", err))
@@ -230,7 +249,8 @@ func (h *Handler) applyPlanPatch(
 	}
 
 	result := &PlanPatchApplyResult{
-		CreatedTasks: tasksToCreate,
+		CreatedTasks:     tasksToCreate,
+		DependencyReport: report,
 	}
 
 	if h.Repo == nil {
@@ -3,11 +3,16 @@ package cli
 import (
 	"flag"
 	"io"
+	"os"
 )
 
 // Flags holds command-line arguments
 type Flags struct {
 	MetaModel string
+
+	// MetaCassette は Meta のやり取りを記録/再生するカセットファイル
+	MetaCassette     string
+	MetaCassetteMode string // record | replay
 }
 
 // ParseFlags parses command-line arguments
@@ -17,6 +22,8 @@ func ParseFlags(args []string, output io.Writer) (*Flags, error) {
 
 	var flags Flags
 	fs.StringVar(&flags.MetaModel, "meta-model", "", "Meta agent LLM model ID")
+	fs.StringVar(&flags.MetaCassette, "meta-cassette", os.Getenv("MULTIVERSE_META_CASSETTE"), "Cassette file to record or replay Meta exchanges")
+	fs.StringVar(&flags.MetaCassetteMode, "meta-cassette-mode", envOr("MULTIVERSE_META_CASSETTE_MODE", "replay"), "Cassette mode: record or replay")
 
 	if err := fs.Parse(args); err != nil {
 		return nil, err
@@ -25,6 +32,13 @@ func ParseFlags(args []string, output io.Writer) (*Flags, error) {
 	return &flags, nil
 }
 
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
 // ResolveMetaModel determines the final model ID based on priority:
 // 1. CLI flag
 // 2. Task YAML configuration
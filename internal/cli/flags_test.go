@@ -47,6 +47,30 @@ func TestParseFlags(t *testing.T) {
 	}
 }
 
+func TestParseFlags_MetaCassette(t *testing.T) {
+	t.Setenv("MULTIVERSE_META_CASSETTE", "")
+	t.Setenv("MULTIVERSE_META_CASSETTE_MODE", "")
+
+	var buf bytes.Buffer
+	got, err := ParseFlags([]string{"--meta-cassette=testdata/run.yaml"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MetaCassette != "testdata/run.yaml" || got.MetaCassetteMode != "replay" {
+		t.Errorf("unexpected flags: %+v", got)
+	}
+
+	t.Setenv("MULTIVERSE_META_CASSETTE", "env.yaml")
+	t.Setenv("MULTIVERSE_META_CASSETTE_MODE", "record")
+	got, err = ParseFlags(nil, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MetaCassette != "env.yaml" || got.MetaCassetteMode != "record" {
+		t.Errorf("env defaults not applied: %+v", got)
+	}
+}
+
 func TestResolveMetaModel(t *testing.T) {
 	tests := []struct {
 		name      string
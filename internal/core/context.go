@@ -3,6 +3,8 @@ package core
 import (
 	"time"
 
+	"github.com/biwakonbu/agent-runner/internal/meta"
+	"github.com/biwakonbu/agent-runner/internal/usage"
 	"github.com/biwakonbu/agent-runner/pkg/config"
 )
 
@@ -38,9 +40,21 @@ type TaskContext struct {
 	MetaCalls          []MetaCallLog     // Meta 呼び出し履歴
 	WorkerRuns         []WorkerRunResult // Worker 実行履歴
 
+	// WorkerSessions は worker kind ごとの最新セッション ID（セッション継続用）
+	WorkerSessions map[string]string
+
+	// Transcript は Meta とのタスク内の会話履歴（計画・判断・Worker 結果）。nil の場合は再送しない
+	Transcript *meta.Transcript
+
 	TestConfig *config.TestDetails
 	TestResult *TestResult
 
+	// Review は独立レビュー（セカンドオピニオン）の結果。Reviewer 未設定の場合は nil
+	Review *ReviewResult
+
+	// Usage はタスク全体のトークン使用量・コスト（終了時に集計）
+	Usage *usage.Summary
+
 	StartedAt  time.Time
 	FinishedAt time.Time
 }
@@ -51,6 +65,16 @@ type MetaCallLog struct {
 	Timestamp    time.Time
 	RequestYAML  string
 	ResponseYAML string
+	Usage        usage.Usage
+	// Route は呼び出しに使ったプロバイダ kind とモデル（メッセージ種別ごとのルーティング結果）
+	Route meta.Route
+
+	// InvalidResponses はスキーマ検証に失敗した応答と、それに対する修復プロンプト
+	InvalidResponses []meta.InvalidResponse
+	// Repairs は送信した修復プロンプトの数
+	Repairs int
+	// Error は呼び出しが失敗した場合のエラー（修復上限到達を含む）
+	Error string
 }
 
 // WorkerRunResult records a single execution of the worker
@@ -62,6 +86,34 @@ type WorkerRunResult struct {
 	RawOutput  string
 	Summary    string
 	Error      error
+
+	// WorkerKind は実際に実行した worker kind（フォールバック後の値）
+	WorkerKind string
+	// SessionID は CLI の構造化出力から取得したセッション ID
+	SessionID string
+	// Usage は CLI の構造化出力から取得したトークン使用量
+	Usage usage.Usage
+	// FallbackAttempts はフォールバック前に失敗したプロバイダの記録
+	FallbackAttempts []ProviderAttempt
+}
+
+// ProviderAttempt records a provider that failed before falling back to the next one.
+type ProviderAttempt struct {
+	WorkerKind   string
+	FailureClass string
+	ExitCode     int
+	Error        string
+	// Usage は失敗までに消費したトークン使用量（実行に至らなかった場合はゼロ）
+	Usage usage.Usage
+}
+
+// Usages returns the usage of every provider run, including failed fallback attempts.
+func (r *WorkerRunResult) Usages() []usage.Usage {
+	usages := make([]usage.Usage, 0, len(r.FallbackAttempts)+1)
+	for _, a := range r.FallbackAttempts {
+		usages = append(usages, a.Usage)
+	}
+	return append(usages, r.Usage)
 }
 
 // TestResult records the result of the test command
@@ -71,3 +123,35 @@ type TestResult struct {
 	Summary   string
 	RawOutput string
 }
+
+// RecordWorkerSession stores the session ID reported by a worker run.
+func (c *TaskContext) RecordWorkerSession(res *WorkerRunResult) {
+	if res == nil || res.SessionID == "" || res.WorkerKind == "" {
+		return
+	}
+	if c.WorkerSessions == nil {
+		c.WorkerSessions = make(map[string]string)
+	}
+	c.WorkerSessions[res.WorkerKind] = res.SessionID
+}
+
+// AppendTranscript records a turn of the Meta conversation. digest is the
+// one-line gist kept when the turn is summarized (empty = dropped).
+func (c *TaskContext) AppendTranscript(role, kind, content, digest string) {
+	if c.Transcript == nil {
+		return
+	}
+	c.Transcript.Append(meta.TranscriptEntry{Role: role, Kind: kind, Content: content, Digest: digest})
+}
+
+// UsageSummary rolls up Meta call and worker run usage with prices.
+func (c *TaskContext) UsageSummary(prices usage.PriceTable) usage.Summary {
+	var usages []usage.Usage
+	for _, call := range c.MetaCalls {
+		usages = append(usages, call.Usage)
+	}
+	for i := range c.WorkerRuns {
+		usages = append(usages, c.WorkerRuns[i].Usages()...)
+	}
+	return usage.Summarize(usages, prices)
+}
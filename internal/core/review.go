@@ -0,0 +1,347 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/biwakonbu/agent-runner/internal/meta"
+	"github.com/biwakonbu/agent-runner/internal/usage"
+)
+
+// Reviewer gives an independent second opinion on task completion.
+type Reviewer interface {
+	// Name identifies the reviewer in notes (e.g. "anthropic-messages / claude-..." or "worker / codex-cli")
+	Name() string
+	Review(ctx context.Context, summary *meta.TaskSummary) (*meta.CompletionAssessmentResponse, error)
+}
+
+// ReviewResult records the independent review of a completion assessment.
+type ReviewResult struct {
+	Reviewer   string
+	Assessment *meta.CompletionAssessmentResponse
+	// Agreed は Meta と Reviewer の双方が全基準を満たすと判定したか
+	Agreed        bool
+	Disagreements []ReviewDisagreement
+	Error         string
+}
+
+// ReviewDisagreement is a criterion (or the overall verdict, CriterionID
+// "overall") on which the Meta assessment and the reviewer differ.
+type ReviewDisagreement struct {
+	CriterionID     string
+	MetaStatus      string
+	ReviewerStatus  string
+	MetaComment     string
+	ReviewerComment string
+}
+
+// reviewDiffLimit はレビューに渡す差分の最大長
+const reviewDiffLimit = 20000
+
+// MetaReviewer reviews with a separately configured Meta client (different
+// provider / model / system prompt from the one that drove the task).
+type MetaReviewer struct {
+	Meta  MetaClient
+	Label string
+}
+
+// NewMetaReviewer creates a reviewer backed by a Meta client.
+func NewMetaReviewer(m MetaClient, label string) *MetaReviewer {
+	return &MetaReviewer{Meta: m, Label: label}
+}
+
+func (r *MetaReviewer) Name() string {
+	return r.Label
+}
+
+func (r *MetaReviewer) Review(ctx context.Context, summary *meta.TaskSummary) (*meta.CompletionAssessmentResponse, error) {
+	return r.Meta.CompletionAssessment(ctx, summary)
+}
+
+// WorkerReviewer runs a reviewer worker in the task's sandbox. The worker is
+// told not to modify the repository; the working tree is compared before and
+// after the run, and any change is reverted to the pre-review state and fails
+// the review so that later steps never pick up reviewer edits.
+type WorkerReviewer struct {
+	Worker     WorkerExecutor
+	WorkerType string
+	Env        map[string]string
+	RepoPath   string
+}
+
+// NewWorkerReviewer creates a reviewer that runs workerType in the sandbox.
+func NewWorkerReviewer(w WorkerExecutor, workerType string, env map[string]string, repoPath string) *WorkerReviewer {
+	return &WorkerReviewer{Worker: w, WorkerType: workerType, Env: env, RepoPath: repoPath}
+}
+
+func (r *WorkerReviewer) Name() string {
+	return "worker / " + r.WorkerType
+}
+
+func (r *WorkerReviewer) Review(ctx context.Context, summary *meta.TaskSummary) (*meta.CompletionAssessmentResponse, error) {
+	before := workingTreeState(ctx, r.RepoPath)
+	snapshot, err := snapshotWorkingTree(ctx, r.RepoPath)
+	if err != nil {
+		// 戻せない状態でレビュアーに作業ツリーを触らせない
+		return nil, fmt.Errorf("failed to snapshot working tree before review: %w", err)
+	}
+	res, err := r.Worker.RunWorker(ctx, meta.WorkerCall{
+		WorkerType: r.WorkerType,
+		Mode:       "exec",
+		Prompt:     meta.ReviewWorkerPrompt(summary),
+		Session:    meta.WorkerSessionNew,
+	}, r.Env)
+	// 失敗時もレビュアーが途中まで書き換えている可能性があるため、先に差分を確認する
+	if after := workingTreeState(ctx, r.RepoPath); after != before {
+		if restoreErr := snapshot.restore(ctx); restoreErr != nil {
+			return nil, fmt.Errorf("reviewer worker modified the working tree and reverting failed: %w", restoreErr)
+		}
+		return nil, fmt.Errorf("reviewer worker modified the working tree (changes reverted)")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reviewer worker failed: %w", err)
+	}
+	for _, u := range res.Usages() {
+		usage.Record(ctx, u)
+	}
+	if res.ExitCode != 0 {
+		return nil, fmt.Errorf("reviewer worker exited with code %d", res.ExitCode)
+	}
+	return meta.ParseCompletionAssessment(res.RawOutput)
+}
+
+// workingTreeState returns the status and diff of the repository, used to
+// detect modifications. git が無い・リポジトリでない場合は空文字（比較不能）。
+func workingTreeState(ctx context.Context, repoPath string) string {
+	status, err := gitOutput(ctx, repoPath, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return ""
+	}
+	diff, _ := gitOutput(ctx, repoPath, "diff", "HEAD")
+	return status + "\n" + diff
+}
+
+// treeSnapshot is the working tree and index of a repository captured before
+// a review so that reviewer modifications can be reverted.
+type treeSnapshot struct {
+	repoPath  string
+	tree      string // 作業ツリー全体（追跡・未追跡、無視ファイルを除く）の tree オブジェクト
+	indexPath string
+	index     []byte // 元のインデックス（存在しなかった場合は nil）
+}
+
+// snapshotWorkingTree writes the current working tree (tracked and untracked,
+// non-ignored files) as a git tree object using a temporary index, leaving
+// the real index untouched. git が無い・リポジトリでない場合は nil（戻せないが比較もできない）。
+func snapshotWorkingTree(ctx context.Context, repoPath string) (*treeSnapshot, error) {
+	if workingTreeState(ctx, repoPath) == "" {
+		return nil, nil
+	}
+	indexPath, err := gitOutput(ctx, repoPath, "rev-parse", "--git-path", "index")
+	if err != nil {
+		return nil, err
+	}
+	indexPath = strings.TrimSpace(indexPath)
+	if !filepath.IsAbs(indexPath) {
+		indexPath = filepath.Join(repoPath, indexPath)
+	}
+	snap := &treeSnapshot{repoPath: repoPath, indexPath: indexPath}
+	if data, err := os.ReadFile(indexPath); err == nil {
+		snap.index = data
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	tmpIndex, err := snap.tempIndex(snap.index)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Remove(tmpIndex) }()
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+	if _, err := gitOutputEnv(ctx, repoPath, env, "add", "-A"); err != nil {
+		return nil, err
+	}
+	tree, err := gitOutputEnv(ctx, repoPath, env, "write-tree")
+	if err != nil {
+		return nil, err
+	}
+	snap.tree = strings.TrimSpace(tree)
+	return snap, nil
+}
+
+// restore resets the working tree and index to the snapshot: files the
+// reviewer created are removed and modified or deleted files are rewritten.
+func (s *treeSnapshot) restore(ctx context.Context) error {
+	if s == nil {
+		return fmt.Errorf("no working tree snapshot (git unavailable)")
+	}
+	// 1. 元のインデックスに戻す（レビュアーの git add 等を取り消す）
+	if s.index != nil {
+		if err := os.WriteFile(s.indexPath, s.index, 0o644); err != nil {
+			return fmt.Errorf("failed to restore index: %w", err)
+		}
+	} else if err := os.Remove(s.indexPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to restore index: %w", err)
+	}
+	// 2. スナップショットに無いファイルを削除する（未追跡ファイルは 3. で書き戻される）
+	if _, err := gitOutput(ctx, s.repoPath, "clean", "-fdq"); err != nil {
+		return err
+	}
+	tracked, err := gitOutput(ctx, s.repoPath, "ls-files", "-z")
+	if err != nil {
+		return err
+	}
+	kept, err := gitOutput(ctx, s.repoPath, "ls-tree", "-r", "-z", "--name-only", s.tree)
+	if err != nil {
+		return err
+	}
+	inSnapshot := make(map[string]bool)
+	for _, name := range strings.Split(kept, "\x00") {
+		inSnapshot[name] = true
+	}
+	for _, name := range strings.Split(tracked, "\x00") {
+		if name == "" || inSnapshot[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.repoPath, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	// 3. スナップショットの内容を書き戻す
+	tmpIndex, err := s.tempIndex(nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmpIndex) }()
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+	if _, err := gitOutputEnv(ctx, s.repoPath, env, "read-tree", s.tree); err != nil {
+		return err
+	}
+	if _, err := gitOutputEnv(ctx, s.repoPath, env, "checkout-index", "-a", "-f"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// tempIndex creates a temporary index file next to the real one, seeded
+// with data (empty when nil).
+func (s *treeSnapshot) tempIndex(data []byte) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(s.indexPath), "review-index-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if data != nil {
+		if _, err := f.Write(data); err != nil {
+			_ = os.Remove(f.Name())
+			return "", fmt.Errorf("failed to write temporary index: %w", err)
+		}
+	}
+	return f.Name(), nil
+}
+
+// collectDiff returns the working tree changes (tracked diff and untracked
+// file names) for review, truncated to reviewDiffLimit.
+func collectDiff(ctx context.Context, repoPath string) string {
+	diff, err := gitOutput(ctx, repoPath, "diff", "HEAD")
+	if err != nil {
+		return fmt.Sprintf("(diff unavailable: %v)", err)
+	}
+	if untracked, err := gitOutput(ctx, repoPath, "ls-files", "--others", "--exclude-standard"); err == nil && untracked != "" {
+		diff += "\nUntracked files:\n" + untracked
+	}
+	if len(diff) > reviewDiffLimit {
+		diff = diff[:reviewDiffLimit] + "\n... (truncated)"
+	}
+	return diff
+}
+
+func gitOutput(ctx context.Context, repoPath string, args ...string) (string, error) {
+	return gitOutputEnv(ctx, repoPath, nil, args...)
+}
+
+// gitOutputEnv runs git with extra environment variables (e.g. GIT_INDEX_FILE).
+func gitOutputEnv(ctx context.Context, repoPath string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repoPath}, args...)...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return string(out), nil
+}
+
+// compareAssessments lists where the Meta assessment and the review differ.
+// 基準がどちらか一方にしか無い場合は "missing" として扱う。
+func compareAssessments(metaA, review *meta.CompletionAssessmentResponse) []ReviewDisagreement {
+	var out []ReviewDisagreement
+	if metaA.AllCriteriaSatisfied != review.AllCriteriaSatisfied {
+		out = append(out, ReviewDisagreement{
+			CriterionID:     "overall",
+			MetaStatus:      verdict(metaA.AllCriteriaSatisfied),
+			ReviewerStatus:  verdict(review.AllCriteriaSatisfied),
+			MetaComment:     metaA.Summary,
+			ReviewerComment: review.Summary,
+		})
+	}
+
+	metaBy := criteriaByID(metaA.ByCriterion)
+	reviewBy := criteriaByID(review.ByCriterion)
+	ids := make([]string, 0, len(metaBy)+len(reviewBy))
+	for id := range metaBy {
+		ids = append(ids, id)
+	}
+	for id := range reviewBy {
+		if _, ok := metaBy[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		m, r := metaBy[id], reviewBy[id]
+		if m.Status == r.Status {
+			continue
+		}
+		out = append(out, ReviewDisagreement{
+			CriterionID:     id,
+			MetaStatus:      nonEmptyStatus(m.Status),
+			ReviewerStatus:  nonEmptyStatus(r.Status),
+			MetaComment:     m.Comment,
+			ReviewerComment: r.Comment,
+		})
+	}
+	return out
+}
+
+func criteriaByID(results []meta.CriterionResult) map[string]meta.CriterionResult {
+	out := make(map[string]meta.CriterionResult, len(results))
+	for _, c := range results {
+		out[c.ID] = c
+	}
+	return out
+}
+
+func verdict(satisfied bool) string {
+	if satisfied {
+		return "passed"
+	}
+	return "failed"
+}
+
+func nonEmptyStatus(status string) string {
+	if status == "" {
+		return "missing"
+	}
+	return status
+}
@@ -0,0 +1,196 @@
+package core_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/biwakonbu/agent-runner/internal/core"
+	"github.com/biwakonbu/agent-runner/internal/meta"
+	"github.com/biwakonbu/agent-runner/internal/mock"
+	"github.com/biwakonbu/agent-runner/pkg/config"
+)
+
+// newReviewedRunner は 1 回 Worker を実行して完了を宣言し、Meta が全基準を満たすと判定する Runner を返す
+func newReviewedRunner(reviewer core.Reviewer) *core.Runner {
+	cfg := &config.TaskConfig{
+		Task: config.TaskDetails{
+			ID: "review-task", Title: "Review Task", Repo: ".", PRD: config.PRDDetails{Text: "PRD"},
+			Test: config.TestDetails{Command: "echo review-tests-ok"},
+		},
+		Runner: config.RunnerConfig{Worker: config.WorkerConfig{Env: map[string]string{}}},
+	}
+	mockMeta := &mock.MetaClient{
+		PlanTaskFunc: func(ctx context.Context, prd string) (*meta.PlanTaskResponse, error) {
+			return &meta.PlanTaskResponse{AcceptanceCriteria: []meta.AcceptanceCriterion{
+				{ID: "AC-1", Description: "Login works"},
+				{ID: "AC-2", Description: "Tests pass"},
+			}}, nil
+		},
+		NextActionFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.NextActionResponse, error) {
+			if summary.WorkerRunsCount == 0 {
+				return &meta.NextActionResponse{Decision: meta.Decision{Action: "run_worker"}, WorkerCall: meta.WorkerCall{Prompt: "do it"}}, nil
+			}
+			return &meta.NextActionResponse{Decision: meta.Decision{Action: "mark_complete"}}, nil
+		},
+		CompletionAssessmentFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.CompletionAssessmentResponse, error) {
+			return &meta.CompletionAssessmentResponse{
+				AllCriteriaSatisfied: true,
+				Summary:              "looks good",
+				ByCriterion: []meta.CriterionResult{
+					{ID: "AC-1", Status: "passed"},
+					{ID: "AC-2", Status: "passed"},
+				},
+			}, nil
+		},
+	}
+	mockWorker := &mock.WorkerExecutor{
+		StartFunc: func(ctx context.Context) error { return nil },
+		StopFunc:  func(ctx context.Context) error { return nil },
+		RunWorkerFunc: func(ctx context.Context, call meta.WorkerCall, env map[string]string) (*core.WorkerRunResult, error) {
+			return &core.WorkerRunResult{ID: "run-1", Summary: "implemented"}, nil
+		},
+	}
+	runner := core.NewRunner(cfg, mockMeta, mockWorker, &mock.NoteWriter{})
+	runner.Reviewer = reviewer
+	return runner
+}
+
+func TestRunner_ReviewerDisagreementFailsTask(t *testing.T) {
+	var reviewed *meta.TaskSummary
+	reviewer := core.NewMetaReviewer(&mock.MetaClient{
+		CompletionAssessmentFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.CompletionAssessmentResponse, error) {
+			reviewed = summary
+			return &meta.CompletionAssessmentResponse{
+				AllCriteriaSatisfied: false,
+				Summary:              "login is not wired",
+				ByCriterion: []meta.CriterionResult{
+					{ID: "AC-1", Status: "failed", Comment: "handler missing in diff"},
+					{ID: "AC-2", Status: "passed"},
+				},
+			}, nil
+		},
+	}, "anthropic-messages / reviewer-model")
+
+	resultCtx, err := newReviewedRunner(reviewer).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Runner.Run failed: %v", err)
+	}
+	if resultCtx.State != core.StateFailed {
+		t.Errorf("state = %s, want FAILED when reviewer disagrees", resultCtx.State)
+	}
+
+	if reviewed == nil || reviewed.Review == nil {
+		t.Fatal("reviewer did not receive review evidence")
+	}
+	if !strings.Contains(reviewed.Review.TestOutput, "review-tests-ok") || reviewed.Review.TestCommand == "" {
+		t.Errorf("test output not passed to reviewer: %+v", reviewed.Review)
+	}
+	if resultCtx.TestResult == nil || resultCtx.TestResult.ExitCode != 0 {
+		t.Errorf("test result should be recorded once before review: %+v", resultCtx.TestResult)
+	}
+
+	review := resultCtx.Review
+	if review == nil || review.Agreed || review.Reviewer != "anthropic-messages / reviewer-model" {
+		t.Fatalf("unexpected review: %+v", review)
+	}
+	var ids []string
+	for _, d := range review.Disagreements {
+		ids = append(ids, d.CriterionID)
+	}
+	if strings.Join(ids, ",") != "overall,AC-1" {
+		t.Errorf("disagreements = %v, want overall,AC-1", ids)
+	}
+	if d := review.Disagreements[1]; d.MetaStatus != "passed" || d.ReviewerStatus != "failed" || d.ReviewerComment != "handler missing in diff" {
+		t.Errorf("unexpected AC-1 disagreement: %+v", d)
+	}
+	if last := resultCtx.MetaCalls[len(resultCtx.MetaCalls)-1]; last.Type != "completion_review" || last.ResponseYAML == "" {
+		t.Errorf("review call not logged: %+v", last)
+	}
+}
+
+func TestRunner_ReviewerAgreementCompletesTask(t *testing.T) {
+	reviewer := core.NewMetaReviewer(&mock.MetaClient{
+		CompletionAssessmentFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.CompletionAssessmentResponse, error) {
+			return &meta.CompletionAssessmentResponse{
+				AllCriteriaSatisfied: true,
+				ByCriterion:          []meta.CriterionResult{{ID: "AC-1", Status: "passed"}},
+			}, nil
+		},
+	}, "reviewer")
+
+	resultCtx, err := newReviewedRunner(reviewer).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Runner.Run failed: %v", err)
+	}
+	if resultCtx.State != core.StateComplete {
+		t.Errorf("state = %s, want COMPLETE", resultCtx.State)
+	}
+	// 総合判定は一致しているが、Reviewer が評価しなかった基準は記録される
+	if !resultCtx.Review.Agreed || len(resultCtx.Review.Disagreements) != 1 || resultCtx.Review.Disagreements[0].ReviewerStatus != "missing" {
+		t.Errorf("unexpected review: %+v", resultCtx.Review)
+	}
+}
+
+func TestWorkerReviewer(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.email=t@example.com", "-c", "user.name=t", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	summary := &meta.TaskSummary{Title: "t", Review: &meta.ReviewEvidence{}}
+
+	verdict := "```yaml\ntype: completion_assessment\nversion: 1\npayload:\n  all_criteria_satisfied: true\n  summary: ok\n```"
+	var prompt string
+	readOnly := core.NewWorkerReviewer(&mock.WorkerExecutor{
+		RunWorkerFunc: func(ctx context.Context, call meta.WorkerCall, env map[string]string) (*core.WorkerRunResult, error) {
+			prompt = call.Prompt
+			return &core.WorkerRunResult{RawOutput: "reviewing...\n" + verdict}, nil
+		},
+	}, "codex-cli", nil, repo)
+	got, err := readOnly.Review(context.Background(), summary)
+	if err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+	if !got.AllCriteriaSatisfied || !strings.Contains(prompt, "MUST NOT modify") {
+		t.Errorf("unexpected review %+v / prompt %q", got, prompt)
+	}
+
+	// タスクの未コミットの変更（レビュー対象）
+	taskFile := filepath.Join(repo, "task.go")
+	if err := os.WriteFile(taskFile, []byte("package task\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writer := core.NewWorkerReviewer(&mock.WorkerExecutor{
+		RunWorkerFunc: func(ctx context.Context, call meta.WorkerCall, env map[string]string) (*core.WorkerRunResult, error) {
+			_ = os.WriteFile(filepath.Join(repo, "fix.go"), []byte("package x\n"), 0o644)
+			_ = os.WriteFile(taskFile, []byte("package task // reviewer edit\n"), 0o644)
+			_ = exec.Command("git", "-C", repo, "add", "-A").Run()
+			return &core.WorkerRunResult{RawOutput: verdict}, nil
+		},
+	}, "codex-cli", nil, repo)
+	if _, err := writer.Review(context.Background(), summary); err == nil || !strings.Contains(err.Error(), "modified the working tree") {
+		t.Errorf("expected modification to fail the review, got %v", err)
+	}
+
+	// レビュアーの変更は取り消され、タスクの変更はそのまま残る
+	if _, err := os.Stat(filepath.Join(repo, "fix.go")); !os.IsNotExist(err) {
+		t.Errorf("reviewer-created file should be removed, stat err = %v", err)
+	}
+	if data, _ := os.ReadFile(taskFile); string(data) != "package task\n" {
+		t.Errorf("task change should be restored, got %q", data)
+	}
+	if out, _ := exec.Command("git", "-C", repo, "status", "--porcelain").Output(); string(out) != "?? task.go\n" {
+		t.Errorf("index and tree should match the pre-review state, got %q", out)
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/logging"
 	"github.com/biwakonbu/agent-runner/internal/meta"
+	"github.com/biwakonbu/agent-runner/internal/usage"
 	"github.com/biwakonbu/agent-runner/pkg/config"
 	"gopkg.in/yaml.v3"
 )
@@ -34,6 +36,12 @@ type NoteWriter interface {
 	Write(taskCtx *TaskContext) error
 }
 
+// Checkpointer is implemented by NoteWriters that also persist intermediate
+// task state (e.g. the Meta transcript) while the task is running.
+type Checkpointer interface {
+	Checkpoint(taskCtx *TaskContext) error
+}
+
 // Runner orchestrates the task execution
 type Runner struct {
 	Config *config.TaskConfig
@@ -41,6 +49,12 @@ type Runner struct {
 	Worker WorkerExecutor
 	Note   NoteWriter
 	Logger *slog.Logger
+
+	// Prices はトークン使用量からコストを算出する価格表
+	Prices usage.PriceTable
+
+	// Reviewer は完了判定のセカンドオピニオン。nil の場合は Meta の判定のみで完了とする
+	Reviewer Reviewer
 }
 
 // NewRunner creates a new Runner instance
@@ -52,6 +66,7 @@ func NewRunner(cfg *config.TaskConfig, m MetaClient, w WorkerExecutor, n NoteWri
 		Worker: w,
 		Note:   n,
 		Logger: logger,
+		Prices: usage.DefaultPriceTable(),
 	}
 }
 
@@ -71,6 +86,9 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 	if r.Config.Task.SuggestedImpl != nil {
 		taskCtx.SuggestedImpl = r.Config.Task.SuggestedImpl
 	}
+	if maxTokens := r.Config.Runner.Meta.TranscriptMaxTokens; maxTokens >= 0 {
+		taskCtx.Transcript = meta.NewTranscript(taskCtx.ID, maxTokens)
+	}
 
 	// Create logger with trace ID and task context
 	logger := logging.WithTraceID(r.Logger, ctx)
@@ -81,6 +99,9 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 		slog.String("state", string(taskCtx.State)),
 	)
 
+	// 失敗時も含め、最終的な使用量を Orchestrator 向けに出力する
+	defer r.reportUsage(logger, taskCtx)
+
 	if taskCtx.RepoPath == "" {
 		taskCtx.RepoPath = "."
 	}
@@ -115,9 +136,11 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 	logger.Info("calling Meta.PlanTask", slog.String("event_type", "meta:thinking"), slog.String("detail", "Planning task..."))
 	logger.Debug("PlanTask request", slog.Int("prd_length", len(taskCtx.PRDText)))
 	planStart := time.Now()
-	plan, err := r.Meta.PlanTask(ctx, taskCtx.PRDText)
+	planCall := newMetaCallTracker()
+	plan, err := r.Meta.PlanTask(planCall.context(ctx), taskCtx.PRDText)
 	if err != nil {
 		logger.Error("PlanTask failed", slog.Any("error", err), logging.LogDuration(planStart))
+		taskCtx.MetaCalls = append(taskCtx.MetaCalls, planCall.log("plan_task", planRequestYAML, "", err))
 		taskCtx.State = StateFailed
 		return taskCtx, fmt.Errorf("planning failed: %w", err)
 	}
@@ -135,18 +158,17 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 	planRespBytes, _ := yaml.Marshal(planRespData)
 	planResponseYAML := string(planRespBytes)
 
-	taskCtx.MetaCalls = append(taskCtx.MetaCalls, MetaCallLog{
-		Type:         "plan_task",
-		Timestamp:    time.Now(),
-		RequestYAML:  planRequestYAML,
-		ResponseYAML: planResponseYAML,
-	})
+	taskCtx.MetaCalls = append(taskCtx.MetaCalls, planCall.log("plan_task", planRequestYAML, planResponseYAML, nil))
 
 	// Map meta.AcceptanceCriterion to core.AcceptanceCriterion (stored as strings)
 	for _, ac := range plan.AcceptanceCriteria {
 		// Just store the description for v2 alignment
 		taskCtx.AcceptanceCriteria = append(taskCtx.AcceptanceCriteria, ac.Description)
 	}
+	taskCtx.AppendTranscript(meta.TranscriptRoleUser, "plan_task", planRequestYAML, "")
+	taskCtx.AppendTranscript(meta.TranscriptRoleAssistant, "plan_task", planResponseYAML,
+		digest("plan_task: acceptance criteria: %s", strings.Join(taskCtx.AcceptanceCriteria, "; ")))
+	r.checkpoint(logger, taskCtx)
 
 	// 3. Start Container for the task
 	taskCtx.State = StateRunning
@@ -163,9 +185,12 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 	logger.Info("worker container started", slog.String("event_type", "container:started"), logging.LogDuration(containerStart))
 
 	// Ensure container is stopped at the end
+	// キャンセル（SIGTERM）で ctx が終了していてもコンテナを止められるよう、キャンセルを引き継がない ctx を使う
 	defer func() {
 		logger.Info("stopping worker container")
-		if err := r.Worker.Stop(ctx); err != nil {
+		stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), containerStopTimeout)
+		defer cancel()
+		if err := r.Worker.Stop(stopCtx); err != nil {
 			logger.Warn("failed to stop container", slog.Any("error", err))
 		} else {
 			logger.Info("worker container stopped")
@@ -194,6 +219,7 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 			State:              string(taskCtx.State),
 			AcceptanceCriteria: metaACs,
 			WorkerRunsCount:    len(taskCtx.WorkerRuns),
+			HasWorkerSession:   len(taskCtx.WorkerSessions) > 0,
 		}
 
 		// Record NextAction request
@@ -202,9 +228,11 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 
 		logger.Info("calling Meta.NextAction", slog.String("event_type", "meta:thinking"), slog.String("detail", "Analyzing..."), slog.Int("worker_runs_count", len(taskCtx.WorkerRuns)))
 		actionStart := time.Now()
-		action, err := r.Meta.NextAction(ctx, summary)
+		actionCall := newMetaCallTracker()
+		action, err := r.Meta.NextAction(meta.WithTranscript(actionCall.context(ctx), taskCtx.Transcript), summary)
 		if err != nil {
 			logger.Error("NextAction failed", slog.Any("error", err), logging.LogDuration(actionStart))
+			taskCtx.MetaCalls = append(taskCtx.MetaCalls, actionCall.log("next_action", nextActionReqYAML, "", err))
 			taskCtx.State = StateFailed
 			return taskCtx, fmt.Errorf("next_action failed: %w", err)
 		}
@@ -222,12 +250,10 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 		actionRespBytes, _ := yaml.Marshal(actionRespData)
 		nextActionRespYAML := string(actionRespBytes)
 
-		taskCtx.MetaCalls = append(taskCtx.MetaCalls, MetaCallLog{
-			Type:         "next_action",
-			Timestamp:    time.Now(),
-			RequestYAML:  nextActionReqYAML,
-			ResponseYAML: nextActionRespYAML,
-		})
+		taskCtx.MetaCalls = append(taskCtx.MetaCalls, actionCall.log("next_action", nextActionReqYAML, nextActionRespYAML, nil))
+		taskCtx.AppendTranscript(meta.TranscriptRoleUser, "next_action", nextActionReqYAML, "")
+		taskCtx.AppendTranscript(meta.TranscriptRoleAssistant, "next_action", nextActionRespYAML, actionDigest(action))
+		r.checkpoint(logger, taskCtx)
 
 		if action.Decision.Action == "mark_complete" {
 			// Transition to VALIDATING state for completion assessment
@@ -256,8 +282,10 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 			assessmentReqYAML := string(validationSummaryBytes)
 
 			// Call CompletionAssessment to evaluate task completion
-			assessment, err := r.Meta.CompletionAssessment(ctx, validationSummary)
+			assessmentCall := newMetaCallTracker()
+			assessment, err := r.Meta.CompletionAssessment(meta.WithTranscript(assessmentCall.context(ctx), taskCtx.Transcript), validationSummary)
 			if err != nil {
+				taskCtx.MetaCalls = append(taskCtx.MetaCalls, assessmentCall.log("completion_assessment", assessmentReqYAML, "", err))
 				taskCtx.State = StateFailed
 				return taskCtx, fmt.Errorf("completion assessment failed: %w", err)
 			}
@@ -271,12 +299,10 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 			assessmentRespBytes, _ := yaml.Marshal(assessmentRespData)
 			assessmentRespYAML := string(assessmentRespBytes)
 
-			taskCtx.MetaCalls = append(taskCtx.MetaCalls, MetaCallLog{
-				Type:         "completion_assessment",
-				Timestamp:    time.Now(),
-				RequestYAML:  assessmentReqYAML,
-				ResponseYAML: assessmentRespYAML,
-			})
+			taskCtx.MetaCalls = append(taskCtx.MetaCalls, assessmentCall.log("completion_assessment", assessmentReqYAML, assessmentRespYAML, nil))
+			taskCtx.AppendTranscript(meta.TranscriptRoleUser, "completion_assessment", assessmentReqYAML, "")
+			taskCtx.AppendTranscript(meta.TranscriptRoleAssistant, "completion_assessment", assessmentRespYAML,
+				digest("completion_assessment: all_criteria_satisfied=%t: %s", assessment.AllCriteriaSatisfied, assessment.Summary))
 
 			// NOTE: We don't update persistent Passed state for []string based ACs
 			// V2 relies on AllCriteriaSatisfied for final decision
@@ -284,6 +310,10 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 			// Determine final state based on assessment
 			if assessment.AllCriteriaSatisfied {
 				taskCtx.State = StateComplete
+				// Reviewer が設定されている場合は、両者が合意したときだけ完了とする
+				if r.Reviewer != nil {
+					r.review(ctx, logger, taskCtx, validationSummary, assessment)
+				}
 			} else {
 				taskCtx.State = StateFailed
 			}
@@ -293,7 +323,12 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 			logger.Info("executing worker", slog.String("event_type", "worker:running"), slog.String("command", action.WorkerCall.Prompt), slog.Int("prompt_length", len(action.WorkerCall.Prompt)))
 			logger.Debug("worker prompt", slog.String("prompt", action.WorkerCall.Prompt))
 			workerStart := time.Now()
-			res, err := r.Worker.RunWorker(ctx, action.WorkerCall, r.Config.Runner.Worker.Env)
+			call := action.WorkerCall
+			if call.Session == meta.WorkerSessionContinue && call.SessionID == "" {
+				// 直前のセッションを継続（同じ worker kind のもの）
+				call.SessionID = taskCtx.WorkerSessions[r.Config.Runner.Worker.KindChain(call.WorkerType)[0]]
+			}
+			res, err := r.Worker.RunWorker(ctx, call, r.Config.Runner.Worker.Env)
 			if err != nil {
 				logger.Error("worker execution failed", slog.Any("error", err), logging.LogDuration(workerStart))
 				// Worker execution failed (system error), record it but maybe continue?
@@ -312,8 +347,12 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 					logging.LogDuration(workerStart),
 				)
 				logger.Debug("worker output", slog.String("output", res.RawOutput))
+				taskCtx.RecordWorkerSession(res)
 			}
 			taskCtx.WorkerRuns = append(taskCtx.WorkerRuns, *res)
+			taskCtx.AppendTranscript(meta.TranscriptRoleUser, "worker_result", workerResultTranscript(res),
+				digest("worker_result: run %s exit_code=%d: %s", res.ID, res.ExitCode, res.Summary))
+			r.checkpoint(logger, taskCtx)
 		} else {
 			// Unknown action or abort
 			taskCtx.State = StateFailed
@@ -321,8 +360,8 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 		}
 	}
 
-	// 4. Run Test Command (if configured and task completed)
-	if taskCtx.State == StateComplete && r.Config.Task.Test.Command != "" {
+	// 4. Run Test Command (if configured and task completed, unless already run for review)
+	if taskCtx.State == StateComplete && r.Config.Task.Test.Command != "" && taskCtx.TestResult == nil {
 		if err := r.runTestCommand(ctx, taskCtx); err != nil {
 			r.Logger.Warn("test command failed", "err", err)
 		}
@@ -338,6 +377,9 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 	)
 
 	// Write Note
+	usageSummary := taskCtx.UsageSummary(r.Prices)
+	taskCtx.Usage = &usageSummary
+	r.checkpoint(logger, taskCtx)
 	if err := r.Note.Write(taskCtx); err != nil {
 		logger.Warn("failed to write task note", slog.Any("error", err))
 	} else {
@@ -347,6 +389,131 @@ func (r *Runner) Run(ctx context.Context) (*TaskContext, error) {
 	return taskCtx, nil
 }
 
+// review asks the independent reviewer to re-assess the acceptance criteria
+// against the diff and test output. レビューが完了を確認できなければタスクは FAILED になる。
+func (r *Runner) review(ctx context.Context, logger *slog.Logger, taskCtx *TaskContext, summary *meta.TaskSummary, assessment *meta.CompletionAssessmentResponse) {
+	// テストを先に実行して結果をレビューの証拠にする（終了処理では再実行しない）
+	if r.Config.Task.Test.Command != "" && taskCtx.TestResult == nil {
+		if err := r.runTestCommand(ctx, taskCtx); err != nil {
+			logger.Warn("test command failed", slog.Any("error", err))
+		}
+	}
+	evidence := &meta.ReviewEvidence{Diff: collectDiff(ctx, taskCtx.RepoPath)}
+	if tr := taskCtx.TestResult; tr != nil {
+		evidence.TestCommand = tr.Command
+		evidence.TestExitCode = tr.ExitCode
+		evidence.TestOutput = tr.RawOutput
+	}
+	reviewSummary := *summary
+	reviewSummary.Review = evidence
+	reqBytes, _ := yaml.Marshal(reviewSummary)
+
+	result := &ReviewResult{Reviewer: r.Reviewer.Name()}
+	taskCtx.Review = result
+
+	logger.Info("calling independent reviewer", slog.String("event_type", "review:started"), slog.String("reviewer", result.Reviewer))
+	reviewStart := time.Now()
+	call := newMetaCallTracker()
+	review, err := r.Reviewer.Review(call.context(ctx), &reviewSummary)
+	if err != nil {
+		logger.Error("independent review failed", slog.Any("error", err), logging.LogDuration(reviewStart))
+		taskCtx.MetaCalls = append(taskCtx.MetaCalls, call.log("completion_review", string(reqBytes), "", err))
+		result.Error = err.Error()
+		taskCtx.State = StateFailed
+		return
+	}
+
+	respBytes, _ := yaml.Marshal(map[string]interface{}{
+		"type":    "completion_assessment",
+		"version": 1,
+		"payload": review,
+	})
+	taskCtx.MetaCalls = append(taskCtx.MetaCalls, call.log("completion_review", string(reqBytes), string(respBytes), nil))
+
+	result.Assessment = review
+	result.Disagreements = compareAssessments(assessment, review)
+	result.Agreed = assessment.AllCriteriaSatisfied && review.AllCriteriaSatisfied
+	if !result.Agreed {
+		taskCtx.State = StateFailed
+	}
+	logger.Info("independent review completed",
+		slog.String("event_type", "review:completed"),
+		slog.Bool("agreed", result.Agreed),
+		slog.Int("disagreements", len(result.Disagreements)),
+		logging.LogDuration(reviewStart),
+	)
+}
+
+// checkpoint persists intermediate task state if the NoteWriter supports it.
+func (r *Runner) checkpoint(logger *slog.Logger, taskCtx *TaskContext) {
+	cp, ok := r.Note.(Checkpointer)
+	if !ok {
+		return
+	}
+	if err := cp.Checkpoint(taskCtx); err != nil {
+		logger.Warn("failed to write task checkpoint", slog.Any("error", err))
+	}
+}
+
+// maxDigestLength はトランスクリプト要約 1 行の最大長
+const maxDigestLength = 200
+
+// containerStopTimeout はタスク終了時（キャンセル時を含む）のコンテナ停止の猶予時間
+const containerStopTimeout = 30 * time.Second
+
+// workerOutputTailLength はトランスクリプトに残す Worker 出力末尾の長さ
+const workerOutputTailLength = 2000
+
+func digest(format string, args ...interface{}) string {
+	s := strings.Join(strings.Fields(fmt.Sprintf(format, args...)), " ")
+	if len(s) > maxDigestLength {
+		s = s[:maxDigestLength] + "..."
+	}
+	return s
+}
+
+func actionDigest(action *meta.NextActionResponse) string {
+	if action.Decision.Action == "run_worker" {
+		return digest("next_action: run_worker (%s) prompt=%q", action.Decision.Reason, action.WorkerCall.Prompt)
+	}
+	return digest("next_action: %s (%s)", action.Decision.Action, action.Decision.Reason)
+}
+
+// workerResultTranscript renders the observed worker result for the Meta transcript.
+func workerResultTranscript(res *WorkerRunResult) string {
+	output := res.RawOutput
+	if len(output) > workerOutputTailLength {
+		output = "..." + output[len(output)-workerOutputTailLength:]
+	}
+	data := map[string]interface{}{
+		"type":      "worker_result",
+		"run_id":    res.ID,
+		"exit_code": res.ExitCode,
+		"summary":   res.Summary,
+		"output":    output,
+	}
+	if res.Error != nil {
+		data["error"] = res.Error.Error()
+	}
+	out, _ := yaml.Marshal(data)
+	return string(out)
+}
+
+// reportUsage logs the task's token usage and cost as a structured event.
+// Orchestrator はこのイベント（event_type=task:usage）から試行ごとの使用量を取得する。
+func (r *Runner) reportUsage(logger *slog.Logger, taskCtx *TaskContext) {
+	summary := taskCtx.UsageSummary(r.Prices)
+	logger.Info("task usage",
+		slog.String("event_type", "task:usage"),
+		slog.Int("input_tokens", summary.InputTokens),
+		slog.Int("cached_input_tokens", summary.CachedInputTokens),
+		slog.Int("output_tokens", summary.OutputTokens),
+		slog.Int("calls", summary.Calls),
+		slog.Float64("cost_usd", summary.CostUSD),
+		slog.Any("by_model", summary.ByModel),
+	)
+}
+
 // runTestCommand executes the test command configured in the task
 func (r *Runner) runTestCommand(ctx context.Context, taskCtx *TaskContext) error {
 	testCmd := r.Config.Task.Test.Command
@@ -395,3 +562,36 @@ func (r *Runner) runTestCommand(ctx context.Context, taskCtx *TaskContext) error
 
 	return nil
 }
+
+// metaCallTracker は 1 回の Meta 呼び出しの使用量と、スキーマ検証に失敗した応答（修復再プロンプト）を収集する
+type metaCallTracker struct {
+	usage      *usage.Recorder
+	validation *meta.ValidationRecorder
+	route      meta.Route
+}
+
+func newMetaCallTracker() *metaCallTracker {
+	return &metaCallTracker{usage: &usage.Recorder{}, validation: &meta.ValidationRecorder{}}
+}
+
+func (t *metaCallTracker) context(ctx context.Context) context.Context {
+	ctx = meta.WithValidationRecorder(usage.WithRecorder(ctx, t.usage), t.validation)
+	return meta.WithRouteRecorder(ctx, &t.route)
+}
+
+func (t *metaCallTracker) log(callType, requestYAML, responseYAML string, err error) MetaCallLog {
+	entry := MetaCallLog{
+		Type:             callType,
+		Timestamp:        time.Now(),
+		RequestYAML:      requestYAML,
+		ResponseYAML:     responseYAML,
+		Usage:            t.usage.Total(),
+		Route:            t.route,
+		InvalidResponses: t.validation.Invalid(),
+		Repairs:          t.validation.Repairs(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
@@ -795,6 +795,121 @@ func TestRunner_ValidatingState_MetaCallsSequence(t *testing.T) {
 	}
 }
 
+func TestRunner_WorkerSessionContinuation(t *testing.T) {
+	cfg := &config.TaskConfig{
+		Task: config.TaskDetails{ID: "test-task", Title: "Test Task", Repo: ".", PRD: config.PRDDetails{Text: "PRD"}},
+		Runner: config.RunnerConfig{
+			Worker: config.WorkerConfig{Kind: "codex-cli", Env: map[string]string{}},
+		},
+	}
+
+	var sawSession []bool
+	mockMeta := &mock.MetaClient{
+		PlanTaskFunc: func(ctx context.Context, prd string) (*meta.PlanTaskResponse, error) {
+			return &meta.PlanTaskResponse{TaskID: "test-task"}, nil
+		},
+		NextActionFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.NextActionResponse, error) {
+			sawSession = append(sawSession, summary.HasWorkerSession)
+			switch summary.WorkerRunsCount {
+			case 0:
+				return &meta.NextActionResponse{
+					Decision:   meta.Decision{Action: "run_worker"},
+					WorkerCall: meta.WorkerCall{Prompt: "first", Session: meta.WorkerSessionNew},
+				}, nil
+			case 1:
+				return &meta.NextActionResponse{
+					Decision:   meta.Decision{Action: "run_worker"},
+					WorkerCall: meta.WorkerCall{Prompt: "second", Session: meta.WorkerSessionContinue},
+				}, nil
+			}
+			return &meta.NextActionResponse{Decision: meta.Decision{Action: "mark_complete"}}, nil
+		},
+		CompletionAssessmentFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.CompletionAssessmentResponse, error) {
+			return &meta.CompletionAssessmentResponse{AllCriteriaSatisfied: true}, nil
+		},
+	}
+
+	var calls []meta.WorkerCall
+	mockWorker := &mock.WorkerExecutor{
+		StartFunc: func(ctx context.Context) error { return nil },
+		StopFunc:  func(ctx context.Context) error { return nil },
+		RunWorkerFunc: func(ctx context.Context, call meta.WorkerCall, env map[string]string) (*core.WorkerRunResult, error) {
+			calls = append(calls, call)
+			return &core.WorkerRunResult{WorkerKind: "codex-cli", SessionID: "sess-1"}, nil
+		},
+	}
+
+	runner := core.NewRunner(cfg, mockMeta, mockWorker, &mock.NoteWriter{})
+	resultCtx, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Runner.Run failed: %v", err)
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 worker calls, got %d", len(calls))
+	}
+	if calls[0].SessionID != "" {
+		t.Errorf("first call should start a fresh session, got %q", calls[0].SessionID)
+	}
+	if calls[1].SessionID != "sess-1" {
+		t.Errorf("second call should continue sess-1, got %q", calls[1].SessionID)
+	}
+	if resultCtx.WorkerSessions["codex-cli"] != "sess-1" {
+		t.Errorf("session not stored in TaskContext: %v", resultCtx.WorkerSessions)
+	}
+	if len(sawSession) < 2 || sawSession[0] || !sawSession[1] {
+		t.Errorf("HasWorkerSession sequence unexpected: %v", sawSession)
+	}
+}
+
+func TestRunner_MetaCallLog_RecordsRepairsAndFailures(t *testing.T) {
+	cfg := &config.TaskConfig{
+		Task: config.TaskDetails{ID: "test-task", Title: "Test Task", Repo: ".", PRD: config.PRDDetails{Text: "PRD"}},
+		Runner: config.RunnerConfig{
+			Worker: config.WorkerConfig{Kind: "codex-cli", Env: map[string]string{}},
+		},
+	}
+
+	mockMeta := &mock.MetaClient{
+		PlanTaskFunc: func(ctx context.Context, prd string) (*meta.PlanTaskResponse, error) {
+			// プロバイダが 1 回修復した場合と同じ記録を残す
+			meta.RecordInvalid(ctx, meta.InvalidResponse{
+				Attempt:      1,
+				Response:     "not yaml",
+				Errors:       []string{"payload.acceptance_criteria: required"},
+				RepairPrompt: "fix it",
+			})
+			return &meta.PlanTaskResponse{TaskID: "test-task"}, nil
+		},
+		NextActionFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.NextActionResponse, error) {
+			meta.RecordInvalid(ctx, meta.InvalidResponse{Attempt: 3, Errors: []string{"payload.decision.action: required"}})
+			return nil, meta.ErrInvalidResponse
+		},
+	}
+	mockWorker := &mock.WorkerExecutor{
+		StartFunc: func(ctx context.Context) error { return nil },
+		StopFunc:  func(ctx context.Context) error { return nil },
+	}
+
+	runner := core.NewRunner(cfg, mockMeta, mockWorker, &mock.NoteWriter{})
+	resultCtx, err := runner.Run(context.Background())
+	if err == nil {
+		t.Fatal("expected next_action failure")
+	}
+
+	if len(resultCtx.MetaCalls) != 2 {
+		t.Fatalf("expected 2 meta calls, got %d", len(resultCtx.MetaCalls))
+	}
+	plan := resultCtx.MetaCalls[0]
+	if plan.Repairs != 1 || len(plan.InvalidResponses) != 1 || plan.Error != "" {
+		t.Errorf("plan_task log unexpected: %+v", plan)
+	}
+	next := resultCtx.MetaCalls[1]
+	if next.Type != "next_action" || next.Error == "" || next.Repairs != 0 || len(next.InvalidResponses) != 1 {
+		t.Errorf("failed next_action log unexpected: %+v", next)
+	}
+}
+
 // Helper function to check if string contains substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && containsAt(s, substr))
@@ -808,3 +923,145 @@ func containsAt(s, substr string) bool {
 	}
 	return false
 }
+
+func TestRunner_TranscriptReplayedToMeta(t *testing.T) {
+	cfg := &config.TaskConfig{
+		Task: config.TaskDetails{ID: "test-task", Title: "Test Task", Repo: ".", PRD: config.PRDDetails{Text: "PRD"}},
+		Runner: config.RunnerConfig{
+			Worker: config.WorkerConfig{Kind: "codex-cli", Env: map[string]string{}},
+		},
+	}
+
+	var seen []int
+	mockMeta := &mock.MetaClient{
+		PlanTaskFunc: func(ctx context.Context, prd string) (*meta.PlanTaskResponse, error) {
+			return &meta.PlanTaskResponse{TaskID: "test-task", AcceptanceCriteria: []meta.AcceptanceCriterion{{ID: "AC-1", Description: "works"}}}, nil
+		},
+		NextActionFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.NextActionResponse, error) {
+			seen = append(seen, len(meta.TranscriptFrom(ctx).Entries))
+			if summary.WorkerRunsCount == 0 {
+				return &meta.NextActionResponse{
+					Decision:   meta.Decision{Action: "run_worker", Reason: "implement"},
+					WorkerCall: meta.WorkerCall{Prompt: "do it"},
+				}, nil
+			}
+			return &meta.NextActionResponse{Decision: meta.Decision{Action: "mark_complete"}}, nil
+		},
+		CompletionAssessmentFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.CompletionAssessmentResponse, error) {
+			seen = append(seen, len(meta.TranscriptFrom(ctx).Entries))
+			return &meta.CompletionAssessmentResponse{AllCriteriaSatisfied: true}, nil
+		},
+	}
+	mockWorker := &mock.WorkerExecutor{
+		StartFunc: func(ctx context.Context) error { return nil },
+		StopFunc:  func(ctx context.Context) error { return nil },
+		RunWorkerFunc: func(ctx context.Context, call meta.WorkerCall, env map[string]string) (*core.WorkerRunResult, error) {
+			return &core.WorkerRunResult{ID: "run-1", Summary: "done"}, nil
+		},
+	}
+
+	note := &checkpointingNoteWriter{}
+	runner := core.NewRunner(cfg, mockMeta, mockWorker, note)
+	resultCtx, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Runner.Run failed: %v", err)
+	}
+
+	// plan(2) → next_action(+2) → worker_result(+1) → next_action(+2) → assessment
+	want := []int{2, 5, 7}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("transcript sizes seen by Meta = %v, want %v", seen, want)
+	}
+	entries := resultCtx.Transcript.Entries
+	if len(entries) != 9 {
+		t.Fatalf("expected 9 transcript entries, got %d", len(entries))
+	}
+	if entries[4].Kind != "worker_result" || entries[4].Role != meta.TranscriptRoleUser {
+		t.Errorf("unexpected worker entry: %+v", entries[4])
+	}
+	if entries[3].Digest == "" || !contains(entries[3].Digest, "run_worker") {
+		t.Errorf("decision digest missing: %q", entries[3].Digest)
+	}
+	if note.checkpoints < 4 || note.last == nil || note.last.State != core.StateComplete {
+		t.Errorf("unexpected checkpoints: %d", note.checkpoints)
+	}
+}
+
+func TestRunner_TranscriptDisabled(t *testing.T) {
+	cfg := &config.TaskConfig{
+		Task: config.TaskDetails{ID: "test-task", Title: "Test Task", Repo: ".", PRD: config.PRDDetails{Text: "PRD"}},
+		Runner: config.RunnerConfig{
+			Meta:   config.MetaConfig{TranscriptMaxTokens: -1},
+			Worker: config.WorkerConfig{Kind: "codex-cli", Env: map[string]string{}},
+		},
+	}
+	mockMeta := &mock.MetaClient{
+		PlanTaskFunc: func(ctx context.Context, prd string) (*meta.PlanTaskResponse, error) {
+			return &meta.PlanTaskResponse{TaskID: "test-task"}, nil
+		},
+		NextActionFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.NextActionResponse, error) {
+			if meta.TranscriptFrom(ctx) != nil {
+				t.Error("transcript should not be replayed when disabled")
+			}
+			return &meta.NextActionResponse{Decision: meta.Decision{Action: "mark_complete"}}, nil
+		},
+		CompletionAssessmentFunc: func(ctx context.Context, summary *meta.TaskSummary) (*meta.CompletionAssessmentResponse, error) {
+			return &meta.CompletionAssessmentResponse{AllCriteriaSatisfied: true}, nil
+		},
+	}
+	mockWorker := &mock.WorkerExecutor{
+		StartFunc: func(ctx context.Context) error { return nil },
+		StopFunc:  func(ctx context.Context) error { return nil },
+	}
+
+	resultCtx, err := core.NewRunner(cfg, mockMeta, mockWorker, &mock.NoteWriter{}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Runner.Run failed: %v", err)
+	}
+	if resultCtx.Transcript != nil {
+		t.Errorf("expected no transcript, got %+v", resultCtx.Transcript)
+	}
+}
+
+// checkpointingNoteWriter は Checkpoint 呼び出しを記録する NoteWriter
+type checkpointingNoteWriter struct {
+	checkpoints int
+	last        *core.TaskContext
+}
+
+func (w *checkpointingNoteWriter) Write(*core.TaskContext) error { return nil }
+
+func (w *checkpointingNoteWriter) Checkpoint(taskCtx *core.TaskContext) error {
+	w.checkpoints++
+	w.last = taskCtx
+	return nil
+}
+
+func TestRunner_MetaCallLog_RecordsRoute(t *testing.T) {
+	cfg := &config.TaskConfig{
+		Task: config.TaskDetails{ID: "test-task", Title: "Test Task", Repo: ".", PRD: config.PRDDetails{Text: "PRD"}},
+		Runner: config.RunnerConfig{
+			Worker: config.WorkerConfig{Kind: "codex-cli", Env: map[string]string{}},
+		},
+	}
+	mockWorker := &mock.WorkerExecutor{
+		StartFunc: func(ctx context.Context) error { return nil },
+		StopFunc:  func(ctx context.Context) error { return nil },
+		RunWorkerFunc: func(ctx context.Context, call meta.WorkerCall, env map[string]string) (*core.WorkerRunResult, error) {
+			return &core.WorkerRunResult{ID: "run-1"}, nil
+		},
+	}
+
+	resultCtx, err := core.NewRunner(cfg, meta.NewMockClient(), mockWorker, &mock.NoteWriter{}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Runner.Run failed: %v", err)
+	}
+	if len(resultCtx.MetaCalls) == 0 {
+		t.Fatal("expected meta calls")
+	}
+	for _, call := range resultCtx.MetaCalls {
+		if call.Route.Kind != "mock" || call.Route.Model != "mock" {
+			t.Errorf("%s: unexpected route %+v", call.Type, call.Route)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Package filelock はプロセス間の排他に使うアドバイザリファイルロックを提供する。
+//
+// 同じワークスペースを複数のオーケストレーターが扱う場合に、
+// state/tasks.json やキューの Read-Modify-Write を直列化するために使う。
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Lock is a held advisory lock on a lock file.
+type Lock struct {
+	f *os.File
+}
+
+// Acquire blocks until the exclusive lock on path is held. ロックファイルと
+// 親ディレクトリは無ければ作成する。ロックはプロセス終了時にも OS によって解放される。
+func Acquire(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release releases the lock. nil の Lock に対しては何もしない。
+func (l *Lock) Release() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package filelock
+
+import "os"
+
+// flock の無いプラットフォームではプロセス間の排他を行わない（プロセス内の排他は呼び出し側で行う）
+
+func lockFile(*os.File) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,46 @@
+//go:build unix
+
+package filelock
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire_ExcludesOtherHolders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "tasks.json.lock")
+
+	first, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// flock は open したファイルごとに排他されるため、同一プロセスでも別プロセスと同様に待たされる
+	acquired := make(chan *Lock)
+	go func() {
+		second, err := Acquire(path)
+		if err != nil {
+			t.Errorf("second Acquire() error = %v", err)
+		}
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second Acquire() should block while the lock is held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	select {
+	case second := <-acquired:
+		if err := second.Release(); err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("second Acquire() did not proceed after Release()")
+	}
+}
@@ -9,10 +9,25 @@ import (
 
 // LLMConfig は LLM プロバイダの設定
 type LLMConfig struct {
-	Kind         string `json:"kind"`                   // mock, codex-cli, openai-chat
+	Kind         string `json:"kind"`                   // mock, codex-cli, openai-chat, anthropic-messages
 	Model        string `json:"model"`                  // 空の場合は各プロバイダのデフォルトを使用（codex-cli: gpt-5.2）
-	BaseURL      string `json:"baseUrl,omitempty"`      // カスタムエンドポイント
+	BaseURL      string `json:"baseUrl,omitempty"`      // カスタムエンドポイント（例: http://localhost:11434/v1）
 	SystemPrompt string `json:"systemPrompt,omitempty"` // カスタムシステムプロンプト
+
+	Headers           map[string]string `json:"headers,omitempty"`           // 追加 HTTP ヘッダー（値の ${VAR} は環境変数で展開）
+	TimeoutSec        int               `json:"timeoutSec,omitempty"`        // HTTP タイムアウト秒（0 はプロバイダ既定）
+	StructuredOutputs *bool             `json:"structuredOutputs,omitempty"` // response_format (json_schema) を使うか（未指定は有効）
+	ToolCalls         *bool             `json:"toolCalls,omitempty"`         // next_action / plan_patch を関数呼び出しで受け取るか（未指定は有効）
+
+	// Routes はメッセージ種別（plan_task / next_action / completion_assessment / decompose / plan_patch）
+	// ごとのプロバイダとモデル。未指定の種別は Kind / Model を使う
+	Routes map[string]LLMRoute `json:"routes,omitempty"`
+}
+
+// LLMRoute はメッセージ種別ごとのプロバイダ種別とモデル（空のフィールドは既定値を引き継ぐ）
+type LLMRoute struct {
+	Kind  string `json:"kind,omitempty"`
+	Model string `json:"model,omitempty"`
 }
 
 // DefaultLLMConfig はデフォルトの LLM 設定を返す
@@ -89,6 +104,15 @@ func (s *LLMConfigStore) GetAPIKey() (string, error) {
 	return "", nil
 }
 
+// GetAPIKeyForKind は Meta プロバイダ種別に応じた API キーを取得する
+// anthropic-messages は ANTHROPIC_API_KEY、それ以外は GetAPIKey と同じ
+func (s *LLMConfigStore) GetAPIKeyForKind(kind string) (string, error) {
+	if kind == "anthropic-messages" {
+		return os.Getenv("ANTHROPIC_API_KEY"), nil
+	}
+	return s.GetAPIKey()
+}
+
 // SetAPIKey は API キーを保存する
 // 注意: 現時点では環境変数を推奨。設定ファイルへの保存はセキュリティリスクがあるため未実装
 func (s *LLMConfigStore) SetAPIKey(_ string) error {
@@ -120,6 +144,9 @@ func (s *LLMConfigStore) GetEffectiveConfig() (*LLMConfig, error) {
 	if prompt := os.Getenv("MULTIVERSE_META_SYSTEM_PROMPT"); prompt != "" {
 		config.SystemPrompt = prompt
 	}
+	if baseURL := os.Getenv("MULTIVERSE_META_BASE_URL"); baseURL != "" {
+		config.BaseURL = baseURL
+	}
 
 	return config, nil
 }
@@ -76,6 +76,20 @@ func TestLLMConfigStore_GetAPIKey_FromEnv(t *testing.T) {
 	assert.Equal(t, "sk-test-key-12345", key)
 }
 
+func TestLLMConfigStore_GetAPIKeyForKind(t *testing.T) {
+	store := NewLLMConfigStore(t.TempDir())
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant")
+
+	key, err := store.GetAPIKeyForKind("anthropic-messages")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-ant", key)
+
+	key, err = store.GetAPIKeyForKind("openai-chat")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-openai", key)
+}
+
 func TestLLMConfigStore_GetAPIKey_Empty(t *testing.T) {
 	tmpDir := t.TempDir()
 	store := NewLLMConfigStore(tmpDir)
@@ -131,3 +145,21 @@ func TestLLMConfigStore_SetAPIKey_NotImplemented(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not implemented")
 }
+
+func TestLLMConfigStore_SaveAndLoad_Routes(t *testing.T) {
+	store := NewLLMConfigStore(t.TempDir())
+
+	config := &LLMConfig{
+		Kind:  "openai-chat",
+		Model: "gpt-5.2",
+		Routes: map[string]LLMRoute{
+			"next_action":           {Model: "gpt-5.2-mini"},
+			"completion_assessment": {Kind: "anthropic-messages", Model: "claude-opus"},
+		},
+	}
+	require.NoError(t, store.Save(config))
+
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, config.Routes, loaded.Routes)
+}
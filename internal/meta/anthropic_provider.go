@@ -0,0 +1,422 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/agenttools"
+	"github.com/biwakonbu/agent-runner/internal/logging"
+	"github.com/biwakonbu/agent-runner/internal/prompt"
+	"github.com/biwakonbu/agent-runner/internal/ratelimit"
+	"github.com/biwakonbu/agent-runner/internal/usage"
+)
+
+const (
+	// AnthropicKind is the LLMConfig.Kind for the Anthropic Messages API provider.
+	AnthropicKind = "anthropic-messages"
+
+	// DefaultAnthropicBaseURL is the Anthropic API endpoint.
+	DefaultAnthropicBaseURL = "https://api.anthropic.com"
+
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 8192
+)
+
+// AnthropicProvider calls the Anthropic Messages API over HTTP.
+type AnthropicProvider struct {
+	apiKey         string
+	model          string
+	systemPrompt   string
+	baseURL        string
+	headers        map[string]string
+	client         *http.Client
+	logger         *slog.Logger
+	limiter        *ratelimit.Limiter
+	prompts        *prompt.Renderer // 呼び出し種別ごとのシステムプロンプト（nil は組み込みテンプレート）
+	retryBaseDelay time.Duration
+
+	// toolCalls は next_action / plan_patch を tool_use で受け取るかどうか。
+	// 互換ゲートウェイが tools 未対応と応答した場合は toolsUnsupported を立てて YAML に戻す。
+	toolCalls        bool
+	toolsUnsupported atomic.Bool
+}
+
+// Ensure AnthropicProvider implements Provider interface
+var _ Provider = (*AnthropicProvider)(nil)
+
+// NewAnthropicProvider creates a new AnthropicProvider
+func NewAnthropicProvider(apiKey, model, systemPrompt string) *AnthropicProvider {
+	if model == "" {
+		model = agenttools.DefaultClaudeModel
+	}
+	return &AnthropicProvider{
+		apiKey:         apiKey,
+		model:          model,
+		systemPrompt:   systemPrompt,
+		baseURL:        DefaultAnthropicBaseURL,
+		client:         &http.Client{Timeout: 120 * time.Second},
+		logger:         logging.WithComponent(slog.Default(), "meta-anthropic"),
+		retryBaseDelay: 1 * time.Second,
+		toolCalls:      true,
+	}
+}
+
+// SetLogger sets a custom logger
+func (p *AnthropicProvider) SetLogger(logger *slog.Logger) {
+	p.logger = logging.WithComponent(logger, "meta-anthropic")
+}
+
+// SetLimiter sets the shared rate limiter
+func (p *AnthropicProvider) SetLimiter(limiter *ratelimit.Limiter) {
+	p.limiter = limiter
+}
+
+// SetBaseURL overrides the API endpoint (e.g. a gateway or test server).
+// 空文字の場合は既定のエンドポイントを使う。
+func (p *AnthropicProvider) SetBaseURL(baseURL string) {
+	if baseURL == "" {
+		baseURL = DefaultAnthropicBaseURL
+	}
+	p.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SetHTTPOptions applies endpoint, header, timeout and tool call settings.
+// Anthropic Messages API には response_format が無いため StructuredOutputs は無視する。
+func (p *AnthropicProvider) SetHTTPOptions(opts HTTPOptions) {
+	p.SetBaseURL(opts.BaseURL)
+	p.headers = opts.expandedHeaders()
+	if opts.Timeout > 0 {
+		p.client.Timeout = opts.Timeout
+	}
+	if opts.ToolCalls != nil {
+		p.toolCalls = *opts.ToolCalls
+	}
+}
+
+// Protocol returns the Meta protocol mode currently used for msgType
+// (ProtocolTools or ProtocolText).
+func (p *AnthropicProvider) Protocol(msgType string) string {
+	if p.toolCalls && !p.toolsUnsupported.Load() && metaTools(msgType) != nil {
+		return ProtocolTools
+	}
+	return ProtocolText
+}
+
+// SetPrompts sets the templates of the per-call system prompts
+func (p *AnthropicProvider) SetPrompts(prompts *prompt.Renderer) {
+	p.prompts = prompts
+}
+
+// SetCassette records or replays HTTP exchanges through cassette
+func (p *AnthropicProvider) SetCassette(cassette *Cassette) {
+	p.client.Transport = cassette.Transport(p.client.Transport)
+}
+
+func (p *AnthropicProvider) Name() string {
+	return AnthropicKind
+}
+
+// TestConnection verifies the API key is configured.
+// 実際の疎通確認はトークンを消費するため行わない。
+func (p *AnthropicProvider) TestConnection(_ context.Context) error {
+	if strings.TrimSpace(p.apiKey) == "" {
+		return fmt.Errorf("ANTHROPIC_API_KEY is not set")
+	}
+	return nil
+}
+
+type anthropicRequest struct {
+	Model      string               `json:"model"`
+	MaxTokens  int                  `json:"max_tokens"`
+	System     string               `json:"system,omitempty"`
+	Messages   []anthropicMessage   `json:"messages"`
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"` // any: いずれかのツールを必ず呼ぶ
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type  string                 `json:"type"`
+		Text  string                 `json:"text"`
+		Name  string                 `json:"name"`  // tool_use
+		Input map[string]interface{} `json:"input"` // tool_use
+	} `json:"content"`
+	StopReason string          `json:"stop_reason"`
+	Usage      *anthropicUsage `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+func (p *AnthropicProvider) callLLM(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return p.callMessage(ctx, "", systemPrompt, userPrompt)
+}
+
+// newRequest builds the Messages API request for msgType, declaring tools
+// when the message type has a tool variant and the endpoint supports them.
+func (p *AnthropicProvider) newRequest(ctx context.Context, msgType, systemPrompt, userPrompt string) anthropicRequest {
+	reqBody := anthropicRequest{
+		Model:     p.model,
+		MaxTokens: anthropicMaxTokens,
+		System:    systemPrompt,
+	}
+	if p.Protocol(msgType) == ProtocolTools {
+		for _, t := range metaTools(msgType) {
+			reqBody.Tools = append(reqBody.Tools, anthropicTool{Name: t.Name, Description: t.Description, InputSchema: t.Parameters})
+		}
+		reqBody.ToolChoice = &anthropicToolChoice{Type: "any"}
+		reqBody.System += toolModeInstruction
+	}
+	// タスクのトランスクリプトがあれば、過去のやり取りを会話として再送する（user/assistant は交互にする）
+	turns := append(TranscriptFrom(ctx).turns(), message{Role: TranscriptRoleUser, Content: userPrompt})
+	for _, m := range alternatingTurns(turns) {
+		reqBody.Messages = append(reqBody.Messages, anthropicMessage{Role: m.Role, Content: m.Content})
+	}
+	return reqBody
+}
+
+// callMessage calls the Messages API for msgType. tool_use ブロックは MetaMessage
+// に組み立て直して返すため、呼び出し元はテキスト応答と同様に解析できる。
+func (p *AnthropicProvider) callMessage(ctx context.Context, msgType, systemPrompt, userPrompt string) (string, error) {
+	const maxRetries = 3
+
+	logger := logging.WithTraceID(p.logger, ctx)
+	start := time.Now()
+
+	reqBody := p.newRequest(ctx, msgType, systemPrompt, userPrompt)
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("calling LLM",
+		slog.String("model", p.model),
+		slog.String("protocol", p.Protocol(msgType)),
+		slog.Int("request_size", len(jsonBody)),
+	)
+	logger.Debug("LLM request",
+		slog.String("system_prompt", systemPrompt),
+		slog.String("user_prompt", userPrompt),
+	)
+
+	limitKey := ratelimit.ModelKey(p.model)
+	wait := func(attempt int) error {
+		delay := p.retryBaseDelay * time.Duration(1<<uint(attempt))
+		select {
+		case <-time.After(delay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/v1/messages", bytes.NewBuffer(jsonBody))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("anthropic-version", anthropicVersion)
+		if p.apiKey != "" {
+			req.Header.Set("x-api-key", p.apiKey)
+		}
+		for k, v := range p.headers {
+			req.Header.Set(k, v)
+		}
+
+		release, err := p.limiter.Acquire(ctx, limitKey)
+		if err != nil {
+			return "", err
+		}
+		resp, err := p.client.Do(req)
+		release()
+		if err != nil {
+			lastErr = err
+			if !isRetryableError(err, nil) {
+				return "", err
+			}
+			if attempt < maxRetries {
+				logger.Warn("LLM request failed, retrying",
+					slog.Int("attempt", attempt+1),
+					slog.Any("error", err),
+				)
+				if err := wait(attempt); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+
+		body, readErr := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if readErr != nil {
+			return "", fmt.Errorf("failed to read Anthropic response: %w", readErr)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("Anthropic API error: %s %s", resp.Status, string(body))
+
+			// tools 非対応のエンドポイントでは以降送らず、YAML/JSON テキストのプロトコルにフォールバックする
+			if len(reqBody.Tools) > 0 && isToolsUnsupported(resp.StatusCode, body) {
+				logger.Warn("endpoint does not support tools, falling back to text protocol",
+					slog.Int("status_code", resp.StatusCode),
+				)
+				p.toolsUnsupported.Store(true)
+				reqBody = p.newRequest(ctx, msgType, systemPrompt, userPrompt)
+				if jsonBody, err = json.Marshal(reqBody); err != nil {
+					return "", err
+				}
+				attempt--
+				continue
+			}
+
+			// 429 は同じモデルを使う全呼び出し元に共有バックオフを適用する
+			if resp.StatusCode == http.StatusTooManyRequests {
+				p.limiter.Throttle(limitKey, retryAfter(resp))
+			}
+			if !isRetryableError(nil, resp) {
+				return "", lastErr
+			}
+			if attempt < maxRetries {
+				logger.Warn("LLM request failed with retryable status, retrying",
+					slog.Int("attempt", attempt+1),
+					slog.Int("status_code", resp.StatusCode),
+				)
+				if err := wait(attempt); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+
+		var result anthropicResponse
+		if err := json.Unmarshal(body, &result); err != nil {
+			return "", fmt.Errorf("failed to decode Anthropic response: %w", err)
+		}
+
+		var text strings.Builder
+		var calls []toolCall
+		for _, block := range result.Content {
+			switch block.Type {
+			case "text":
+				text.WriteString(block.Text)
+			case "tool_use":
+				calls = append(calls, toolCall{Name: block.Name, Arguments: block.Input})
+			}
+		}
+		responseContent := text.String()
+		if len(calls) > 0 {
+			if responseContent, err = messageFromToolCalls(msgType, calls, responseContent); err != nil {
+				return "", err
+			}
+		}
+		if responseContent == "" {
+			return "", fmt.Errorf("no text content returned from LLM (stop_reason: %s)", result.StopReason)
+		}
+
+		p.limiter.Succeeded(limitKey)
+		if result.Usage != nil {
+			u := result.Usage
+			usage.Record(ctx, usage.Usage{
+				Model:             nonEmptyString(result.Model, p.model),
+				InputTokens:       u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+				CachedInputTokens: u.CacheReadInputTokens,
+				OutputTokens:      u.OutputTokens,
+			})
+		}
+		logger.Info("LLM call completed",
+			slog.Int("response_size", len(responseContent)),
+			logging.LogDuration(start),
+		)
+		return responseContent, nil
+	}
+
+	if lastErr != nil {
+		return "", fmt.Errorf("LLM request failed after %d retries: %w", maxRetries, lastErr)
+	}
+	return "", fmt.Errorf("LLM request failed after %d retries", maxRetries)
+}
+
+// caller returns an llmCall that sends prompts for msgType with systemPrompt
+func (p *AnthropicProvider) caller(msgType, systemPrompt string) llmCall {
+	return func(ctx context.Context, userPrompt string) (string, error) {
+		return p.callMessage(ctx, msgType, systemPrompt, userPrompt)
+	}
+}
+
+func (p *AnthropicProvider) Decompose(ctx context.Context, req *DecomposeRequest) (*DecomposeResponse, error) {
+	logger := logging.WithTraceID(p.logger, ctx)
+
+	decompose, err := requestMessage[DecomposeResponse](ctx, p.logger, MessageTypeDecompose,
+		buildDecomposeUserPrompt(req), p.caller(MessageTypeDecompose, decomposeSystemPrompt))
+	if err != nil {
+		return nil, fmt.Errorf("LLM call failed: %w", err)
+	}
+
+	logger.Info("decompose completed", slog.Int("phases", len(decompose.Phases)))
+	return decompose, nil
+}
+
+func (p *AnthropicProvider) PlanPatch(ctx context.Context, req *PlanPatchRequest) (*PlanPatchResponse, error) {
+	return requestMessage[PlanPatchResponse](ctx, p.logger, MessageTypePlanPatch,
+		buildPlanPatchUserPrompt(req), p.caller(MessageTypePlanPatch, planPatchSystemPrompt))
+}
+
+func (p *AnthropicProvider) PlanTask(ctx context.Context, prdText string) (*PlanTaskResponse, error) {
+	systemPrompt, err := callSystemPrompt(p.prompts, p.systemPrompt, MessageTypePlanTask, prompt.MetaFormatJSON)
+	if err != nil {
+		return nil, err
+	}
+	userPrompt := fmt.Sprintf("PRD:\n%s\n\nGenerate the plan.", prdText)
+
+	return requestMessage[PlanTaskResponse](ctx, p.logger, MessageTypePlanTask, userPrompt, p.caller(MessageTypePlanTask, systemPrompt))
+}
+
+func (p *AnthropicProvider) NextAction(ctx context.Context, taskSummary *TaskSummary) (*NextActionResponse, error) {
+	systemPrompt, err := callSystemPrompt(p.prompts, p.systemPrompt, MessageTypeNextAction, prompt.MetaFormatJSON)
+	if err != nil {
+		return nil, err
+	}
+	userPrompt := buildNextActionUserPrompt(taskSummary)
+
+	return requestMessage[NextActionResponse](ctx, p.logger, MessageTypeNextAction, userPrompt, p.caller(MessageTypeNextAction, systemPrompt))
+}
+
+func (p *AnthropicProvider) CompletionAssessment(ctx context.Context, taskSummary *TaskSummary) (*CompletionAssessmentResponse, error) {
+	systemPrompt, err := callSystemPrompt(p.prompts, p.systemPrompt, MessageTypeCompletionAssessment, prompt.MetaFormatJSON)
+	if err != nil {
+		return nil, err
+	}
+	userPrompt := buildCompletionAssessmentUserPrompt(taskSummary)
+
+	return requestMessage[CompletionAssessmentResponse](ctx, p.logger, MessageTypeCompletionAssessment, userPrompt, p.caller(MessageTypeCompletionAssessment, systemPrompt))
+}
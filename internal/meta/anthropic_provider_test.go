@@ -0,0 +1,206 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/usage"
+)
+
+func newTestAnthropicProvider(t *testing.T, handler http.HandlerFunc) *AnthropicProvider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	p := NewAnthropicProvider("test-key", "claude-test", "")
+	p.SetBaseURL(server.URL)
+	p.retryBaseDelay = time.Millisecond
+	return p
+}
+
+func anthropicTextResponse(text string) string {
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":       "claude-test",
+		"stop_reason": "end_turn",
+		"content":     []map[string]string{{"type": "text", "text": text}},
+		"usage": map[string]int{
+			"input_tokens":                10,
+			"output_tokens":               5,
+			"cache_creation_input_tokens": 2,
+			"cache_read_input_tokens":     3,
+		},
+	})
+	return string(body)
+}
+
+func TestAnthropicProvider_Request(t *testing.T) {
+	var got anthropicRequest
+	p := newTestAnthropicProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s, want /v1/messages", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("x-api-key = %q", r.Header.Get("x-api-key"))
+		}
+		if r.Header.Get("anthropic-version") != anthropicVersion {
+			t.Errorf("anthropic-version = %q", r.Header.Get("anthropic-version"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		_, _ = io.WriteString(w, anthropicTextResponse("hello"))
+	})
+
+	recorder := &usage.Recorder{}
+	ctx := usage.WithRecorder(context.Background(), recorder)
+	resp, err := p.callLLM(ctx, "system text", "user text")
+	if err != nil {
+		t.Fatalf("callLLM() error = %v", err)
+	}
+	if resp != "hello" {
+		t.Errorf("response = %q, want hello", resp)
+	}
+
+	if got.Model != "claude-test" || got.System != "system text" || got.MaxTokens != anthropicMaxTokens {
+		t.Errorf("unexpected request: %+v", got)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Role != "user" || got.Messages[0].Content != "user text" {
+		t.Errorf("unexpected messages: %+v", got.Messages)
+	}
+
+	summary := recorder.Total()
+	if summary.InputTokens != 15 || summary.CachedInputTokens != 3 || summary.OutputTokens != 5 {
+		t.Errorf("unexpected usage: %+v", summary)
+	}
+}
+
+func TestAnthropicProvider_RetryOnRetryableStatus(t *testing.T) {
+	var calls int32
+	p := newTestAnthropicProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(529) // overloaded
+		default:
+			_, _ = io.WriteString(w, anthropicTextResponse("ok"))
+		}
+	})
+
+	resp, err := p.callLLM(context.Background(), "", "hi")
+	if err != nil {
+		t.Fatalf("callLLM() error = %v", err)
+	}
+	if resp != "ok" || atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("resp = %q, calls = %d", resp, calls)
+	}
+}
+
+func TestAnthropicProvider_NonRetryableStatus(t *testing.T) {
+	var calls int32
+	p := newTestAnthropicProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"type":"error"}`)
+	})
+
+	_, err := p.callLLM(context.Background(), "", "hi")
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("expected 400 error, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestAnthropicProvider_RetriesExhausted(t *testing.T) {
+	var calls int32
+	p := newTestAnthropicProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := p.callLLM(context.Background(), "", "hi")
+	if err == nil || !strings.Contains(err.Error(), "after 3 retries") {
+		t.Fatalf("expected retries exhausted error, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 4 {
+		t.Errorf("calls = %d, want 4", calls)
+	}
+}
+
+func TestAnthropicProvider_NextAction(t *testing.T) {
+	var system string
+	p := newTestAnthropicProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		system = req.System
+		_, _ = io.WriteString(w, anthropicTextResponse("```yaml\ntype: next_action\nversion: 1\npayload:\n  decision:\n    action: mark_complete\n    reason: done\n```"))
+	})
+
+	action, err := p.NextAction(context.Background(), &TaskSummary{Title: "t", State: "RUNNING"})
+	if err != nil {
+		t.Fatalf("NextAction() error = %v", err)
+	}
+	if action.Decision.Action != "mark_complete" {
+		t.Errorf("action = %q, want mark_complete", action.Decision.Action)
+	}
+	// ツール呼び出しモードの指示が追記される（テキスト応答でも解析できる）
+	want := "You are a Meta-agent that orchestrates a coding task.\nOutput MUST be a JSON block." + toolModeInstruction
+	if system != want {
+		t.Errorf("default system prompt not used: %q", system)
+	}
+}
+
+func TestAnthropicProvider_NextAction_WorkerSession(t *testing.T) {
+	var userPrompt string
+	p := newTestAnthropicProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		userPrompt = req.Messages[len(req.Messages)-1].Content
+		_, _ = io.WriteString(w, anthropicTextResponse("```yaml\ntype: next_action\nversion: 1\npayload:\n  decision:\n    action: mark_complete\n    reason: done\n```"))
+	})
+
+	if _, err := p.NextAction(context.Background(), &TaskSummary{Title: "t", State: "RUNNING", HasWorkerSession: true}); err != nil {
+		t.Fatalf("NextAction() error = %v", err)
+	}
+	if !strings.Contains(userPrompt, "a resumable worker session exists") || !strings.Contains(userPrompt, "session: continue") {
+		t.Errorf("user prompt should mention the resumable session: %q", userPrompt)
+	}
+}
+
+func TestAnthropicProvider_TestConnection(t *testing.T) {
+	if err := NewAnthropicProvider("", "", "").TestConnection(context.Background()); err == nil {
+		t.Error("expected error without API key")
+	}
+	if err := NewAnthropicProvider("key", "", "").TestConnection(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewClient_Anthropic(t *testing.T) {
+	c := NewClient(AnthropicKind, "key", "", "")
+	p, ok := c.provider.(*AnthropicProvider)
+	if !ok {
+		t.Fatalf("provider = %T, want *AnthropicProvider", c.provider)
+	}
+	if p.model == "" || strings.HasPrefix(p.model, "gpt") {
+		t.Errorf("unexpected default model %q", p.model)
+	}
+
+	c.SetHTTPOptions(HTTPOptions{BaseURL: "http://localhost:1234/", Timeout: 5 * time.Second})
+	if p.baseURL != "http://localhost:1234" {
+		t.Errorf("baseURL = %q", p.baseURL)
+	}
+	if p.client.Timeout != 5*time.Second {
+		t.Errorf("timeout = %v", p.client.Timeout)
+	}
+}
@@ -0,0 +1,325 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CassetteMode selects whether a cassette records real exchanges or replays them.
+type CassetteMode string
+
+const (
+	CassetteRecord CassetteMode = "record"
+	CassetteReplay CassetteMode = "replay"
+)
+
+// ErrCassetteMiss is returned in replay mode when no recorded exchange matches the request.
+var ErrCassetteMiss = errors.New("no recorded meta exchange matches the request")
+
+// Interaction is one recorded Meta exchange.
+//
+// HTTP プロバイダでは Request/Response は HTTP ボディそのもの、
+// CLI プロバイダでは結合済みプロンプトと CLI 出力を保存する。
+type Interaction struct {
+	Type       string            `yaml:"type"` // Meta メッセージ種別（plan_task 等）
+	Hash       string            `yaml:"hash"` // 正規化したリクエストの SHA-256
+	Request    string            `yaml:"request"`
+	Status     int               `yaml:"status,omitempty"`  // HTTP ステータス（CLI は 0）
+	Headers    map[string]string `yaml:"headers,omitempty"` // 再生に必要な HTTP レスポンスヘッダ（Content-Type 等）
+	Response   string            `yaml:"response"`
+	RecordedAt time.Time         `yaml:"recorded_at"`
+}
+
+type cassetteFile struct {
+	Version      int           `yaml:"version"`
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Cassette stores Meta request/response pairs in a YAML file.
+// Replay matches by message type and normalized request hash; identical
+// requests are served in recorded order (the last one repeats when exhausted).
+type Cassette struct {
+	path string
+	mode CassetteMode
+
+	mu           sync.Mutex
+	interactions []Interaction
+	cursors      map[string]int
+}
+
+// OpenCassette opens path in the given mode. Record mode starts an empty
+// cassette (overwriting path on the first save); replay mode loads path.
+func OpenCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, cursors: make(map[string]int)}
+	switch mode {
+	case CassetteRecord:
+		return c, nil
+	case CassetteReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette %s: %w", path, err)
+		}
+		var file cassetteFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		c.interactions = file.Interactions
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown cassette mode %q (want record or replay)", mode)
+}
+
+// Mode returns the cassette mode.
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Interactions returns a copy of the recorded interactions.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// record appends an interaction and saves the cassette so that a crashed run
+// still leaves a usable file.
+func (c *Cassette) record(in Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, in)
+
+	data, err := yaml.Marshal(cassetteFile{Version: 1, Interactions: c.interactions})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette dir: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// replay returns the next recorded interaction for msgType and hash.
+func (c *Cassette) replay(msgType, hash string) (Interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var matches []int
+	for i, in := range c.interactions {
+		if in.Type == msgType && in.Hash == hash {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		return Interaction{}, fmt.Errorf("%w (type=%s hash=%s)", ErrCassetteMiss, msgType, shortHash(hash))
+	}
+	key := msgType + ":" + hash
+	n := c.cursors[key]
+	if n >= len(matches) {
+		n = len(matches) - 1
+	}
+	c.cursors[key] = n + 1
+	return c.interactions[matches[n]], nil
+}
+
+// exchange records or replays a text exchange (used by the CLI provider).
+func (c *Cassette) exchange(ctx context.Context, request string, do func() (string, error)) (string, error) {
+	msgType := messageTypeFrom(ctx)
+	hash := RequestHash(msgType, request)
+	if c.mode == CassetteReplay {
+		in, err := c.replay(msgType, hash)
+		if err != nil {
+			return "", err
+		}
+		return in.Response, nil
+	}
+
+	resp, err := do()
+	if err != nil {
+		return "", err
+	}
+	if err := c.record(Interaction{Type: msgType, Hash: hash, Request: request, Response: resp, RecordedAt: time.Now()}); err != nil {
+		return "", err
+	}
+	return resp, nil
+}
+
+// Transport wraps base with a RoundTripper that records or replays HTTP exchanges.
+func (c *Cassette) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &cassetteTransport{base: base, cassette: c}
+}
+
+type cassetteTransport struct {
+	base     http.RoundTripper
+	cassette *Cassette
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	msgType := messageTypeFrom(req.Context())
+	hash := RequestHash(msgType, string(reqBody))
+
+	if t.cassette.mode == CassetteReplay {
+		in, err := t.cassette.replay(msgType, hash)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: in.Status,
+			Status:     fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+			Header:     replayHeader(in.Headers),
+			Body:       io.NopCloser(strings.NewReader(in.Response)),
+			Request:    req,
+		}, nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if err := t.cassette.record(Interaction{
+		Type:       msgType,
+		Hash:       hash,
+		Request:    string(reqBody),
+		Status:     resp.StatusCode,
+		Headers:    recordHeaders(resp.Header),
+		Response:   string(respBody),
+		RecordedAt: time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// recordedResponseHeaders are the response headers saved in a cassette.
+// 認証情報などを残さないよう、応答の解釈に必要なものだけを記録する
+// （Content-Type は stream 応答と JSON 応答の判別に使う）。
+var recordedResponseHeaders = []string{"Content-Type"}
+
+func recordHeaders(h http.Header) map[string]string {
+	headers := make(map[string]string)
+	for _, name := range recordedResponseHeaders {
+		if v := h.Get(name); v != "" {
+			headers[name] = v
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// replayHeader rebuilds the response header of a recorded interaction.
+// ヘッダを記録していない古いカセットは JSON 応答として扱う。
+func replayHeader(headers map[string]string) http.Header {
+	h := http.Header{}
+	for name, v := range headers {
+		h.Set(name, v)
+	}
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", "application/json")
+	}
+	return h
+}
+
+var (
+	uuidPattern      = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	spacePattern     = regexp.MustCompile(`\s+`)
+)
+
+// normalizeRequest removes differences that do not change the meaning of a
+// request: JSON key order and formatting, UUIDs, timestamps and whitespace.
+func normalizeRequest(request string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(request), &v); err == nil {
+		// モデルを変えても同じ会話を再生できるよう、モデル指定はキーに含めない
+		if obj, ok := v.(map[string]interface{}); ok {
+			delete(obj, "model")
+		}
+		if canonical, err := json.Marshal(v); err == nil {
+			request = string(canonical)
+		}
+	}
+	request = uuidPattern.ReplaceAllString(request, "<uuid>")
+	request = timestampPattern.ReplaceAllString(request, "<time>")
+	// JSON 文字列内の改行は \n にエスケープされているため、両方の表記をまとめて空白に寄せる
+	request = strings.ReplaceAll(request, `\n`, " ")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(request, " "))
+}
+
+// RequestHash returns the replay key of a request for msgType.
+func RequestHash(msgType, request string) string {
+	sum := sha256.Sum256([]byte(msgType + "\x00" + normalizeRequest(request)))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewReplayClient returns a Meta client of kind that serves every call from
+// the cassette at path without network or CLI access.
+func NewReplayClient(kind, path string) (*Client, error) {
+	cassette, err := OpenCassette(path, CassetteReplay)
+	if err != nil {
+		return nil, err
+	}
+	client := NewClient(kind, "", "", "")
+	client.SetCassette(cassette)
+	return client, nil
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+type messageTypeKey struct{}
+
+// withMessageType tags ctx with the Meta message type being requested so
+// that transports can key recorded exchanges by type.
+func withMessageType(ctx context.Context, msgType string) context.Context {
+	return context.WithValue(ctx, messageTypeKey{}, msgType)
+}
+
+func messageTypeFrom(ctx context.Context) string {
+	if msgType, ok := ctx.Value(messageTypeKey{}).(string); ok {
+		return msgType
+	}
+	return ""
+}
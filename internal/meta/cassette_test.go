@@ -0,0 +1,183 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+const cassetteNextActionContent = `type: next_action\nversion: 1\npayload:\n  decision:\n    action: mark_complete\n    reason: recorded`
+
+func TestCassette_RecordAndReplayHTTP(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"`+cassetteNextActionContent+`"}}]}`)
+	}))
+	path := filepath.Join(t.TempDir(), "session.yaml")
+
+	// record
+	recorder, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient("openai-chat", "key", "gpt-test", "")
+	client.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+	client.SetCassette(recorder)
+
+	summary := &TaskSummary{Title: "t", State: "RUNNING"}
+	if _, err := client.NextAction(context.Background(), summary); err != nil {
+		t.Fatalf("record NextAction failed: %v", err)
+	}
+	server.Close()
+
+	recorded := recorder.Interactions()
+	if len(recorded) != 1 || recorded[0].Type != MessageTypeNextAction || recorded[0].Status != 200 {
+		t.Fatalf("unexpected recording: %+v", recorded)
+	}
+
+	// replay (server is gone, model differs)
+	replayer, err := OpenCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offline := NewClient("openai-chat", "", "other-model", "")
+	offline.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+	offline.SetCassette(replayer)
+
+	action, err := offline.NextAction(context.Background(), summary)
+	if err != nil {
+		t.Fatalf("replay NextAction failed: %v", err)
+	}
+	if action.Decision.Reason != "recorded" {
+		t.Errorf("unexpected replayed action: %+v", action.Decision)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("replay should not hit the server, hits = %d", hits)
+	}
+
+	// 別のリクエストはカセットに無いので即座に失敗する（再試行しない）
+	_, err = offline.NextAction(context.Background(), &TaskSummary{Title: "other"})
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("expected ErrCassetteMiss, got %v", err)
+	}
+}
+
+func TestCassette_ReplaysStreamingResponse(t *testing.T) {
+	content := "type: next_action\nversion: 1\npayload:\n  decision:\n    action: mark_complete\n    reason: streamed\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":"+jsonString(content)+"}}]}\n\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	path := filepath.Join(t.TempDir(), "stream.yaml")
+	summary := &TaskSummary{Title: "t", State: "RUNNING"}
+
+	recorder, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient("openai-chat", "key", "gpt-test", "")
+	client.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+	client.SetCassette(recorder)
+	streamCtx := WithStreamHandler(context.Background(), func(StreamChunk) {})
+	if _, err := client.NextAction(streamCtx, summary); err != nil {
+		t.Fatalf("record NextAction failed: %v", err)
+	}
+	server.Close()
+
+	if got := recorder.Interactions()[0].Headers["Content-Type"]; got != "text/event-stream" {
+		t.Fatalf("Content-Type not recorded: %q", got)
+	}
+
+	replayer, err := OpenCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offline := NewClient("openai-chat", "", "gpt-test", "")
+	offline.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+	offline.SetCassette(replayer)
+
+	// 再生でも stream 応答として解釈され、チャンクが届く
+	var chunks int
+	ctx := WithStreamHandler(context.Background(), func(StreamChunk) { chunks++ })
+	action, err := offline.NextAction(ctx, summary)
+	if err != nil {
+		t.Fatalf("replay NextAction failed: %v", err)
+	}
+	if action.Decision.Reason != "streamed" {
+		t.Errorf("unexpected replayed action: %+v", action.Decision)
+	}
+	if chunks == 0 {
+		t.Error("replayed stream should deliver chunks")
+	}
+}
+
+func TestCassette_ReplayCLIExchangeInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cli.yaml")
+	recorder, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := withMessageType(context.Background(), MessageTypePlanTask)
+	for _, out := range []string{"first", "second"} {
+		out := out
+		if _, err := recorder.exchange(ctx, "same prompt", func() (string, error) { return out, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replayer, err := OpenCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := NewCLIProvider("codex-cli", "", "")
+	provider.SetCassette(replayer)
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		resp, err := replayer.exchange(ctx, "same  prompt\n", func() (string, error) {
+			t.Fatal("replay must not run the CLI")
+			return "", nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, resp)
+	}
+	want := []string{"first", "second", "second"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("replay %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if _, err := replayer.exchange(withMessageType(context.Background(), MessageTypeNextAction), "same prompt", nil); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("message type must be part of the key, got %v", err)
+	}
+}
+
+func TestRequestHash_Normalization(t *testing.T) {
+	a := RequestHash(MessageTypeDecompose, `{"model":"a","messages":[{"role":"user","content":"task 123e4567-e89b-12d3-a456-426614174000 at 2026-01-02T03:04:05Z"}]}`)
+	b := RequestHash(MessageTypeDecompose, `{"messages": [{"content": "task 00000000-0000-0000-0000-000000000001 at 2025-12-31T23:59:59+09:00", "role": "user"}], "model": "b"}`)
+	if a != b {
+		t.Error("hash should ignore key order, formatting, model, UUIDs and timestamps")
+	}
+	if a == RequestHash(MessageTypePlanPatch, `{"messages":[]}`) || a == RequestHash(MessageTypeDecompose, `{"messages":[{"role":"user","content":"other"}]}`) {
+		t.Error("different requests should not collide")
+	}
+}
+
+func TestOpenCassette_Errors(t *testing.T) {
+	if _, err := OpenCassette(filepath.Join(t.TempDir(), "missing.yaml"), CassetteReplay); err == nil {
+		t.Error("expected error for missing replay cassette")
+	}
+	if _, err := OpenCassette("x.yaml", "stream"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
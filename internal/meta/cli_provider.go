@@ -10,7 +10,9 @@ import (
 
 	"github.com/biwakonbu/agent-runner/internal/agenttools"
 	"github.com/biwakonbu/agent-runner/internal/logging"
-	"gopkg.in/yaml.v3"
+	"github.com/biwakonbu/agent-runner/internal/prompt"
+	"github.com/biwakonbu/agent-runner/internal/ratelimit"
+	"github.com/biwakonbu/agent-runner/internal/usage"
 )
 
 // DefaultMetaAgentTimeout is 10 minutes for Meta processing
@@ -23,6 +25,9 @@ type CLIProvider struct {
 	model        string
 	systemPrompt string
 	logger       *slog.Logger
+	limiter      *ratelimit.Limiter
+	cassette     *Cassette
+	prompts      *prompt.Renderer // 呼び出し種別ごとのシステムプロンプト（nil は組み込みテンプレート）
 }
 
 // Ensure CLIProvider implements Provider interface
@@ -59,6 +64,11 @@ func (p *CLIProvider) SetLogger(logger *slog.Logger) {
 	p.logger = logging.WithComponent(logger, "meta-cli-"+p.kind)
 }
 
+// SetLimiter sets the shared rate limiter
+func (p *CLIProvider) SetLimiter(limiter *ratelimit.Limiter) {
+	p.limiter = limiter
+}
+
 // Name returns provider Kind
 func (p *CLIProvider) Name() string {
 	return p.kind
@@ -87,8 +97,32 @@ func (p *CLIProvider) TestConnection(ctx context.Context) error {
 	return nil
 }
 
-// callExec calls the CLI using agenttools wrapper
+// SetPrompts sets the templates of the per-call system prompts
+func (p *CLIProvider) SetPrompts(prompts *prompt.Renderer) {
+	p.prompts = prompts
+}
+
+// SetCassette records or replays CLI exchanges through cassette
+func (p *CLIProvider) SetCassette(cassette *Cassette) {
+	p.cassette = cassette
+}
+
+// callExec calls the CLI, or serves the exchange from the cassette when set
 func (p *CLIProvider) callExec(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	// CLI は単発のプロンプトしか受け付けないため、トランスクリプトはテキストとして前置する
+	if history := TranscriptFrom(ctx).Render(); history != "" {
+		userPrompt = history + "\n---\n\n" + userPrompt
+	}
+	if p.cassette == nil {
+		return p.execCLI(ctx, systemPrompt, userPrompt)
+	}
+	return p.cassette.exchange(ctx, systemPrompt+"\n\n"+userPrompt, func() (string, error) {
+		return p.execCLI(ctx, systemPrompt, userPrompt)
+	})
+}
+
+// execCLI calls the CLI using agenttools wrapper
+func (p *CLIProvider) execCLI(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
 	logger := logging.WithTraceID(p.logger, ctx)
 	start := time.Now()
 
@@ -140,7 +174,31 @@ func (p *CLIProvider) callExec(ctx context.Context, systemPrompt, userPrompt str
 		slog.String("model", p.model),
 	)
 
-	result := agenttools.Execute(ctx, plan)
+	// Worker と同じアカウントを使うため、プロバイダ kind とモデルの両方で制限する
+	limitKeys := []string{ratelimit.ProviderKey(agentToolKind), ratelimit.ModelKey(p.model)}
+	release, err := p.limiter.Acquire(ctx, limitKeys...)
+	if err != nil {
+		return "", err
+	}
+	var result agenttools.ExecResult
+	if onOutput := streamForwarder(ctx); onOutput != nil {
+		// 標準出力を逐次転送し、呼び出し元のキャンセルで CLI を停止する
+		result = agenttools.ExecuteStream(ctx, plan, onOutput)
+	} else {
+		result = agenttools.Execute(ctx, plan)
+	}
+	release()
+
+	if agenttools.ClassifyFailure(agentToolKind, result.ExitCode, result.Output) == agenttools.FailureRateLimit {
+		for _, key := range limitKeys {
+			p.limiter.Throttle(key, 0)
+		}
+	} else if result.Error == nil {
+		for _, key := range limitKeys {
+			p.limiter.Succeeded(key)
+		}
+	}
+
 	if result.Error != nil {
 		logger.Error("CLI call failed",
 			slog.String("output", result.Output),
@@ -151,6 +209,8 @@ func (p *CLIProvider) callExec(ctx context.Context, systemPrompt, userPrompt str
 		return "", fmt.Errorf("CLI call failed: %w (Output: %s)", result.Error, result.Output)
 	}
 
+	usage.Record(ctx, agenttools.ExtractUsage(agentToolKind, p.model, result.Output))
+
 	response := strings.TrimSpace(result.Output)
 	logger.Info("CLI call completed",
 		slog.Int("response_length", len(response)),
@@ -160,6 +220,13 @@ func (p *CLIProvider) callExec(ctx context.Context, systemPrompt, userPrompt str
 	return response, nil
 }
 
+// caller returns an llmCall that sends prompts with systemPrompt via callExec
+func (p *CLIProvider) caller(systemPrompt string) llmCall {
+	return func(ctx context.Context, userPrompt string) (string, error) {
+		return p.callExec(ctx, systemPrompt, userPrompt)
+	}
+}
+
 // Decompose delegates to callExec and extracts response
 func (p *CLIProvider) Decompose(ctx context.Context, req *DecomposeRequest) (*DecomposeResponse, error) {
 	logger := logging.WithTraceID(p.logger, ctx)
@@ -170,37 +237,16 @@ func (p *CLIProvider) Decompose(ctx context.Context, req *DecomposeRequest) (*De
 	}
 	userPrompt := buildDecomposeUserPrompt(req)
 
-	resp, err := p.callExec(ctx, systemPrompt, userPrompt)
+	decompose, err := requestMessage[DecomposeResponse](ctx, p.logger, MessageTypeDecompose, userPrompt, p.caller(systemPrompt))
 	if err != nil {
 		return nil, err
 	}
 
-	jsonStr := extractJSON(resp)
-	yamlStr, err := jsonToYAML(jsonStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert JSON to YAML: %w\nJSON: %s", err, jsonStr)
-	}
-
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(yamlStr), &msg); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w\nYAML: %s", err, yamlStr)
-	}
-
-	payloadBytes, err := yaml.Marshal(msg.Payload)
-	if err != nil {
-		return nil, err
-	}
-
-	var decompose DecomposeResponse
-	if err := yaml.Unmarshal(payloadBytes, &decompose); err != nil {
-		return nil, fmt.Errorf("failed to parse decompose response: %w", err)
-	}
-
 	logger.Info("decompose completed",
 		slog.Int("phases", len(decompose.Phases)),
 	)
 
-	return &decompose, nil
+	return decompose, nil
 }
 
 // PlanPatch delegates to callExec
@@ -213,129 +259,45 @@ func (p *CLIProvider) PlanPatch(ctx context.Context, req *PlanPatchRequest) (*Pl
 	}
 	userPrompt := buildPlanPatchUserPrompt(req)
 
-	resp, err := p.callExec(ctx, systemPrompt, userPrompt)
+	patch, err := requestMessage[PlanPatchResponse](ctx, p.logger, MessageTypePlanPatch, userPrompt, p.caller(systemPrompt))
 	if err != nil {
 		return nil, err
 	}
 
-	jsonStr := extractJSON(resp)
-	yamlStr, err := jsonToYAML(jsonStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert JSON to YAML: %w\nJSON: %s", err, jsonStr)
-	}
-
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(yamlStr), &msg); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w\nYAML: %s", err, yamlStr)
-	}
-
-	payloadBytes, err := yaml.Marshal(msg.Payload)
-	if err != nil {
-		return nil, err
-	}
-
-	var patch PlanPatchResponse
-	if err := yaml.Unmarshal(payloadBytes, &patch); err != nil {
-		return nil, fmt.Errorf("failed to parse plan_patch response: %w", err)
-	}
-
 	logger.Info("plan_patch completed",
 		slog.Int("operations", len(patch.Operations)),
 	)
 
-	return &patch, nil
+	return patch, nil
 }
 
 // PlanTask delegates to callExec
 func (p *CLIProvider) PlanTask(ctx context.Context, prdText string) (*PlanTaskResponse, error) {
-	systemPrompt := `You are a Meta-agent that plans software development tasks.
-Your goal is to read a PRD and break it down into Acceptance Criteria.
-Output MUST be a YAML block with the following structure:
-type: plan_task
-version: 1
-payload:
-  task_id: "TASK-..."
-  acceptance_criteria:
-    - id: "AC-1"
-      description: "..."
-      type: "e2e"
-      critical: true
-`
-	if p.systemPrompt != "" {
-		systemPrompt = p.systemPrompt
-	}
-	userPrompt := fmt.Sprintf("PRD:\n%s\n\nGenerate the plan.", prdText)
-
-	resp, err := p.callExec(ctx, systemPrompt, userPrompt)
-	if err != nil {
-		return nil, err
-	}
-
-	resp = extractYAML(resp)
-
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(resp), &msg); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w\nResponse: %s", err, resp)
-	}
-
-	payloadBytes, err := yaml.Marshal(msg.Payload)
+	systemPrompt, err := callSystemPrompt(p.prompts, p.systemPrompt, MessageTypePlanTask, prompt.MetaFormatYAML)
 	if err != nil {
 		return nil, err
 	}
-	var plan PlanTaskResponse
-	if err := yaml.Unmarshal(payloadBytes, &plan); err != nil {
-		return nil, err
-	}
+	userPrompt := fmt.Sprintf("PRD:\n%s\n\nGenerate the plan.", prdText)
 
-	return &plan, nil
+	return requestMessage[PlanTaskResponse](ctx, p.logger, MessageTypePlanTask, userPrompt, p.caller(systemPrompt))
 }
 
 // NextAction delegates to callExec
 func (p *CLIProvider) NextAction(ctx context.Context, taskSummary *TaskSummary) (*NextActionResponse, error) {
-	systemPrompt := `You are a Meta-agent that orchestrates a coding task.
-Decide the next action based on the current context.
-Output MUST be a YAML block with type: next_action.
-`
-	if p.systemPrompt != "" {
-		systemPrompt = p.systemPrompt
-	}
-	contextSummary := fmt.Sprintf("Task: %s\nState: %s\nACs: %v\nWorkerRuns: %d",
-		taskSummary.Title, taskSummary.State, len(taskSummary.AcceptanceCriteria), taskSummary.WorkerRunsCount)
-
-	userPrompt := fmt.Sprintf("Context:\n%s\n\nDecide next action.", contextSummary)
-
-	resp, err := p.callExec(ctx, systemPrompt, userPrompt)
-	if err != nil {
-		return nil, err
-	}
-
-	resp = extractYAML(resp)
-
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(resp), &msg); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w\nResponse: %s", err, resp)
-	}
-
-	payloadBytes, err := yaml.Marshal(msg.Payload)
+	systemPrompt, err := callSystemPrompt(p.prompts, p.systemPrompt, MessageTypeNextAction, prompt.MetaFormatYAML)
 	if err != nil {
 		return nil, err
 	}
-	var action NextActionResponse
-	if err := yaml.Unmarshal(payloadBytes, &action); err != nil {
-		return nil, err
-	}
+	userPrompt := buildNextActionUserPrompt(taskSummary)
 
-	return &action, nil
+	return requestMessage[NextActionResponse](ctx, p.logger, MessageTypeNextAction, userPrompt, p.caller(systemPrompt))
 }
 
 // CompletionAssessment delegates to callExec
 func (p *CLIProvider) CompletionAssessment(ctx context.Context, taskSummary *TaskSummary) (*CompletionAssessmentResponse, error) {
-	systemPrompt := `You are a Meta-agent evaluating task completion.
-Review the Acceptance Criteria and Worker execution results.
-Output MUST be a YAML block with type: completion_assessment.
-`
-	if p.systemPrompt != "" {
-		systemPrompt = p.systemPrompt
+	systemPrompt, err := callSystemPrompt(p.prompts, p.systemPrompt, MessageTypeCompletionAssessment, prompt.MetaFormatYAML)
+	if err != nil {
+		return nil, err
 	}
 
 	acText := ""
@@ -356,30 +318,9 @@ Acceptance Criteria:
 
 Worker Execution Results:
 %s
-
+%s
 Evaluate whether all acceptance criteria are satisfied.`,
-		taskSummary.Title, taskSummary.State, acText, workerText)
-
-	resp, err := p.callExec(ctx, systemPrompt, userPrompt)
-	if err != nil {
-		return nil, err
-	}
-
-	resp = extractYAML(resp)
-
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(resp), &msg); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w\nResponse: %s", err, resp)
-	}
-
-	payloadBytes, err := yaml.Marshal(msg.Payload)
-	if err != nil {
-		return nil, err
-	}
-	var assessment CompletionAssessmentResponse
-	if err := yaml.Unmarshal(payloadBytes, &assessment); err != nil {
-		return nil, err
-	}
+		taskSummary.Title, taskSummary.State, acText, workerText, buildReviewEvidence(taskSummary.Review))
 
-	return &assessment, nil
+	return requestMessage[CompletionAssessmentResponse](ctx, p.logger, MessageTypeCompletionAssessment, userPrompt, p.caller(systemPrompt))
 }
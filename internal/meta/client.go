@@ -2,11 +2,16 @@ package meta
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/agenttools"
 	"github.com/biwakonbu/agent-runner/internal/logging"
+	"github.com/biwakonbu/agent-runner/internal/prompt"
+	"github.com/biwakonbu/agent-runner/internal/ratelimit"
 )
 
 type Client struct {
@@ -16,13 +21,33 @@ type Client struct {
 	systemPrompt string
 	logger       *slog.Logger
 	provider     Provider // Unified Provider Interface
+
+	// routes はメッセージ種別ごとのプロバイダ（未指定の種別は provider を使う）
+	routes map[string]routedProvider
+
+	// 後から追加されるルートにも同じ設定を適用するために保持する
+	limiter  *ratelimit.Limiter
+	httpOpts *HTTPOptions
+	cassette *Cassette
+	prompts  *prompt.Renderer
+}
+
+// Route selects the provider kind and model used for a Meta message type.
+type Route struct {
+	Kind  string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	Model string `yaml:"model,omitempty" json:"model,omitempty"`
+}
+
+type routedProvider struct {
+	route    Route
+	provider Provider
 }
 
 // NewClient creates a new Meta client.
 // It prioritizes CLI providers (codex, claude) over HTTP providers if configured or defaulted.
 func NewClient(kind, apiKey, model, systemPrompt string) *Client {
 	if model == "" {
-		model = agenttools.DefaultMetaModel // Meta-agent default model
+		model = defaultModel(kind)
 	}
 	c := &Client{
 		kind:         kind,
@@ -31,68 +56,269 @@ func NewClient(kind, apiKey, model, systemPrompt string) *Client {
 		systemPrompt: systemPrompt,
 		logger:       logging.WithComponent(slog.Default(), "meta-client"),
 	}
+	c.provider = newProvider(kind, apiKey, model, systemPrompt, c.logger)
+	return c
+}
 
-	// Initialize Provider based on kind
+// APIKeyEnv returns the environment variable holding the API key for kind.
+func APIKeyEnv(kind string) string {
+	if kind == AnthropicKind {
+		return "ANTHROPIC_API_KEY"
+	}
+	return "OPENAI_API_KEY"
+}
+
+func defaultModel(kind string) string {
+	if kind == AnthropicKind {
+		return agenttools.DefaultClaudeModel
+	}
+	return agenttools.DefaultMetaModel // Meta-agent default model
+}
+
+// newProvider initializes a Provider based on kind
+func newProvider(kind, apiKey, model, systemPrompt string, logger *slog.Logger) Provider {
 	switch {
 	case kind == "mock":
-		c.provider = NewMockClient().provider
+		return NewMockClient().provider
+	case kind == AnthropicKind:
+		anthropicProvider := NewAnthropicProvider(apiKey, model, systemPrompt)
+		anthropicProvider.SetLogger(logger)
+		return anthropicProvider
 	case strings.Contains(kind, "codex") || strings.Contains(kind, "claude"):
 		// CLI based providers
 		cliProvider := NewCLIProvider(kind, model, systemPrompt)
-		cliProvider.SetLogger(c.logger)
-		c.provider = cliProvider
+		cliProvider.SetLogger(logger)
+		return cliProvider
 	case kind == "openai-chat":
 		openaiProvider := NewOpenAIProvider(apiKey, model, systemPrompt)
-		openaiProvider.SetLogger(c.logger)
-		c.provider = openaiProvider
+		openaiProvider.SetLogger(logger)
+		return openaiProvider
 	default:
 		// Fallback to OpenAI if unknown, logic in app.go should prevent this but for safety
 		openaiProvider := NewOpenAIProvider(apiKey, model, systemPrompt)
-		openaiProvider.SetLogger(c.logger)
-		c.provider = openaiProvider
+		openaiProvider.SetLogger(logger)
+		return openaiProvider
 	}
+}
 
-	return c
+// SetRoutes routes message types (MessageType*) to other provider kinds or
+// models. Empty Kind uses the client's kind; empty Model uses the client's
+// model for the same kind and the provider default otherwise. apiKeyFor
+// returns the API key of a kind that differs from the client's (nil = none).
+func (c *Client) SetRoutes(routes map[string]Route, apiKeyFor func(kind string) string) error {
+	resolved := make(map[string]routedProvider, len(routes))
+	for msgType, route := range routes {
+		if MessageSchema(msgType) == nil {
+			return fmt.Errorf("unknown meta message type in routes: %q", msgType)
+		}
+		if route.Kind == "" {
+			route.Kind = c.kind
+		}
+		if route.Model == "" {
+			route.Model = defaultModel(route.Kind)
+			if route.Kind == c.kind {
+				route.Model = c.model
+			}
+		}
+		if route == c.defaultRoute() {
+			continue
+		}
+
+		apiKey := c.apiKey
+		if route.Kind != c.kind {
+			apiKey = ""
+			if apiKeyFor != nil {
+				apiKey = apiKeyFor(route.Kind)
+			}
+		}
+		provider := newProvider(route.Kind, apiKey, route.Model, c.systemPrompt, c.logger)
+		c.configure(provider, route.Kind)
+		resolved[msgType] = routedProvider{route: route, provider: provider}
+	}
+	c.routes = resolved
+	return nil
+}
+
+func (c *Client) defaultRoute() Route {
+	return Route{Kind: c.kind, Model: c.model}
+}
+
+// route returns the provider and route for msgType
+func (c *Client) route(msgType string) (Provider, Route) {
+	if r, ok := c.routes[msgType]; ok {
+		return r.provider, r.route
+	}
+	return c.provider, c.defaultRoute()
+}
+
+// providers returns the default provider and every routed provider with its kind
+func (c *Client) providers() []routedProvider {
+	var out []routedProvider
+	if c.provider != nil {
+		out = append(out, routedProvider{route: c.defaultRoute(), provider: c.provider})
+	}
+	for _, r := range c.routes {
+		out = append(out, r)
+	}
+	return out
+}
+
+// configure applies the client's current settings to a newly created routed provider
+func (c *Client) configure(provider Provider, kind string) {
+	if p, ok := provider.(interface{ SetLimiter(*ratelimit.Limiter) }); ok && c.limiter != nil {
+		p.SetLimiter(c.limiter)
+	}
+	if p, ok := provider.(interface{ SetHTTPOptions(HTTPOptions) }); ok && c.httpOpts != nil {
+		p.SetHTTPOptions(c.httpOpts.forKind(c.kind, kind))
+	}
+	if p, ok := provider.(interface{ SetCassette(*Cassette) }); ok && c.cassette != nil {
+		p.SetCassette(c.cassette)
+	}
+	if p, ok := provider.(interface{ SetPrompts(*prompt.Renderer) }); ok && c.prompts != nil {
+		p.SetPrompts(c.prompts)
+	}
 }
 
 // SetLogger sets a custom logger for the client
 func (c *Client) SetLogger(logger *slog.Logger) {
 	c.logger = logging.WithComponent(logger, "meta-client")
-	if c.provider != nil {
-		if p, ok := c.provider.(interface{ SetLogger(*slog.Logger) }); ok {
+	for _, r := range c.providers() {
+		if p, ok := r.provider.(interface{ SetLogger(*slog.Logger) }); ok {
 			p.SetLogger(c.logger)
 		}
 	}
 }
 
+// SetLimiter sets the shared rate limiter used by the provider
+func (c *Client) SetLimiter(limiter *ratelimit.Limiter) {
+	c.limiter = limiter
+	for _, r := range c.providers() {
+		if p, ok := r.provider.(interface{ SetLimiter(*ratelimit.Limiter) }); ok {
+			p.SetLimiter(limiter)
+		}
+	}
+}
+
+// HTTPOptions configures HTTP based providers (openai-chat, anthropic-messages).
+type HTTPOptions struct {
+	BaseURL           string            // 空の場合は各プロバイダの既定エンドポイント
+	Headers           map[string]string // 追加ヘッダー（値の ${VAR} は環境変数で展開）
+	Timeout           time.Duration     // 0 の場合は既定値
+	StructuredOutputs *bool             // nil の場合は既定値（openai-chat は有効）
+	ToolCalls         *bool             // nil の場合は既定値（HTTP プロバイダは有効）。next_action / plan_patch をツール呼び出しで受け取る
+}
+
+func (o HTTPOptions) expandedHeaders() map[string]string {
+	if len(o.Headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(o.Headers))
+	for k, v := range o.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	return headers
+}
+
+// forKind returns the options for a routed provider of kind. エンドポイントと
+// ヘッダーは既定プロバイダ用の設定なので、別の kind には引き継がない。
+func (o HTTPOptions) forKind(defaultKind, kind string) HTTPOptions {
+	if kind == defaultKind {
+		return o
+	}
+	return HTTPOptions{Timeout: o.Timeout, StructuredOutputs: o.StructuredOutputs, ToolCalls: o.ToolCalls}
+}
+
+// SetHTTPOptions applies HTTP options to providers that support them
+func (c *Client) SetHTTPOptions(opts HTTPOptions) {
+	c.httpOpts = &opts
+	for _, r := range c.providers() {
+		if p, ok := r.provider.(interface{ SetHTTPOptions(HTTPOptions) }); ok {
+			p.SetHTTPOptions(opts.forKind(c.kind, r.route.Kind))
+		}
+	}
+}
+
+// SetCassette records or replays the provider's exchanges through cassette
+func (c *Client) SetCassette(cassette *Cassette) {
+	if cassette == nil {
+		return
+	}
+	c.cassette = cassette
+	for _, r := range c.providers() {
+		if p, ok := r.provider.(interface{ SetCassette(*Cassette) }); ok {
+			p.SetCassette(cassette)
+		}
+	}
+}
+
+// SetPrompts sets the templates used for the per-call system prompts
+// (meta_plan_task / meta_next_action / meta_completion_assessment)
+func (c *Client) SetPrompts(prompts *prompt.Renderer) {
+	if prompts == nil {
+		return
+	}
+	c.prompts = prompts
+	for _, r := range c.providers() {
+		if p, ok := r.provider.(interface{ SetPrompts(*prompt.Renderer) }); ok {
+			p.SetPrompts(prompts)
+		}
+	}
+}
+
 // TestConnection verifies the provider connection
 func (c *Client) TestConnection(ctx context.Context) error {
-	if c.provider == nil {
-		return nil
+	for _, r := range c.providers() {
+		if err := r.provider.TestConnection(ctx); err != nil {
+			return err
+		}
 	}
-	return c.provider.TestConnection(ctx)
+	return nil
 }
 
 func (c *Client) PlanTask(ctx context.Context, prdText string) (*PlanTaskResponse, error) {
-	return c.provider.PlanTask(ctx, prdText)
+	p, route := c.route(MessageTypePlanTask)
+	recordRoute(ctx, route)
+	return p.PlanTask(ctx, prdText)
 }
 
 func (c *Client) NextAction(ctx context.Context, taskSummary *TaskSummary) (*NextActionResponse, error) {
-	return c.provider.NextAction(ctx, taskSummary)
+	p, route := c.route(MessageTypeNextAction)
+	recordRoute(ctx, route)
+	return p.NextAction(ctx, taskSummary)
 }
 
 func (c *Client) CompletionAssessment(ctx context.Context, taskSummary *TaskSummary) (*CompletionAssessmentResponse, error) {
-	return c.provider.CompletionAssessment(ctx, taskSummary)
+	p, route := c.route(MessageTypeCompletionAssessment)
+	recordRoute(ctx, route)
+	return p.CompletionAssessment(ctx, taskSummary)
 }
 
 // Decompose decomposes user input into tasks (v2.0 Chat Driven)
 func (c *Client) Decompose(ctx context.Context, req *DecomposeRequest) (*DecomposeResponse, error) {
-	return c.provider.Decompose(ctx, req)
+	p, route := c.route(MessageTypeDecompose)
+	recordRoute(ctx, route)
+	return p.Decompose(ctx, req)
 }
 
 // PlanPatch generates patch operations from user input (v1.0)
 func (c *Client) PlanPatch(ctx context.Context, req *PlanPatchRequest) (*PlanPatchResponse, error) {
-	return c.provider.PlanPatch(ctx, req)
+	p, route := c.route(MessageTypePlanPatch)
+	recordRoute(ctx, route)
+	return p.PlanPatch(ctx, req)
+}
+
+type routeRecorderKey struct{}
+
+// WithRouteRecorder returns a context in which the Client stores the route
+// (provider kind and model) it used for the call into rec.
+func WithRouteRecorder(ctx context.Context, rec *Route) context.Context {
+	return context.WithValue(ctx, routeRecorderKey{}, rec)
+}
+
+func recordRoute(ctx context.Context, route Route) {
+	if rec, ok := ctx.Value(routeRecorderKey{}).(*Route); ok && rec != nil {
+		*rec = route
+	}
 }
 
 const decomposeSystemPrompt = `You are a Meta-agent that decomposes user requests into structured development tasks.
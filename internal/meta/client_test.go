@@ -2,8 +2,14 @@ package meta
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/biwakonbu/agent-runner/internal/agenttools"
 )
 
 // TestClient_PlanTask_Success tests successful PlanTask with mock provider
@@ -242,3 +248,64 @@ var _ = func(c *Client) Provider {
 var _ = func(v interface{}, t string) bool {
 	return reflect.TypeOf(v).String() == t
 }
+
+func TestClient_SetRoutes(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		models = append(models, req.Model)
+		content := "type: next_action\nversion: 1\npayload:\n  decision:\n    action: mark_complete\n"
+		if strings.Contains(req.Messages[len(req.Messages)-1].Content, "Generate the plan") {
+			content = "type: plan_task\nversion: 1\npayload:\n  acceptance_criteria:\n    - id: AC-1\n      description: d\n"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": content}}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient("openai-chat", "key", "strong", "")
+	client.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+	err := client.SetRoutes(map[string]Route{
+		MessageTypeNextAction: {Model: "small"},
+		MessageTypeDecompose:  {Kind: AnthropicKind},
+	}, func(kind string) string { return "anthropic-key" })
+	if err != nil {
+		t.Fatalf("SetRoutes failed: %v", err)
+	}
+
+	var planRoute, actionRoute Route
+	if _, err := client.PlanTask(WithRouteRecorder(context.Background(), &planRoute), "prd"); err != nil {
+		t.Fatalf("PlanTask failed: %v", err)
+	}
+	if _, err := client.NextAction(WithRouteRecorder(context.Background(), &actionRoute), &TaskSummary{Title: "t"}); err != nil {
+		t.Fatalf("NextAction failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(models, []string{"strong", "small"}) {
+		t.Errorf("models sent = %v", models)
+	}
+	if planRoute != (Route{Kind: "openai-chat", Model: "strong"}) || actionRoute != (Route{Kind: "openai-chat", Model: "small"}) {
+		t.Errorf("recorded routes: plan=%+v action=%+v", planRoute, actionRoute)
+	}
+
+	decompose, ok := client.routes[MessageTypeDecompose].provider.(*AnthropicProvider)
+	if !ok {
+		t.Fatalf("decompose provider = %T", client.routes[MessageTypeDecompose].provider)
+	}
+	// 別の kind には既定プロバイダ用のエンドポイントを引き継がない
+	if decompose.apiKey != "anthropic-key" || decompose.baseURL != DefaultAnthropicBaseURL || decompose.model != agenttools.DefaultClaudeModel {
+		t.Errorf("unexpected anthropic route: key=%q url=%q model=%q", decompose.apiKey, decompose.baseURL, decompose.model)
+	}
+}
+
+func TestClient_SetRoutes_UnknownType(t *testing.T) {
+	client := NewClient("openai-chat", "key", "m", "")
+	if err := client.SetRoutes(map[string]Route{"next": {Model: "x"}}, nil); err == nil {
+		t.Error("expected error for unknown message type")
+	}
+	if len(client.routes) != 0 {
+		t.Error("routes should be unchanged on error")
+	}
+}
@@ -49,6 +49,11 @@ func (m *shimMockRoundTripper) RoundTrip(req *http.Request) (*http.Response, err
 	req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
 	bodyStr := string(bodyBytes)
+	// トランスクリプトの会話履歴に影響されないよう、system と最後のメッセージだけで判定する
+	var chat chatRequest
+	if err := json.Unmarshal(bodyBytes, &chat); err == nil && len(chat.Messages) > 2 {
+		bodyStr = chat.Messages[0].Content + "\n" + chat.Messages[len(chat.Messages)-1].Content
+	}
 
 	// Determine response based on content
 	var content string
@@ -92,7 +97,7 @@ payload:
     reason: "Mock complete"
 `
 		}
-	} else if strings.Contains(bodyStr, "Evaluate whether all acceptance criteria are satisfied") {
+	} else if strings.Contains(bodyStr, "Evaluate whether all acceptance criteria are satisfied") || strings.Contains(bodyStr, "Evaluate completion.") {
 		// CompletionAssessment
 		content = `
 type: completion_assessment
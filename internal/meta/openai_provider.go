@@ -4,26 +4,48 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/agenttools"
 	"github.com/biwakonbu/agent-runner/internal/logging"
-	"gopkg.in/yaml.v3"
+	"github.com/biwakonbu/agent-runner/internal/prompt"
+	"github.com/biwakonbu/agent-runner/internal/ratelimit"
+	"github.com/biwakonbu/agent-runner/internal/usage"
 )
 
+// DefaultOpenAIBaseURL is the OpenAI API endpoint (without /chat/completions).
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
 // OpenAIProvider uses compatible HTTP API.
 type OpenAIProvider struct {
 	apiKey       string
 	model        string
 	systemPrompt string
+	baseURL      string
+	headers      map[string]string
 	client       *http.Client
 	logger       *slog.Logger
+	limiter      *ratelimit.Limiter
+	prompts      *prompt.Renderer // 呼び出し種別ごとのシステムプロンプト（nil は組み込みテンプレート）
+
+	// structuredOutputs は response_format (json_schema) を送るかどうか。
+	// エンドポイントが未対応と応答した場合は structuredUnsupported を立てて以降送らない。
+	structuredOutputs     bool
+	structuredUnsupported atomic.Bool
+
+	// toolCalls は next_action / plan_patch をツール呼び出しで受け取るかどうか。
+	// エンドポイントが tools 未対応と応答した場合は toolsUnsupported を立てて YAML に戻す。
+	toolCalls        bool
+	toolsUnsupported atomic.Bool
 }
 
 // NewOpenAIProvider creates a new OpenAIProvider
@@ -32,11 +54,14 @@ func NewOpenAIProvider(apiKey, model, systemPrompt string) *OpenAIProvider {
 		model = agenttools.DefaultMetaModel
 	}
 	return &OpenAIProvider{
-		apiKey:       apiKey,
-		model:        model,
-		systemPrompt: systemPrompt,
-		client:       &http.Client{Timeout: 60 * time.Second},
-		logger:       logging.WithComponent(slog.Default(), "meta-openai"),
+		apiKey:            apiKey,
+		model:             model,
+		systemPrompt:      systemPrompt,
+		baseURL:           DefaultOpenAIBaseURL,
+		client:            &http.Client{Timeout: 60 * time.Second},
+		logger:            logging.WithComponent(slog.Default(), "meta-openai"),
+		structuredOutputs: true,
+		toolCalls:         true,
 	}
 }
 
@@ -45,6 +70,49 @@ func (p *OpenAIProvider) SetLogger(logger *slog.Logger) {
 	p.logger = logging.WithComponent(logger, "meta-openai")
 }
 
+// SetLimiter sets the shared rate limiter
+func (p *OpenAIProvider) SetLimiter(limiter *ratelimit.Limiter) {
+	p.limiter = limiter
+}
+
+// SetHTTPOptions applies endpoint, header, timeout, structured output and tool call settings
+func (p *OpenAIProvider) SetHTTPOptions(opts HTTPOptions) {
+	p.baseURL = strings.TrimRight(nonEmptyString(opts.BaseURL, DefaultOpenAIBaseURL), "/")
+	p.headers = opts.expandedHeaders()
+	if opts.Timeout > 0 {
+		p.client.Timeout = opts.Timeout
+	}
+	if opts.StructuredOutputs != nil {
+		p.structuredOutputs = *opts.StructuredOutputs
+	}
+	if opts.ToolCalls != nil {
+		p.toolCalls = *opts.ToolCalls
+	}
+}
+
+// Protocol returns the Meta protocol mode currently used for msgType
+// (ProtocolTools or ProtocolText).
+func (p *OpenAIProvider) Protocol(msgType string) string {
+	if p.toolCalls && !p.toolsUnsupported.Load() && metaTools(msgType) != nil {
+		return ProtocolTools
+	}
+	return ProtocolText
+}
+
+// SetPrompts sets the templates of the per-call system prompts
+func (p *OpenAIProvider) SetPrompts(prompts *prompt.Renderer) {
+	p.prompts = prompts
+}
+
+// SetCassette records or replays HTTP exchanges through cassette
+func (p *OpenAIProvider) SetCassette(cassette *Cassette) {
+	p.client.Transport = cassette.Transport(p.client.Transport)
+}
+
+func (p *OpenAIProvider) endpoint() string {
+	return strings.TrimRight(nonEmptyString(p.baseURL, DefaultOpenAIBaseURL), "/") + "/chat/completions"
+}
+
 func (p *OpenAIProvider) Name() string {
 	return "openai-chat"
 }
@@ -71,23 +139,79 @@ func (p *OpenAIProvider) TestConnection(ctx context.Context) error {
 
 // Implementation specific structs
 type chatRequest struct {
-	Model    string    `json:"model"`
-	Messages []message `json:"messages"`
+	Model          string          `json:"model"`
+	Messages       []message       `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Tools          []chatTool      `json:"tools,omitempty"`
+	ToolChoice     string          `json:"tool_choice,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatTool is a function declared to the model.
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Arguments   string                 `json:"arguments,omitempty"` // 応答側: JSON 文字列
+}
+
+// chatToolCall is a tool call in an assistant message.
+type chatToolCall struct {
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function chatFunction `json:"function"`
+}
+
+// responseFormat is the structured outputs setting of a chat completion request.
+type responseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type jsonSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict"`
 }
 
 type message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	ToolCalls []chatToolCall `json:"tool_calls,omitempty"`
 }
 
 type chatResponse struct {
+	Model   string `json:"model"`
 	Choices []struct {
 		Message message `json:"message"`
 	} `json:"choices"`
+	Usage *chatUsage `json:"usage,omitempty"`
+}
+
+// chatUsage is the token usage block of a chat completion response.
+type chatUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
 }
 
 func isRetryableError(err error, resp *http.Response) bool {
 	if err != nil {
+		if errors.Is(err, ErrCassetteMiss) {
+			return false
+		}
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 			return true
 		}
@@ -110,7 +234,36 @@ func isRetryableError(err error, resp *http.Response) bool {
 	return false
 }
 
+// retryAfter parses the Retry-After header (seconds form) of a 429 response.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After")))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// isResponseFormatUnsupported reports whether an error response indicates the
+// endpoint does not support response_format / json_schema.
+func isResponseFormatUnsupported(statusCode int, body []byte) bool {
+	if statusCode != http.StatusBadRequest && statusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	lower := strings.ToLower(string(body))
+	return strings.Contains(lower, "response_format") || strings.Contains(lower, "json_schema")
+}
+
 func (p *OpenAIProvider) callLLM(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return p.callLLMWithSchema(ctx, "", systemPrompt, userPrompt)
+}
+
+// callLLMWithSchema calls the chat completions endpoint. When msgType has a
+// schema and structured outputs are enabled, response_format is sent so the
+// model returns the MetaMessage as plain JSON.
+func (p *OpenAIProvider) callLLMWithSchema(ctx context.Context, msgType, systemPrompt, userPrompt string) (string, error) {
 	const maxRetries = 3
 	const baseDelay = 1 * time.Second
 
@@ -119,13 +272,9 @@ func (p *OpenAIProvider) callLLM(ctx context.Context, systemPrompt, userPrompt s
 
 	// REMOVED: explicit empty check for apiKey.
 
-	reqBody := chatRequest{
-		Model: p.model,
-		Messages: []message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-	}
+	// ストリームハンドラが設定されている場合は SSE で受信し、差分を逐次転送する
+	onDelta := streamForwarder(ctx)
+	reqBody := p.newChatRequest(ctx, msgType, systemPrompt, userPrompt, onDelta != nil)
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
 		return "", err
@@ -133,6 +282,7 @@ func (p *OpenAIProvider) callLLM(ctx context.Context, systemPrompt, userPrompt s
 
 	logger.Info("calling LLM",
 		slog.String("model", p.model),
+		slog.String("protocol", p.Protocol(msgType)),
 		slog.Int("request_size", len(jsonBody)),
 	)
 	logger.Debug("LLM request",
@@ -140,9 +290,10 @@ func (p *OpenAIProvider) callLLM(ctx context.Context, systemPrompt, userPrompt s
 		slog.String("user_prompt", userPrompt),
 	)
 
+	limitKey := ratelimit.ModelKey(p.model)
 	var lastErr error
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonBody))
+		req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint(), bytes.NewBuffer(jsonBody))
 		if err != nil {
 			return "", err
 		}
@@ -150,8 +301,16 @@ func (p *OpenAIProvider) callLLM(ctx context.Context, systemPrompt, userPrompt s
 		if p.apiKey != "" {
 			req.Header.Set("Authorization", "Bearer "+p.apiKey)
 		}
+		for k, v := range p.headers {
+			req.Header.Set(k, v)
+		}
 
+		release, err := p.limiter.Acquire(ctx, limitKey)
+		if err != nil {
+			return "", err
+		}
 		resp, err := p.client.Do(req)
+		release()
 		if err != nil {
 			lastErr = err
 			if !isRetryableError(err, nil) {
@@ -178,6 +337,39 @@ func (p *OpenAIProvider) callLLM(ctx context.Context, systemPrompt, userPrompt s
 			body, _ := io.ReadAll(resp.Body)
 			lastErr = fmt.Errorf("OpenAI API error: %s %s", resp.Status, string(body))
 
+			// tools 非対応のエンドポイントでは以降送らず、YAML/JSON テキストのプロトコルにフォールバックする
+			if len(reqBody.Tools) > 0 && isToolsUnsupported(resp.StatusCode, body) {
+				logger.Warn("endpoint does not support tools, falling back to text protocol",
+					slog.Int("status_code", resp.StatusCode),
+				)
+				p.toolsUnsupported.Store(true)
+				reqBody = p.newChatRequest(ctx, msgType, systemPrompt, userPrompt, reqBody.Stream)
+				if jsonBody, err = json.Marshal(reqBody); err != nil {
+					return "", err
+				}
+				attempt--
+				continue
+			}
+
+			// response_format 非対応のエンドポイントでは以降送らず、抽出ベースの解析にフォールバックする
+			if reqBody.ResponseFormat != nil && isResponseFormatUnsupported(resp.StatusCode, body) {
+				logger.Warn("endpoint does not support response_format, falling back to text extraction",
+					slog.Int("status_code", resp.StatusCode),
+				)
+				p.structuredUnsupported.Store(true)
+				reqBody.ResponseFormat = nil
+				if jsonBody, err = json.Marshal(reqBody); err != nil {
+					return "", err
+				}
+				attempt--
+				continue
+			}
+
+			// 429 / クォータ超過は同じモデルを使う全呼び出し元に共有バックオフを適用する
+			if resp.StatusCode == http.StatusTooManyRequests {
+				p.limiter.Throttle(limitKey, retryAfter(resp))
+			}
+
 			if !isRetryableError(nil, resp) {
 				return "", lastErr
 			}
@@ -196,8 +388,15 @@ func (p *OpenAIProvider) callLLM(ctx context.Context, systemPrompt, userPrompt s
 			continue
 		}
 
+		// stream 非対応のエンドポイントは通常の JSON 応答を返すため Content-Type で判別する
 		var result chatResponse
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		if reqBody.Stream && strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+			streamed, err := readChatStream(ctx, resp.Body, onDelta)
+			if err != nil {
+				return "", err
+			}
+			result = *streamed
+		} else if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 			return "", err
 		}
 
@@ -205,7 +404,22 @@ func (p *OpenAIProvider) callLLM(ctx context.Context, systemPrompt, userPrompt s
 			return "", fmt.Errorf("no choices returned from LLM")
 		}
 
+		p.limiter.Succeeded(limitKey)
 		responseContent := result.Choices[0].Message.Content
+		// ツール呼び出しは MetaMessage に組み立て直し、テキスト応答と同じ検証に通す
+		if calls := result.Choices[0].Message.ToolCalls; len(calls) > 0 {
+			if responseContent, err = chatToolCallsMessage(msgType, calls, responseContent); err != nil {
+				return "", err
+			}
+		}
+		if result.Usage != nil {
+			usage.Record(ctx, usage.Usage{
+				Model:             nonEmptyString(result.Model, p.model),
+				InputTokens:       result.Usage.PromptTokens,
+				CachedInputTokens: result.Usage.PromptTokensDetails.CachedTokens,
+				OutputTokens:      result.Usage.CompletionTokens,
+			})
+		}
 		logger.Info("LLM call completed",
 			slog.Int("response_size", len(responseContent)),
 			logging.LogDuration(start),
@@ -219,169 +433,110 @@ func (p *OpenAIProvider) callLLM(ctx context.Context, systemPrompt, userPrompt s
 	return "", fmt.Errorf("LLM request failed after %d retries", maxRetries)
 }
 
-func (p *OpenAIProvider) Decompose(ctx context.Context, req *DecomposeRequest) (*DecomposeResponse, error) {
-	logger := logging.WithTraceID(p.logger, ctx)
-
-	systemPrompt := decomposeSystemPrompt
-	userPrompt := buildDecomposeUserPrompt(req)
-
-	resp, err := p.callLLM(ctx, systemPrompt, userPrompt)
-	if err != nil {
-		return nil, fmt.Errorf("LLM call failed: %w", err)
+// newChatRequest builds the chat completion request for msgType. Tools are
+// declared when the message type has a tool variant and the endpoint supports
+// them; otherwise response_format is used when structured outputs are enabled.
+func (p *OpenAIProvider) newChatRequest(ctx context.Context, msgType, systemPrompt, userPrompt string, stream bool) chatRequest {
+	tools := []chatTool(nil)
+	if p.Protocol(msgType) == ProtocolTools {
+		for _, t := range metaTools(msgType) {
+			tools = append(tools, chatTool{Type: "function", Function: chatFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters}})
+		}
+		systemPrompt += toolModeInstruction
 	}
 
-	jsonStr := extractJSON(resp)
-	yamlStr, err := jsonToYAML(jsonStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert JSON to YAML: %w", err)
+	// タスクのトランスクリプトがあれば、過去のやり取りを会話として再送する
+	messages := []message{{Role: "system", Content: systemPrompt}}
+	messages = append(messages, TranscriptFrom(ctx).turns()...)
+	messages = append(messages, message{Role: "user", Content: userPrompt})
+	reqBody := chatRequest{
+		Model:    p.model,
+		Messages: messages,
+	}
+	if len(tools) > 0 {
+		reqBody.Tools = tools
+		reqBody.ToolChoice = "required"
+	} else if schema := MessageSchema(msgType); schema != nil && p.structuredOutputs && !p.structuredUnsupported.Load() {
+		reqBody.ResponseFormat = &responseFormat{
+			Type:       "json_schema",
+			JSONSchema: &jsonSchema{Name: msgType, Schema: schema},
+		}
 	}
-
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(yamlStr), &msg); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	if stream {
+		reqBody.Stream = true
+		reqBody.StreamOptions = &streamOptions{IncludeUsage: true}
 	}
+	return reqBody
+}
 
-	payloadBytes, err := yaml.Marshal(msg.Payload)
-	if err != nil {
-		return nil, err
+// chatToolCallsMessage converts the tool calls of a chat completion into a MetaMessage.
+func chatToolCallsMessage(msgType string, calls []chatToolCall, text string) (string, error) {
+	converted := make([]toolCall, 0, len(calls))
+	for _, c := range calls {
+		call, err := parseToolArguments(c.Function.Name, c.Function.Arguments)
+		if err != nil {
+			return "", err
+		}
+		converted = append(converted, call)
 	}
+	return messageFromToolCalls(msgType, converted, text)
+}
 
-	var decompose DecomposeResponse
-	if err := yaml.Unmarshal(payloadBytes, &decompose); err != nil {
-		return nil, fmt.Errorf("failed to parse decompose response: %w", err)
+// caller returns an llmCall that sends prompts for msgType with systemPrompt
+func (p *OpenAIProvider) caller(msgType, systemPrompt string) llmCall {
+	return func(ctx context.Context, userPrompt string) (string, error) {
+		return p.callLLMWithSchema(ctx, msgType, systemPrompt, userPrompt)
 	}
-
-	logger.Info("decompose completed", slog.Int("phases", len(decompose.Phases)))
-	return &decompose, nil
 }
 
-func (p *OpenAIProvider) PlanPatch(ctx context.Context, req *PlanPatchRequest) (*PlanPatchResponse, error) {
-	systemPrompt := planPatchSystemPrompt
-	userPrompt := buildPlanPatchUserPrompt(req)
-
-	resp, err := p.callLLM(ctx, systemPrompt, userPrompt)
-	if err != nil {
-		return nil, err
-	}
+func (p *OpenAIProvider) Decompose(ctx context.Context, req *DecomposeRequest) (*DecomposeResponse, error) {
+	logger := logging.WithTraceID(p.logger, ctx)
 
-	jsonStr := extractJSON(resp)
-	yamlStr, err := jsonToYAML(jsonStr)
+	decompose, err := requestMessage[DecomposeResponse](ctx, p.logger, MessageTypeDecompose,
+		buildDecomposeUserPrompt(req), p.caller(MessageTypeDecompose, decomposeSystemPrompt))
 	if err != nil {
-		return nil, err
-	}
-
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(yamlStr), &msg); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("LLM call failed: %w", err)
 	}
 
-	payloadBytes, err := yaml.Marshal(msg.Payload)
-	if err != nil {
-		return nil, err
-	}
-	var patch PlanPatchResponse
-	if err := yaml.Unmarshal(payloadBytes, &patch); err != nil {
-		return nil, err
-	}
+	logger.Info("decompose completed", slog.Int("phases", len(decompose.Phases)))
+	return decompose, nil
+}
 
-	return &patch, nil
+func (p *OpenAIProvider) PlanPatch(ctx context.Context, req *PlanPatchRequest) (*PlanPatchResponse, error) {
+	return requestMessage[PlanPatchResponse](ctx, p.logger, MessageTypePlanPatch,
+		buildPlanPatchUserPrompt(req), p.caller(MessageTypePlanPatch, planPatchSystemPrompt))
 }
 
 func (p *OpenAIProvider) PlanTask(ctx context.Context, prdText string) (*PlanTaskResponse, error) {
-	systemPrompt := p.systemPrompt
-	if systemPrompt == "" {
-		systemPrompt = `You are a Meta-agent that plans software development tasks.
-Output MUST be a JSON block with the following structure:
-{
-  "type": "plan_task",
-  "version": 1,
-  "payload": { ... }
-}`
-	}
-	userPrompt := fmt.Sprintf("PRD:\n%s\n\nGenerate the plan.", prdText)
-
-	resp, err := p.callLLM(ctx, systemPrompt, userPrompt)
+	systemPrompt, err := callSystemPrompt(p.prompts, p.systemPrompt, MessageTypePlanTask, prompt.MetaFormatJSON)
 	if err != nil {
 		return nil, err
 	}
+	userPrompt := fmt.Sprintf("PRD:\n%s\n\nGenerate the plan.", prdText)
 
-	resp = extractYAML(resp)
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(resp), &msg); err != nil {
-		if jsonErr := json.Unmarshal([]byte(resp), &msg); jsonErr != nil {
-			return nil, fmt.Errorf("failed to parse response as YAML or JSON: %w", err)
-		}
-	}
-
-	payloadBytes, err := yaml.Marshal(msg.Payload)
-	if err != nil {
-		return nil, err
-	}
-	var plan PlanTaskResponse
-	if err := yaml.Unmarshal(payloadBytes, &plan); err != nil {
-		return nil, err
-	}
-	return &plan, nil
+	return requestMessage[PlanTaskResponse](ctx, p.logger, MessageTypePlanTask,
+		userPrompt, p.caller(MessageTypePlanTask, systemPrompt))
 }
 
 func (p *OpenAIProvider) NextAction(ctx context.Context, taskSummary *TaskSummary) (*NextActionResponse, error) {
-	systemPrompt := p.systemPrompt
-	if systemPrompt == "" {
-		systemPrompt = `You are a Meta-agent that orchestrates a coding task.
-Output MUST be a JSON block.`
-	}
-	// QH-005: Include WorkerRunsCount for mock detection
-	contextSummary := fmt.Sprintf("Task: %s\nState: %s\nACs: %v\nWorkerRuns: %d",
-		taskSummary.Title, taskSummary.State, len(taskSummary.AcceptanceCriteria), taskSummary.WorkerRunsCount)
-	userPrompt := fmt.Sprintf("Context:\n%s\n\nDecide next action.", contextSummary)
-
-	resp, err := p.callLLM(ctx, systemPrompt, userPrompt)
+	systemPrompt, err := callSystemPrompt(p.prompts, p.systemPrompt, MessageTypeNextAction, prompt.MetaFormatJSON)
 	if err != nil {
 		return nil, err
 	}
+	// QH-005: Include WorkerRunsCount for mock detection
+	userPrompt := buildNextActionUserPrompt(taskSummary)
 
-	resp = extractYAML(resp)
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(resp), &msg); err != nil {
-		return nil, err
-	}
-
-	payloadBytes, err := yaml.Marshal(msg.Payload)
-	if err != nil {
-		return nil, err
-	}
-	var action NextActionResponse
-	if err := yaml.Unmarshal(payloadBytes, &action); err != nil {
-		return nil, err
-	}
-	return &action, nil
+	return requestMessage[NextActionResponse](ctx, p.logger, MessageTypeNextAction,
+		userPrompt, p.caller(MessageTypeNextAction, systemPrompt))
 }
 
 func (p *OpenAIProvider) CompletionAssessment(ctx context.Context, taskSummary *TaskSummary) (*CompletionAssessmentResponse, error) {
-	systemPrompt := p.systemPrompt
-	if systemPrompt == "" {
-		systemPrompt = `You are a Meta-agent evaluating task completion.`
-	}
-	userPrompt := fmt.Sprintf("Task: %s\nEvaluate completion.", taskSummary.Title)
-
-	resp, err := p.callLLM(ctx, systemPrompt, userPrompt)
+	systemPrompt, err := callSystemPrompt(p.prompts, p.systemPrompt, MessageTypeCompletionAssessment, prompt.MetaFormatJSON)
 	if err != nil {
 		return nil, err
 	}
+	userPrompt := buildCompletionAssessmentUserPrompt(taskSummary)
 
-	resp = extractYAML(resp)
-	var msg MetaMessage
-	if err := yaml.Unmarshal([]byte(resp), &msg); err != nil {
-		return nil, err
-	}
-
-	payloadBytes, err := yaml.Marshal(msg.Payload)
-	if err != nil {
-		return nil, err
-	}
-	var assessment CompletionAssessmentResponse
-	if err := yaml.Unmarshal(payloadBytes, &assessment); err != nil {
-		return nil, err
-	}
-	return &assessment, nil
+	return requestMessage[CompletionAssessmentResponse](ctx, p.logger, MessageTypeCompletionAssessment,
+		userPrompt, p.caller(MessageTypeCompletionAssessment, systemPrompt))
 }
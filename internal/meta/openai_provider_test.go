@@ -6,10 +6,15 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/prompt"
 )
 
 // mockRoundTripper allows controlling HTTP responses for testing
@@ -483,3 +488,165 @@ func TestOpenAIProvider_SystemPromptUsage(t *testing.T) {
 		t.Error("System prompt not found in PlanTask request")
 	}
 }
+
+// TestClient_SetPromptsOverridesCallPrompt checks that the per-call system
+// prompt is rendered from the prompt templates (with overrides).
+func TestClient_SetPromptsOverridesCallPrompt(t *testing.T) {
+	var gotReq chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotReq)
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"{\"type\":\"next_action\",\"version\":1,\"payload\":{\"decision\":{\"action\":\"mark_complete\",\"reason\":\"done\"}}}"}}]}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "meta_next_action.tmpl"), []byte("Custom {{.Type}} prompt ({{.Format}})."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prompts, err := prompt.NewRenderer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	toolCalls := false
+	client := NewClient("openai-chat", "key", "gpt-test", "")
+	client.SetHTTPOptions(HTTPOptions{BaseURL: server.URL, ToolCalls: &toolCalls})
+	client.SetPrompts(prompts)
+
+	if _, err := client.NextAction(context.Background(), &TaskSummary{Title: "t"}); err != nil {
+		t.Fatalf("NextAction failed: %v", err)
+	}
+	if len(gotReq.Messages) == 0 || gotReq.Messages[0].Role != "system" {
+		t.Fatalf("system message missing: %+v", gotReq.Messages)
+	}
+	if got := gotReq.Messages[0].Content; got != "Custom next_action prompt (json)." {
+		t.Errorf("system prompt = %q, want the overridden template", got)
+	}
+}
+
+// TestOpenAIProvider_HTTPOptions tests custom base URL, headers and structured outputs
+func TestOpenAIProvider_HTTPOptions(t *testing.T) {
+	t.Setenv("GATEWAY_TOKEN", "secret")
+
+	var gotPath, gotHeader string
+	var gotReq chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotHeader = r.Header.Get("X-Gateway-Token")
+		_ = json.NewDecoder(r.Body).Decode(&gotReq)
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"{\"type\":\"next_action\",\"version\":1,\"payload\":{\"decision\":{\"action\":\"mark_complete\",\"reason\":\"done\"}}}"}}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("", "local-model", "")
+	toolCalls := false
+	provider.SetHTTPOptions(HTTPOptions{
+		BaseURL:   server.URL + "/v1/",
+		Headers:   map[string]string{"X-Gateway-Token": "${GATEWAY_TOKEN}"},
+		ToolCalls: &toolCalls,
+	})
+
+	action, err := provider.NextAction(context.Background(), &TaskSummary{Title: "t"})
+	if err != nil {
+		t.Fatalf("NextAction failed: %v", err)
+	}
+	if action.Decision.Action != "mark_complete" {
+		t.Errorf("Expected mark_complete, got %q", action.Decision.Action)
+	}
+	if gotPath != "/v1/chat/completions" {
+		t.Errorf("Expected /v1/chat/completions, got %q", gotPath)
+	}
+	if gotHeader != "secret" {
+		t.Errorf("Expected expanded header, got %q", gotHeader)
+	}
+	if gotReq.ResponseFormat == nil || gotReq.ResponseFormat.JSONSchema == nil || gotReq.ResponseFormat.JSONSchema.Name != MessageTypeNextAction {
+		t.Errorf("Expected json_schema response_format for next_action, got %+v", gotReq.ResponseFormat)
+	}
+}
+
+// TestOpenAIProvider_StructuredOutputsFallback tests fallback to text extraction
+// when the endpoint rejects response_format
+func TestOpenAIProvider_StructuredOutputsFallback(t *testing.T) {
+	var formats []bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		formats = append(formats, req.ResponseFormat != nil)
+		if req.ResponseFormat != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"message":"response_format json_schema is not supported"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"`+"```yaml\\ntype: completion_assessment\\nversion: 1\\npayload:\\n  all_criteria_satisfied: true\\n  summary: ok\\n```"+`"}}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("key", "local-model", "")
+	provider.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+
+	for i := 0; i < 2; i++ {
+		assessment, err := provider.CompletionAssessment(context.Background(), &TaskSummary{Title: "t"})
+		if err != nil {
+			t.Fatalf("CompletionAssessment failed: %v", err)
+		}
+		if !assessment.AllCriteriaSatisfied {
+			t.Errorf("Expected all_criteria_satisfied")
+		}
+	}
+
+	// 1 回目: schema 付き -> 400 -> schema なしで再送、2 回目: 最初から schema なし
+	want := []bool{true, false, false}
+	if len(formats) != len(want) {
+		t.Fatalf("Expected %d calls, got %d (%v)", len(want), len(formats), formats)
+	}
+	for i := range want {
+		if formats[i] != want[i] {
+			t.Errorf("call %d: response_format sent = %v, want %v", i, formats[i], want[i])
+		}
+	}
+}
+
+// TestOpenAIProvider_StructuredOutputsDisabled tests that response_format can be turned off
+func TestOpenAIProvider_StructuredOutputsDisabled(t *testing.T) {
+	var sent bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		sent = req.ResponseFormat != nil
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"type: plan_task\nversion: 1\npayload:\n  task_id: T-1\n  acceptance_criteria:\n    - id: AC-1\n      description: works\n"}}]}`)
+	}))
+	defer server.Close()
+
+	disabled := false
+	provider := NewOpenAIProvider("key", "local-model", "")
+	provider.SetHTTPOptions(HTTPOptions{BaseURL: server.URL, StructuredOutputs: &disabled, Timeout: 5 * time.Second})
+
+	plan, err := provider.PlanTask(context.Background(), "prd")
+	if err != nil {
+		t.Fatalf("PlanTask failed: %v", err)
+	}
+	if plan.TaskID != "T-1" {
+		t.Errorf("Expected T-1, got %q", plan.TaskID)
+	}
+	if sent {
+		t.Error("response_format should not be sent when structured outputs are disabled")
+	}
+	if provider.client.Timeout != 5*time.Second {
+		t.Errorf("Expected timeout 5s, got %v", provider.client.Timeout)
+	}
+}
+
+func TestMessageSchema(t *testing.T) {
+	for _, msgType := range []string{MessageTypePlanTask, MessageTypeNextAction, MessageTypeCompletionAssessment, MessageTypeDecompose, MessageTypePlanPatch} {
+		schema := MessageSchema(msgType)
+		if schema == nil {
+			t.Fatalf("schema for %s is nil", msgType)
+		}
+		if _, err := json.Marshal(schema); err != nil {
+			t.Errorf("schema for %s is not serializable: %v", msgType, err)
+		}
+	}
+	if MessageSchema("unknown") != nil {
+		t.Error("unknown message type should have no schema")
+	}
+}
@@ -48,8 +48,16 @@ type WorkerCall struct {
 	ToolSpecific    map[string]interface{} `yaml:"tool_specific,omitempty" json:"tool_specific,omitempty"`
 	Workdir         string                 `yaml:"workdir,omitempty" json:"workdir,omitempty"`
 	UseStdin        bool                   `yaml:"use_stdin,omitempty" json:"use_stdin,omitempty"`
+	Session         string                 `yaml:"session,omitempty" json:"session,omitempty"`       // "new" | "continue"（省略時は new）
+	SessionID       string                 `yaml:"session_id,omitempty" json:"session_id,omitempty"` // 継続するセッション ID（省略時は直前のセッション）
 }
 
+// WorkerCall.Session の値
+const (
+	WorkerSessionNew      = "new"
+	WorkerSessionContinue = "continue"
+)
+
 // CompletionAssessmentResponse is the expected payload for "completion_assessment"
 type CompletionAssessmentResponse struct {
 	AllCriteriaSatisfied bool              `yaml:"all_criteria_satisfied" json:"all_criteria_satisfied"`
@@ -77,6 +85,19 @@ type TaskSummary struct {
 	AcceptanceCriteria []AcceptanceCriterion
 	WorkerRunsCount    int
 	WorkerRuns         []WorkerRunSummary
+	HasWorkerSession   bool // 継続可能な Worker セッションがあるか
+
+	// Review は独立レビュー用の証拠（差分・テスト結果）。completion_assessment でのみ使う
+	Review *ReviewEvidence
+}
+
+// ReviewEvidence is what an independent reviewer assesses the acceptance
+// criteria against.
+type ReviewEvidence struct {
+	Diff         string // 作業ツリーの差分（git diff）
+	TestCommand  string // 未設定の場合はテスト未実行
+	TestExitCode int
+	TestOutput   string
 }
 
 // ============================================================================
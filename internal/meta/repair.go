@@ -0,0 +1,211 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/biwakonbu/agent-runner/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// MaxRepairAttempts is the number of repair re-prompts sent after an invalid
+// Meta response before giving up.
+const MaxRepairAttempts = 2
+
+// ErrInvalidResponse is returned when a Meta response is still invalid after
+// MaxRepairAttempts repair prompts.
+var ErrInvalidResponse = errors.New("invalid meta response")
+
+// InvalidResponse records a Meta response that failed parsing or schema validation.
+type InvalidResponse struct {
+	Attempt      int      `yaml:"attempt" json:"attempt"` // 1 始まり（1 = 最初の応答）
+	Response     string   `yaml:"response" json:"response"`
+	Errors       []string `yaml:"errors" json:"errors"`
+	RepairPrompt string   `yaml:"repair_prompt,omitempty" json:"repair_prompt,omitempty"` // 空の場合は修復上限に達した
+}
+
+// ValidationRecorder collects invalid responses and repairs of Meta calls.
+// Attach it to the context with WithValidationRecorder.
+type ValidationRecorder struct {
+	mu      sync.Mutex
+	invalid []InvalidResponse
+}
+
+// Record appends an invalid response.
+func (r *ValidationRecorder) Record(ir InvalidResponse) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invalid = append(r.invalid, ir)
+}
+
+// Invalid returns a copy of the recorded invalid responses.
+func (r *ValidationRecorder) Invalid() []InvalidResponse {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]InvalidResponse(nil), r.invalid...)
+}
+
+// Repairs returns how many repair prompts were sent.
+func (r *ValidationRecorder) Repairs() int {
+	n := 0
+	for _, ir := range r.Invalid() {
+		if ir.RepairPrompt != "" {
+			n++
+		}
+	}
+	return n
+}
+
+type validationRecorderKey struct{}
+
+// WithValidationRecorder returns a context that reports invalid responses to rec.
+func WithValidationRecorder(ctx context.Context, rec *ValidationRecorder) context.Context {
+	return context.WithValue(ctx, validationRecorderKey{}, rec)
+}
+
+// RecordInvalid reports an invalid response to the ValidationRecorder in ctx, if any.
+func RecordInvalid(ctx context.Context, ir InvalidResponse) {
+	if rec, ok := ctx.Value(validationRecorderKey{}).(*ValidationRecorder); ok {
+		rec.Record(ir)
+	}
+}
+
+// llmCall sends userPrompt (with the provider's system prompt) and returns the raw response.
+type llmCall func(ctx context.Context, userPrompt string) (string, error)
+
+// requestMessage calls the LLM, validates the response against the msgType
+// schema and decodes its payload into T. Invalid responses are answered with
+// up to MaxRepairAttempts repair prompts quoting the validation errors.
+// Transport errors from call are returned as-is (retries are the provider's job).
+func requestMessage[T any](ctx context.Context, logger *slog.Logger, msgType, userPrompt string, call llmCall) (*T, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logging.WithTraceID(logger, ctx)
+	ctx = withMessageType(ctx, msgType)
+
+	prompt := userPrompt
+	for attempt := 0; ; attempt++ {
+		resp, err := call(ctx, prompt)
+		if err != nil {
+			return nil, err
+		}
+
+		out, errs := parseMessage[T](msgType, resp)
+		if len(errs) == 0 {
+			if attempt > 0 {
+				logger.Info("meta response repaired",
+					slog.String("type", msgType),
+					slog.Int("repairs", attempt),
+				)
+			}
+			return out, nil
+		}
+
+		invalid := InvalidResponse{Attempt: attempt + 1, Response: resp, Errors: errs}
+		if attempt >= MaxRepairAttempts {
+			RecordInvalid(ctx, invalid)
+			logger.Error("meta response invalid, repair attempts exhausted",
+				slog.String("type", msgType),
+				slog.Any("errors", errs),
+			)
+			return nil, fmt.Errorf("%w: %s after %d repair attempts: %s", ErrInvalidResponse, msgType, attempt, strings.Join(errs, "; "))
+		}
+
+		prompt = buildRepairPrompt(userPrompt, msgType, resp, errs)
+		invalid.RepairPrompt = prompt
+		RecordInvalid(ctx, invalid)
+		logger.Warn("meta response invalid, sending repair prompt",
+			slog.String("type", msgType),
+			slog.Int("attempt", attempt+1),
+			slog.Any("errors", errs),
+		)
+	}
+}
+
+// parseMessage extracts the MetaMessage from resp, validates it and decodes the payload.
+func parseMessage[T any](msgType, resp string) (*T, []string) {
+	doc, err := parseMessageDoc(msgType, resp)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	if errs := ValidateMessage(msgType, doc); len(errs) > 0 {
+		return nil, errs
+	}
+
+	payloadBytes, err := yaml.Marshal(doc["payload"])
+	if err != nil {
+		return nil, []string{fmt.Sprintf("payload: %v", err)}
+	}
+	var out T
+	if err := yaml.Unmarshal(payloadBytes, &out); err != nil {
+		return nil, []string{fmt.Sprintf("payload: %v", err)}
+	}
+	return &out, nil
+}
+
+// parseMessageDoc extracts a YAML or JSON mapping from resp. decompose /
+// plan_patch are requested as JSON, the others as YAML, so the preferred
+// format is tried first.
+func parseMessageDoc(msgType, resp string) (map[string]interface{}, error) {
+	fromYAML := func() (map[string]interface{}, error) {
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal([]byte(extractYAML(resp)), &doc); err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			return nil, fmt.Errorf("no YAML mapping found")
+		}
+		return doc, nil
+	}
+	fromJSON := func() (map[string]interface{}, error) {
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(extractJSON(resp)), &doc); err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			return nil, fmt.Errorf("no JSON object found")
+		}
+		return doc, nil
+	}
+
+	first, second := fromYAML, fromJSON
+	if msgType == MessageTypeDecompose || msgType == MessageTypePlanPatch {
+		first, second = fromJSON, fromYAML
+	}
+	doc, firstErr := first()
+	if firstErr == nil {
+		return doc, nil
+	}
+	if doc, err := second(); err == nil {
+		return doc, nil
+	}
+	return nil, fmt.Errorf("response is not a valid YAML/JSON message: %v", firstErr)
+}
+
+// buildRepairPrompt asks the model to re-emit a corrected message.
+func buildRepairPrompt(userPrompt, msgType, resp string, errs []string) string {
+	b := &strings.Builder{}
+	b.WriteString(userPrompt)
+	b.WriteString("\n\n---\n")
+	fmt.Fprintf(b, "Your previous response was not a valid %q message.\n\n", msgType)
+	b.WriteString("Previous response:\n")
+	b.WriteString(resp)
+	b.WriteString("\n\nValidation errors:\n")
+	for _, e := range errs {
+		fmt.Fprintf(b, "- %s\n", e)
+	}
+	fmt.Fprintf(b, "\nRespond again with the complete corrected message only (type: %s, version: 1, payload: {...}). Do not add explanations.", msgType)
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgType string
+		doc     map[string]interface{}
+		want    []string
+	}{
+		{
+			name:    "valid next_action",
+			msgType: MessageTypeNextAction,
+			doc: map[string]interface{}{
+				"type": "next_action", "version": 1,
+				"payload": map[string]interface{}{"decision": map[string]interface{}{"action": "run_worker"}},
+			},
+		},
+		{
+			name:    "missing decision.action",
+			msgType: MessageTypeNextAction,
+			doc: map[string]interface{}{
+				"type": "next_action", "version": 1,
+				"payload": map[string]interface{}{"decision": map[string]interface{}{"reason": "?"}},
+			},
+			want: []string{"payload.decision.action: required"},
+		},
+		{
+			name:    "unknown action and wrong type",
+			msgType: MessageTypeNextAction,
+			doc: map[string]interface{}{
+				"type": "plan_task", "version": 1.0,
+				"payload": map[string]interface{}{"decision": map[string]interface{}{"action": "dance"}},
+			},
+			want: []string{"payload.decision.action: must be one of", "type: must be one of"},
+		},
+		{
+			name:    "wrong item type",
+			msgType: MessageTypeCompletionAssessment,
+			doc: map[string]interface{}{
+				"type": "completion_assessment", "version": 1,
+				"payload": map[string]interface{}{
+					"all_criteria_satisfied": "yes",
+					"by_criterion":           []interface{}{map[string]interface{}{"id": "AC-1"}},
+				},
+			},
+			want: []string{"payload.all_criteria_satisfied: expected boolean", "payload.by_criterion[0].status: required"},
+		},
+		{
+			name:    "missing envelope",
+			msgType: MessageTypePlanTask,
+			doc:     map[string]interface{}{"task_id": "x"},
+			want:    []string{"payload: required", "type: required", "version: required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateMessage(tt.msgType, tt.doc)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ValidateMessage() = %v, want %d errors", got, len(tt.want))
+			}
+			for _, w := range tt.want {
+				found := false
+				for _, g := range got {
+					if strings.HasPrefix(g, w) {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("missing error %q in %v", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestMessage_Repair(t *testing.T) {
+	responses := []string{
+		"I think we should run the worker.",
+		"type: next_action\nversion: 1\npayload:\n  decision:\n    reason: missing action\n",
+		"```yaml\ntype: next_action\nversion: 1\npayload:\n  decision:\n    action: mark_complete\n```",
+	}
+	var prompts []string
+	call := func(ctx context.Context, prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return responses[len(prompts)-1], nil
+	}
+
+	rec := &ValidationRecorder{}
+	ctx := WithValidationRecorder(context.Background(), rec)
+	action, err := requestMessage[NextActionResponse](ctx, nil, MessageTypeNextAction, "Decide next action.", call)
+	if err != nil {
+		t.Fatalf("requestMessage() error = %v", err)
+	}
+	if action.Decision.Action != "mark_complete" {
+		t.Errorf("action = %q", action.Decision.Action)
+	}
+
+	if len(prompts) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(prompts))
+	}
+	if !strings.HasPrefix(prompts[2], "Decide next action.") || !strings.Contains(prompts[2], "payload.decision.action: required") {
+		t.Errorf("repair prompt should quote the original request and errors: %s", prompts[2])
+	}
+
+	invalid := rec.Invalid()
+	if len(invalid) != 2 || rec.Repairs() != 2 {
+		t.Fatalf("expected 2 recorded repairs, got %+v", invalid)
+	}
+	if invalid[0].Attempt != 1 || invalid[0].Response != responses[0] {
+		t.Errorf("unexpected first record: %+v", invalid[0])
+	}
+}
+
+func TestRequestMessage_Exhausted(t *testing.T) {
+	calls := 0
+	call := func(ctx context.Context, prompt string) (string, error) {
+		calls++
+		return `{"type":"decompose","version":1,"payload":{"phases":[{"name":"p"}]}}`, nil
+	}
+
+	rec := &ValidationRecorder{}
+	_, err := requestMessage[DecomposeResponse](WithValidationRecorder(context.Background(), rec), nil, MessageTypeDecompose, "req", call)
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("expected ErrInvalidResponse, got %v", err)
+	}
+	if calls != MaxRepairAttempts+1 {
+		t.Errorf("calls = %d, want %d", calls, MaxRepairAttempts+1)
+	}
+	invalid := rec.Invalid()
+	if len(invalid) != MaxRepairAttempts+1 || invalid[len(invalid)-1].RepairPrompt != "" {
+		t.Errorf("last invalid response should have no repair prompt: %+v", invalid)
+	}
+}
+
+func TestRequestMessage_TransportErrorNotRepaired(t *testing.T) {
+	calls := 0
+	boom := errors.New("boom")
+	_, err := requestMessage[PlanTaskResponse](context.Background(), nil, MessageTypePlanTask, "req", func(ctx context.Context, prompt string) (string, error) {
+		calls++
+		return "", boom
+	})
+	if !errors.Is(err, boom) || calls != 1 {
+		t.Errorf("err = %v, calls = %d", err, calls)
+	}
+}
@@ -0,0 +1,45 @@
+package meta
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReviewerSystemPrompt is the system prompt of the independent reviewer. 作業を
+// 進めた Meta とは別のモデルが、差分とテスト結果だけを根拠に判定する。
+const ReviewerSystemPrompt = `You are an independent code reviewer. Another agent implemented the task and claims it is complete.
+Do not trust that claim or the worker summaries: judge each acceptance criterion only from the diff and the test output.
+Mark a criterion "failed" when the evidence does not clearly show it is met.
+Output MUST be a YAML block with type: completion_assessment.`
+
+// ReviewWorkerPrompt builds the prompt for a reviewer worker running in the
+// sandbox. The worker must only read the repository and print its verdict.
+func ReviewWorkerPrompt(s *TaskSummary) string {
+	b := &strings.Builder{}
+	b.WriteString(ReviewerSystemPrompt)
+	b.WriteString("\nYou may read files and run read-only commands (e.g. tests), but you MUST NOT modify, create or delete any file.\n\n")
+	b.WriteString(buildCompletionAssessmentUserPrompt(s))
+	b.WriteString(`
+
+Print exactly one YAML block in this format:
+type: completion_assessment
+version: 1
+payload:
+  all_criteria_satisfied: false
+  summary: "..."
+  by_criterion:
+    - id: AC-1
+      status: passed # or failed
+      comment: "..."`)
+	return b.String()
+}
+
+// ParseCompletionAssessment extracts and validates a completion_assessment
+// message from free-form output (e.g. a reviewer worker).
+func ParseCompletionAssessment(output string) (*CompletionAssessmentResponse, error) {
+	out, errs := parseMessage[CompletionAssessmentResponse](MessageTypeCompletionAssessment, output)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, strings.Join(errs, "; "))
+	}
+	return out, nil
+}
@@ -0,0 +1,45 @@
+package meta
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuildCompletionAssessmentUserPrompt_Review(t *testing.T) {
+	s := &TaskSummary{
+		Title:              "Add login",
+		AcceptanceCriteria: []AcceptanceCriterion{{ID: "AC-1", Description: "Login works"}},
+		WorkerRuns:         []WorkerRunSummary{{ID: "run-1", Summary: "done"}},
+	}
+	// レビュー証拠が無い場合は従来のプロンプト（カセットのキーを変えない）
+	if got := buildCompletionAssessmentUserPrompt(s); got != "Task: Add login\nEvaluate completion." {
+		t.Errorf("unexpected prompt without evidence: %q", got)
+	}
+
+	s.Review = &ReviewEvidence{Diff: "+func Login() {}", TestCommand: "go test ./...", TestExitCode: 1, TestOutput: "FAIL login_test.go"}
+	got := buildCompletionAssessmentUserPrompt(s)
+	for _, want := range []string{"- AC-1: Login works", "- Run run-1", "+func Login() {}", "`go test ./...` exit_code=1", "FAIL login_test.go", "Evaluate completion."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt should contain %q:\n%s", want, got)
+		}
+	}
+
+	if got := buildReviewEvidence(&ReviewEvidence{}); !strings.Contains(got, "(no changes)") || !strings.Contains(got, "Tests: not configured") {
+		t.Errorf("unexpected empty evidence: %q", got)
+	}
+}
+
+func TestParseCompletionAssessment(t *testing.T) {
+	out, err := ParseCompletionAssessment("thinking...\n```yaml\ntype: completion_assessment\nversion: 1\npayload:\n  all_criteria_satisfied: false\n  by_criterion:\n    - id: AC-1\n      status: failed\n```\n")
+	if err != nil {
+		t.Fatalf("ParseCompletionAssessment failed: %v", err)
+	}
+	if out.AllCriteriaSatisfied || len(out.ByCriterion) != 1 || out.ByCriterion[0].Status != "failed" {
+		t.Errorf("unexpected assessment: %+v", out)
+	}
+
+	if _, err := ParseCompletionAssessment("no verdict"); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("expected ErrInvalidResponse, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package meta
+
+// Meta message types (MetaMessage.Type)
+const (
+	MessageTypePlanTask             = "plan_task"
+	MessageTypeNextAction           = "next_action"
+	MessageTypeCompletionAssessment = "completion_assessment"
+	MessageTypeDecompose            = "decompose"
+	MessageTypePlanPatch            = "plan_patch"
+)
+
+// JSON Schema の組み立て用ヘルパー
+func schemaObject(properties map[string]interface{}, required ...string) map[string]interface{} {
+	s := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func schemaArray(items map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items}
+}
+
+func schemaType(t string) map[string]interface{} {
+	return map[string]interface{}{"type": t}
+}
+
+func schemaEnum(values ...string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "enum": values}
+}
+
+func suggestedImplSchema() map[string]interface{} {
+	return schemaObject(map[string]interface{}{
+		"language":    schemaType("string"),
+		"file_paths":  schemaArray(schemaType("string")),
+		"constraints": schemaArray(schemaType("string")),
+	})
+}
+
+func potentialConflictsSchema() map[string]interface{} {
+	return schemaArray(schemaObject(map[string]interface{}{
+		"file":    schemaType("string"),
+		"tasks":   schemaArray(schemaType("string")),
+		"warning": schemaType("string"),
+	}))
+}
+
+// payloadSchemas は各メッセージ種別の payload の JSON Schema。
+// 応答を壊さないよう必須項目は各 Response 型の解釈に最低限必要なものに留める。
+var payloadSchemas = map[string]func() map[string]interface{}{
+	MessageTypePlanTask: func() map[string]interface{} {
+		return schemaObject(map[string]interface{}{
+			"task_id": schemaType("string"),
+			"acceptance_criteria": schemaArray(schemaObject(map[string]interface{}{
+				"id":          schemaType("string"),
+				"description": schemaType("string"),
+				"type":        schemaType("string"),
+				"critical":    schemaType("boolean"),
+			}, "id", "description")),
+		}, "acceptance_criteria")
+	},
+	MessageTypeNextAction: func() map[string]interface{} {
+		return schemaObject(map[string]interface{}{
+			"decision": schemaObject(map[string]interface{}{
+				"action": schemaEnum("run_worker", "mark_complete", "ask_human", "abort"),
+				"reason": schemaType("string"),
+			}, "action"),
+			"worker_call": schemaObject(map[string]interface{}{
+				"worker_type":      schemaType("string"),
+				"mode":             schemaType("string"),
+				"prompt":           schemaType("string"),
+				"model":            schemaType("string"),
+				"reasoning_effort": schemaType("string"),
+				"session":          schemaEnum(WorkerSessionNew, WorkerSessionContinue),
+				"session_id":       schemaType("string"),
+			}),
+		}, "decision")
+	},
+	MessageTypeCompletionAssessment: func() map[string]interface{} {
+		return schemaObject(map[string]interface{}{
+			"all_criteria_satisfied": schemaType("boolean"),
+			"summary":                schemaType("string"),
+			"by_criterion": schemaArray(schemaObject(map[string]interface{}{
+				"id":      schemaType("string"),
+				"status":  schemaEnum("passed", "failed"),
+				"comment": schemaType("string"),
+			}, "id", "status")),
+		}, "all_criteria_satisfied")
+	},
+	MessageTypeDecompose: func() map[string]interface{} {
+		task := schemaObject(map[string]interface{}{
+			"id":                  schemaType("string"),
+			"title":               schemaType("string"),
+			"description":         schemaType("string"),
+			"acceptance_criteria": schemaArray(schemaType("string")),
+			"dependencies":        schemaArray(schemaType("string")),
+			"wbs_level":           schemaType("integer"),
+			"estimated_effort":    schemaType("string"),
+			"suggested_impl":      suggestedImplSchema(),
+		}, "id", "title")
+		return schemaObject(map[string]interface{}{
+			"understanding": schemaType("string"),
+			"phases": schemaArray(schemaObject(map[string]interface{}{
+				"name":      schemaType("string"),
+				"milestone": schemaType("string"),
+				"tasks":     schemaArray(task),
+			}, "name", "tasks")),
+			"potential_conflicts": potentialConflictsSchema(),
+		}, "phases")
+	},
+	MessageTypePlanPatch: func() map[string]interface{} {
+		op := schemaObject(map[string]interface{}{
+			"op":                  schemaEnum(string(PlanOpCreate), string(PlanOpUpdate), string(PlanOpDelete), string(PlanOpMove)),
+			"temp_id":             schemaType("string"),
+			"task_id":             schemaType("string"),
+			"title":               schemaType("string"),
+			"description":         schemaType("string"),
+			"acceptance_criteria": schemaArray(schemaType("string")),
+			"dependencies":        schemaArray(schemaType("string")),
+			"wbs_level":           schemaType("integer"),
+			"phase_name":          schemaType("string"),
+			"milestone":           schemaType("string"),
+			"suggested_impl":      suggestedImplSchema(),
+			"parent_id":           schemaType("string"),
+			"position": schemaObject(map[string]interface{}{
+				"index":  schemaType("integer"),
+				"before": schemaType("string"),
+				"after":  schemaType("string"),
+			}),
+			"cascade": schemaType("boolean"),
+		}, "op")
+		return schemaObject(map[string]interface{}{
+			"understanding":       schemaType("string"),
+			"operations":          schemaArray(op),
+			"potential_conflicts": potentialConflictsSchema(),
+		}, "operations")
+	},
+}
+
+// MessageSchema returns the JSON Schema of the MetaMessage envelope for
+// msgType, or nil if the type is unknown.
+func MessageSchema(msgType string) map[string]interface{} {
+	payload, ok := payloadSchemas[msgType]
+	if !ok {
+		return nil
+	}
+	return schemaObject(map[string]interface{}{
+		"type":    schemaEnum(msgType),
+		"version": schemaType("integer"),
+		"payload": payload(),
+	}, "type", "version", "payload")
+}
@@ -0,0 +1,146 @@
+package meta
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamChunk is a piece of a Meta response received while it is generated.
+type StreamChunk struct {
+	Type     string // Meta メッセージ種別（plan_task 等）
+	Delta    string // 今回受信した差分
+	Received int    // この呼び出しで受信済みの累計バイト数
+}
+
+// StreamHandler receives partial Meta responses.
+type StreamHandler func(StreamChunk)
+
+type streamHandlerKey struct{}
+
+// WithStreamHandler returns a context that makes providers stream their
+// responses and forward partial content to h. 最終的な応答は従来どおり解析される。
+func WithStreamHandler(ctx context.Context, h StreamHandler) context.Context {
+	return context.WithValue(ctx, streamHandlerKey{}, h)
+}
+
+// StreamHandlerFrom returns the StreamHandler attached to ctx, or nil.
+func StreamHandlerFrom(ctx context.Context) StreamHandler {
+	if h, ok := ctx.Value(streamHandlerKey{}).(StreamHandler); ok {
+		return h
+	}
+	return nil
+}
+
+// streamForwarder returns a function that forwards deltas to the stream
+// handler in ctx, or nil if there is none.
+func streamForwarder(ctx context.Context) func(delta string) {
+	h := StreamHandlerFrom(ctx)
+	if h == nil {
+		return nil
+	}
+	msgType := messageTypeFrom(ctx)
+	received := 0
+	return func(delta string) {
+		if delta == "" {
+			return
+		}
+		received += len(delta)
+		h(StreamChunk{Type: msgType, Delta: delta, Received: received})
+	}
+}
+
+// chatStreamChunk is one SSE event of a streaming chat completion.
+type chatStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int          `json:"index"`
+				ID       string       `json:"id"`
+				Function chatFunction `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage,omitempty"`
+}
+
+// readChatStream assembles a streaming chat completion (server-sent events)
+// and forwards each content delta (and tool call arguments delta) to onDelta.
+func readChatStream(ctx context.Context, body io.Reader, onDelta func(string)) (*chatResponse, error) {
+	result := &chatResponse{}
+	var content strings.Builder
+	var toolCalls []chatToolCall
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue // コメント行・event 行・空行は無視
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			break
+		}
+
+		var chunk chatStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			// ツール呼び出しは index ごとに名前と引数の断片が届くので連結する
+			for _, tc := range choice.Delta.ToolCalls {
+				for len(toolCalls) <= tc.Index {
+					toolCalls = append(toolCalls, chatToolCall{Type: "function"})
+				}
+				call := &toolCalls[tc.Index]
+				if tc.ID != "" {
+					call.ID = tc.ID
+				}
+				call.Function.Name += tc.Function.Name
+				call.Function.Arguments += tc.Function.Arguments
+				if onDelta != nil {
+					onDelta(tc.Function.Arguments)
+				}
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// 呼び出し元のキャンセルで Body が閉じられた場合はキャンセルとして返す
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Choices = append(result.Choices, struct {
+		Message message `json:"message"`
+	}{Message: message{Role: "assistant", Content: content.String(), ToolCalls: toolCalls}})
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biwakonbu/agent-runner/internal/usage"
+)
+
+func TestOpenAIProvider_Streaming(t *testing.T) {
+	content := "type: next_action\nversion: 1\npayload:\n  decision:\n    action: mark_complete\n    reason: streamed\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"stream":true`) || !strings.Contains(string(body), `"include_usage":true`) {
+			t.Errorf("streaming not requested: %s", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, part := range strings.SplitAfter(content, "\n") {
+			if part == "" {
+				continue
+			}
+			_, _ = io.WriteString(w, "data: {\"model\":\"gpt-test\",\"choices\":[{\"delta\":{\"content\":"+jsonString(part)+"}}]}\n\n")
+			flusher.Flush()
+		}
+		_, _ = io.WriteString(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":10,\"completion_tokens\":5}}\n\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-test", "")
+	p.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+
+	var chunks []StreamChunk
+	rec := &usage.Recorder{}
+	ctx := WithStreamHandler(usage.WithRecorder(context.Background(), rec), func(c StreamChunk) {
+		chunks = append(chunks, c)
+	})
+
+	resp, err := p.NextAction(ctx, &TaskSummary{Title: "t"})
+	if err != nil {
+		t.Fatalf("NextAction failed: %v", err)
+	}
+	if resp.Decision.Reason != "streamed" {
+		t.Errorf("unexpected decision: %+v", resp.Decision)
+	}
+	if len(chunks) != 6 {
+		t.Fatalf("expected 6 chunks, got %d", len(chunks))
+	}
+	last := chunks[len(chunks)-1]
+	if last.Type != MessageTypeNextAction || last.Received != len(content) {
+		t.Errorf("unexpected last chunk: %+v", last)
+	}
+	if total := rec.Total(); total.InputTokens != 10 || total.OutputTokens != 5 {
+		t.Errorf("usage not recorded from stream: %+v", total)
+	}
+}
+
+func TestOpenAIProvider_StreamingFallsBackToJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// stream を無視するエンドポイント
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"type: next_action\nversion: 1\npayload:\n  decision:\n    action: abort\n"}}]}`)
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-test", "")
+	p.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+
+	called := false
+	ctx := WithStreamHandler(context.Background(), func(StreamChunk) { called = true })
+	resp, err := p.NextAction(ctx, &TaskSummary{Title: "t"})
+	if err != nil {
+		t.Fatalf("NextAction failed: %v", err)
+	}
+	if resp.Decision.Action != "abort" || called {
+		t.Errorf("unexpected result: action=%s called=%v", resp.Decision.Action, called)
+	}
+}
+
+func TestOpenAIProvider_StreamingCancel(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"type: \"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-test", "")
+	p.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = WithStreamHandler(ctx, func(StreamChunk) {})
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err := p.NextAction(ctx, &TaskSummary{Title: "t"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func jsonString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
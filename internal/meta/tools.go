@@ -0,0 +1,197 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Meta protocol modes
+const (
+	// ProtocolText は YAML/JSON ブロックをテキストで返してもらう従来の方式
+	ProtocolText = "text"
+	// ProtocolTools は判断や計画変更をツール（関数）呼び出しとして返してもらう方式
+	ProtocolTools = "tools"
+)
+
+// toolModeInstruction is appended to the system prompt when tools are sent.
+const toolModeInstruction = "\n\nRespond by calling the provided tools. Do not write the YAML/JSON message as text."
+
+// toolDef is a function the model can call, with JSON Schema parameters.
+type toolDef struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+}
+
+// toolCall is a tool invocation returned by the model.
+type toolCall struct {
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// Tool names. next_action の判断名は Decision.Action と同じ。
+const (
+	toolRunWorker         = "run_worker"
+	toolMarkComplete      = "mark_complete"
+	toolAskHuman          = "ask_human"
+	toolAbort             = "abort"
+	toolCreateTask        = "create_task"
+	toolUpdateTask        = "update_task"
+	toolDeleteTask        = "delete_task"
+	toolMoveTask          = "move_task"
+	toolDescribePlanPatch = "describe_plan_patch"
+)
+
+// planPatchToolOps maps plan_patch tools to operation types.
+var planPatchToolOps = map[string]PlanOperationType{
+	toolCreateTask: PlanOpCreate,
+	toolUpdateTask: PlanOpUpdate,
+	toolDeleteTask: PlanOpDelete,
+	toolMoveTask:   PlanOpMove,
+}
+
+// metaTools returns the tools declared for msgType, or nil if the message
+// type has no tool variant (the text protocol is used).
+func metaTools(msgType string) []toolDef {
+	switch msgType {
+	case MessageTypeNextAction:
+		workerCall := payloadProperty(MessageTypeNextAction, "worker_call")
+		runWorker := pickProperties(workerCall, "worker_type", "mode", "prompt", "model", "reasoning_effort", "session", "session_id")
+		runWorker["reason"] = schemaType("string")
+		return []toolDef{
+			{Name: toolRunWorker, Description: "Run the worker agent with a prompt to make progress on the task.", Parameters: toolParams(runWorker, "prompt")},
+			{Name: toolMarkComplete, Description: "Declare that the task is complete and request completion assessment.", Parameters: reasonParams()},
+			{Name: toolAskHuman, Description: "Ask the human for information or a decision that is needed to continue.", Parameters: reasonParams()},
+			{Name: toolAbort, Description: "Abort the task because it cannot be completed.", Parameters: reasonParams()},
+		}
+	case MessageTypePlanPatch:
+		op := payloadProperty(MessageTypePlanPatch, "operations", "items")
+		payload := payloadProperty(MessageTypePlanPatch)
+		return []toolDef{
+			{Name: toolCreateTask, Description: "Create a new task. Use a temp_id; the real ID is assigned later.",
+				Parameters: toolParams(pickProperties(op, "temp_id", "title", "description", "acceptance_criteria", "dependencies", "wbs_level", "phase_name", "milestone", "suggested_impl", "parent_id", "position"), "temp_id", "title")},
+			{Name: toolUpdateTask, Description: "Partially update an existing task by task_id.",
+				Parameters: toolParams(pickProperties(op, "task_id", "title", "description", "acceptance_criteria", "dependencies", "wbs_level", "phase_name", "milestone", "suggested_impl"), "task_id")},
+			{Name: toolDeleteTask, Description: "Remove a task from the active plan (optionally with its descendants).",
+				Parameters: toolParams(pickProperties(op, "task_id", "cascade"), "task_id")},
+			{Name: toolMoveTask, Description: "Move a task to another parent or position in the WBS.",
+				Parameters: toolParams(pickProperties(op, "task_id", "parent_id", "position", "wbs_level", "phase_name", "milestone"), "task_id")},
+			{Name: toolDescribePlanPatch, Description: "Summarize your understanding of the request and any potential file conflicts. Call once.",
+				Parameters: toolParams(pickProperties(payload, "understanding", "potential_conflicts"), "understanding")},
+		}
+	}
+	return nil
+}
+
+// payloadProperty returns the schema at path under the payload of msgType
+// ("items" descends into array items).
+func payloadProperty(msgType string, path ...string) map[string]interface{} {
+	schema, _ := MessageSchema(msgType)["properties"].(map[string]interface{})["payload"].(map[string]interface{})
+	for _, name := range path {
+		if name == "items" {
+			schema, _ = schema["items"].(map[string]interface{})
+			continue
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		schema, _ = props[name].(map[string]interface{})
+	}
+	return schema
+}
+
+func pickProperties(schema map[string]interface{}, names ...string) map[string]interface{} {
+	props, _ := schema["properties"].(map[string]interface{})
+	out := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		if p, ok := props[name]; ok {
+			out[name] = p
+		}
+	}
+	return out
+}
+
+func toolParams(properties map[string]interface{}, required ...string) map[string]interface{} {
+	return schemaObject(properties, required...)
+}
+
+func reasonParams() map[string]interface{} {
+	return toolParams(map[string]interface{}{"reason": schemaType("string")}, "reason")
+}
+
+// messageFromToolCalls maps tool calls onto the MetaMessage envelope of
+// msgType (as JSON), so that they go through the same validation as text
+// responses. text is the assistant's text content, used as plan_patch
+// understanding when describe_plan_patch is not called.
+func messageFromToolCalls(msgType string, calls []toolCall, text string) (string, error) {
+	var payload map[string]interface{}
+	switch msgType {
+	case MessageTypeNextAction:
+		call := calls[0] // 判断は 1 つ。複数呼ばれた場合は最初のものを採用する
+		args := copyArgs(call.Arguments)
+		reason, _ := args["reason"].(string)
+		delete(args, "reason")
+		payload = map[string]interface{}{
+			"decision": map[string]interface{}{"action": call.Name, "reason": reason},
+		}
+		if call.Name == toolRunWorker {
+			payload["worker_call"] = args
+		}
+	case MessageTypePlanPatch:
+		operations := []interface{}{}
+		payload = map[string]interface{}{"understanding": strings.TrimSpace(text)}
+		for _, call := range calls {
+			if call.Name == toolDescribePlanPatch {
+				for k, v := range call.Arguments {
+					payload[k] = v
+				}
+				continue
+			}
+			op, ok := planPatchToolOps[call.Name]
+			if !ok {
+				return "", fmt.Errorf("unknown tool %q for %s", call.Name, msgType)
+			}
+			args := copyArgs(call.Arguments)
+			args["op"] = string(op)
+			operations = append(operations, args)
+		}
+		payload["operations"] = operations
+	default:
+		return "", fmt.Errorf("message type %s has no tool variant", msgType)
+	}
+
+	out, err := json.Marshal(map[string]interface{}{"type": msgType, "version": 1, "payload": payload})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func copyArgs(args map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+	return out
+}
+
+// parseToolArguments decodes the JSON arguments string of an OpenAI tool call.
+func parseToolArguments(name, raw string) (toolCall, error) {
+	call := toolCall{Name: name, Arguments: map[string]interface{}{}}
+	if strings.TrimSpace(raw) == "" {
+		return call, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &call.Arguments); err != nil {
+		return call, fmt.Errorf("invalid arguments for tool %s: %w", name, err)
+	}
+	return call, nil
+}
+
+// isToolsUnsupported reports whether an error response indicates the endpoint
+// does not support tools / function calling.
+func isToolsUnsupported(statusCode int, body []byte) bool {
+	if statusCode != 400 && statusCode != 422 {
+		return false
+	}
+	lower := strings.ToLower(string(body))
+	return strings.Contains(lower, "tool") || strings.Contains(lower, "function")
+}
@@ -0,0 +1,221 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// openAIToolCallResponse returns a chat completion whose message only has tool calls.
+func openAIToolCallResponse(calls ...[2]string) string {
+	var toolCalls []map[string]interface{}
+	for i, c := range calls {
+		toolCalls = append(toolCalls, map[string]interface{}{
+			"id":       "call_" + string(rune('a'+i)),
+			"type":     "function",
+			"function": map[string]string{"name": c[0], "arguments": c[1]},
+		})
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model": "gpt-test",
+		"choices": []map[string]interface{}{
+			{"message": map[string]interface{}{"role": "assistant", "content": nil, "tool_calls": toolCalls}},
+		},
+	})
+	return string(body)
+}
+
+func TestOpenAIProvider_NextActionToolCall(t *testing.T) {
+	var gotReq chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotReq)
+		_, _ = io.WriteString(w, openAIToolCallResponse([2]string{toolRunWorker,
+			`{"reason":"implement it","prompt":"Add the handler","session":"continue","mode":"exec"}`}))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-test", "")
+	p.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+
+	resp, err := p.NextAction(context.Background(), &TaskSummary{Title: "t"})
+	if err != nil {
+		t.Fatalf("NextAction failed: %v", err)
+	}
+	if resp.Decision.Action != "run_worker" || resp.Decision.Reason != "implement it" {
+		t.Errorf("unexpected decision: %+v", resp.Decision)
+	}
+	if resp.WorkerCall.Prompt != "Add the handler" || resp.WorkerCall.Session != WorkerSessionContinue || resp.WorkerCall.Mode != "exec" {
+		t.Errorf("unexpected worker call: %+v", resp.WorkerCall)
+	}
+
+	if gotReq.ToolChoice != "required" || gotReq.ResponseFormat != nil {
+		t.Errorf("expected tools without response_format, got tool_choice=%q response_format=%+v", gotReq.ToolChoice, gotReq.ResponseFormat)
+	}
+	var names []string
+	for _, tool := range gotReq.Tools {
+		names = append(names, tool.Function.Name)
+	}
+	if strings.Join(names, ",") != "run_worker,mark_complete,ask_human,abort" {
+		t.Errorf("unexpected tools: %v", names)
+	}
+	if p.Protocol(MessageTypeNextAction) != ProtocolTools || p.Protocol(MessageTypePlanTask) != ProtocolText {
+		t.Error("unexpected protocol modes")
+	}
+}
+
+func TestOpenAIProvider_PlanPatchToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, openAIToolCallResponse(
+			[2]string{toolCreateTask, `{"temp_id":"tmp-1","title":"Add login","dependencies":["task-1"]}`},
+			[2]string{toolDeleteTask, `{"task_id":"task-9","cascade":true}`},
+			[2]string{toolDescribePlanPatch, `{"understanding":"split auth work"}`},
+		))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-test", "")
+	p.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+
+	resp, err := p.PlanPatch(context.Background(), &PlanPatchRequest{UserInput: "add login"})
+	if err != nil {
+		t.Fatalf("PlanPatch failed: %v", err)
+	}
+	if resp.Understanding != "split auth work" {
+		t.Errorf("understanding = %q", resp.Understanding)
+	}
+	if len(resp.Operations) != 2 {
+		t.Fatalf("expected 2 operations, got %+v", resp.Operations)
+	}
+	create, del := resp.Operations[0], resp.Operations[1]
+	if create.Op != PlanOpCreate || create.TempID != "tmp-1" || create.Title == nil || *create.Title != "Add login" || len(create.Dependencies) != 1 {
+		t.Errorf("unexpected create op: %+v", create)
+	}
+	if del.Op != PlanOpDelete || del.TaskID != "task-9" || !del.Cascade {
+		t.Errorf("unexpected delete op: %+v", del)
+	}
+}
+
+func TestOpenAIProvider_ToolsFallbackToText(t *testing.T) {
+	var withTools []bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		withTools = append(withTools, len(req.Tools) > 0)
+		if len(req.Tools) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"message":"tools are not supported by this model"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"type: next_action\nversion: 1\npayload:\n  decision:\n    action: abort\n    reason: text\n"}}]}`)
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-test", "")
+	p.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+
+	for i := 0; i < 2; i++ {
+		resp, err := p.NextAction(context.Background(), &TaskSummary{Title: "t"})
+		if err != nil {
+			t.Fatalf("NextAction failed: %v", err)
+		}
+		if resp.Decision.Action != "abort" || resp.Decision.Reason != "text" {
+			t.Errorf("unexpected decision: %+v", resp.Decision)
+		}
+	}
+	// 1 回目で tools 非対応を検出し、以降はテキストのプロトコルのみを使う
+	if len(withTools) != 3 || !withTools[0] || withTools[1] || withTools[2] {
+		t.Errorf("unexpected tools usage: %v", withTools)
+	}
+	if p.Protocol(MessageTypeNextAction) != ProtocolText {
+		t.Error("protocol should fall back to text")
+	}
+}
+
+func TestOpenAIProvider_StreamingToolCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_a","function":{"name":"ask_human","arguments":""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"reason\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"which DB?\"}"}}]}}]}`,
+		}
+		for _, e := range events {
+			_, _ = io.WriteString(w, "data: "+e+"\n\n")
+		}
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-test", "")
+	p.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+
+	var received int
+	ctx := WithStreamHandler(context.Background(), func(c StreamChunk) { received = c.Received })
+
+	resp, err := p.NextAction(ctx, &TaskSummary{Title: "t"})
+	if err != nil {
+		t.Fatalf("NextAction failed: %v", err)
+	}
+	if resp.Decision.Action != "ask_human" || resp.Decision.Reason != "which DB?" {
+		t.Errorf("unexpected decision: %+v", resp.Decision)
+	}
+	if received != len(`{"reason":"which DB?"}`) {
+		t.Errorf("arguments not forwarded as progress: received=%d", received)
+	}
+}
+
+func TestAnthropicProvider_NextActionToolUse(t *testing.T) {
+	var gotReq anthropicRequest
+	p := newTestAnthropicProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotReq)
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":       "claude-test",
+			"stop_reason": "tool_use",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "Looks done."},
+				{"type": "tool_use", "id": "toolu_1", "name": toolMarkComplete, "input": map[string]string{"reason": "all ACs pass"}},
+			},
+		})
+		_, _ = w.Write(body)
+	})
+
+	resp, err := p.NextAction(context.Background(), &TaskSummary{Title: "t"})
+	if err != nil {
+		t.Fatalf("NextAction() error = %v", err)
+	}
+	if resp.Decision.Action != "mark_complete" || resp.Decision.Reason != "all ACs pass" {
+		t.Errorf("unexpected decision: %+v", resp.Decision)
+	}
+	if len(gotReq.Tools) != 4 || gotReq.ToolChoice == nil || gotReq.ToolChoice.Type != "any" {
+		t.Errorf("tools not declared: tools=%d choice=%+v", len(gotReq.Tools), gotReq.ToolChoice)
+	}
+	if gotReq.Tools[0].InputSchema["type"] != "object" {
+		t.Errorf("unexpected input schema: %+v", gotReq.Tools[0].InputSchema)
+	}
+}
+
+func TestMessageFromToolCalls_InvalidArgumentsAreRepaired(t *testing.T) {
+	// 型の合わない引数は既存のスキーマ検証で検出され、修正依頼の対象になる
+	msg, err := messageFromToolCalls(MessageTypeNextAction, []toolCall{
+		{Name: toolRunWorker, Arguments: map[string]interface{}{"prompt": 42}},
+	}, "")
+	if err != nil {
+		t.Fatalf("messageFromToolCalls failed: %v", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal([]byte(msg), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	errs := ValidateMessage(MessageTypeNextAction, doc)
+	if len(errs) == 0 || !strings.Contains(strings.Join(errs, ";"), "worker_call.prompt") {
+		t.Errorf("expected validation error for prompt, got %v (msg=%s)", errs, msg)
+	}
+
+	if _, err := messageFromToolCalls(MessageTypePlanPatch, []toolCall{{Name: "rename_task"}}, ""); err == nil {
+		t.Error("expected error for unknown plan_patch tool")
+	}
+}
@@ -0,0 +1,167 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTranscriptMaxTokens is the estimated token budget of a replayed
+// transcript. Older turns are summarized once it is exceeded.
+const DefaultTranscriptMaxTokens = 8000
+
+// transcriptKeepRecent は要約せずに常に残す直近のエントリ数
+const transcriptKeepRecent = 4
+
+// Transcript entry roles
+const (
+	TranscriptRoleUser      = "user"
+	TranscriptRoleAssistant = "assistant"
+)
+
+// TranscriptEntry is one turn of the per-task Meta conversation.
+type TranscriptEntry struct {
+	Role      string    `yaml:"role" json:"role"` // user | assistant
+	Kind      string    `yaml:"kind" json:"kind"` // plan_task / next_action / worker_result / completion_assessment
+	Content   string    `yaml:"content" json:"content"`
+	Digest    string    `yaml:"digest,omitempty" json:"digest,omitempty"` // 要約時に残す 1 行の要旨（空なら要約時に捨てる）
+	Timestamp time.Time `yaml:"timestamp" json:"timestamp"`
+}
+
+// Transcript is the conversation the Meta agent has had about one task: the
+// plan, each decision and the observed worker results. Providers replay it
+// on calls whose context carries it (see WithTranscript).
+//
+// 推定トークン数が MaxTokens を超えると、古いエントリから Digest に畳み込んで Summary に移す。
+type Transcript struct {
+	TaskID     string            `yaml:"task_id" json:"task_id"`
+	MaxTokens  int               `yaml:"max_tokens" json:"max_tokens"`
+	Summary    []string          `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Omitted    int               `yaml:"omitted,omitempty" json:"omitted,omitempty"`       // Summary からも溢れて省略した要旨の数
+	Summarized int               `yaml:"summarized,omitempty" json:"summarized,omitempty"` // 要約に畳み込んだエントリの累計
+	Entries    []TranscriptEntry `yaml:"entries" json:"entries"`
+}
+
+// NewTranscript creates an empty transcript. maxTokens <= 0 uses DefaultTranscriptMaxTokens.
+func NewTranscript(taskID string, maxTokens int) *Transcript {
+	if maxTokens <= 0 {
+		maxTokens = DefaultTranscriptMaxTokens
+	}
+	return &Transcript{TaskID: taskID, MaxTokens: maxTokens}
+}
+
+// Append adds an entry and summarizes older turns if the budget is exceeded.
+func (t *Transcript) Append(entry TranscriptEntry) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	t.Entries = append(t.Entries, entry)
+	t.compact()
+}
+
+// Tokens returns the estimated token count of the replayed transcript.
+func (t *Transcript) Tokens() int {
+	n := EstimateTokens(t.summaryText())
+	for _, e := range t.Entries {
+		n += EstimateTokens(e.Content)
+	}
+	return n
+}
+
+// EstimateTokens returns a rough token estimate (4 bytes per token).
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+func (t *Transcript) compact() {
+	for t.Tokens() > t.MaxTokens && len(t.Entries) > transcriptKeepRecent {
+		oldest := t.Entries[0]
+		t.Entries = t.Entries[1:]
+		t.Summarized++
+		if oldest.Digest != "" {
+			t.Summary = append(t.Summary, oldest.Digest)
+		}
+	}
+	// 要旨自体が予算の半分を超える場合は古い要旨から省略する
+	for len(t.Summary) > 1 && EstimateTokens(t.summaryText()) > t.MaxTokens/2 {
+		t.Summary = t.Summary[1:]
+		t.Omitted++
+	}
+}
+
+func (t *Transcript) summaryText() string {
+	if len(t.Summary) == 0 && t.Omitted == 0 {
+		return ""
+	}
+	b := &strings.Builder{}
+	b.WriteString("Summary of earlier turns in this task:\n")
+	if t.Omitted > 0 {
+		fmt.Fprintf(b, "- (%d earlier turns omitted)\n", t.Omitted)
+	}
+	for _, line := range t.Summary {
+		fmt.Fprintf(b, "- %s\n", line)
+	}
+	return b.String()
+}
+
+// turns returns the messages to replay: the summary (as a user turn) followed by the entries.
+func (t *Transcript) turns() []message {
+	if t == nil {
+		return nil
+	}
+	var out []message
+	if summary := t.summaryText(); summary != "" {
+		out = append(out, message{Role: TranscriptRoleUser, Content: summary})
+	}
+	for _, e := range t.Entries {
+		out = append(out, message{Role: e.Role, Content: e.Content})
+	}
+	return out
+}
+
+// Render returns the transcript as plain text (for CLI providers and notes).
+func (t *Transcript) Render() string {
+	history := t.turns()
+	if len(history) == 0 {
+		return ""
+	}
+	b := &strings.Builder{}
+	b.WriteString("Conversation so far:\n")
+	for _, m := range history {
+		fmt.Fprintf(b, "\n[%s]\n%s\n", m.Role, strings.TrimRight(m.Content, "\n"))
+	}
+	return b.String()
+}
+
+type transcriptKey struct{}
+
+// WithTranscript returns a context whose Meta calls replay t before the request.
+func WithTranscript(ctx context.Context, t *Transcript) context.Context {
+	return context.WithValue(ctx, transcriptKey{}, t)
+}
+
+// TranscriptFrom returns the Transcript attached to ctx, or nil.
+func TranscriptFrom(ctx context.Context) *Transcript {
+	if t, ok := ctx.Value(transcriptKey{}).(*Transcript); ok {
+		return t
+	}
+	return nil
+}
+
+// alternatingTurns merges consecutive turns of the same role and makes the
+// conversation start with a user turn (Anthropic Messages API requirement).
+func alternatingTurns(turns []message) []message {
+	var out []message
+	for _, m := range turns {
+		if len(out) == 0 && m.Role != TranscriptRoleUser {
+			out = append(out, message{Role: TranscriptRoleUser, Content: "(conversation start)"})
+		}
+		if n := len(out); n > 0 && out[n-1].Role == m.Role {
+			out[n-1].Content += "\n\n" + m.Content
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTranscript_SummarizesOverBudget(t *testing.T) {
+	tr := NewTranscript("T1", 100)
+	for i := 0; i < 10; i++ {
+		tr.Append(TranscriptEntry{Role: TranscriptRoleAssistant, Kind: "next_action", Content: strings.Repeat("x", 100), Digest: "decision"})
+	}
+
+	if len(tr.Entries) != transcriptKeepRecent {
+		t.Errorf("expected %d recent entries, got %d", transcriptKeepRecent, len(tr.Entries))
+	}
+	if tr.Summarized != 10-transcriptKeepRecent {
+		t.Errorf("Summarized = %d", tr.Summarized)
+	}
+	if len(tr.Summary) == 0 || EstimateTokens(tr.summaryText()) > tr.MaxTokens/2 && len(tr.Summary) > 1 {
+		t.Errorf("summary not bounded: %v (omitted %d)", tr.Summary, tr.Omitted)
+	}
+	turns := tr.turns()
+	if turns[0].Role != TranscriptRoleUser || !strings.Contains(turns[0].Content, "- decision") {
+		t.Errorf("summary turn missing: %+v", turns[0])
+	}
+}
+
+func TestTranscript_DropsEntriesWithoutDigest(t *testing.T) {
+	tr := NewTranscript("T1", 10)
+	for i := 0; i < transcriptKeepRecent+1; i++ {
+		tr.Append(TranscriptEntry{Role: TranscriptRoleUser, Content: strings.Repeat("y", 80)})
+	}
+	if len(tr.Summary) != 0 || tr.Summarized != 1 {
+		t.Errorf("unexpected summary: %v summarized=%d", tr.Summary, tr.Summarized)
+	}
+}
+
+func TestAlternatingTurns(t *testing.T) {
+	got := alternatingTurns([]message{
+		{Role: "assistant", Content: "a"},
+		{Role: "user", Content: "b"},
+		{Role: "user", Content: "c"},
+	})
+	if len(got) != 3 || got[0].Role != "user" || got[1].Role != "assistant" || got[2].Content != "b\n\nc" {
+		t.Errorf("unexpected turns: %+v", got)
+	}
+}
+
+func TestOpenAIProvider_ReplaysTranscript(t *testing.T) {
+	var got chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &got)
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"type: next_action\nversion: 1\npayload:\n  decision:\n    action: mark_complete\n"}}]}`)
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-test", "")
+	p.SetHTTPOptions(HTTPOptions{BaseURL: server.URL})
+
+	tr := NewTranscript("T1", 0)
+	tr.Append(TranscriptEntry{Role: TranscriptRoleUser, Kind: "next_action", Content: "state: RUNNING"})
+	tr.Append(TranscriptEntry{Role: TranscriptRoleAssistant, Kind: "next_action", Content: "action: run_worker"})
+	tr.Append(TranscriptEntry{Role: TranscriptRoleUser, Kind: "worker_result", Content: "exit_code: 1"})
+
+	if _, err := p.NextAction(WithTranscript(context.Background(), tr), &TaskSummary{Title: "t"}); err != nil {
+		t.Fatalf("NextAction failed: %v", err)
+	}
+	if len(got.Messages) != 5 {
+		t.Fatalf("expected system + 3 turns + user, got %d messages", len(got.Messages))
+	}
+	if got.Messages[2].Role != "assistant" || got.Messages[2].Content != "action: run_worker" {
+		t.Errorf("unexpected replayed turn: %+v", got.Messages[2])
+	}
+	if !strings.Contains(got.Messages[4].Content, "Decide next action") {
+		t.Errorf("current prompt should be last: %+v", got.Messages[4])
+	}
+}
+
+func TestTranscript_Render(t *testing.T) {
+	var nilTranscript *Transcript
+	if nilTranscript.Render() != "" {
+		t.Error("nil transcript should render empty")
+	}
+	tr := NewTranscript("T1", 0)
+	tr.Append(TranscriptEntry{Role: TranscriptRoleAssistant, Content: "plan\n"})
+	if got := tr.Render(); got != "Conversation so far:\n\n[assistant]\nplan\n" {
+		t.Errorf("Render() = %q", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/biwakonbu/agent-runner/internal/prompt"
 	"gopkg.in/yaml.v3"
 )
 
@@ -150,6 +151,58 @@ func buildDecomposeUserPrompt(req *DecomposeRequest) string {
 	return b.String()
 }
 
+// buildNextActionUserPrompt builds the user prompt for next_action (all
+// providers). 継続可能な Worker セッションがある場合のみ追記する（カセットのキーを変えない）。
+func buildNextActionUserPrompt(s *TaskSummary) string {
+	contextSummary := fmt.Sprintf("Task: %s\nState: %s\nACs: %v\nWorkerRuns: %d",
+		s.Title, s.State, len(s.AcceptanceCriteria), s.WorkerRunsCount)
+	if s.HasWorkerSession {
+		contextSummary += "\nWorkerSession: a resumable worker session exists; prefer `session: " + WorkerSessionContinue + "` in worker_call to keep its context"
+	}
+	return fmt.Sprintf("Context:\n%s\n\nDecide next action.", contextSummary)
+}
+
+// buildCompletionAssessmentUserPrompt builds the user prompt for completion
+// assessment (HTTP providers). 独立レビューでは基準・Worker 結果・差分・テスト結果を添える。
+func buildCompletionAssessmentUserPrompt(s *TaskSummary) string {
+	if s.Review == nil {
+		return fmt.Sprintf("Task: %s\nEvaluate completion.", s.Title)
+	}
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Task: %s\n\nAcceptance Criteria:\n", s.Title)
+	for _, ac := range s.AcceptanceCriteria {
+		fmt.Fprintf(b, "- %s: %s\n", ac.ID, ac.Description)
+	}
+	if len(s.WorkerRuns) > 0 {
+		b.WriteString("\nWorker Execution Results:\n")
+		for _, run := range s.WorkerRuns {
+			fmt.Fprintf(b, "- Run %s: exit_code=%d, summary=%s\n", run.ID, run.ExitCode, run.Summary)
+		}
+	}
+	b.WriteString(buildReviewEvidence(s.Review))
+	b.WriteString("\nEvaluate completion.")
+	return b.String()
+}
+
+// buildReviewEvidence renders the diff and test output for a reviewer, or "" without evidence.
+func buildReviewEvidence(r *ReviewEvidence) string {
+	if r == nil {
+		return ""
+	}
+	b := &strings.Builder{}
+	diff := r.Diff
+	if strings.TrimSpace(diff) == "" {
+		diff = "(no changes)"
+	}
+	fmt.Fprintf(b, "\nDiff:\n```diff\n%s\n```\n", strings.TrimRight(diff, "\n"))
+	if r.TestCommand == "" {
+		b.WriteString("\nTests: not configured\n")
+	} else {
+		fmt.Fprintf(b, "\nTests: `%s` exit_code=%d\n```text\n%s\n```\n", r.TestCommand, r.TestExitCode, strings.TrimRight(r.TestOutput, "\n"))
+	}
+	return b.String()
+}
+
 // buildPlanPatchUserPrompt builds the user prompt for plan patch request
 // QH-001: Includes full structured context (WBS node_index, conversation history, task details)
 func buildPlanPatchUserPrompt(req *PlanPatchRequest) string {
@@ -214,3 +267,30 @@ func buildPlanPatchUserPrompt(req *PlanPatchRequest) string {
 
 	return b.String()
 }
+
+// nonEmptyString returns the first non-empty string.
+// callSystemPrompt returns the system prompt of a plan_task / next_action /
+// completion_assessment call: the configured prompt if set, otherwise the
+// "meta_<msgType>" template rendered for the provider's response format.
+func callSystemPrompt(prompts *prompt.Renderer, configured, msgType, format string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	if prompts == nil {
+		prompts = prompt.Default()
+	}
+	systemPrompt, err := prompts.MetaCallPrompt(msgType, format)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s system prompt: %w", msgType, err)
+	}
+	return systemPrompt, nil
+}
+
+func nonEmptyString(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package meta
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildNextActionUserPrompt(t *testing.T) {
+	s := &TaskSummary{Title: "Add login", State: "RUNNING", WorkerRunsCount: 2}
+	// セッションが無い場合は従来のプロンプト（カセットのキーを変えない）
+	want := "Context:\nTask: Add login\nState: RUNNING\nACs: 0\nWorkerRuns: 2\n\nDecide next action."
+	if got := buildNextActionUserPrompt(s); got != want {
+		t.Errorf("unexpected prompt without session: %q", got)
+	}
+
+	s.HasWorkerSession = true
+	got := buildNextActionUserPrompt(s)
+	for _, want := range []string{"WorkerRuns: 2", "a resumable worker session exists", "`session: continue`", "Decide next action."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt should contain %q:\n%s", want, got)
+		}
+	}
+}
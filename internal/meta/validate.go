@@ -0,0 +1,141 @@
+package meta
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ValidateMessage validates a decoded MetaMessage document (as produced by
+// yaml/json Unmarshal into interface{}) against the schema for msgType.
+// It returns one human readable error per violation ("payload.decision.action: required").
+func ValidateMessage(msgType string, doc interface{}) []string {
+	schema := MessageSchema(msgType)
+	if schema == nil {
+		return []string{fmt.Sprintf("unknown message type %q", msgType)}
+	}
+	var errs []string
+	validateValue("", schema, doc, &errs)
+	return errs
+}
+
+// validateValue は JSON Schema のサブセット（type / properties / required / items / enum）を検証する
+func validateValue(path string, schema map[string]interface{}, value interface{}, errs *[]string) {
+	label := path
+	if label == "" {
+		label = "(root)"
+	}
+
+	if t, ok := schema["type"].(string); ok && !matchesType(t, value) {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", label, t, describeType(value)))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]string); ok {
+		s, _ := value.(string)
+		if !containsString(enum, s) {
+			*errs = append(*errs, fmt.Sprintf("%s: must be one of [%s], got %q", label, strings.Join(enum, ", "), s))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]string); ok {
+			for _, name := range required {
+				if v[name] == nil {
+					*errs = append(*errs, fmt.Sprintf("%s: required", joinPath(path, name)))
+				}
+			}
+		}
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child, ok := v[name]
+			if !ok || child == nil {
+				continue
+			}
+			if propSchema, ok := properties[name].(map[string]interface{}); ok {
+				validateValue(joinPath(path, name), propSchema, child, errs)
+			}
+		}
+	case []interface{}:
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		for i, item := range v {
+			validateValue(fmt.Sprintf("%s[%d]", path, i), items, item, errs)
+		}
+	}
+}
+
+func matchesType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch n := value.(type) {
+		case int, int64, uint64:
+			return true
+		case float64:
+			return n == math.Trunc(n)
+		}
+		return false
+	case "number":
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+func describeType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64, float64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -5,12 +5,57 @@ import (
 	"os"
 	"path/filepath"
 	"text/template"
+	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/core"
+	"github.com/biwakonbu/agent-runner/internal/meta"
+	"gopkg.in/yaml.v3"
 )
 
 type Writer struct{}
 
+// checkpoint は実行中のタスク状態（Meta トランスクリプトを含む）のスナップショット
+type checkpoint struct {
+	TaskID             string            `yaml:"task_id"`
+	Title              string            `yaml:"title"`
+	State              core.TaskState    `yaml:"state"`
+	AcceptanceCriteria []string          `yaml:"acceptance_criteria,omitempty"`
+	WorkerRuns         int               `yaml:"worker_runs"`
+	WorkerSessions     map[string]string `yaml:"worker_sessions,omitempty"`
+	Transcript         *meta.Transcript  `yaml:"transcript,omitempty"`
+	UpdatedAt          time.Time         `yaml:"updated_at"`
+}
+
+// Checkpoint writes .agent-runner/task-<id>.checkpoint.yaml. It is called
+// after each Meta decision and worker run, so it survives an aborted task.
+func (w *Writer) Checkpoint(taskCtx *core.TaskContext) error {
+	dir := filepath.Join(taskCtx.RepoPath, ".agent-runner")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(checkpoint{
+		TaskID:             taskCtx.ID,
+		Title:              taskCtx.Title,
+		State:              taskCtx.State,
+		AcceptanceCriteria: taskCtx.AcceptanceCriteria,
+		WorkerRuns:         len(taskCtx.WorkerRuns),
+		WorkerSessions:     taskCtx.WorkerSessions,
+		Transcript:         taskCtx.Transcript,
+		UpdatedAt:          time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("task-%s.checkpoint.yaml", taskCtx.ID))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
 func NewWriter() *Writer {
 	return &Writer{}
 }
@@ -89,7 +134,15 @@ N/A
 
 {{ range .MetaCalls }}
 #### {{ .Type }} at {{ .Timestamp }}
-
+{{ if .Route.Kind }}
+Route: {{ .Route.Kind }} / {{ .Route.Model }}
+{{ end }}{{ if not .Usage.IsZero }}
+Tokens: input={{ .Usage.InputTokens }} output={{ .Usage.OutputTokens }}{{ if .Usage.Model }} ({{ .Usage.Model }}){{ end }}
+{{ end }}{{ if .Error }}
+Error: {{ .Error }}
+{{ end }}{{ range .InvalidResponses }}- Invalid response (attempt {{ .Attempt }}){{ if .RepairPrompt }}, repair requested{{ end }}
+{{ range .Errors }}  - {{ . }}
+{{ end }}{{ end }}
 ` + "```" + `yaml
 {{ .RequestYAML }}
 ` + "```" + `
@@ -106,14 +159,40 @@ N/A
 #### Run {{ .ID }} (ExitCode={{ .ExitCode }}) at {{ .StartedAt }}
 
 Summary: {{ .Summary }}
-
+{{ if .WorkerKind }}
+Worker: {{ .WorkerKind }}{{ if .SessionID }} (session {{ .SessionID }}){{ end }}
+{{ end }}{{ if not .Usage.IsZero }}
+Tokens: input={{ .Usage.InputTokens }} output={{ .Usage.OutputTokens }}{{ if .Usage.Model }} ({{ .Usage.Model }}){{ end }}
+{{ end }}{{ range .FallbackAttempts }}- Fallback from {{ .WorkerKind }} ({{ .FailureClass }}, ExitCode={{ .ExitCode }})
+{{ end }}
 ` + "```" + `text
 {{ .RawOutput }}
 ` + "```" + `
 
 {{ end }}
 
-### 3.3 Test Results
+### 3.3 Meta Transcript
+
+{{ with .Transcript }}
+- Estimated Tokens: {{ .Tokens }} / {{ .MaxTokens }}
+- Summarized Turns: {{ .Summarized }}
+{{ if .Summary }}
+Summary of earlier turns:
+{{ if .Omitted }}- ({{ .Omitted }} earlier turns omitted)
+{{ end }}{{ range .Summary }}- {{ . }}
+{{ end }}{{ end }}
+{{ range .Entries }}
+#### [{{ .Role }}] {{ .Kind }} at {{ .Timestamp }}
+
+` + "```" + `text
+{{ .Content }}
+` + "```" + `
+
+{{ end }}{{ else }}
+Transcript disabled.
+{{ end }}
+
+### 3.4 Test Results
 
 {{ if .TestResult }}
 - Command: {{ .TestResult.Command }}
@@ -129,6 +208,40 @@ No test configured or executed.
 
 {{ end }}
 
+### 3.5 Independent Review
+
+{{ with .Review }}
+- Reviewer: {{ .Reviewer }}
+- Agreed: {{ .Agreed }}
+{{ if .Error }}- Error: {{ .Error }}
+{{ end }}{{ with .Assessment }}- Reviewer Verdict: all_criteria_satisfied={{ .AllCriteriaSatisfied }}
+- Reviewer Summary: {{ .Summary }}
+{{ end }}{{ if .Disagreements }}
+Disagreements (Meta vs Reviewer) — needs human review:
+{{ range .Disagreements }}
+- {{ .CriterionID }}: Meta={{ .MetaStatus }} / Reviewer={{ .ReviewerStatus }}{{ if .MetaComment }}
+  - Meta: {{ .MetaComment }}{{ end }}{{ if .ReviewerComment }}
+  - Reviewer: {{ .ReviewerComment }}{{ end }}
+{{ end }}{{ end }}
+{{ else }}
+No independent review configured.
+{{ end }}
+
+---
+
+## 4. Usage
+
+{{ if .Usage }}
+- Input Tokens: {{ .Usage.InputTokens }} (cached: {{ .Usage.CachedInputTokens }})
+- Output Tokens: {{ .Usage.OutputTokens }}
+- Calls: {{ .Usage.Calls }}
+- Estimated Cost: ${{ printf "%.4f" .Usage.CostUSD }}
+{{ range $model := .Usage.Models }}{{ with index $.Usage.ByModel $model }}
+  - {{ $model }}: input={{ .InputTokens }} output={{ .OutputTokens }} cost=${{ printf "%.4f" .CostUSD }}{{ end }}{{ end }}
+{{ else }}
+No usage recorded.
+{{ end }}
+
 ---
 `
 
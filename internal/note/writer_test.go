@@ -8,7 +8,10 @@ import (
 	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/core"
+	"github.com/biwakonbu/agent-runner/internal/meta"
+	"github.com/biwakonbu/agent-runner/internal/usage"
 	"github.com/biwakonbu/agent-runner/pkg/config"
+	"gopkg.in/yaml.v3"
 )
 
 func TestWriter_Write_CreatesDotAgentRunnerDir(t *testing.T) {
@@ -437,3 +440,114 @@ func TestWriter_Write_WithSuggestedImpl(t *testing.T) {
 		}
 	}
 }
+
+func TestWriter_Write_IncludesUsageTotals(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	taskCtx := &core.TaskContext{
+		ID:       "TASK-USAGE",
+		RepoPath: tmpDir,
+		State:    core.StateComplete,
+		MetaCalls: []core.MetaCallLog{
+			{Type: "plan_task", Usage: usage.Usage{Model: "gpt-5.2", InputTokens: 1000, OutputTokens: 200}},
+		},
+		WorkerRuns: []core.WorkerRunResult{
+			{ID: "run-1", Usage: usage.Usage{Model: "gpt-5.1-codex", InputTokens: 5000, OutputTokens: 800}},
+		},
+	}
+	summary := taskCtx.UsageSummary(usage.DefaultPriceTable())
+	taskCtx.Usage = &summary
+
+	if err := NewWriter().Write(taskCtx); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, ".agent-runner", "task-TASK-USAGE.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## 4. Usage", "Input Tokens: 6000", "Output Tokens: 1000", "gpt-5.1-codex: input=5000", "Tokens: input=1000 output=200 (gpt-5.2)"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("note should contain %q", want)
+		}
+	}
+}
+
+func TestWriter_CheckpointAndTranscript(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tr := meta.NewTranscript("TASK-TR", 0)
+	tr.Append(meta.TranscriptEntry{Role: meta.TranscriptRoleAssistant, Kind: "next_action", Content: "action: run_worker", Digest: "next_action: run_worker"})
+	taskCtx := &core.TaskContext{
+		ID:         "TASK-TR",
+		RepoPath:   tmpDir,
+		State:      core.StateRunning,
+		Transcript: tr,
+	}
+
+	w := NewWriter()
+	if err := w.Checkpoint(taskCtx); err != nil {
+		t.Fatalf("Checkpoint() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".agent-runner", "task-TASK-TR.checkpoint.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp struct {
+		State      string          `yaml:"state"`
+		Transcript meta.Transcript `yaml:"transcript"`
+	}
+	if err := yaml.Unmarshal(data, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if cp.State != "RUNNING" || len(cp.Transcript.Entries) != 1 || cp.Transcript.Entries[0].Content != "action: run_worker" {
+		t.Errorf("unexpected checkpoint: %+v", cp)
+	}
+
+	if err := w.Write(taskCtx); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, ".agent-runner", "task-TASK-TR.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"### 3.3 Meta Transcript", "[assistant] next_action", "action: run_worker"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("note should contain %q", want)
+		}
+	}
+}
+
+func TestWriter_IndependentReview(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskCtx := &core.TaskContext{
+		ID:       "TASK-RV",
+		RepoPath: tmpDir,
+		State:    core.StateFailed,
+		Review: &core.ReviewResult{
+			Reviewer:   "anthropic-messages / reviewer",
+			Assessment: &meta.CompletionAssessmentResponse{Summary: "login is not wired"},
+			Disagreements: []core.ReviewDisagreement{
+				{CriterionID: "AC-1", MetaStatus: "passed", ReviewerStatus: "failed", ReviewerComment: "handler missing"},
+			},
+		},
+	}
+	if err := NewWriter().Write(taskCtx); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, ".agent-runner", "task-TASK-RV.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"### 3.5 Independent Review",
+		"- Reviewer: anthropic-messages / reviewer",
+		"- Agreed: false",
+		"- AC-1: Meta=passed / Reviewer=failed",
+		"  - Reviewer: handler missing",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("note should contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+)
+
+// DependencyIssueKind classifies a problem in the node dependency graph.
+type DependencyIssueKind string
+
+const (
+	DependencyIssueCycle          DependencyIssueKind = "cycle"
+	DependencyIssueSelfDependency DependencyIssueKind = "self_dependency"
+	DependencyIssueDangling       DependencyIssueKind = "dangling_reference"
+)
+
+// DependencyIssue is a single problem found by ValidateDependencyGraph.
+type DependencyIssue struct {
+	Kind DependencyIssueKind `json:"kind"`
+	// NodeIDs は問題に関わるノード。cycle の場合は循環順（先頭ノードを末尾に繰り返さない）
+	NodeIDs []string `json:"nodeIds"`
+	// Dependency は dangling_reference の場合の存在しない依存先
+	Dependency string `json:"dependency,omitempty"`
+	Message    string `json:"message"`
+}
+
+// DependencyReport is the result of validating a dependency graph.
+type DependencyReport struct {
+	Valid  bool              `json:"valid"`
+	Issues []DependencyIssue `json:"issues,omitempty"`
+}
+
+// HasBlockingIssues reports whether the graph contains cycles or
+// self-dependencies, which make the involved tasks unschedulable.
+func (r *DependencyReport) HasBlockingIssues() bool {
+	for _, issue := range r.Issues {
+		if issue.Kind == DependencyIssueCycle || issue.Kind == DependencyIssueSelfDependency {
+			return true
+		}
+	}
+	return false
+}
+
+// DependencyGraphError is returned when a plan change would create an
+// invalid dependency graph. Report を IDE にそのまま渡せる。
+type DependencyGraphError struct {
+	Report *DependencyReport
+}
+
+func (e *DependencyGraphError) Error() string {
+	msgs := make([]string, 0, len(e.Report.Issues))
+	for _, issue := range e.Report.Issues {
+		msgs = append(msgs, issue.Message)
+	}
+	return "invalid dependency graph: " + strings.Join(msgs, "; ")
+}
+
+// ValidateDependencyGraph checks graph (node ID -> dependency node IDs) for
+// self-dependencies, dangling references and cycles.
+func ValidateDependencyGraph(graph map[string][]string) *DependencyReport {
+	report := &DependencyReport{}
+	ids := sortedNodeIDs(graph)
+
+	for _, id := range ids {
+		for _, dep := range graph[id] {
+			switch _, exists := graph[dep]; {
+			case dep == id:
+				report.Issues = append(report.Issues, DependencyIssue{
+					Kind:    DependencyIssueSelfDependency,
+					NodeIDs: []string{id},
+					Message: fmt.Sprintf("%s depends on itself", id),
+				})
+			case !exists:
+				report.Issues = append(report.Issues, DependencyIssue{
+					Kind:       DependencyIssueDangling,
+					NodeIDs:    []string{id},
+					Dependency: dep,
+					Message:    fmt.Sprintf("%s depends on unknown node %s", id, dep),
+				})
+			}
+		}
+	}
+
+	for _, cycle := range findCycles(graph, ids) {
+		report.Issues = append(report.Issues, DependencyIssue{
+			Kind:    DependencyIssueCycle,
+			NodeIDs: cycle,
+			Message: "dependency cycle: " + strings.Join(cycle, " -> ") + " -> " + cycle[0],
+		})
+	}
+
+	report.Valid = len(report.Issues) == 0
+	return report
+}
+
+// findCycles returns the cycles (of length >= 2) reachable in graph, each
+// rotated to start at its smallest node ID. 自己依存は別途報告するため除外する。
+func findCycles(graph map[string][]string, ids []string) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(graph))
+	seen := make(map[string]bool)
+	var cycles [][]string
+	var stack []string
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range graph[id] {
+			if dep == id {
+				continue
+			}
+			if _, exists := graph[dep]; !exists {
+				continue
+			}
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				// stack 上の dep から現在ノードまでが循環
+				start := len(stack) - 1
+				for stack[start] != dep {
+					start--
+				}
+				cycle := normalizeCycle(stack[start:])
+				key := strings.Join(cycle, "\x00")
+				if !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return cycles
+}
+
+func normalizeCycle(path []string) []string {
+	min := 0
+	for i := range path {
+		if path[i] < path[min] {
+			min = i
+		}
+	}
+	out := make([]string, 0, len(path))
+	out = append(out, path[min:]...)
+	return append(out, path[:min]...)
+}
+
+func sortedNodeIDs(graph map[string][]string) []string {
+	ids := make([]string, 0, len(graph))
+	for id := range graph {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LoadDependencyGraph builds the dependency graph of the nodes of the tasks
+// in state/tasks.json. 設計が読めないノードは依存なしとして扱う。
+func LoadDependencyGraph(repo persistence.WorkspaceRepository) (map[string][]string, error) {
+	tasksState, err := repo.State().LoadTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks state: %w", err)
+	}
+	nodeIDs := make([]string, 0, len(tasksState.Tasks))
+	for _, ts := range tasksState.Tasks {
+		nodeIDs = append(nodeIDs, ts.NodeID)
+	}
+	// タスクを持たないが実行時状態のあるノード（取り込み済みの依存先など）も既知のノードとする
+	if nodesRuntime, err := repo.State().LoadNodesRuntime(); err == nil {
+		for _, nr := range nodesRuntime.Nodes {
+			nodeIDs = append(nodeIDs, nr.NodeID)
+		}
+	}
+
+	graph := make(map[string][]string, len(nodeIDs))
+	for _, id := range nodeIDs {
+		if id == "" {
+			continue
+		}
+		if _, ok := graph[id]; ok {
+			continue
+		}
+		graph[id] = nil
+		if node, err := repo.Design().GetNode(id); err == nil {
+			graph[id] = append([]string(nil), node.Dependencies...)
+		}
+	}
+	return graph, nil
+}
@@ -0,0 +1,55 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDependencyGraph(t *testing.T) {
+	t.Run("valid DAG", func(t *testing.T) {
+		report := ValidateDependencyGraph(map[string][]string{
+			"a": nil, "b": {"a"}, "c": {"a", "b"},
+		})
+		if !report.Valid || len(report.Issues) != 0 {
+			t.Errorf("expected valid report, got %+v", report)
+		}
+	})
+
+	t.Run("issues", func(t *testing.T) {
+		report := ValidateDependencyGraph(map[string][]string{
+			"self":  {"self"},
+			"ghost": {"missing"},
+			"c":     {"a"},
+			"a":     {"b"},
+			"b":     {"c"},
+		})
+		if report.Valid || !report.HasBlockingIssues() {
+			t.Fatalf("expected blocking issues, got %+v", report)
+		}
+
+		byKind := map[DependencyIssueKind][]DependencyIssue{}
+		for _, issue := range report.Issues {
+			byKind[issue.Kind] = append(byKind[issue.Kind], issue)
+		}
+		if got := byKind[DependencyIssueSelfDependency]; len(got) != 1 || got[0].NodeIDs[0] != "self" {
+			t.Errorf("unexpected self dependency issues: %+v", got)
+		}
+		if got := byKind[DependencyIssueDangling]; len(got) != 1 || got[0].NodeIDs[0] != "ghost" || got[0].Dependency != "missing" {
+			t.Errorf("unexpected dangling issues: %+v", got)
+		}
+		cycles := byKind[DependencyIssueCycle]
+		if len(cycles) != 1 {
+			t.Fatalf("expected 1 cycle, got %+v", cycles)
+		}
+		if strings.Join(cycles[0].NodeIDs, ",") != "a,b,c" || cycles[0].Message != "dependency cycle: a -> b -> c -> a" {
+			t.Errorf("unexpected cycle: %+v", cycles[0])
+		}
+	})
+
+	t.Run("dangling only is not blocking", func(t *testing.T) {
+		report := ValidateDependencyGraph(map[string][]string{"a": {"gone"}})
+		if report.Valid || report.HasBlockingIssues() {
+			t.Errorf("dangling reference should be reported but not blocking: %+v", report)
+		}
+	})
+}
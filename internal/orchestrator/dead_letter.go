@@ -0,0 +1,122 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/ipc"
+)
+
+// History action kinds of dead-letter operations (history/*.jsonl).
+const (
+	ActionKindDeadLetterRequeued = "dead_letter.requeued"
+	ActionKindDeadLetterPurged   = "dead_letter.purged"
+)
+
+// deadLetterJob moves a job that cannot be processed to ipc/dead/<pool-id>
+// with reason, instead of completing (and losing) it.
+func (e *ExecutionOrchestrator) deadLetterJob(job *ipc.Job, reason string) {
+	if err := e.Queue.DeadLetter(job.ID, job.PoolID, reason); err != nil {
+		e.logger.Error("failed to dead-letter job",
+			slog.String("job_id", job.ID),
+			slog.String("reason", reason),
+			slog.Any("error", err),
+		)
+		return
+	}
+	e.logger.Error("job moved to dead-letter queue",
+		slog.String("job_id", job.ID),
+		slog.String("task_id", job.TaskID),
+		slog.String("pool_id", job.PoolID),
+		slog.String("reason", reason),
+	)
+	if e.EventEmitter != nil {
+		e.EventEmitter.Emit(EventJobDeadLettered, JobDeadLetteredEvent{
+			JobID:     job.ID,
+			TaskID:    job.TaskID,
+			PoolID:    job.PoolID,
+			Reason:    reason,
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// ListDeadLetters returns the dead-lettered jobs of poolID (every pool when
+// poolID is empty).
+func (e *ExecutionOrchestrator) ListDeadLetters(poolID string) ([]*ipc.DeadLetter, error) {
+	return e.Queue.ListDeadLetters(poolID)
+}
+
+// RequeueDeadLetter puts a dead-lettered job back into the queue.
+// タスクが恒久的な失敗になっていても、ジョブの実行開始時に解除される。
+func (e *ExecutionOrchestrator) RequeueDeadLetter(jobID, poolID string) error {
+	dl, err := e.findDeadLetter(jobID, poolID)
+	if err != nil {
+		return err
+	}
+	if err := e.Queue.RequeueDeadLetter(jobID, poolID); err != nil {
+		return err
+	}
+	e.logger.Info("dead letter requeued", slog.String("job_id", jobID), slog.String("task_id", dl.TaskID))
+	e.recordTaskAction(ActionKindDeadLetterRequeued, dl.TaskID, map[string]interface{}{
+		"job_id":  jobID,
+		"pool_id": poolID,
+	})
+	return nil
+}
+
+// PurgeDeadLetter deletes a dead-lettered job.
+func (e *ExecutionOrchestrator) PurgeDeadLetter(jobID, poolID string) error {
+	dl, err := e.findDeadLetter(jobID, poolID)
+	if err != nil {
+		return err
+	}
+	if err := e.Queue.PurgeDeadLetter(jobID, poolID); err != nil {
+		return err
+	}
+	e.logger.Info("dead letter purged", slog.String("job_id", jobID), slog.String("task_id", dl.TaskID))
+	e.recordTaskAction(ActionKindDeadLetterPurged, dl.TaskID, map[string]interface{}{
+		"job_id":  jobID,
+		"pool_id": poolID,
+		"reason":  dl.Reason,
+	})
+	return nil
+}
+
+// PurgeDeadLetters deletes every dead letter of poolID (every pool when
+// poolID is empty) and records one history action per purged job.
+// 返り値は削除した件数（途中で失敗した場合もそれまでの件数を返す）。
+func (e *ExecutionOrchestrator) PurgeDeadLetters(poolID string) (int, error) {
+	letters, err := e.Queue.ListDeadLetters(poolID)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, dl := range letters {
+		if err := e.Queue.PurgeDeadLetter(dl.JobID, dl.PoolID); err != nil {
+			return purged, err
+		}
+		purged++
+		e.recordTaskAction(ActionKindDeadLetterPurged, dl.TaskID, map[string]interface{}{
+			"job_id":  dl.JobID,
+			"pool_id": dl.PoolID,
+			"reason":  dl.Reason,
+		})
+	}
+	e.logger.Info("dead letters purged", slog.String("pool_id", poolID), slog.Int("count", purged))
+	return purged, nil
+}
+
+func (e *ExecutionOrchestrator) findDeadLetter(jobID, poolID string) (*ipc.DeadLetter, error) {
+	letters, err := e.Queue.ListDeadLetters(poolID)
+	if err != nil {
+		return nil, err
+	}
+	for _, dl := range letters {
+		if dl.JobID == jobID {
+			return dl, nil
+		}
+	}
+	return nil, fmt.Errorf("dead letter not found: %s", jobID)
+}
@@ -0,0 +1,101 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/ipc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionOrchestrator_DeadLettersJobWithoutTask(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	saveState(t, repo, nil, nil)
+	require.NoError(t, queue.Enqueue(&ipc.Job{ID: "job-ghost", TaskID: "task-ghost", PoolID: "default"}))
+
+	emitter := new(MockEventEmitter)
+	emitter.On("Emit", mock.Anything, mock.Anything).Return()
+	orch := NewExecutionOrchestrator(nil, newBlockingExecutor(), repo, queue, emitter, nil, []string{"default"})
+	orch.dispatchJobs(context.Background(), "default")
+	orch.jobs.Wait()
+
+	letters, err := orch.ListDeadLetters("")
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, "job-ghost", letters[0].JobID)
+	assert.Equal(t, "task not found: task-ghost", letters[0].Reason)
+	emitter.AssertCalled(t, "Emit", EventJobDeadLettered, mock.MatchedBy(func(ev JobDeadLetteredEvent) bool {
+		return ev.JobID == "job-ghost" && ev.TaskID == "task-ghost"
+	}))
+}
+
+func TestExecutionOrchestrator_DeadLettersPoisonJob(t *testing.T) {
+	orch, repo, emitter := setupControlTasks(t, TaskStatusReady, nil)
+	orch.Executor = newBlockingExecutor()
+	orch.Queue.MaxDeliveries = 1
+
+	// 実行中にプロセスが落ち、リカバリで戻されたジョブが再配送された状態
+	require.NoError(t, orch.Queue.Enqueue(&ipc.Job{ID: "job-a", TaskID: "task-a", PoolID: "default", Deliveries: 1}))
+	orch.dispatchJobs(context.Background(), "default")
+	orch.jobs.Wait()
+
+	tasks := loadStatuses(t, repo)
+	assert.Equal(t, string(TaskStatusFailed), tasks["task-a"].Status)
+	assert.Equal(t, true, tasks["task-a"].Inputs[InputKeyFailedPermanently])
+	assert.Contains(t, tasks["task-a"].Inputs[InputKeyLastError], "dead-lettered: job delivered 2 times")
+	assert.Equal(t, string(TaskStatusDependencyFailed), tasks["task-b"].Status)
+	assertStateChange(t, emitter, "task-a", TaskStatusReady, TaskStatusFailed)
+
+	letters, err := orch.ListDeadLetters("default")
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, 2, letters[0].Deliveries)
+
+	// 再投入するとタスクが再実行できる状態でキューに戻る
+	require.NoError(t, orch.RequeueDeadLetter("job-a", "default"))
+	assertHistoryAction(t, repo, ActionKindDeadLetterRequeued, "task-a")
+	queued, err := orch.Queue.ListJobs("default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"job-a"}, queued)
+
+	assert.Error(t, orch.PurgeDeadLetter("job-a", "default"), "already requeued")
+}
+
+func TestExecutionOrchestrator_PurgeDeadLetter(t *testing.T) {
+	orch, repo, _ := setupControlTasks(t, TaskStatusReady, nil)
+	require.NoError(t, orch.Queue.Enqueue(&ipc.Job{ID: "job-a", TaskID: "task-a", PoolID: "default"}))
+	job, err := orch.Queue.Dequeue("default")
+	require.NoError(t, err)
+	require.NoError(t, orch.Queue.DeadLetter(job.ID, job.PoolID, "broken"))
+
+	require.NoError(t, orch.PurgeDeadLetter("job-a", "default"))
+	assertHistoryAction(t, repo, ActionKindDeadLetterPurged, "task-a")
+	letters, err := orch.ListDeadLetters("")
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+func TestExecutionOrchestrator_PurgeDeadLetters(t *testing.T) {
+	orch, repo, _ := setupControlTasks(t, TaskStatusReady, nil)
+	for _, j := range []*ipc.Job{
+		{ID: "job-a", TaskID: "task-a", PoolID: "default"},
+		{ID: "job-b", TaskID: "task-b", PoolID: "default"},
+	} {
+		require.NoError(t, orch.Queue.Enqueue(j))
+		job, err := orch.Queue.Dequeue("default")
+		require.NoError(t, err)
+		require.NoError(t, orch.Queue.DeadLetter(job.ID, job.PoolID, "broken"))
+	}
+
+	purged, err := orch.PurgeDeadLetters("")
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	// 一括削除でもジョブごとに履歴が残る
+	assertHistoryAction(t, repo, ActionKindDeadLetterPurged, "task-a")
+	assertHistoryAction(t, repo, ActionKindDeadLetterPurged, "task-b")
+	letters, err := orch.ListDeadLetters("")
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
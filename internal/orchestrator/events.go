@@ -33,9 +33,12 @@ func (w *WailsEventEmitter) Emit(eventName string, data any) {
 const (
 	EventTaskStateChange        = "task:stateChange"
 	EventExecutionStateChange   = "execution:stateChange"
+	EventExecutionRecovery      = "execution:recovery"
+	EventJobDeadLettered        = "job:deadLettered"
 	EventTaskCreated            = "task:created"
 	EventChatProgress           = "chat:progress"
 	EventBacklogAdded           = "backlog:added"
+	EventDependencyValidation   = "dependency:validation"
 	EventTaskLog                = "task:log"
 	EventProcessMetaUpdate      = "process:metaUpdate"
 	EventProcessWorkerUpdate    = "process:workerUpdate"
@@ -50,6 +53,32 @@ type TaskStateChangeEvent struct {
 	Timestamp time.Time  `json:"timestamp"`
 }
 
+// DependencyValidationEvent reports problems found in the dependency graph
+// of a plan change (decompose / plan_patch).
+type DependencyValidationEvent struct {
+	SessionID string            `json:"sessionId"`
+	Source    string            `json:"source"`   // decompose / plan_patch
+	Rejected  bool              `json:"rejected"` // 循環・自己依存により計画変更を拒否したか
+	Report    *DependencyReport `json:"report"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// ExecutionRecoveryEvent reports the orphaned jobs and RUNNING tasks the
+// recovery pass handled when the orchestrator started.
+type ExecutionRecoveryEvent struct {
+	Recovered []RecoveredTask `json:"recovered"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// JobDeadLetteredEvent reports a job moved to the dead-letter directory.
+type JobDeadLetteredEvent struct {
+	JobID     string    `json:"jobId"`
+	TaskID    string    `json:"taskId"`
+	PoolID    string    `json:"poolId"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // TaskCreatedEvent represents a task creation event
 type TaskCreatedEvent struct {
 	Task Task `json:"task"`
@@ -65,8 +94,9 @@ type ExecutionStateChangeEvent struct {
 // ChatProgressEvent represents a progress update during chat processing
 type ChatProgressEvent struct {
 	SessionID string    `json:"sessionId"`
-	Step      string    `json:"step"`    // e.g. "Decomposing", "Persisting"
-	Message   string    `json:"message"` // Human readable message
+	Step      string    `json:"step"`            // e.g. "Decomposing", "Persisting"
+	Message   string    `json:"message"`         // Human readable message
+	Delta     string    `json:"delta,omitempty"` // Partial Meta response received since the last "Streaming" event
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -2,9 +2,12 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/logging"
@@ -29,20 +32,38 @@ type ExecutionOrchestrator struct {
 	Queue        *ipc.FilesystemQueue
 	EventEmitter EventEmitter
 	BacklogStore *BacklogStore
-	RetryPolicy  *RetryPolicy
-	PoolIDs      []string
+	UsageLedger  *UsageLedger // nil の場合は使用量を記録しない
+	RetryPolicy  *RetryPolicy // どの RetryPolicies にも一致しないタスクに使うポリシー
+	// RetryPolicies はタスク種別・プールごとのリトライポリシー。nil なら失敗のたびに
+	// state/retry-policies.json から読み込む
+	RetryPolicies []*RetryPolicy
+	PoolIDs       []string
+	// PoolConcurrency はプールごとの同時実行数。未設定のプールは
+	// agents.json の MaxParallel（AgentID または Kind がプール ID に一致するもの）の合計、
+	// それも無ければ DefaultPoolConcurrency を使う
+	PoolConcurrency map[string]int
+	// Containers は起動時のリカバリで中断されたタスクのコンテナを停止する。nil なら停止しない
+	Containers ContainerCleaner
+	// WorkspaceID は履歴アクション（キャンセル・リトライ・スキップ）に記録するワークスペース ID
+	WorkspaceID string
 
 	state   ExecutionState
 	stateMu sync.RWMutex
 
-	// Force Stop support
-	runningCancel context.CancelFunc
-	cancelMu      sync.Mutex
+	// 実行中ジョブ（タスク ID ごとのキャンセル関数）とプールごとの使用スロット数
+	running     map[string]context.CancelFunc
+	poolRunning map[string]int
+	// canceled は CancelTask でキャンセルされた実行中タスク（Stop によるキャンセルと区別する）
+	canceled map[string]bool
+	// claimed はこのプロセスが取り出して実行中のジョブ（ジョブ ID → タスク ID）。起動時のリカバリで除外する
+	claimed  map[string]string
+	cancelMu sync.Mutex
 
 	stopCh   chan struct{}
 	resumeCh chan struct{}
 
-	wg sync.WaitGroup
+	wg   sync.WaitGroup // runLoop
+	jobs sync.WaitGroup // 実行中ジョブのゴルーチン
 
 	logger *slog.Logger
 }
@@ -70,12 +91,20 @@ func NewExecutionOrchestrator(
 		RetryPolicy:  DefaultRetryPolicy(),
 		PoolIDs:      poolIDs,
 		state:        ExecutionStateIdle,
+		running:      make(map[string]context.CancelFunc),
+		canceled:     make(map[string]bool),
+		claimed:      make(map[string]string),
+		poolRunning:  make(map[string]int),
 		stopCh:       nil,
 		resumeCh:     make(chan struct{}),
 		logger:       logging.WithComponent(slog.Default(), "execution-orchestrator"),
 	}
 }
 
+// DefaultPoolConcurrency is the number of concurrent jobs per pool when
+// neither PoolConcurrency nor agents.json specifies it.
+const DefaultPoolConcurrency = 1
+
 // Start starts the execution loop
 func (e *ExecutionOrchestrator) Start(ctx context.Context) error {
 	e.stateMu.Lock()
@@ -93,6 +122,13 @@ func (e *ExecutionOrchestrator) Start(ctx context.Context) error {
 	e.emitStateChange(oldState, ExecutionStateRunning)
 	e.logger.Info("execution orchestrator started")
 
+	if oldState == ExecutionStateIdle {
+		// 前回のプロセスが異常終了して残したジョブと RUNNING タスクを回収する
+		if _, err := e.RecoverOrphans(ctx); err != nil {
+			e.logger.Error("failed to recover orphaned jobs", slog.Any("error", err))
+		}
+	}
+
 	// Start the loop in a goroutine
 	e.wg.Add(1)
 	go e.runLoop(ctx, stopCh)
@@ -149,12 +185,11 @@ func (e *ExecutionOrchestrator) Stop() error {
 		close(stopCh) // runLoop を確実に終了させる
 	}
 
-	// Cancel currently running task if any
+	// Cancel currently running tasks if any
 	e.cancelMu.Lock()
-	if e.runningCancel != nil {
-		e.logger.Info("canceling running task due to stop signal")
-		e.runningCancel()
-		e.runningCancel = nil
+	for taskID, cancel := range e.running {
+		e.logger.Info("canceling running task due to stop signal", slog.String("task_id", taskID))
+		cancel()
 	}
 	e.cancelMu.Unlock()
 
@@ -170,9 +205,91 @@ func (e *ExecutionOrchestrator) State() ExecutionState {
 	return e.state
 }
 
-// Wait waits for the run loop to exit
+// Wait waits for the run loop and running jobs to exit
 func (e *ExecutionOrchestrator) Wait() {
 	e.wg.Wait()
+	e.jobs.Wait()
+}
+
+// RunningTasks returns the IDs of tasks whose jobs are currently running.
+func (e *ExecutionOrchestrator) RunningTasks() []string {
+	e.cancelMu.Lock()
+	defer e.cancelMu.Unlock()
+	ids := make([]string, 0, len(e.running))
+	for id := range e.running {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// registerRunning records the cancel handle of a starting job. 同じタスクが
+// 既に実行中なら false を返す。
+func (e *ExecutionOrchestrator) registerRunning(taskID string, cancel context.CancelFunc) bool {
+	e.cancelMu.Lock()
+	defer e.cancelMu.Unlock()
+	if _, ok := e.running[taskID]; ok {
+		return false
+	}
+	e.running[taskID] = cancel
+	return true
+}
+
+func (e *ExecutionOrchestrator) unregisterRunning(taskID string) {
+	e.cancelMu.Lock()
+	defer e.cancelMu.Unlock()
+	delete(e.running, taskID)
+	delete(e.canceled, taskID)
+}
+
+// wasCanceled reports whether the running job of taskID was canceled by CancelTask.
+func (e *ExecutionOrchestrator) wasCanceled(taskID string) bool {
+	e.cancelMu.Lock()
+	defer e.cancelMu.Unlock()
+	return e.canceled[taskID]
+}
+
+// poolCapacity returns the number of jobs poolID may run concurrently.
+func (e *ExecutionOrchestrator) poolCapacity(poolID string) int {
+	if n := e.PoolConcurrency[poolID]; n > 0 {
+		return n
+	}
+	if e.Repo != nil {
+		agents, err := e.Repo.State().LoadAgents()
+		if err != nil {
+			e.logger.Warn("failed to load agents state", slog.Any("error", err))
+		} else {
+			total := 0
+			for _, a := range agents.Agents {
+				if a.AgentID == poolID || a.Kind == poolID {
+					total += a.MaxParallel
+				}
+			}
+			if total > 0 {
+				return total
+			}
+		}
+	}
+	return DefaultPoolConcurrency
+}
+
+// acquireSlot reserves a worker slot in poolID if one is free.
+func (e *ExecutionOrchestrator) acquireSlot(poolID string, capacity int) bool {
+	e.cancelMu.Lock()
+	defer e.cancelMu.Unlock()
+	if e.poolRunning[poolID] >= capacity {
+		return false
+	}
+	e.poolRunning[poolID]++
+	return true
+}
+
+func (e *ExecutionOrchestrator) releaseSlot(poolID string) {
+	e.cancelMu.Lock()
+	defer e.cancelMu.Unlock()
+	if e.poolRunning[poolID] > 0 {
+		e.poolRunning[poolID]--
+	}
 }
 
 func (e *ExecutionOrchestrator) emitStateChange(oldState, newState ExecutionState) {
@@ -209,6 +326,9 @@ func (e *ExecutionOrchestrator) runLoop(ctx context.Context, stopCh <-chan struc
 				return
 			}
 
+			// 0. Requeue jobs whose lease expired (owner crashed or stopped heartbeating)
+			e.reclaimExpiredLeases()
+
 			// 0-a. Reset Retry Tasks (RETRY_WAIT -> PENDING when backoff expired)
 			if e.Scheduler != nil {
 				if reset, err := e.Scheduler.ResetRetryTasks(); err != nil {
@@ -220,7 +340,15 @@ func (e *ExecutionOrchestrator) runLoop(ctx context.Context, stopCh <-chan struc
 				}
 			}
 
-			// 0-b. Update Blocked Tasks (BLOCKED -> PENDING when dependencies satisfied)
+			// 0-b. Trigger Scheduled Tasks (SCHEDULED -> PENDING when due, finished recurring runs -> SCHEDULED)
+			// イベントは Scheduler 側で発火済み
+			if e.Scheduler != nil {
+				if _, err := e.Scheduler.TriggerScheduledTasks(); err != nil {
+					e.logger.Error("failed to trigger scheduled tasks", slog.Any("error", err))
+				}
+			}
+
+			// 0-c. Update Blocked Tasks (BLOCKED -> PENDING when dependencies satisfied)
 			if e.Scheduler != nil {
 				if unblocked, err := e.Scheduler.UpdateBlockedTasks(); err != nil {
 					e.logger.Error("failed to update blocked tasks", slog.Any("error", err))
@@ -231,7 +359,7 @@ func (e *ExecutionOrchestrator) runLoop(ctx context.Context, stopCh <-chan struc
 				}
 			}
 
-			// 0-c. Set BLOCKED status for pending tasks with unsatisfied dependencies
+			// 0-d. Set BLOCKED status for pending tasks with unsatisfied dependencies
 			if e.Scheduler != nil {
 				if newlyBlocked, err := e.Scheduler.SetBlockedStatusForPendingWithUnsatisfiedDeps(); err != nil {
 					e.logger.Error("failed to set blocked status for pending tasks", slog.Any("error", err))
@@ -242,6 +370,14 @@ func (e *ExecutionOrchestrator) runLoop(ctx context.Context, stopCh <-chan struc
 				}
 			}
 
+			// 0-e. Propagate permanent failures (PENDING/BLOCKED <-> DEPENDENCY_FAILED)
+			// イベントは Scheduler 側で発火済み
+			if e.Scheduler != nil {
+				if _, err := e.Scheduler.PropagateDependencyFailures(); err != nil {
+					e.logger.Error("failed to propagate dependency failures", slog.Any("error", err))
+				}
+			}
+
 			// 1. Schedule Ready Tasks
 			// This moves tasks from PENDING/BLOCKED -> READY -> QUEUE
 			if e.Scheduler != nil {
@@ -250,75 +386,144 @@ func (e *ExecutionOrchestrator) runLoop(ctx context.Context, stopCh <-chan struc
 				}
 			}
 
-			// 2. Consume from Queue (Simulating Worker Pool resource availability)
-			// Process job for each configured pool
+			// 2. Consume from Queue while the pool has free worker slots
 			for _, poolID := range e.PoolIDs {
-				job, err := e.Queue.Dequeue(poolID)
-				if err != nil {
-					e.logger.Error("failed to dequeue job", slog.String("pool_id", poolID), slog.Any("error", err))
-					continue
-				}
+				e.dispatchJobs(ctx, poolID)
+			}
+		}
+	}
+}
 
-				if job != nil {
-					e.processJob(ctx, job)
-				}
+// dispatchJobs dequeues jobs of poolID up to its free worker slots and runs
+// each in its own goroutine.
+func (e *ExecutionOrchestrator) dispatchJobs(ctx context.Context, poolID string) {
+	capacity := e.poolCapacity(poolID)
+	for e.acquireSlot(poolID, capacity) {
+		job, err := e.Queue.Dequeue(poolID)
+		if err != nil || job == nil {
+			e.releaseSlot(poolID)
+			if err != nil {
+				e.logger.Error("failed to dequeue job", slog.String("pool_id", poolID), slog.Any("error", err))
 			}
+			return
 		}
+
+		e.cancelMu.Lock()
+		e.claimed[job.ID] = job.TaskID
+		e.cancelMu.Unlock()
+
+		e.jobs.Add(1)
+		go func(job *ipc.Job) {
+			defer e.jobs.Done()
+			defer e.releaseSlot(poolID)
+			defer func() {
+				e.cancelMu.Lock()
+				delete(e.claimed, job.ID)
+				e.cancelMu.Unlock()
+			}()
+			e.processJob(ctx, job)
+		}(job)
 	}
 }
 
 func (e *ExecutionOrchestrator) processJob(ctx context.Context, job *ipc.Job) {
 	e.logger.Info("processing job", slog.String("job_id", job.ID), slog.String("task_id", job.TaskID))
 
-	// Load Task
-	// We use Repo.State()
-	tasksState, err := e.Repo.State().LoadTasks()
-	if err != nil {
-		e.logger.Error("failed to load tasks state", slog.Any("error", err))
+	// Create cancellable context for this job (per-task cancel handle)
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if !e.registerRunning(job.TaskID, cancel) {
+		// 同じタスクが既に実行中（重複ジョブ）の場合は実行しない
+		e.logger.Warn("task already running, dropping duplicate job",
+			slog.String("job_id", job.ID),
+			slog.String("task_id", job.TaskID),
+		)
 		_ = e.Queue.Complete(job.ID, job.PoolID)
 		return
 	}
+	defer e.unregisterRunning(job.TaskID)
 
-	var task *persistence.TaskState
-	for i := range tasksState.Tasks {
-		if tasksState.Tasks[i].TaskID == job.TaskID {
-			task = &tasksState.Tasks[i]
-			break
-		}
+	// Pre-exec update: increment attempt count and set RUNNING.
+	// tasks.json は他のジョブや Scheduler と共有するため、Load → 変更 → Save はロック内で行う
+	mu := tasksStateLock(e.Repo)
+	mu.Lock()
+	tasksState, err := e.Repo.State().LoadTasks()
+	if err != nil {
+		mu.Unlock()
+		// ジョブを消さずにデッドレターに残し、調査・再投入できるようにする
+		e.deadLetterJob(job, fmt.Sprintf("failed to load tasks state: %v", err))
+		return
 	}
+
+	task := findTaskState(tasksState, job.TaskID)
 	if task == nil {
-		e.logger.Error("task not found in state", slog.String("task_id", job.TaskID))
+		mu.Unlock()
+		e.deadLetterJob(job, fmt.Sprintf("task not found: %s", job.TaskID))
+		return
+	}
+	if isTaskFinished(TaskStatus(task.Status)) {
+		// キューに残っていたジョブのタスクが既にキャンセル・スキップ等で終了している
+		mu.Unlock()
+		e.logger.Info("task already finished, dropping job",
+			slog.String("job_id", job.ID),
+			slog.String("task_id", job.TaskID),
+			slog.String("status", task.Status),
+		)
 		_ = e.Queue.Complete(job.ID, job.PoolID)
 		return
 	}
 
-	// Pre-exec update: increment attempt count and set RUNNING.
 	now := time.Now()
 	if task.Inputs == nil {
 		task.Inputs = make(map[string]interface{})
 	}
-	attemptCount := 0
-	switch v := task.Inputs[InputKeyAttemptCount].(type) {
-	case float64:
-		attemptCount = int(v)
-	case int:
-		attemptCount = v
+	if e.Queue.ExceedsMaxDeliveries(job) {
+		// 何度配送しても完了しないジョブ（実行中にプロセスが落ちる等）は実行せず、タスクを恒久的な失敗にする
+		reason := fmt.Sprintf("job delivered %d times (max %d)", job.Deliveries, e.Queue.MaxDeliveries)
+		oldStatus := TaskStatus(task.Status)
+		task.Status = string(TaskStatusFailed)
+		task.UpdatedAt = now
+		task.Inputs[InputKeyLastError] = "dead-lettered: " + reason
+		task.Inputs[InputKeyFailedPermanently] = true
+		if err := e.Repo.State().SaveTasks(tasksState); err != nil {
+			e.logger.Error("failed to save poison job task state", slog.String("task_id", task.TaskID), slog.Any("error", err))
+		}
+		if oldStatus != TaskStatusFailed {
+			e.emitTaskStateChange(task.TaskID, oldStatus, TaskStatusFailed)
+		}
+		mu.Unlock()
+
+		e.updateLegacyTask(task.TaskID, func(t *Task) {
+			t.Status = TaskStatusFailed
+		})
+		e.deadLetterJob(job, reason)
+		if e.Scheduler != nil {
+			if _, err := e.Scheduler.PropagateDependencyFailures(); err != nil {
+				e.logger.Warn("failed to propagate dependency failures", slog.Any("error", err))
+			}
+		}
+		return
 	}
+	attemptCount := inputAttemptCount(task.Inputs)
 	attemptCount++
 	task.Inputs[InputKeyAttemptCount] = attemptCount
+	// 再実行されるタスクは恒久的な失敗ではなくなる
+	delete(task.Inputs, InputKeyFailedPermanently)
 
 	preExecStatus := TaskStatus(task.Status)
 	task.Status = string(TaskStatusRunning)
 	task.UpdatedAt = now
-	if preExecStatus != TaskStatusRunning {
-		e.emitTaskStateChange(task.TaskID, preExecStatus, TaskStatusRunning)
-	}
 	if err := e.Repo.State().SaveTasks(tasksState); err != nil {
 		e.logger.Error("failed to persist pre-exec task update",
 			slog.String("task_id", task.TaskID),
 			slog.Any("error", err),
 		)
 	}
+	if preExecStatus != TaskStatusRunning {
+		e.emitTaskStateChange(task.TaskID, preExecStatus, TaskStatusRunning)
+	}
+	mu.Unlock()
+
 	e.updateLegacyTask(task.TaskID, func(t *Task) {
 		if t.StartedAt == nil {
 			t.StartedAt = &now
@@ -327,36 +532,16 @@ func (e *ExecutionOrchestrator) processJob(ctx context.Context, job *ipc.Job) {
 		t.AttemptCount = attemptCount
 	})
 
-	// Create cancellable context for this job
-	jobCtx, cancel := context.WithCancel(ctx)
-	e.cancelMu.Lock()
-	e.runningCancel = cancel
-	e.cancelMu.Unlock()
-
-	defer func() {
-		e.cancelMu.Lock()
-		if e.runningCancel != nil {
-			// Ensure cancel is called if not already
-			cancel()
-			e.runningCancel = nil
-		}
-		e.cancelMu.Unlock()
-	}()
-
-	// Execute Task (Need to map persistence.TaskState to orchestrator.Task for Executor?)
-	// Executor takes *orchestrator.Task.
-	// We need a mapper.
-	// taskDTO := mapStateToDTO(task)
-	// attempt, execErr := e.Executor.ExecuteTask(jobCtx, taskDTO)
-
-	// Mapping:
+	// Map persistence.TaskState to orchestrator.Task for Executor
 	taskDTO := &Task{
 		ID:     task.TaskID,
 		Title:  task.Kind + ":" + task.NodeID, // Title fallback
 		Status: TaskStatus(task.Status),       // constant cast
-		// Other fields...
 	}
-	taskDTO.Runner = runnerSpecFromInputs(task.Inputs)
+	taskDTO.Runner = e.withRunnerSettings(runnerSpecFromInputs(task.Inputs))
+	taskDTO.Kind = task.Kind
+	taskDTO.AttemptCount = attemptCount
+	taskDTO.LastError, _ = task.Inputs[InputKeyLastError].(string)
 	// Try to get Title from Design?
 	if node, err := e.Repo.Design().GetNode(task.NodeID); err == nil {
 		taskDTO.Title = node.Name
@@ -371,23 +556,27 @@ func (e *ExecutionOrchestrator) processJob(ctx context.Context, job *ipc.Job) {
 	}
 
 	oldStatus := TaskStatus(task.Status)
+	stopHeartbeat, leaseLost := e.startHeartbeat(jobCtx, job, cancel)
 	attempt, execErr := e.Executor.ExecuteTask(jobCtx, taskDTO)
-
-	// Fetch latest state (reload in case changed? or just use tasksState?)
-	// Reloading is safer for concurrency.
-	tasksState, _ = e.Repo.State().LoadTasks() // Ignore error for reload?
-	// finding task again
-	for i := range tasksState.Tasks {
-		if tasksState.Tasks[i].TaskID == job.TaskID {
-			task = &tasksState.Tasks[i]
-			break
-		}
+	stopHeartbeat()
+	e.recordUsage(attempt, attemptCount)
+	if leaseLost.Load() {
+		// リースが切れたジョブは他のプロセス（または次の取り出し）が実行し直すため、結果を書き込まない
+		e.logger.Warn("job lease lost, leaving task to the next owner",
+			slog.String("job_id", job.ID),
+			slog.String("task_id", job.TaskID),
+		)
+		return
 	}
-
-	if task != nil {
-		// Update Status from Executor result?
-		// Executor returns Attempt. Status is in Attempt.
-		// If attempt succeeded -> Succeeded.
+	canceled := e.wasCanceled(job.TaskID)
+
+	// 実行中に他のジョブが tasks.json を更新している可能性があるため、ロックを取り直して再読込する
+	succeeded := false
+	mu.Lock()
+	if reloaded, err := e.Repo.State().LoadTasks(); err != nil {
+		e.logger.Error("failed to reload tasks state", slog.String("task_id", job.TaskID), slog.Any("error", err))
+	} else if t := findTaskState(reloaded, job.TaskID); t != nil {
+		tasksState, task = reloaded, t
 		if attempt != nil {
 			finishedAt := attempt.FinishedAt
 			if finishedAt == nil {
@@ -395,14 +584,6 @@ func (e *ExecutionOrchestrator) processJob(ctx context.Context, job *ipc.Job) {
 				finishedAt = &finished
 			}
 			if attempt.Status == AttemptStatusSucceeded {
-				// 依存解決前に現在の状態を保存
-				if err := e.Repo.State().SaveTasks(tasksState); err != nil {
-					e.logger.Error("failed to save tasks before dependency resolution",
-						slog.String("task_id", task.TaskID),
-						slog.Any("error", err),
-					)
-				}
-
 				// ノードの実行時ステータスを更新（依存解決に必要）
 				if err := e.markNodeImplemented(task.NodeID); err != nil {
 					// ノード更新に失敗した場合、後続タスクが永遠にブロックされる
@@ -419,8 +600,10 @@ func (e *ExecutionOrchestrator) processJob(ctx context.Context, job *ipc.Job) {
 						t.AttemptCount = attemptCount
 					})
 				} else {
+					succeeded = true
 					task.Status = string(TaskStatusSucceeded)
 					task.Outputs.Status = string(TaskStatusSucceeded) // 表記統一: "SUCCEEDED" に統一
+					delete(task.Inputs, InputKeyLastError)
 					// Artifacts を persistence.TaskState にも同期
 					if taskDTO.Artifacts != nil {
 						task.Outputs.Files = taskDTO.Artifacts.Files
@@ -434,21 +617,14 @@ func (e *ExecutionOrchestrator) processJob(ctx context.Context, job *ipc.Job) {
 							t.Artifacts = taskDTO.Artifacts
 						}
 					})
-
-					// task の変更内容を persistence に保存（dependencyResolution前）
-					// ここで保存することで、task.Status = SUCCEEDED が永続化される
-					if err := e.Repo.State().SaveTasks(tasksState); err != nil {
-						e.logger.Error("failed to save tasks before dependency resolution",
-							slog.String("task_id", task.TaskID),
-							slog.Any("error", err),
-						)
-					}
-
-					// 成功時：依存解決を即時実行して後続タスクを迅速に開始
-					e.triggerDependencyResolution()
 				}
 			} else if attempt.Status == AttemptStatusFailed {
 				task.Status = string(TaskStatusFailed)
+				// 次の試行のリトライプロンプトに使う
+				if task.Inputs == nil {
+					task.Inputs = make(map[string]interface{})
+				}
+				task.Inputs[InputKeyLastError] = truncateTail(attempt.ErrorSummary, maxPreviousErrorLen)
 				e.updateLegacyTask(task.TaskID, func(t *Task) {
 					t.Status = TaskStatusFailed
 					t.DoneAt = finishedAt
@@ -456,39 +632,47 @@ func (e *ExecutionOrchestrator) processJob(ctx context.Context, job *ipc.Job) {
 				})
 			}
 		}
+		if canceled && !succeeded {
+			// CancelTask によるキャンセルはリトライせず CANCELED で終える
+			task.Status = string(TaskStatusCanceled)
+			e.updateLegacyTask(task.TaskID, func(t *Task) {
+				t.Status = TaskStatusCanceled
+			})
+		}
 
-		if task != nil && oldStatus != TaskStatus(task.Status) {
+		if err := e.Repo.State().SaveTasks(tasksState); err != nil {
+			e.logger.Error("failed to save task result",
+				slog.String("task_id", task.TaskID),
+				slog.Any("error", err),
+			)
+		}
+		// 同一タスクのイベント順序を保つため、状態変更イベントはロック内で発行する
+		if oldStatus != TaskStatus(task.Status) {
 			e.emitTaskStateChange(task.TaskID, oldStatus, TaskStatus(task.Status))
 		}
+	}
+	mu.Unlock()
 
-		// 状態を保存
-		// 注: reload後の場合は taskポインターが古いメモリを指すため、
-		// task変更内容を反映させるため、ここで保存する
-		_ = e.Repo.State().SaveTasks(tasksState)
+	if succeeded {
+		// 成功時：依存解決を即時実行して後続タスクを迅速に開始
+		e.triggerDependencyResolution()
 	}
 
-	if execErr != nil {
+	if canceled && !succeeded {
+		e.logger.Info("task execution canceled", slog.String("task_id", task.TaskID))
+		// キャンセルされたタスクの後続を DEPENDENCY_FAILED にする
+		if e.Scheduler != nil {
+			if _, err := e.Scheduler.PropagateDependencyFailures(); err != nil {
+				e.logger.Warn("failed to propagate cancellation", slog.Any("error", err))
+			}
+		}
+	} else if execErr != nil {
 		e.logger.Error("task execution failed", slog.String("task_id", task.TaskID), slog.Any("error", execErr))
 
-		// Check if it was canceled
 		if jobCtx.Err() == context.Canceled {
-			e.logger.Info("task execution canceled by user/system", slog.String("task_id", task.TaskID))
-			// Canceled handling -> likely no retry, just mark as CANCELED or FAILED
-			// For now, let HandleFailure decide or just log
+			e.logger.Info("task execution canceled by system", slog.String("task_id", task.TaskID))
 		}
 
-		// HandleFailure relies on attempt count.
-		// Get attempt count from inputs or state?
-		attemptCount := 0
-		if val, ok := task.Inputs[InputKeyAttemptCount].(float64); ok {
-			attemptCount = int(val)
-		} else if val, ok := task.Inputs[InputKeyAttemptCount].(int); ok {
-			attemptCount = val
-		}
-		// Should increment attempt count? done earlier?
-		// Logic earlier was missing.
-		// I will rely on HandleFailure to use the count passed.
-
 		if handleErr := e.HandleFailure(task, execErr, attemptCount); handleErr != nil {
 			e.logger.Error("failed to handle task failure", slog.String("task_id", task.TaskID), slog.Any("error", handleErr))
 		}
@@ -502,6 +686,115 @@ func (e *ExecutionOrchestrator) processJob(ctx context.Context, job *ipc.Job) {
 	}
 }
 
+// startHeartbeat renews the lease of job every Queue.HeartbeatInterval while
+// it runs. リースを失った場合はジョブのコンテキストをキャンセルし、leaseLost を立てる。
+// stop はハートビートのゴルーチンの終了を待つ（Complete と競合してファイルを書き戻さないため）。
+func (e *ExecutionOrchestrator) startHeartbeat(ctx context.Context, job *ipc.Job, cancel context.CancelFunc) (stop func(), leaseLost *atomic.Bool) {
+	leaseLost = &atomic.Bool{}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(e.Queue.HeartbeatInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := e.Queue.RenewLease(job)
+				if err == nil {
+					continue
+				}
+				if errors.Is(err, ipc.ErrLeaseLost) {
+					e.logger.Error("job lease lost, canceling execution",
+						slog.String("job_id", job.ID),
+						slog.String("task_id", job.TaskID),
+						slog.Any("error", err),
+					)
+					leaseLost.Store(true)
+					cancel()
+					return
+				}
+				// 一時的な I/O エラーは次の周期で再試行する
+				e.logger.Warn("failed to renew job lease", slog.String("job_id", job.ID), slog.Any("error", err))
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}, leaseLost
+}
+
+// reclaimExpiredLeases returns jobs whose owner stopped renewing the lease
+// (the process crashed or hung) to the queue.
+func (e *ExecutionOrchestrator) reclaimExpiredLeases() {
+	requeued, err := e.Queue.RequeueExpired()
+	if err != nil {
+		e.logger.Error("failed to requeue expired jobs", slog.Any("error", err))
+	}
+	for _, job := range requeued {
+		owner := ""
+		if job.Lease != nil {
+			owner = job.Lease.Owner
+		}
+		e.logger.Warn("requeued job with expired lease",
+			slog.String("job_id", job.ID),
+			slog.String("task_id", job.TaskID),
+			slog.String("owner", owner),
+		)
+	}
+}
+
+// inputAttemptCount returns inputs.attempt_count (a float64 once loaded from JSON).
+func inputAttemptCount(inputs map[string]interface{}) int {
+	return inputInt(inputs, InputKeyAttemptCount)
+}
+
+// inputInt returns the integer stored at inputs[key] (a float64 once loaded from JSON).
+func inputInt(inputs map[string]interface{}, key string) int {
+	switch v := inputs[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// findTaskState returns the task with taskID in state, or nil.
+func findTaskState(state *persistence.TasksState, taskID string) *persistence.TaskState {
+	for i := range state.Tasks {
+		if state.Tasks[i].TaskID == taskID {
+			return &state.Tasks[i]
+		}
+	}
+	return nil
+}
+
+// recordUsage は試行のトークン使用量を台帳に追記する
+func (e *ExecutionOrchestrator) recordUsage(attempt *Attempt, attemptNum int) {
+	if e.UsageLedger == nil || attempt == nil || attempt.Usage == nil {
+		return
+	}
+	rec := UsageRecord{
+		Source:    UsageSourceTask,
+		TaskID:    attempt.TaskID,
+		AttemptID: attempt.ID,
+		Attempt:   attemptNum,
+		Usage:     *attempt.Usage,
+	}
+	if err := e.UsageLedger.Append(rec); err != nil {
+		e.logger.Warn("failed to record usage",
+			slog.String("task_id", attempt.TaskID),
+			slog.Any("error", err),
+		)
+	}
+}
+
 // emitTaskStateChange はタスク状態変更イベントを発行する
 func (e *ExecutionOrchestrator) emitTaskStateChange(taskID string, oldStatus, newStatus TaskStatus) {
 	if e.EventEmitter != nil {
@@ -516,6 +809,12 @@ func (e *ExecutionOrchestrator) emitTaskStateChange(taskID string, oldStatus, ne
 
 // markNodeImplemented updates NodesRuntime so dependency resolution can proceed.
 func (e *ExecutionOrchestrator) markNodeImplemented(nodeID string) error {
+	return e.markNodeStatus(nodeID, persistence.NodeRuntimeStatusImplemented, "auto-marked implemented on task success")
+}
+
+// markNodeStatus sets the runtime status of nodeID, creating its runtime entry
+// (with note) when missing.
+func (e *ExecutionOrchestrator) markNodeStatus(nodeID string, status persistence.NodeRuntimeStatus, note string) error {
 	if nodeID == "" || e.Repo == nil {
 		return nil
 	}
@@ -527,9 +826,15 @@ func (e *ExecutionOrchestrator) markNodeImplemented(nodeID string) error {
 
 	for i := range nodesRuntime.Nodes {
 		if nodesRuntime.Nodes[i].NodeID == nodeID {
-			nodesRuntime.Nodes[i].Status = string(persistence.NodeRuntimeStatusImplemented)
-			nodesRuntime.Nodes[i].Implementation.LastModifiedAt = now
-			nodesRuntime.Nodes[i].Implementation.LastModifiedBy = "agent-runner"
+			nodesRuntime.Nodes[i].Status = string(status)
+			if status == persistence.NodeRuntimeStatusImplemented {
+				nodesRuntime.Nodes[i].Implementation.LastModifiedAt = now
+				nodesRuntime.Nodes[i].Implementation.LastModifiedBy = "agent-runner"
+			} else {
+				nodesRuntime.Nodes[i].Notes = append(nodesRuntime.Nodes[i].Notes, persistence.NodeNote{
+					At: now, By: "execution-orchestrator", Text: note,
+				})
+			}
 			if nodesRuntime.Nodes[i].Implementation.Files == nil {
 				nodesRuntime.Nodes[i].Implementation.Files = []string{}
 			}
@@ -537,19 +842,20 @@ func (e *ExecutionOrchestrator) markNodeImplemented(nodeID string) error {
 		}
 	}
 
+	impl := persistence.NodeImplementation{Files: []string{}}
+	if status == persistence.NodeRuntimeStatusImplemented {
+		impl.LastModifiedAt = now
+		impl.LastModifiedBy = "agent-runner"
+	}
 	nodesRuntime.Nodes = append(nodesRuntime.Nodes, persistence.NodeRuntime{
-		NodeID: nodeID,
-		Status: string(persistence.NodeRuntimeStatusImplemented),
-		Implementation: persistence.NodeImplementation{
-			Files:          []string{},
-			LastModifiedAt: now,
-			LastModifiedBy: "agent-runner",
-		},
+		NodeID:         nodeID,
+		Status:         string(status),
+		Implementation: impl,
 		Verification: persistence.NodeVerification{
 			Status: "not_tested",
 		},
 		Notes: []persistence.NodeNote{
-			{At: now, By: "execution-orchestrator", Text: "auto-marked implemented on task success"},
+			{At: now, By: "execution-orchestrator", Text: note},
 		},
 	})
 
@@ -572,8 +878,20 @@ func runnerSpecFromInputs(inputs map[string]interface{}) *RunnerSpec {
 	}
 
 	workerKind, _ := inputs[InputKeyRunnerWorkerKind].(string)
+	reasoningEffort, _ := inputs[InputKeyRunnerReasoningEffort].(string)
+	var workerFallback []string
+	switch v := inputs[InputKeyRunnerWorkerFallback].(type) {
+	case []interface{}:
+		for _, k := range v {
+			if s, ok := k.(string); ok && s != "" {
+				workerFallback = append(workerFallback, s)
+			}
+		}
+	case []string:
+		workerFallback = v
+	}
 
-	if maxLoops <= 0 && workerKind == "" {
+	if maxLoops <= 0 && workerKind == "" && reasoningEffort == "" && len(workerFallback) == 0 {
 		return nil
 	}
 	if maxLoops <= 0 {
@@ -584,9 +902,39 @@ func runnerSpecFromInputs(inputs map[string]interface{}) *RunnerSpec {
 	}
 
 	return &RunnerSpec{
-		MaxLoops:   maxLoops,
-		WorkerKind: workerKind,
+		MaxLoops:        maxLoops,
+		WorkerKind:      workerKind,
+		ReasoningEffort: reasoningEffort,
+		WorkerFallback:  workerFallback,
+	}
+}
+
+// withRunnerSettings applies the workspace runner settings (state/runner.json)
+// to spec. 設定ファイルはジョブごとに読み込むため、変更に再起動は不要。
+func (e *ExecutionOrchestrator) withRunnerSettings(spec *RunnerSpec) *RunnerSpec {
+	if e.Repo == nil {
+		return spec
+	}
+	settings, err := e.Repo.State().LoadRunnerSettings()
+	if err != nil {
+		e.logger.Warn("failed to load runner settings, using defaults", slog.Any("error", err))
+		return spec
+	}
+	if settings.Reviewer == nil && len(settings.WorkerFallback) == 0 {
+		return spec
 	}
+	if spec == nil {
+		spec = &RunnerSpec{MaxLoops: DefaultRunnerMaxLoops, WorkerKind: DefaultWorkerKind}
+	}
+	// タスク単位の指定がある項目はワークスペース設定より優先する
+	if spec.Reviewer == nil && settings.Reviewer != nil {
+		reviewer := *settings.Reviewer
+		spec.Reviewer = &reviewer
+	}
+	if len(spec.WorkerFallback) == 0 && len(settings.WorkerFallback) > 0 {
+		spec.WorkerFallback = append([]string(nil), settings.WorkerFallback...)
+	}
+	return spec
 }
 
 func (e *ExecutionOrchestrator) updateLegacyTask(taskID string, update func(t *Task)) {
@@ -633,19 +981,25 @@ func (e *ExecutionOrchestrator) triggerDependencyResolution() {
 	}
 }
 
-// HandleFailure handles task failure logic
+// HandleFailure handles task failure logic. タスク種別・プールに合うリトライポリシーで
+// 失敗を分類し（transient / permanent / needs_human）、リトライ・失敗・バックログ行きを決める。
 func (e *ExecutionOrchestrator) HandleFailure(task *persistence.TaskState, execErr error, attemptNum int) error {
-	if e.RetryPolicy == nil {
+	policy, class, nextAction := e.failureAction(task, execErr, attemptNum)
+	if policy == nil {
 		e.logger.Warn("no retry policy configured, skipping failure handling")
-		return nil
+		return e.markPermanentFailure(task.TaskID, class)
 	}
-
-	nextAction := e.RetryPolicy.DetermineNextAction(attemptNum)
+	e.logger.Info("classified task failure",
+		slog.String("task_id", task.TaskID),
+		slog.String("policy", policy.Name),
+		slog.String("class", string(class)),
+		slog.String("next_action", string(nextAction)),
+	)
 
 	switch nextAction {
 	case NextActionRetry:
 		// リトライをスケジュール (DB更新)
-		backoff := e.RetryPolicy.CalculateBackoff(attemptNum)
+		backoff := policy.CalculateBackoff(attemptNum)
 		nextRetryAt := time.Now().Add(backoff)
 
 		e.logger.Info("scheduling retry (persisted)",
@@ -656,18 +1010,15 @@ func (e *ExecutionOrchestrator) HandleFailure(task *persistence.TaskState, execE
 		)
 
 		// Load state to update
+		mu := tasksStateLock(e.Repo)
+		mu.Lock()
+		defer mu.Unlock()
 		tasksState, err := e.Repo.State().LoadTasks()
 		if err != nil {
 			return fmt.Errorf("failed to load tasks for retry: %w", err)
 		}
 
-		var taskState *persistence.TaskState
-		for i := range tasksState.Tasks {
-			if tasksState.Tasks[i].TaskID == task.TaskID {
-				taskState = &tasksState.Tasks[i]
-				break
-			}
-		}
+		taskState := findTaskState(tasksState, task.TaskID)
 		if taskState == nil {
 			return fmt.Errorf("task not found for retry: %s", task.TaskID)
 		}
@@ -678,6 +1029,11 @@ func (e *ExecutionOrchestrator) HandleFailure(task *persistence.TaskState, execE
 			taskState.Inputs = make(map[string]interface{})
 		}
 		taskState.Inputs[InputKeyNextRetryAt] = nextRetryAt.Format(time.RFC3339)
+		taskState.Inputs[InputKeyLastErrorClass] = string(class)
+		escalation := policy.EscalationFor(attemptNum + 1)
+		if escalation != nil {
+			applyEscalation(taskState.Inputs, escalation)
+		}
 
 		if err := e.Repo.State().SaveTasks(tasksState); err != nil {
 			return fmt.Errorf("failed to save retry state: %w", err)
@@ -689,9 +1045,29 @@ func (e *ExecutionOrchestrator) HandleFailure(task *persistence.TaskState, execE
 			nr := nextRetryAt
 			t.NextRetryAt = &nr
 		})
+		if escalation != nil {
+			e.logger.Info("escalating task retry",
+				slog.String("task_id", task.TaskID),
+				slog.Int("next_attempt", attemptNum+1),
+				slog.String("worker_kind", escalation.WorkerKind),
+				slog.String("reasoning_effort", escalation.ReasoningEffort),
+				slog.Int("max_loops", escalation.MaxLoops),
+			)
+			e.recordTaskAction(ActionKindTaskEscalated, task.TaskID, map[string]interface{}{
+				"policy":           policy.Name,
+				"next_attempt":     attemptNum + 1,
+				"worker_kind":      escalation.WorkerKind,
+				"reasoning_effort": escalation.ReasoningEffort,
+				"max_loops":        escalation.MaxLoops,
+			})
+		}
 		return nil
 
 	case NextActionBacklog:
+		// 自動リトライされないため、後続タスクへ失敗を伝播する
+		if err := e.markPermanentFailure(task.TaskID, class); err != nil {
+			return err
+		}
 		// バックログに追加
 		if e.BacklogStore == nil {
 			e.logger.Warn("no backlog store configured, cannot add to backlog")
@@ -705,6 +1081,7 @@ func (e *ExecutionOrchestrator) HandleFailure(task *persistence.TaskState, execE
 		// Need better title fallback. "Task {Kind}:{NodeID}"?
 		title := fmt.Sprintf("%s: %s", task.Kind, task.NodeID)
 		item := CreateFailureItem(task.TaskID, title, execErr, attemptNum)
+		item.Metadata["errorClass"] = string(class)
 		if err := e.BacklogStore.Add(item); err != nil {
 			return fmt.Errorf("failed to add to backlog: %w", err)
 		}
@@ -716,10 +1093,106 @@ func (e *ExecutionOrchestrator) HandleFailure(task *persistence.TaskState, execE
 
 	case NextActionFail:
 		// 失敗としてマーク（既に Executor で実施済み）
-		e.logger.Warn("task permanently failed", slog.String("task_id", task.TaskID))
-		return nil
+		e.logger.Warn("task permanently failed", slog.String("task_id", task.TaskID), slog.String("class", string(class)))
+		return e.markPermanentFailure(task.TaskID, class)
 
 	default:
 		return nil
 	}
 }
+
+// failureAction selects the retry policy for task, classifies execErr and
+// decides the next action. ポリシーが無い場合は policy が nil になる。
+func (e *ExecutionOrchestrator) failureAction(task *persistence.TaskState, execErr error, attemptNum int) (*RetryPolicy, ErrorClass, NextAction) {
+	policy := e.retryPolicyFor(task)
+	if policy == nil {
+		return nil, "", NextActionFail
+	}
+	class := policy.Classify(failureFromError(execErr))
+	return policy, class, policy.NextActionFor(class, attemptNum)
+}
+
+// retryPolicyFor returns the most specific retry policy for the kind and
+// pool of task, falling back to RetryPolicy.
+func (e *ExecutionOrchestrator) retryPolicyFor(task *persistence.TaskState) *RetryPolicy {
+	policies := e.RetryPolicies
+	if policies == nil && e.Repo != nil {
+		loaded, err := LoadRetryPolicies(e.Repo)
+		if err != nil {
+			e.logger.Warn("failed to load retry policies, using default", slog.Any("error", err))
+		}
+		policies = loaded
+	}
+	if len(policies) == 0 {
+		return e.RetryPolicy
+	}
+
+	// 呼び出し元の task は ID だけの場合があるため、種別とプールは保存済みの状態から取る
+	kind, inputs := task.Kind, task.Inputs
+	if kind == "" && inputs == nil && e.Repo != nil {
+		if state, err := e.Repo.State().LoadTasks(); err == nil {
+			if stored := findTaskState(state, task.TaskID); stored != nil {
+				kind, inputs = stored.Kind, stored.Inputs
+			}
+		}
+	}
+	pool, _ := inputs[InputKeyPoolID].(string)
+	if pool == "" {
+		pool = "default"
+	}
+	if policy := SelectRetryPolicy(policies, kind, pool); policy != nil {
+		return policy
+	}
+	return e.RetryPolicy
+}
+
+// applyEscalation overrides the runner settings of the next attempt.
+func applyEscalation(inputs map[string]interface{}, esc *RetryEscalation) {
+	if esc.WorkerKind != "" {
+		inputs[InputKeyRunnerWorkerKind] = esc.WorkerKind
+	}
+	if esc.ReasoningEffort != "" {
+		inputs[InputKeyRunnerReasoningEffort] = esc.ReasoningEffort
+	}
+	if esc.MaxLoops > 0 {
+		inputs[InputKeyRunnerMaxLoops] = esc.MaxLoops
+	}
+}
+
+// markPermanentFailure flags a FAILED task as permanently failed and
+// propagates the failure to its dependents (DEPENDENCY_FAILED).
+// class は失敗の分類（空なら記録しない）。
+func (e *ExecutionOrchestrator) markPermanentFailure(taskID string, class ErrorClass) error {
+	mu := tasksStateLock(e.Repo)
+	mu.Lock()
+	tasksState, err := e.Repo.State().LoadTasks()
+	if err != nil {
+		mu.Unlock()
+		return fmt.Errorf("failed to load tasks for permanent failure: %w", err)
+	}
+	taskState := findTaskState(tasksState, taskID)
+	if taskState == nil || TaskStatus(taskState.Status) != TaskStatusFailed {
+		mu.Unlock()
+		return nil
+	}
+	if taskState.Inputs == nil {
+		taskState.Inputs = make(map[string]interface{})
+	}
+	taskState.Inputs[InputKeyFailedPermanently] = true
+	if class != "" {
+		taskState.Inputs[InputKeyLastErrorClass] = string(class)
+	}
+	if err := e.Repo.State().SaveTasks(tasksState); err != nil {
+		mu.Unlock()
+		return fmt.Errorf("failed to save permanent failure: %w", err)
+	}
+	mu.Unlock()
+
+	if e.Scheduler == nil {
+		return nil
+	}
+	if _, err := e.Scheduler.PropagateDependencyFailures(); err != nil {
+		return fmt.Errorf("failed to propagate dependency failure: %w", err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package orchestrator
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -388,3 +389,155 @@ func TestExecutionOrchestrator_processJob_MarksNodeImplementedOnSuccess(t *testi
 
 	mockExecutor.AssertExpectations(t)
 }
+
+// blockingExecutor はテストから解放されるまで ExecuteTask をブロックし、同時実行数を記録する
+type blockingExecutor struct {
+	mu        sync.Mutex
+	active    int
+	maxActive int
+	started   chan string
+	release   chan struct{}
+	canceled  []string
+}
+
+func newBlockingExecutor() *blockingExecutor {
+	return &blockingExecutor{started: make(chan string, 10), release: make(chan struct{})}
+}
+
+func (b *blockingExecutor) ExecuteTask(ctx context.Context, task *Task) (*Attempt, error) {
+	b.mu.Lock()
+	b.active++
+	if b.active > b.maxActive {
+		b.maxActive = b.active
+	}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.active--
+		b.mu.Unlock()
+	}()
+
+	b.started <- task.ID
+	select {
+	case <-b.release:
+		finished := time.Now()
+		return &Attempt{TaskID: task.ID, Status: AttemptStatusSucceeded, FinishedAt: &finished}, nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.canceled = append(b.canceled, task.ID)
+		b.mu.Unlock()
+		return &Attempt{TaskID: task.ID, Status: AttemptStatusFailed, ErrorSummary: "canceled"}, ctx.Err()
+	}
+}
+
+func setupParallelTasks(t *testing.T, repo persistence.WorkspaceRepository, queue *ipc.FilesystemQueue, ids ...string) {
+	t.Helper()
+	var tasks []persistence.TaskState
+	for _, id := range ids {
+		tasks = append(tasks, persistence.TaskState{TaskID: id, NodeID: "node-" + id, Kind: "test", Status: string(TaskStatusReady), CreatedAt: time.Now()})
+		saveDesign(t, repo, []persistence.NodeDesign{{NodeID: "node-" + id}})
+		if err := queue.Enqueue(&ipc.Job{ID: "job-" + id, TaskID: id, PoolID: "default"}); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond) // 投入順を安定させる
+	}
+	saveState(t, repo, tasks, nil)
+}
+
+func waitStarted(t *testing.T, exec *blockingExecutor, n int) []string {
+	t.Helper()
+	var ids []string
+	for len(ids) < n {
+		select {
+		case id := <-exec.started:
+			ids = append(ids, id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d jobs started", len(ids), n)
+		}
+	}
+	return ids
+}
+
+func TestExecutionOrchestrator_DispatchJobs_HonorsPoolCapacity(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	setupParallelTasks(t, repo, queue, "task-1", "task-2", "task-3")
+
+	exec := newBlockingExecutor()
+	orch := NewExecutionOrchestrator(nil, exec, repo, queue, nil, nil, []string{"default"})
+	orch.PoolConcurrency = map[string]int{"default": 2}
+
+	ctx := context.Background()
+	orch.dispatchJobs(ctx, "default")
+	waitStarted(t, exec, 2)
+	assert.ElementsMatch(t, []string{"task-1", "task-2"}, orch.RunningTasks())
+
+	// スロットが埋まっている間は追加のジョブを取り出さない
+	orch.dispatchJobs(ctx, "default")
+	jobs, err := queue.ListJobs("default")
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+
+	close(exec.release)
+	orch.jobs.Wait()
+	orch.dispatchJobs(ctx, "default")
+	waitStarted(t, exec, 1)
+	orch.jobs.Wait()
+
+	assert.Equal(t, 2, exec.maxActive)
+	tasksState, err := repo.State().LoadTasks()
+	assert.NoError(t, err)
+	for _, ts := range tasksState.Tasks {
+		assert.Equal(t, string(TaskStatusSucceeded), ts.Status, ts.TaskID)
+		assert.EqualValues(t, 1, ts.Inputs[InputKeyAttemptCount], ts.TaskID)
+	}
+	assert.Empty(t, orch.RunningTasks())
+}
+
+func TestExecutionOrchestrator_CancelTask_OnlyCancelsThatTask(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	setupParallelTasks(t, repo, queue, "task-1", "task-2")
+
+	exec := newBlockingExecutor()
+	orch := NewExecutionOrchestrator(nil, exec, repo, queue, nil, nil, []string{"default"})
+	orch.PoolConcurrency = map[string]int{"default": 2}
+
+	orch.dispatchJobs(context.Background(), "default")
+	waitStarted(t, exec, 2)
+
+	assert.NoError(t, orch.CancelTask("task-1"))
+	assert.Error(t, orch.CancelTask("task-unknown"))
+	assert.Eventually(t, func() bool {
+		return len(orch.RunningTasks()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"task-2"}, orch.RunningTasks())
+
+	close(exec.release)
+	orch.jobs.Wait()
+
+	assert.Equal(t, []string{"task-1"}, exec.canceled)
+	tasksState, err := repo.State().LoadTasks()
+	assert.NoError(t, err)
+	statuses := map[string]string{}
+	for _, ts := range tasksState.Tasks {
+		statuses[ts.TaskID] = ts.Status
+	}
+	assert.Equal(t, string(TaskStatusSucceeded), statuses["task-2"])
+	// CancelTask によるキャンセルはリトライされない
+	assert.Equal(t, string(TaskStatusCanceled), statuses["task-1"])
+}
+
+func TestExecutionOrchestrator_PoolCapacity(t *testing.T) {
+	repo, _ := setupTestRepo(t)
+	assert.NoError(t, repo.State().SaveAgents(&persistence.AgentsState{Agents: []persistence.AgentState{
+		{AgentID: "codex-1", Kind: "codegen", MaxParallel: 2},
+		{AgentID: "codex-2", Kind: "codegen", MaxParallel: 1},
+		{AgentID: "test", Kind: "test", MaxParallel: 4},
+	}}))
+
+	orch := NewExecutionOrchestrator(nil, nil, repo, nil, nil, nil, []string{"default", "codegen", "test"})
+	orch.PoolConcurrency = map[string]int{"test": 1}
+
+	assert.Equal(t, DefaultPoolConcurrency, orch.poolCapacity("default"))
+	assert.Equal(t, 3, orch.poolCapacity("codegen"))
+	assert.Equal(t, 1, orch.poolCapacity("test")) // 明示設定が優先
+}
@@ -133,3 +133,70 @@ func TestRetryPersistence_Integration(t *testing.T) {
 		assert.True(t, ok, "next_retry_at should stay")
 	})
 }
+
+func TestHandleFailure_WorkspaceRetryPolicies(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	saveDesign(t, repo, []persistence.NodeDesign{{NodeID: "node-1"}, {NodeID: "node-2"}})
+	saveState(t, repo, []persistence.TaskState{
+		{TaskID: "task-test", NodeID: "node-1", Kind: "test", Status: string(TaskStatusFailed), CreatedAt: time.Now(),
+			Inputs: map[string]interface{}{InputKeyPoolID: "default"}},
+		{TaskID: "task-impl", NodeID: "node-2", Kind: "implementation", Status: string(TaskStatusFailed), CreatedAt: time.Now()},
+	}, nil)
+	require.NoError(t, repo.State().SaveRetryPolicies(&persistence.RetryPoliciesConfig{
+		Policies: []persistence.RetryPolicyConfig{{
+			Name:        "tests",
+			TaskKinds:   []string{"test"},
+			MaxAttempts: 4,
+			Classifiers: []persistence.ErrorClassifierConfig{{Class: "permanent", Patterns: []string{"fixture missing"}}},
+			Escalations: []persistence.RetryEscalationConfig{{FromAttempt: 2, ReasoningEffort: "high", WorkerKind: "claude-code"}},
+		}},
+	}))
+	orch := NewExecutionOrchestrator(NewScheduler(repo, queue, nil), nil, repo, queue, nil, nil, []string{"default"})
+
+	// 一時的な失敗: リトライ待ちにし、次の試行をエスカレーションする
+	require.NoError(t, orch.HandleFailure(&persistence.TaskState{TaskID: "task-test"}, fmt.Errorf("LLM request timed out"), 1))
+	task := loadStatuses(t, repo)["task-test"]
+	assert.Equal(t, string(TaskStatusRetryWait), task.Status)
+	assert.Equal(t, string(ErrorClassTransient), task.Inputs[InputKeyLastErrorClass])
+	assert.Equal(t, "high", task.Inputs[InputKeyRunnerReasoningEffort])
+	assert.Equal(t, "claude-code", task.Inputs[InputKeyRunnerWorkerKind])
+	assert.Equal(t, &RunnerSpec{MaxLoops: DefaultRunnerMaxLoops, WorkerKind: "claude-code", ReasoningEffort: "high"}, runnerSpecFromInputs(task.Inputs))
+
+	actions, err := repo.History().ListActions(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, ActionKindTaskEscalated, actions[0].Kind)
+	assert.Equal(t, "tests", actions[0].Payload["policy"])
+
+	// ポリシーの分類ルールで恒久的な失敗: リトライせずに失敗を確定する
+	state, err := repo.State().LoadTasks()
+	require.NoError(t, err)
+	state.Tasks[0].Status = string(TaskStatusFailed)
+	require.NoError(t, repo.State().SaveTasks(state))
+	require.NoError(t, orch.HandleFailure(&persistence.TaskState{TaskID: "task-test"}, fmt.Errorf("fixture missing: users.sql"), 2))
+	task = loadStatuses(t, repo)["task-test"]
+	assert.Equal(t, string(TaskStatusFailed), task.Status)
+	assert.Equal(t, true, task.Inputs[InputKeyFailedPermanently])
+	assert.Equal(t, string(ErrorClassPermanent), task.Inputs[InputKeyLastErrorClass])
+
+	// どのポリシーにも一致しないタスクは RetryPolicy（既定）を使う
+	require.NoError(t, orch.HandleFailure(&persistence.TaskState{TaskID: "task-impl"}, fmt.Errorf("fixture missing"), 1))
+	task = loadStatuses(t, repo)["task-impl"]
+	assert.Equal(t, string(TaskStatusRetryWait), task.Status)
+	assert.Nil(t, task.Inputs[InputKeyRunnerWorkerKind])
+}
+
+func TestHandleFailure_InvalidRetryPoliciesFallBackToDefault(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	saveDesign(t, repo, []persistence.NodeDesign{{NodeID: "node-1"}})
+	saveState(t, repo, []persistence.TaskState{
+		{TaskID: "task-1", NodeID: "node-1", Status: string(TaskStatusFailed), CreatedAt: time.Now()},
+	}, nil)
+	require.NoError(t, repo.State().SaveRetryPolicies(&persistence.RetryPoliciesConfig{
+		Policies: []persistence.RetryPolicyConfig{{Name: "broken", BackoffBase: "soon"}},
+	}))
+	orch := NewExecutionOrchestrator(nil, nil, repo, queue, nil, nil, nil)
+
+	require.NoError(t, orch.HandleFailure(&persistence.TaskState{TaskID: "task-1"}, fmt.Errorf("flaky"), 1))
+	assert.Equal(t, string(TaskStatusRetryWait), loadStatuses(t, repo)["task-1"].Status)
+}
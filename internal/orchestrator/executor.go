@@ -5,15 +5,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"os/exec"
-	"strings"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/logging"
+	"github.com/biwakonbu/agent-runner/internal/prompt"
+	"github.com/biwakonbu/agent-runner/pkg/config"
 	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
 )
 
 // TaskExecutor defines the interface for executing tasks
@@ -21,20 +27,45 @@ type TaskExecutor interface {
 	ExecuteTask(ctx context.Context, task *Task) (*Attempt, error)
 }
 
+// ExecutionError is returned by Executor.ExecuteTask when agent-runner fails.
+// リトライポリシーのエラー分類に使う終了コード・結果ファイルの状態・出力を保持する。
+type ExecutionError struct {
+	Err         error
+	ExitCode    int    // agent-runner の終了コード（起動できなかった場合は -1）
+	ResultState string // .agent-runner/task-<id>.checkpoint.yaml の state（今回の実行で書かれていなければ空）
+	Output      string
+}
+
+func (e *ExecutionError) Error() string { return e.Err.Error() }
+
+func (e *ExecutionError) Unwrap() error { return e.Err }
+
+// agentRunnerStopGrace はキャンセル後に agent-runner がコンテナを停止して終了するまでの猶予時間
+const agentRunnerStopGrace = 45 * time.Second
+
 // Executor wraps AgentRunner Core execution.
 type Executor struct {
-	AgentRunnerPath string // Path to agent-runner binary
-	ProjectRoot     string // Root directory of the project
+	AgentRunnerPath string           // Path to agent-runner binary
+	ProjectRoot     string           // Root directory of the project
+	Prompts         *prompt.Renderer // Prompt templates (nil uses built-in defaults)
 	logger          *slog.Logger
 	events          EventEmitter // Event emitter for streaming logs
 }
 
 // NewExecutor creates a new Executor.
 func NewExecutor(agentRunnerPath string, projectRoot string) *Executor {
+	logger := logging.WithComponent(slog.Default(), "orchestrator-executor")
+	prompts, err := prompt.Load(projectRoot)
+	if err != nil {
+		// 上書きテンプレートが壊れていても既定テンプレートで実行を継続する
+		logger.Warn("failed to load prompt templates, using defaults", slog.Any("error", err))
+		prompts = prompt.Default()
+	}
 	return &Executor{
 		AgentRunnerPath: agentRunnerPath,
 		ProjectRoot:     projectRoot,
-		logger:          logging.WithComponent(slog.Default(), "orchestrator-executor"),
+		Prompts:         prompts,
+		logger:          logger,
 		events:          nil, // Set via SetEventEmitter if needed
 	}
 }
@@ -75,7 +106,11 @@ func (e *Executor) ExecuteTask(ctx context.Context, task *Task) (*Attempt, error
 	logger.Info("task status updated to RUNNING")
 
 	// Generate task YAML for agent-runner
-	taskYAML := e.generateTaskYAML(task)
+	taskYAML, err := e.generateTaskYAML(task)
+	if err != nil {
+		logger.Error("failed to generate task YAML", slog.Any("error", err))
+		return e.handleExecutionError(attempt, task, err)
+	}
 	logger.Debug("generated task YAML", slog.Int("yaml_length", len(taskYAML)))
 
 	// Execute agent-runner
@@ -93,6 +128,9 @@ func (e *Executor) ExecuteTask(ctx context.Context, task *Task) (*Attempt, error
 	}
 	cmd := exec.CommandContext(ctx, e.AgentRunnerPath)
 	cmd.Dir = e.ProjectRoot
+	// キャンセル時は SIGTERM で agent-runner にコンテナを停止させ、応答が無ければ猶予後に強制終了する
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = agentRunnerStopGrace
 
 	// Pass task YAML via stdin
 	stdin, err := cmd.StdinPipe()
@@ -172,19 +210,39 @@ func (e *Executor) ExecuteTask(ctx context.Context, task *Task) (*Attempt, error
 	err = cmd.Start()
 	if err != nil {
 		logger.Error("failed to start agent-runner", slog.Any("error", err))
-		return e.handleExecutionError(attempt, task, err)
+		return e.handleExecutionError(attempt, task, &ExecutionError{Err: err, ExitCode: -1})
 	}
 
 	err = cmd.Wait()
 	finishedAt := time.Now()
 	attempt.FinishedAt = &finishedAt
 	output := outputBuf.String()
+	attempt.Usage = parseUsageEvent(output)
+
+	// 終了コード 0 でも結果ファイルが COMPLETE 以外なら失敗として扱う（旧バージョンの agent-runner は FAILED でも 0 で終了する）
+	resultState := e.readResultState(task.ID, attempt.StartedAt)
+	exitCode := -1
+	if err == nil && resultState != "" && resultState != resultStateComplete {
+		err = fmt.Errorf("agent-runner exited successfully but task finished in state %s", resultState)
+		exitCode = 0
+	}
 
 	if err != nil {
 		attempt.Status = AttemptStatusFailed
 		attempt.ErrorSummary = fmt.Sprintf("Execution failed: %s\nOutput: %s", err.Error(), string(output))
 		task.Status = TaskStatusFailed
 		task.DoneAt = &finishedAt
+		execErr := &ExecutionError{
+			Err:         err,
+			ExitCode:    exitCode,
+			ResultState: resultState,
+			Output:      output,
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			execErr.ExitCode = exitErr.ExitCode()
+		}
+		err = execErr
 		logger.Error("agent-runner execution failed",
 			slog.Any("error", err),
 			slog.Int("output_length", len(output)),
@@ -254,99 +312,49 @@ func (e *Executor) handleExecutionError(attempt *Attempt, task *Task, err error)
 	return attempt, err
 }
 
-func (e *Executor) generateTaskYAML(task *Task) string {
-	// Construct the prompt text with Description, AcceptanceCriteria, and SuggestedImpl
-	promptText := fmt.Sprintf("Execute task: %s", task.Title)
-	if task.Description != "" {
-		promptText += fmt.Sprintf("\n\nDescription:\n%s", task.Description)
+// resultStateComplete は agent-runner の結果ファイルで成功を表す state
+const resultStateComplete = "COMPLETE"
+
+// readResultState returns the task state agent-runner recorded in its
+// checkpoint file (.agent-runner/task-<id>.checkpoint.yaml) since since.
+// 読めない場合や以前の実行のファイルの場合は空文字を返す。
+func (e *Executor) readResultState(taskID string, since time.Time) string {
+	data, err := os.ReadFile(filepath.Join(e.ProjectRoot, ".agent-runner", fmt.Sprintf("task-%s.checkpoint.yaml", taskID)))
+	if err != nil {
+		return ""
 	}
-	if len(task.AcceptanceCriteria) > 0 {
-		promptText += "\n\nAcceptance Criteria:"
-		for _, ac := range task.AcceptanceCriteria {
-			promptText += fmt.Sprintf("\n- %s", ac)
-		}
+	var checkpoint struct {
+		State     string    `yaml:"state"`
+		UpdatedAt time.Time `yaml:"updated_at"`
 	}
-	if task.SuggestedImpl != nil {
-		promptText += "\n\nSuggested Implementation:"
-		if task.SuggestedImpl.Language != "" {
-			promptText += fmt.Sprintf("\nLanguage: %s", task.SuggestedImpl.Language)
-		}
-		if len(task.SuggestedImpl.FilePaths) > 0 {
-			promptText += "\nTarget Files:"
-			for _, f := range task.SuggestedImpl.FilePaths {
-				promptText += fmt.Sprintf("\n- %s", f)
-			}
-		}
-		if len(task.SuggestedImpl.Constraints) > 0 {
-			promptText += "\nConstraints:"
-			for _, c := range task.SuggestedImpl.Constraints {
-				promptText += fmt.Sprintf("\n- %s", c)
-			}
-		}
+	// 秒精度で書かれたファイルも受け付ける
+	if err := yaml.Unmarshal(data, &checkpoint); err != nil || checkpoint.UpdatedAt.Before(since.Truncate(time.Second)) {
+		return ""
 	}
+	return checkpoint.State
+}
 
-	// Simple task YAML for agent-runner
-	// Using literal style Block Scalar (|) for prd.text to handle multi-line strings safely.
-	// We also populate V2 fields in the YAML.
-
-	promptTextIndented := ""
-	for _, line := range strings.Split(promptText, "\n") {
-		promptTextIndented += fmt.Sprintf("      %s\n", line)
+// generateTaskYAML はテンプレートでプロンプトを生成し、TaskConfig を YAML に変換する
+func (e *Executor) generateTaskYAML(task *Task) (string, error) {
+	details := config.TaskDetails{
+		Title:        task.Title,
+		Description:  task.Description,
+		Dependencies: task.Dependencies,
+		WBSLevel:     task.WBSLevel,
+		PhaseName:    task.PhaseName,
 	}
-
-	// Marshaling SuggestedImpl to YAML manually or via helper would be cleaner,
-	// but sticking to string formatting for dependency simplicity as per current pattern.
-	suggestedImplYAML := ""
 	if task.SuggestedImpl != nil {
-		suggestedImplYAML = fmt.Sprintf(`  suggested_impl:
-    language: %q
-    file_paths: [%s]
-    constraints: [%s]`,
-			task.SuggestedImpl.Language,
-			quoteList(task.SuggestedImpl.FilePaths),
-			quoteList(task.SuggestedImpl.Constraints),
-		)
-	}
-
-	// Dependencies
-	dependenciesYAML := fmt.Sprintf("dependencies: [%s]", quoteList(task.Dependencies))
-
-	runnerMaxLoops := DefaultRunnerMaxLoops
-	workerKind := DefaultWorkerKind
-	if task.Runner != nil {
-		if task.Runner.MaxLoops > 0 {
-			runnerMaxLoops = task.Runner.MaxLoops
-		}
-		if task.Runner.WorkerKind != "" {
-			workerKind = task.Runner.WorkerKind
+		details.SuggestedImpl = &config.SuggestedImpl{
+			Language:    task.SuggestedImpl.Language,
+			FilePaths:   task.SuggestedImpl.FilePaths,
+			Constraints: task.SuggestedImpl.Constraints,
 		}
 	}
-
-	return fmt.Sprintf(`version: "1"
-task:
-  id: %s
-  title: %q
-  repo: "."
-  description: %q
-  wbs_level: %d
-  phase_name: %q
-  %s
-%s
-  prd:
-    text: |
-%srunner:
-  max_loops: %d
-  worker:
-    kind: %q
-`, task.ID, task.Title, task.Description, task.WBSLevel, task.PhaseName, dependenciesYAML, suggestedImplYAML, promptTextIndented, runnerMaxLoops, workerKind)
-}
-
-func quoteList(items []string) string {
-	quoted := make([]string, len(items))
-	for i, item := range items {
-		quoted[i] = fmt.Sprintf("%q", item)
+	cfg, err := buildTaskConfig(e.Prompts, taskPromptData(task), details, task.Runner)
+	if err != nil {
+		return "", err
 	}
-	return strings.Join(quoted, ", ")
+	return marshalTaskConfig(cfg)
 }
 
 func (e *Executor) handleStructuredLog(taskID, taskTitle string, entry map[string]interface{}) {
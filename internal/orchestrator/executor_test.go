@@ -2,12 +2,17 @@ package orchestrator
 
 import (
 	"context"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/biwakonbu/agent-runner/pkg/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 // TestExecutor_ExecuteTask_Cancellation verifies that canceling the context kills the process.
@@ -72,7 +77,82 @@ exec sleep 10
 	// In real usage, the Orchestrator calling this would handle saving Failed status.
 }
 
+func TestExecutor_ExecuteTask_ExecutionError(t *testing.T) {
+	tmpDir := t.TempDir()
+	mockRunnerPath := filepath.Join(tmpDir, "mock_runner.sh")
+	// 結果ファイル（チェックポイント）を書き、エラーを出力して終了コード 3 で終わる
+	scriptContent := `#!/bin/sh
+mkdir -p .agent-runner
+printf 'task_id: task-exit\nstate: FAILED\nupdated_at: %s\n' "$(date -u +%Y-%m-%dT%H:%M:%SZ)" > .agent-runner/task-task-exit.checkpoint.yaml
+echo "Error: No such image: multiverse/worker:latest"
+exit 3
+`
+	require.NoError(t, os.WriteFile(mockRunnerPath, []byte(scriptContent), 0755))
+
+	executor := NewExecutor(mockRunnerPath, tmpDir)
+	attempt, err := executor.ExecuteTask(context.Background(), &Task{ID: "task-exit", Title: "Exit Task"})
+	require.Error(t, err)
+	assert.Equal(t, AttemptStatusFailed, attempt.Status)
+
+	var execErr *ExecutionError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, 3, execErr.ExitCode)
+	assert.Equal(t, "FAILED", execErr.ResultState)
+	assert.Contains(t, execErr.Output, "No such image")
+
+	failure := failureFromError(err)
+	require.NotNil(t, failure.ExitCode)
+	assert.Equal(t, 3, *failure.ExitCode)
+	assert.Equal(t, ErrorClassPermanent, DefaultRetryPolicy().Classify(failure))
+
+	// 以前の実行の結果ファイルは使わない
+	assert.Empty(t, executor.readResultState("task-exit", time.Now().Add(time.Hour)))
+}
+
 // TestGenerateTaskYAML verifies that V2 fields are correctly correctly populated in the YAML
+func TestExecutor_ExecuteTask_ExitZeroWithFailedState(t *testing.T) {
+	tmpDir := t.TempDir()
+	mockRunnerPath := filepath.Join(tmpDir, "mock_runner.sh")
+	// 結果ファイルは FAILED だが終了コードは 0
+	scriptContent := `#!/bin/sh
+mkdir -p .agent-runner
+printf 'task_id: task-zero\nstate: FAILED\nupdated_at: %s\n' "$(date -u +%Y-%m-%dT%H:%M:%SZ)" > .agent-runner/task-task-zero.checkpoint.yaml
+echo "Error: authentication required"
+exit 0
+`
+	require.NoError(t, os.WriteFile(mockRunnerPath, []byte(scriptContent), 0755))
+
+	executor := NewExecutor(mockRunnerPath, tmpDir)
+	attempt, err := executor.ExecuteTask(context.Background(), &Task{ID: "task-zero", Title: "Zero Task"})
+	require.Error(t, err)
+	assert.Equal(t, AttemptStatusFailed, attempt.Status)
+
+	var execErr *ExecutionError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, 0, execErr.ExitCode)
+	assert.Equal(t, "FAILED", execErr.ResultState)
+
+	// 分類ルールは結果ファイルの state で一致する
+	policy := DefaultRetryPolicy()
+	policy.Classifiers = append([]ErrorClassifier{{Class: ErrorClassNeedsHuman, ResultStates: []string{"FAILED"}}}, policy.Classifiers...)
+	assert.Equal(t, ErrorClassNeedsHuman, policy.Classify(failureFromError(err)))
+}
+
+func TestExecutor_ExecuteTask_ExitZeroWithCompleteState(t *testing.T) {
+	tmpDir := t.TempDir()
+	mockRunnerPath := filepath.Join(tmpDir, "mock_runner.sh")
+	scriptContent := `#!/bin/sh
+mkdir -p .agent-runner
+printf 'task_id: task-ok\nstate: COMPLETE\nupdated_at: %s\n' "$(date -u +%Y-%m-%dT%H:%M:%SZ)" > .agent-runner/task-task-ok.checkpoint.yaml
+exit 0
+`
+	require.NoError(t, os.WriteFile(mockRunnerPath, []byte(scriptContent), 0755))
+
+	attempt, err := NewExecutor(mockRunnerPath, tmpDir).ExecuteTask(context.Background(), &Task{ID: "task-ok", Title: "OK Task"})
+	require.NoError(t, err)
+	assert.Equal(t, AttemptStatusSucceeded, attempt.Status)
+}
+
 func TestGenerateTaskYAML(t *testing.T) {
 	// 1. Setup Executor (mocking dependencies not needed for this method)
 	executor := &Executor{}
@@ -94,28 +174,111 @@ func TestGenerateTaskYAML(t *testing.T) {
 	}
 
 	// 3. Generate YAML
-	yamlStr := executor.generateTaskYAML(task)
+	yamlStr, err := executor.generateTaskYAML(task)
+	require.NoError(t, err)
 
-	// 4. Verify Content
-	assert.Contains(t, yamlStr, `id: task-v2-test`)
-	assert.Contains(t, yamlStr, `title: "V2 Feature"`)
-	assert.Contains(t, yamlStr, `description: "Implement V2 feature with AI"`)
-	assert.Contains(t, yamlStr, `wbs_level: 2`)
-	assert.Contains(t, yamlStr, `phase_name: "Implementation"`)
-	assert.Contains(t, yamlStr, `dependencies: ["task-dep-1", "task-dep-2"]`)
+	// 4. Verify Content (struct marshaling なので TaskConfig として読み戻せる)
+	var cfg config.TaskConfig
+	require.NoError(t, yaml.Unmarshal([]byte(yamlStr), &cfg))
+	assert.Equal(t, 1, cfg.Version)
+	assert.Equal(t, "task-v2-test", cfg.Task.ID)
+	assert.Equal(t, "V2 Feature", cfg.Task.Title)
+	assert.Equal(t, "Implement V2 feature with AI", cfg.Task.Description)
+	assert.Equal(t, 2, cfg.Task.WBSLevel)
+	assert.Equal(t, "Implementation", cfg.Task.PhaseName)
+	assert.Equal(t, []string{"task-dep-1", "task-dep-2"}, cfg.Task.Dependencies)
 
 	// Check SuggestedImpl structured field
-	assert.Contains(t, yamlStr, `suggested_impl:`)
-	assert.Contains(t, yamlStr, `language: "go"`)
-	assert.Contains(t, yamlStr, `file_paths: ["main.go", "utils.go"]`)
-	assert.Contains(t, yamlStr, `constraints: ["No external libs", "Use stdlib"]`)
+	require.NotNil(t, cfg.Task.SuggestedImpl)
+	assert.Equal(t, "go", cfg.Task.SuggestedImpl.Language)
+	assert.Equal(t, []string{"main.go", "utils.go"}, cfg.Task.SuggestedImpl.FilePaths)
+	assert.Equal(t, []string{"No external libs", "Use stdlib"}, cfg.Task.SuggestedImpl.Constraints)
 
 	// Check PRD Text includes legacy-compatible descriptions
-	assert.Contains(t, yamlStr, "      Execute task: V2 Feature")
-	assert.Contains(t, yamlStr, "      Description:")
-	assert.Contains(t, yamlStr, "      Implement V2 feature with AI")
-	assert.Contains(t, yamlStr, "      Acceptance Criteria:")
-	assert.Contains(t, yamlStr, "      - AC1: works")
-	assert.Contains(t, yamlStr, "      Suggested Implementation:")
-	assert.Contains(t, yamlStr, "      Language: go")
+	prd := cfg.Task.PRD.Text
+	assert.Contains(t, prd, "Execute task: V2 Feature")
+	assert.Contains(t, prd, "Description:\nImplement V2 feature with AI")
+	assert.Contains(t, prd, "Acceptance Criteria:\n- AC1: works\n- AC2: fast")
+	assert.Contains(t, prd, "Suggested Implementation:")
+	assert.Contains(t, prd, "Language: go")
+	assert.NotContains(t, prd, "attempt")
+
+	assert.Equal(t, DefaultRunnerMaxLoops, cfg.Runner.MaxLoops)
+	assert.Equal(t, DefaultWorkerKind, cfg.Runner.Worker.Kind)
+	assert.Nil(t, cfg.Runner.Reviewer)
+}
+
+// TestGenerateTaskYAML_WorkspaceReviewer verifies state/runner.json reaches runner.reviewer
+func TestGenerateTaskYAML_WorkspaceReviewer(t *testing.T) {
+	repo, _ := setupTestRepo(t)
+	require.NoError(t, repo.State().SaveRunnerSettings(&persistence.RunnerSettingsConfig{
+		Reviewer: &persistence.ReviewerConfig{Kind: config.ReviewerKindWorker, WorkerType: "claude-code"},
+	}))
+	orch := &ExecutionOrchestrator{Repo: repo, logger: slog.Default()}
+
+	task := &Task{ID: "task-review", Title: "Reviewed", Runner: orch.withRunnerSettings(nil)}
+	yamlStr, err := (&Executor{}).generateTaskYAML(task)
+	require.NoError(t, err)
+
+	var cfg config.TaskConfig
+	require.NoError(t, yaml.Unmarshal([]byte(yamlStr), &cfg))
+	require.NotNil(t, cfg.Runner.Reviewer)
+	assert.Equal(t, config.ReviewerKindWorker, cfg.Runner.Reviewer.Kind)
+	assert.Equal(t, "claude-code", cfg.Runner.Reviewer.WorkerType)
+	assert.Equal(t, DefaultWorkerKind, cfg.Runner.Worker.Kind)
+
+	// タスク単位の指定はワークスペース設定より優先する
+	own := &RunnerSpec{Reviewer: &persistence.ReviewerConfig{Kind: "anthropic-messages"}}
+	assert.Equal(t, "anthropic-messages", orch.withRunnerSettings(own).Reviewer.Kind)
+}
+
+func TestGenerateTaskYAML_WorkerFallback(t *testing.T) {
+	repo, _ := setupTestRepo(t)
+	require.NoError(t, repo.State().SaveRunnerSettings(&persistence.RunnerSettingsConfig{
+		WorkerFallback: []string{"claude-code", "gemini-cli"},
+	}))
+	orch := &ExecutionOrchestrator{Repo: repo, logger: slog.Default()}
+
+	task := &Task{ID: "task-fallback", Title: "Fallback", Runner: orch.withRunnerSettings(nil)}
+	yamlStr, err := (&Executor{}).generateTaskYAML(task)
+	require.NoError(t, err)
+
+	var cfg config.TaskConfig
+	require.NoError(t, yaml.Unmarshal([]byte(yamlStr), &cfg))
+	assert.Equal(t, DefaultWorkerKind, cfg.Runner.Worker.Kind)
+	assert.Equal(t, []string{"claude-code", "gemini-cli"}, cfg.Runner.Worker.Fallback)
+	assert.Nil(t, cfg.Runner.Reviewer)
+
+	// タスクの inputs（JSON 由来の []interface{}）での指定はワークスペース設定より優先する
+	spec := runnerSpecFromInputs(map[string]interface{}{
+		InputKeyRunnerWorkerFallback: []interface{}{"cursor-cli"},
+	})
+	require.NotNil(t, spec)
+	assert.Equal(t, []string{"cursor-cli"}, orch.withRunnerSettings(spec).WorkerFallback)
+}
+
+// TestGenerateTaskYAML_SpecialCharactersAndRetry verifies odd characters survive and retries get context
+func TestGenerateTaskYAML_SpecialCharactersAndRetry(t *testing.T) {
+	executor := &Executor{}
+	task := &Task{
+		ID:           "task-odd",
+		Title:        `Fix "quotes": colons & {braces} #hash`,
+		Description:  "line1\n  - not a list\n---\nkey: value",
+		AttemptCount: 2,
+		LastError:    "panic: nil pointer dereference",
+		Runner:       &RunnerSpec{MaxLoops: 7, WorkerKind: "claude-code"},
+	}
+
+	yamlStr, err := executor.generateTaskYAML(task)
+	require.NoError(t, err)
+
+	var cfg config.TaskConfig
+	require.NoError(t, yaml.Unmarshal([]byte(yamlStr), &cfg))
+	assert.Equal(t, task.Title, cfg.Task.Title)
+	assert.Equal(t, task.Description, cfg.Task.Description)
+	assert.Contains(t, cfg.Task.PRD.Text, task.Description)
+	assert.Contains(t, cfg.Task.PRD.Text, "attempt 2")
+	assert.Contains(t, cfg.Task.PRD.Text, "panic: nil pointer dereference")
+	assert.Equal(t, 7, cfg.Runner.MaxLoops)
+	assert.Equal(t, "claude-code", cfg.Runner.Worker.Kind)
 }
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/biwakonbu/agent-runner/internal/prompt"
+	"github.com/biwakonbu/agent-runner/pkg/config"
 	"github.com/google/uuid"
 )
 
@@ -22,14 +24,21 @@ type executorV2Impl struct {
 	AgentRunnerPath string
 	ProjectRoot     string
 	Repo            persistence.WorkspaceRepository
+	Prompts         *prompt.Renderer
 	Logger          *slog.Logger
 }
 
 func NewExecutorV2(agentRunnerPath, projectRoot string, repo persistence.WorkspaceRepository, logger *slog.Logger) ExecutorV2 {
+	prompts, err := prompt.Load(projectRoot)
+	if err != nil {
+		logger.Warn("failed to load prompt templates, using defaults", "err", err)
+		prompts = prompt.Default()
+	}
 	return &executorV2Impl{
 		AgentRunnerPath: agentRunnerPath,
 		ProjectRoot:     projectRoot,
 		Repo:            repo,
+		Prompts:         prompts,
 		Logger:          logger,
 	}
 }
@@ -37,7 +46,10 @@ func NewExecutorV2(agentRunnerPath, projectRoot string, repo persistence.Workspa
 func (e *executorV2Impl) Execute(ctx context.Context, task persistence.TaskState) error {
 	e.Logger.Info("ExecutorV2: starting task execution", "task_id", task.TaskID)
 
-	taskYAML := e.generateTaskYAML(task)
+	taskYAML, err := e.generateTaskYAML(task)
+	if err != nil {
+		return fmt.Errorf("failed to generate task YAML: %w", err)
+	}
 
 	// Create Attempt Action
 	attemptID := uuid.New().String()
@@ -116,53 +128,12 @@ func (e *executorV2Impl) Execute(ctx context.Context, task persistence.TaskState
 	return err
 }
 
-func (e *executorV2Impl) generateTaskYAML(task persistence.TaskState) string {
-	// Extract inputs
-	goal, _ := task.Inputs["goal"].(string)
-
-	// Construct prompt
-	promptText := fmt.Sprintf("Execute task: %s\n\nGoal:\n%s", task.TaskID, goal)
-
-	if constraints, ok := task.Inputs["constraints"].([]interface{}); ok {
-		promptText += "\n\nConstraints:"
-		for _, c := range constraints {
-			promptText += fmt.Sprintf("\n- %v", c)
-		}
-	}
-
-	promptTextIndented := ""
-	for _, line := range strings.Split(promptText, "\n") {
-		promptTextIndented += fmt.Sprintf("      %s\n", line)
+func (e *executorV2Impl) generateTaskYAML(task persistence.TaskState) (string, error) {
+	data := taskStatePromptData(task)
+	details := config.TaskDetails{Title: "Task " + task.TaskID}
+	cfg, err := buildTaskConfig(e.Prompts, data, details, runnerSpecFromInputs(task.Inputs))
+	if err != nil {
+		return "", err
 	}
-
-	runnerMaxLoops := DefaultRunnerMaxLoops
-	workerKind := DefaultWorkerKind
-	if task.Inputs != nil {
-		switch v := task.Inputs[InputKeyRunnerMaxLoops].(type) {
-		case float64:
-			if int(v) > 0 {
-				runnerMaxLoops = int(v)
-			}
-		case int:
-			if v > 0 {
-				runnerMaxLoops = v
-			}
-		}
-		if kind, ok := task.Inputs[InputKeyRunnerWorkerKind].(string); ok && kind != "" {
-			workerKind = kind
-		}
-	}
-
-	return fmt.Sprintf(`version: "1"
-task:
-  id: %s
-  title: "Task %s"
-  repo: "."
-  prd:
-    text: |
-%srunner:
-  max_loops: %d
-  worker:
-    kind: %q
-`, task.TaskID, task.TaskID, promptTextIndented, runnerMaxLoops, workerKind)
+	return marshalTaskConfig(cfg)
 }
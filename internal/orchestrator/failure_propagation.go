@@ -0,0 +1,168 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+)
+
+// isPermanentlyFailed reports whether task will not complete without manual
+// intervention: it failed without further automatic retries (retry limit
+// reached or moved to the backlog), or it was canceled.
+func isPermanentlyFailed(task *persistence.TaskState) bool {
+	switch TaskStatus(task.Status) {
+	case TaskStatusCanceled:
+		return true
+	case TaskStatusFailed:
+		permanent, _ := task.Inputs[InputKeyFailedPermanently].(bool)
+		return permanent
+	}
+	return false
+}
+
+// failedAncestorPath returns the dependency path from nodeID to its nearest
+// permanently failed ancestor, or nil when no ancestor has failed.
+// 完了済みノードの先は辿らない（その依存はすでに満たされている）。
+func failedAncestorPath(graph map[string][]string, failed, completed map[string]bool, nodeID string) []string {
+	parent := map[string]string{nodeID: ""}
+	queue := []string{nodeID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range graph[current] {
+			if _, seen := parent[dep]; seen || completed[dep] {
+				continue
+			}
+			parent[dep] = current
+			if failed[dep] {
+				path := []string{dep}
+				for n := current; n != ""; n = parent[n] {
+					path = append([]string{n}, path...)
+				}
+				return path
+			}
+			queue = append(queue, dep)
+		}
+	}
+	return nil
+}
+
+// setDependencyFailure records (or clears, when path is nil) the failed
+// ancestor of a DEPENDENCY_FAILED task. 経路と理由は BLOCKED と同じキーに記録する。
+func setDependencyFailure(task *persistence.TaskState, path []string) {
+	if path == nil {
+		setBlockedReason(task, nil, "")
+		if task.Inputs != nil {
+			delete(task.Inputs, InputKeyFailedAncestor)
+		}
+		return
+	}
+	ancestor := path[len(path)-1]
+	setBlockedReason(task, path, fmt.Sprintf("dependency failed: %s (%s)", ancestor, strings.Join(path, " -> ")))
+	task.Inputs[InputKeyFailedAncestor] = ancestor
+}
+
+// PropagateDependencyFailures marks PENDING/BLOCKED tasks whose ancestor node
+// has permanently failed as DEPENDENCY_FAILED, and returns DEPENDENCY_FAILED
+// tasks to PENDING once no failed ancestor remains (the ancestor was retried,
+// succeeded, or the dependency was removed). 状態が変わったタスク ID を返す。
+func (s *Scheduler) PropagateDependencyFailures() ([]string, error) {
+	mu := tasksStateLock(s.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+
+	tasksState, err := s.Repo.State().LoadTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks state: %w", err)
+	}
+
+	failed := make(map[string]bool)
+	hasDependencyFailed := false
+	for i := range tasksState.Tasks {
+		task := &tasksState.Tasks[i]
+		if isPermanentlyFailed(task) {
+			failed[task.NodeID] = true
+		}
+		if TaskStatus(task.Status) == TaskStatusDependencyFailed {
+			hasDependencyFailed = true
+		}
+	}
+	if len(failed) == 0 && !hasDependencyFailed {
+		return nil, nil
+	}
+
+	graph, err := LoadDependencyGraph(s.Repo)
+	if err != nil {
+		return nil, err
+	}
+	completed := make(map[string]bool)
+	if nodesRuntime, err := s.Repo.State().LoadNodesRuntime(); err == nil {
+		for _, nr := range nodesRuntime.Nodes {
+			if persistence.NodeRuntimeStatus(nr.Status).IsCompleted() {
+				completed[nr.NodeID] = true
+			}
+		}
+	}
+
+	type transition struct {
+		taskID     string
+		oldStatus  TaskStatus
+		newStatus  TaskStatus
+		failedNode string
+	}
+	var transitions []transition
+	dirty := false
+	for i := range tasksState.Tasks {
+		task := &tasksState.Tasks[i]
+		status := TaskStatus(task.Status)
+		switch status {
+		case TaskStatusPending, TaskStatusBlocked:
+			path := failedAncestorPath(graph, failed, completed, task.NodeID)
+			if path == nil {
+				continue
+			}
+			task.Status = string(TaskStatusDependencyFailed)
+			setDependencyFailure(task, path)
+			transitions = append(transitions, transition{task.TaskID, status, TaskStatusDependencyFailed, path[len(path)-1]})
+		case TaskStatusDependencyFailed:
+			path := failedAncestorPath(graph, failed, completed, task.NodeID)
+			if path == nil {
+				task.Status = string(TaskStatusPending)
+				setDependencyFailure(task, nil)
+				transitions = append(transitions, transition{task.TaskID, status, TaskStatusPending, ""})
+				continue
+			}
+			// 原因ノードが変わった場合（元の祖先は復旧したが別の祖先が失敗中）は理由だけ更新する
+			if ancestor, _ := task.Inputs[InputKeyFailedAncestor].(string); ancestor != path[len(path)-1] {
+				setDependencyFailure(task, path)
+				dirty = true
+			}
+		}
+	}
+	if len(transitions) == 0 && !dirty {
+		return nil, nil
+	}
+
+	if err := s.Repo.State().SaveTasks(tasksState); err != nil {
+		return nil, fmt.Errorf("failed to save dependency failure state: %w", err)
+	}
+
+	changed := make([]string, 0, len(transitions))
+	for _, t := range transitions {
+		s.emitStateChange(t.taskID, t.oldStatus, t.newStatus)
+		changed = append(changed, t.taskID)
+		if t.newStatus == TaskStatusDependencyFailed {
+			s.logger.Warn("task dependency failed",
+				slog.String("task_id", t.taskID),
+				slog.String("failed_ancestor", t.failedNode),
+			)
+		} else {
+			s.logger.Info("task dependency failure resolved",
+				slog.String("task_id", t.taskID),
+			)
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,213 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxDeliveries is how many times a job may be dequeued before it is
+// treated as a poison job and moved to the dead-letter directory.
+const DefaultMaxDeliveries = 5
+
+// DeadLetter is a job that was taken out of circulation, stored as
+// ipc/dead/<pool-id>/<job-id>.json together with the reason.
+type DeadLetter struct {
+	JobID      string    `json:"jobId"`
+	PoolID     string    `json:"poolId"`
+	TaskID     string    `json:"taskId,omitempty"`
+	Reason     string    `json:"reason"`
+	DeadAt     time.Time `json:"deadAt"`
+	Deliveries int       `json:"deliveries,omitempty"`
+	// Job は元のジョブ。JSON として読めなかったジョブは nil で、元の内容を Raw に残す
+	Job *Job   `json:"job,omitempty"`
+	Raw string `json:"raw,omitempty"`
+}
+
+// GetDeadLetterDir returns the directory for a specific pool's dead letters.
+func (q *FilesystemQueue) GetDeadLetterDir(poolID string) string {
+	return filepath.Join(q.WorkspaceDir, "ipc", "dead", poolID)
+}
+
+// ExceedsMaxDeliveries reports whether job has been delivered more often than
+// MaxDeliveries. MaxDeliveries が 0 以下なら上限なし。
+func (q *FilesystemQueue) ExceedsMaxDeliveries(job *Job) bool {
+	return q.MaxDeliveries > 0 && job.Deliveries > q.MaxDeliveries
+}
+
+// DeadLetter moves a claimed job from the processing directory to the
+// dead-letter directory, recording reason. 他の所有者の有効なリースを持つジョブは
+// 移動せず ErrLeaseLost を返す。
+func (q *FilesystemQueue) DeadLetter(jobID, poolID, reason string) error {
+	path := filepath.Join(q.GetProcessingDir(poolID), jobID+".json")
+	job, err := q.readProcessingJob(path)
+	if err != nil {
+		return fmt.Errorf("failed to read job to dead-letter: %w", err)
+	}
+	if q.LeasedByOther(job) {
+		return fmt.Errorf("job %s is leased by %s: %w", jobID, job.Lease.Owner, ErrLeaseLost)
+	}
+	job.PoolID = poolID
+	job.Lease = nil
+	return q.moveToDeadLetter(path, &DeadLetter{
+		JobID:      jobID,
+		PoolID:     poolID,
+		TaskID:     job.TaskID,
+		Reason:     reason,
+		Deliveries: job.Deliveries,
+		Job:        job,
+	})
+}
+
+// deadLetterRaw moves an unreadable job file at path to the dead-letter
+// directory, keeping its original contents.
+func (q *FilesystemQueue) deadLetterRaw(path, poolID string, data []byte, reason string) error {
+	return q.moveToDeadLetter(path, &DeadLetter{
+		JobID:  strings.TrimSuffix(filepath.Base(path), ".json"),
+		PoolID: poolID,
+		Reason: reason,
+		Raw:    string(data),
+	})
+}
+
+// moveToDeadLetter writes dl to the dead-letter directory, then removes the
+// original job file at src.
+func (q *FilesystemQueue) moveToDeadLetter(src string, dl *DeadLetter) error {
+	dir := q.GetDeadLetterDir(dl.PoolID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	dl.DeadAt = time.Now()
+	data, err := json.MarshalIndent(dl, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, dl.JobID+".json"), data); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	if err := os.Remove(src); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove dead-lettered job file: %w", err)
+	}
+	return nil
+}
+
+// ListDeadLetters returns the dead letters of poolID (every pool when poolID
+// is empty), oldest first.
+func (q *FilesystemQueue) ListDeadLetters(poolID string) ([]*DeadLetter, error) {
+	pools := []string{poolID}
+	if poolID == "" {
+		root := filepath.Join(q.WorkspaceDir, "ipc", "dead")
+		entries, err := os.ReadDir(root)
+		if os.IsNotExist(err) {
+			return []*DeadLetter{}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dead-letter directory: %w", err)
+		}
+		pools = pools[:0]
+		for _, entry := range entries {
+			if entry.IsDir() {
+				pools = append(pools, entry.Name())
+			}
+		}
+	}
+
+	letters := []*DeadLetter{}
+	for _, pool := range pools {
+		dir := q.GetDeadLetterDir(pool)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dead-letter directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+				continue
+			}
+			dl, err := q.readDeadLetter(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue // 読めないファイルはスキップする
+			}
+			dl.PoolID = pool
+			letters = append(letters, dl)
+		}
+	}
+	sort.SliceStable(letters, func(i, j int) bool {
+		return letters[i].DeadAt.Before(letters[j].DeadAt)
+	})
+	return letters, nil
+}
+
+// RequeueDeadLetter puts a dead-lettered job back into its pool's queue with
+// the delivery count reset. JSON として読めなかったジョブは戻せない。
+func (q *FilesystemQueue) RequeueDeadLetter(jobID, poolID string) error {
+	path := filepath.Join(q.GetDeadLetterDir(poolID), jobID+".json")
+	dl, err := q.readDeadLetter(path)
+	if err != nil {
+		return fmt.Errorf("failed to read dead letter %s: %w", jobID, err)
+	}
+	if dl.Job == nil {
+		return fmt.Errorf("dead letter %s has no readable job and cannot be requeued", jobID)
+	}
+
+	job := dl.Job
+	job.ID = jobID
+	job.PoolID = poolID
+	job.Deliveries = 0
+	job.Lease = nil
+	if err := q.Enqueue(job); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove dead letter: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeadLetter deletes a dead-lettered job.
+func (q *FilesystemQueue) PurgeDeadLetter(jobID, poolID string) error {
+	path := filepath.Join(q.GetDeadLetterDir(poolID), jobID+".json")
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("dead letter not found: %s", jobID)
+		}
+		return fmt.Errorf("failed to purge dead letter: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeadLetters deletes every dead letter of poolID (every pool when
+// poolID is empty) and returns how many were deleted.
+func (q *FilesystemQueue) PurgeDeadLetters(poolID string) (int, error) {
+	letters, err := q.ListDeadLetters(poolID)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, dl := range letters {
+		if err := q.PurgeDeadLetter(dl.JobID, dl.PoolID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (q *FilesystemQueue) readDeadLetter(path string) (*DeadLetter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dl DeadLetter
+	if err := json.Unmarshal(data, &dl); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+	dl.JobID = strings.TrimSuffix(filepath.Base(path), ".json")
+	return &dl, nil
+}
@@ -0,0 +1,124 @@
+package ipc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDequeueDeadLettersUnreadableJob(t *testing.T) {
+	queue := NewFilesystemQueue(t.TempDir())
+	dir := queue.GetQueueDir("default")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "job-broken.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := queue.Enqueue(&Job{ID: "job-ok", TaskID: "task-ok", PoolID: "default", Priority: -1}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	// 読めないジョブはデッドレターに移し、次のジョブを返す
+	job, err := queue.Dequeue("default")
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if job == nil || job.ID != "job-ok" || job.Deliveries != 1 {
+		t.Fatalf("expected job-ok on first delivery, got %+v", job)
+	}
+
+	letters, err := queue.ListDeadLetters("")
+	if err != nil {
+		t.Fatalf("ListDeadLetters failed: %v", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+	dl := letters[0]
+	if dl.JobID != "job-broken" || dl.PoolID != "default" || dl.Job != nil || dl.Raw != "{not json" {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+	if !strings.Contains(dl.Reason, "unmarshal") {
+		t.Errorf("expected unmarshal reason, got %q", dl.Reason)
+	}
+	if err := queue.RequeueDeadLetter("job-broken", "default"); err == nil {
+		t.Error("expected error when requeueing an unreadable job")
+	}
+}
+
+func TestDeadLetterRequeueAndPurge(t *testing.T) {
+	queue := NewFilesystemQueue(t.TempDir())
+	queue.MaxDeliveries = 1
+	for _, id := range []string{"job-1", "job-2"} {
+		if err := queue.Enqueue(&Job{ID: id, TaskID: "task-" + id, PoolID: "codegen"}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	job, err := queue.Dequeue("codegen")
+	if err != nil || job == nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if queue.ExceedsMaxDeliveries(job) {
+		t.Error("first delivery should not exceed MaxDeliveries")
+	}
+	if err := queue.Requeue(job.ID, job.PoolID); err != nil {
+		t.Fatalf("Requeue failed: %v", err)
+	}
+	job, err = queue.Dequeue("codegen")
+	if err != nil || job == nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if job.Deliveries != 2 || !queue.ExceedsMaxDeliveries(job) {
+		t.Fatalf("expected second delivery to exceed MaxDeliveries, got %d", job.Deliveries)
+	}
+
+	if err := queue.DeadLetter(job.ID, job.PoolID, "poison job"); err != nil {
+		t.Fatalf("DeadLetter failed: %v", err)
+	}
+	letters, err := queue.ListDeadLetters("codegen")
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d (%v)", len(letters), err)
+	}
+	if dl := letters[0]; dl.Reason != "poison job" || dl.TaskID != job.TaskID || dl.Deliveries != 2 || dl.Job == nil {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+	if processing, _ := queue.ProcessingJobs(); len(processing) != 0 {
+		t.Errorf("expected processing directory to be empty, got %d jobs", len(processing))
+	}
+
+	// 再投入すると配送回数がリセットされる
+	if err := queue.RequeueDeadLetter(job.ID, "codegen"); err != nil {
+		t.Fatalf("RequeueDeadLetter failed: %v", err)
+	}
+	requeued, err := queue.Dequeue("codegen")
+	if err != nil || requeued == nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if requeued.ID != job.ID || requeued.Deliveries != 1 {
+		t.Errorf("unexpected requeued job: %+v", requeued)
+	}
+
+	if err := queue.DeadLetter(requeued.ID, "codegen", "again"); err != nil {
+		t.Fatalf("DeadLetter failed: %v", err)
+	}
+	other, err := queue.Dequeue("codegen")
+	if err != nil || other == nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if err := queue.DeadLetter(other.ID, "codegen", "also broken"); err != nil {
+		t.Fatalf("DeadLetter failed: %v", err)
+	}
+	if err := queue.PurgeDeadLetter("job-missing", "codegen"); err == nil {
+		t.Error("expected error when purging a missing dead letter")
+	}
+	purged, err := queue.PurgeDeadLetters("")
+	if err != nil || purged != 2 {
+		t.Errorf("expected 2 purged dead letters, got %d (%v)", purged, err)
+	}
+	if letters, _ := queue.ListDeadLetters(""); len(letters) != 0 {
+		t.Errorf("expected no dead letters after purge, got %d", len(letters))
+	}
+}
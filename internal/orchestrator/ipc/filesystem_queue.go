@@ -2,27 +2,110 @@ package ipc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/filelock"
+	"github.com/google/uuid"
+)
+
+// Default aging of queued jobs: a job gains DefaultAgingBoost priority for
+// every DefaultAgingInterval it has waited, so low-priority jobs cannot starve.
+const (
+	DefaultAgingInterval = time.Minute
+	DefaultAgingBoost    = 10
 )
 
+// DefaultLeaseDuration is how long a claimed job stays owned without a
+// heartbeat (RenewLease) before RequeueExpired returns it to the queue.
+const DefaultLeaseDuration = 2 * time.Minute
+
+// ErrLeaseLost is returned when a job is no longer leased by this queue's
+// owner: the lease expired, or the job was requeued or claimed by another owner.
+var ErrLeaseLost = errors.New("job lease lost")
+
+// Lease records which consumer claimed a job and until when the claim is valid.
+type Lease struct {
+	Owner     string    `json:"owner"`
+	ClaimedAt time.Time `json:"claimedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the lease has passed its deadline at now.
+func (l *Lease) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
 // Job represents a unit of work in the queue.
 type Job struct {
 	ID      string `json:"id"`
 	TaskID  string `json:"taskId"`
 	PoolID  string `json:"poolId"`
 	Payload any    `json:"payload"`
+	// Priority は大きいほど先に取り出される（待ち時間に応じて加算される）
+	Priority int `json:"priority,omitempty"`
+	// Dependents はこのジョブの完了で解放される後続ノード数。同じ優先度なら多い方を先に取り出す
+	Dependents int       `json:"dependents,omitempty"`
+	EnqueuedAt time.Time `json:"enqueuedAt,omitempty"`
+	// Deliveries は Dequeue で取り出された回数（リース切れ・リカバリによる再配送を含む）
+	Deliveries int `json:"deliveries,omitempty"`
+	// Lease は取り出し中（processing/）のジョブの所有者と期限。キュー内では前回の値が残っていても無視する
+	Lease *Lease `json:"lease,omitempty"`
 }
 
 // FilesystemQueue handles file-based IPC queue operations.
 type FilesystemQueue struct {
 	WorkspaceDir string
+	// AgingInterval ごとに AgingBoost を優先度に加算する。0 ならエージングしない
+	AgingInterval time.Duration
+	AgingBoost    int
+	// OwnerID は Dequeue したジョブのリース所有者。同じワークスペースを扱うプロセスごとに一意にする
+	OwnerID string
+	// LeaseDuration は取り出し・RenewLease ごとに延長されるリース期間。0 以下なら DefaultLeaseDuration
+	LeaseDuration time.Duration
+	// MaxDeliveries を超えて取り出されたジョブは ExceedsMaxDeliveries が true になる。0 以下なら上限なし
+	MaxDeliveries int
 }
 
-// NewFilesystemQueue creates a new FilesystemQueue.
+// NewFilesystemQueue creates a new FilesystemQueue with an owner ID unique to
+// this process.
 func NewFilesystemQueue(workspaceDir string) *FilesystemQueue {
-	return &FilesystemQueue{WorkspaceDir: workspaceDir}
+	return &FilesystemQueue{
+		WorkspaceDir:  workspaceDir,
+		AgingInterval: DefaultAgingInterval,
+		AgingBoost:    DefaultAgingBoost,
+		OwnerID:       newOwnerID(),
+		LeaseDuration: DefaultLeaseDuration,
+		MaxDeliveries: DefaultMaxDeliveries,
+	}
+}
+
+// newOwnerID returns "<hostname>-<pid>-<random>" so that leases of
+// different processes (and restarts of the same PID) never collide.
+func newOwnerID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8])
+}
+
+// HeartbeatInterval is how often a consumer should call RenewLease: a third of
+// the lease, so that a couple of missed heartbeats do not lose the job.
+func (q *FilesystemQueue) HeartbeatInterval() time.Duration {
+	return q.leaseDuration() / 3
+}
+
+func (q *FilesystemQueue) leaseDuration() time.Duration {
+	if q.LeaseDuration <= 0 {
+		return DefaultLeaseDuration
+	}
+	return q.LeaseDuration
 }
 
 // GetQueueDir returns the directory for a specific pool's queue.
@@ -36,6 +119,9 @@ func (q *FilesystemQueue) Enqueue(job *Job) error {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create queue directory: %w", err)
 	}
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
 
 	path := filepath.Join(dir, job.ID+".json")
 	data, err := json.MarshalIndent(job, "", "  ")
@@ -43,70 +129,190 @@ func (q *FilesystemQueue) Enqueue(job *Job) error {
 		return fmt.Errorf("failed to marshal job: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	// 書き込み途中のファイルを他のコンシューマが読まないよう、一時ファイル経由で置く
+	if err := writeFileAtomic(path, data); err != nil {
 		return fmt.Errorf("failed to write job file: %w", err)
 	}
 
 	return nil
 }
 
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path. 一時ファイルは "." で始まり拡張子が .json ではないため、
+// キューの走査対象にならない。
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // Dequeue claims the next available job from the queue.
-// It moves the job file from the queue directory to a processing directory.
+// Jobs are taken in order of effective priority (priority plus aging),
+// then dependents, then age. It moves the job file from the queue directory
+// to a processing directory and records a lease owned by OwnerID that must be
+// renewed with RenewLease before it expires.
 func (q *FilesystemQueue) Dequeue(poolID string) (*Job, error) {
-	queueDir := q.GetQueueDir(poolID)
-	entries, err := os.ReadDir(queueDir)
-	if os.IsNotExist(err) {
-		return nil, nil // Queue not created yet, empty
-	}
+	candidates, err := q.pendingJobs(poolID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read queue directory: %w", err)
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil // No jobs found
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
-			// Found a job
-			filename := entry.Name()
-			srcPath := filepath.Join(queueDir, filename)
-
-			// Create processing directory if not exists
-			procDir := q.GetProcessingDir(poolID)
-			if err := os.MkdirAll(procDir, 0755); err != nil {
-				return nil, fmt.Errorf("failed to create processing directory: %w", err)
-			}
+	// Create processing directory if not exists
+	procDir := q.GetProcessingDir(poolID)
+	if err := os.MkdirAll(procDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create processing directory: %w", err)
+	}
 
-			destPath := filepath.Join(procDir, filename)
+	for _, c := range candidates {
+		destPath := filepath.Join(procDir, c.filename)
 
-			// Move file to processing (Claim)
-			// Using Rename as atomic-ish operation on same filesystem
-			if err := os.Rename(srcPath, destPath); err != nil {
-				// Potential race condition or error, skip to next or return error
-				// For now, return error to retry
-				return nil, fmt.Errorf("failed to claim job (move): %w", err)
+		// リースを書き込むまでの間も期限切れと判定されないよう、取得前に更新時刻を現在にする
+		// （リースの無い processing/ のジョブは更新時刻 + LeaseDuration を期限とみなす）
+		now := time.Now()
+		if err := os.Chtimes(c.path, now, now); err != nil {
+			if os.IsNotExist(err) {
+				continue // 他のコンシューマが先に取得した
 			}
+			return nil, fmt.Errorf("failed to touch job file: %w", err)
+		}
 
-			// Read and unmarshal
-			data, err := os.ReadFile(destPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read claimed job file: %w", err)
+		// Move file to processing (Claim)
+		// Rename is atomic on the same filesystem, so only one consumer wins
+		if err := os.Rename(c.path, destPath); err != nil {
+			if os.IsNotExist(err) {
+				continue // 他のコンシューマが先に取得した
 			}
+			return nil, fmt.Errorf("failed to claim job (move): %w", err)
+		}
+
+		// Read and unmarshal
+		data, err := os.ReadFile(destPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read claimed job file: %w", err)
+		}
 
-			var job Job
-			if err := json.Unmarshal(data, &job); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			// 読めないジョブは毎回エラーになるため、デッドレターに移して次の候補に進む
+			if dlErr := q.deadLetterRaw(destPath, poolID, data, fmt.Sprintf("failed to unmarshal job: %v", err)); dlErr != nil {
+				return nil, fmt.Errorf("failed to dead-letter unreadable job: %w", dlErr)
 			}
+			continue
+		}
 
-			return &job, nil
+		job.Deliveries++
+		job.Lease = &Lease{Owner: q.OwnerID, ClaimedAt: now, ExpiresAt: now.Add(q.leaseDuration())}
+		if err := q.writeJob(destPath, &job); err != nil {
+			return nil, fmt.Errorf("failed to write job lease: %w", err)
 		}
+
+		return &job, nil
 	}
 
 	return nil, nil // No jobs found
 }
 
+// queuedJob is a job file waiting in the queue directory.
+type queuedJob struct {
+	filename   string
+	path       string
+	priority   int // エージング込みの実効優先度
+	dependents int
+	enqueuedAt time.Time
+}
+
+// pendingJobs returns the queued jobs of poolID in dequeue order.
+func (q *FilesystemQueue) pendingJobs(poolID string) ([]queuedJob, error) {
+	queueDir := q.GetQueueDir(poolID)
+	entries, err := os.ReadDir(queueDir)
+	if os.IsNotExist(err) {
+		return nil, nil // Queue not created yet, empty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue directory: %w", err)
+	}
+
+	now := time.Now()
+	var jobs []queuedJob
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		c := queuedJob{filename: entry.Name(), path: filepath.Join(queueDir, entry.Name())}
+		// 読めないファイルは優先度 0 として扱い、取得時にエラーを返す
+		var job Job
+		if data, err := os.ReadFile(c.path); err == nil && json.Unmarshal(data, &job) == nil {
+			c.priority = job.Priority
+			c.dependents = job.Dependents
+			c.enqueuedAt = job.EnqueuedAt
+		}
+		if c.enqueuedAt.IsZero() {
+			// 優先度導入前のジョブはファイルの更新時刻を投入時刻とみなす
+			if info, err := entry.Info(); err == nil {
+				c.enqueuedAt = info.ModTime()
+			}
+		}
+		c.priority += q.agingBonus(now.Sub(c.enqueuedAt))
+		jobs = append(jobs, c)
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		a, b := jobs[i], jobs[j]
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		if a.dependents != b.dependents {
+			return a.dependents > b.dependents
+		}
+		if !a.enqueuedAt.Equal(b.enqueuedAt) {
+			return a.enqueuedAt.Before(b.enqueuedAt)
+		}
+		return a.filename < b.filename
+	})
+	return jobs, nil
+}
+
+// agingBonus returns the priority added to a job that has waited for waited.
+func (q *FilesystemQueue) agingBonus(waited time.Duration) int {
+	if q.AgingInterval <= 0 || waited <= 0 {
+		return 0
+	}
+	return int(waited/q.AgingInterval) * q.AgingBoost
+}
+
 // Complete removes a job from the processing directory, marking it as done.
+// A job whose lease is still held by another owner is left untouched and
+// ErrLeaseLost is returned.
 func (q *FilesystemQueue) Complete(jobID, poolID string) error {
 	procDir := q.GetProcessingDir(poolID)
 	path := filepath.Join(procDir, jobID+".json")
 
+	if err := q.checkNotLeasedByOther(path); err != nil {
+		return err
+	}
 	if err := os.Remove(path); err != nil {
 		if os.IsNotExist(err) {
 			return nil // Already gone
@@ -141,3 +347,184 @@ func (q *FilesystemQueue) ListJobs(poolID string) ([]string, error) {
 	}
 	return jobIDs, nil
 }
+
+// ProcessingJobs returns the claimed jobs of every pool (ipc/processing/*),
+// including their leases. 読めないファイルはスキップする。
+func (q *FilesystemQueue) ProcessingJobs() ([]*Job, error) {
+	root := filepath.Join(q.WorkspaceDir, "ipc", "processing")
+	pools, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processing directory: %w", err)
+	}
+
+	var jobs []*Job
+	for _, pool := range pools {
+		if !pool.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(root, pool.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read processing directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+				continue
+			}
+			job, err := q.readProcessingJob(filepath.Join(root, pool.Name(), entry.Name()))
+			if err != nil {
+				continue
+			}
+			// Complete / Requeue はファイル名とディレクトリで解決するため、それに合わせる
+			job.PoolID = pool.Name()
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// Requeue moves a claimed job back from the processing directory to the
+// queue. EnqueuedAt は変えないため、待ち時間によるエージングは引き継がれる。
+// 他の所有者のリースが有効なジョブは戻さず ErrLeaseLost を返す。
+func (q *FilesystemQueue) Requeue(jobID, poolID string) error {
+	dir := q.GetQueueDir(poolID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create queue directory: %w", err)
+	}
+	src := filepath.Join(q.GetProcessingDir(poolID), jobID+".json")
+	if err := q.checkNotLeasedByOther(src); err != nil {
+		return err
+	}
+	if err := os.Rename(src, filepath.Join(dir, jobID+".json")); err != nil {
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+	return nil
+}
+
+// LeasedByOther reports whether job is held by another owner whose lease has
+// not expired yet, i.e. it must not be recovered or completed by this owner.
+func (q *FilesystemQueue) LeasedByOther(job *Job) bool {
+	return job.Lease != nil && job.Lease.Owner != q.OwnerID && !job.Lease.Expired(time.Now())
+}
+
+// RenewLease extends the lease of a job claimed by this owner (heartbeat) and
+// updates job.Lease. ジョブが処理中ディレクトリに無い・他の所有者に渡った・
+// 期限切れの場合は ErrLeaseLost を返す（期限切れのジョブは他のコンシューマが取得し得る）。
+func (q *FilesystemQueue) RenewLease(job *Job) error {
+	lock, err := q.lockLeases()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	path := filepath.Join(q.GetProcessingDir(job.PoolID), job.ID+".json")
+	current, err := q.readProcessingJob(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("job %s: %w", job.ID, ErrLeaseLost)
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if current.Lease.Owner != q.OwnerID || current.Lease.Expired(now) {
+		return fmt.Errorf("job %s: %w", job.ID, ErrLeaseLost)
+	}
+
+	current.Lease.ExpiresAt = now.Add(q.leaseDuration())
+	if err := q.writeJob(path, current); err != nil {
+		return fmt.Errorf("failed to renew job lease: %w", err)
+	}
+	job.Lease = current.Lease
+	return nil
+}
+
+// RequeueExpired returns every claimed job whose lease has expired (its owner
+// crashed or stopped sending heartbeats) to the queue, and returns those jobs.
+func (q *FilesystemQueue) RequeueExpired() ([]*Job, error) {
+	lock, err := q.lockLeases()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = lock.Release() }()
+
+	jobs, err := q.ProcessingJobs()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var requeued []*Job
+	for _, job := range jobs {
+		if !job.Lease.Expired(now) {
+			continue
+		}
+		dir := q.GetQueueDir(job.PoolID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return requeued, fmt.Errorf("failed to create queue directory: %w", err)
+		}
+		src := filepath.Join(q.GetProcessingDir(job.PoolID), job.ID+".json")
+		if err := os.Rename(src, filepath.Join(dir, job.ID+".json")); err != nil {
+			if os.IsNotExist(err) {
+				continue // 所有者が完了したか、他のプロセスが先に戻した
+			}
+			return requeued, fmt.Errorf("failed to requeue expired job: %w", err)
+		}
+		requeued = append(requeued, job)
+	}
+	return requeued, nil
+}
+
+// lockLeases acquires the cross-process lock that makes lease renewal and
+// expired-lease requeue atomic with each other. これが無いと、他プロセスが
+// pending に戻した直後のジョブを更新で processing に再作成し、二重実行になりうる。
+func (q *FilesystemQueue) lockLeases() (*filelock.Lock, error) {
+	lock, err := filelock.Acquire(filepath.Join(q.WorkspaceDir, "ipc", "leases.lock"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock job leases: %w", err)
+	}
+	return lock, nil
+}
+
+// readProcessingJob reads a claimed job file. リースを持たないジョブ（リース導入前、
+// または取得直後でリースを書き込む前）はファイルの更新時刻 + LeaseDuration を期限とする。
+func (q *FilesystemQueue) readProcessingJob(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	job.ID = strings.TrimSuffix(filepath.Base(path), ".json")
+	if job.Lease == nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		job.Lease = &Lease{ClaimedAt: info.ModTime(), ExpiresAt: info.ModTime().Add(q.leaseDuration())}
+	}
+	return &job, nil
+}
+
+// checkNotLeasedByOther returns ErrLeaseLost when the claimed job at path is
+// held by another owner's live lease. 読めないファイルは呼び出し側の処理に任せる。
+func (q *FilesystemQueue) checkNotLeasedByOther(path string) error {
+	job, err := q.readProcessingJob(path)
+	if err != nil {
+		return nil
+	}
+	if q.LeasedByOther(job) {
+		return fmt.Errorf("job %s is leased by %s: %w", job.ID, job.Lease.Owner, ErrLeaseLost)
+	}
+	return nil
+}
+
+func (q *FilesystemQueue) writeJob(path string, job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
@@ -2,9 +2,14 @@ package ipc
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/filelock"
 )
 
 func TestNewFilesystemQueue(t *testing.T) {
@@ -293,3 +298,279 @@ func TestEnqueueMultiplePools(t *testing.T) {
 		}
 	}
 }
+
+func dequeueOrder(t *testing.T, queue *FilesystemQueue, poolID string) []string {
+	t.Helper()
+	var ids []string
+	for {
+		job, err := queue.Dequeue(poolID)
+		if err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+		if job == nil {
+			return ids
+		}
+		ids = append(ids, job.ID)
+	}
+}
+
+func TestDequeuePriorityOrder(t *testing.T) {
+	queue := NewFilesystemQueue(t.TempDir())
+	queue.AgingInterval = 0 // エージングなしで並び順だけを確認する
+
+	base := time.Now()
+	jobs := []*Job{
+		{ID: "a-low-old", PoolID: "default", Priority: 50, EnqueuedAt: base.Add(-time.Hour)},
+		{ID: "b-high", PoolID: "default", Priority: 200, EnqueuedAt: base},
+		{ID: "c-medium-new", PoolID: "default", Priority: 100, EnqueuedAt: base},
+		{ID: "d-medium-old", PoolID: "default", Priority: 100, EnqueuedAt: base.Add(-time.Minute)},
+		{ID: "e-medium-unblocks", PoolID: "default", Priority: 100, Dependents: 3, EnqueuedAt: base},
+	}
+	for _, job := range jobs {
+		if err := queue.Enqueue(job); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	got := dequeueOrder(t, queue, "default")
+	want := []string{"b-high", "e-medium-unblocks", "d-medium-old", "c-medium-new", "a-low-old"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("dequeue order = %v, want %v", got, want)
+	}
+}
+
+func TestDequeueAgingPreventsStarvation(t *testing.T) {
+	queue := NewFilesystemQueue(t.TempDir())
+
+	// 既定では 1 分ごとに +10。10 分待った low (50) は新しい medium (100) より先に出る
+	_ = queue.Enqueue(&Job{ID: "fresh-medium", PoolID: "default", Priority: 100})
+	_ = queue.Enqueue(&Job{ID: "starving-low", PoolID: "default", Priority: 50, EnqueuedAt: time.Now().Add(-10 * time.Minute)})
+
+	if got := dequeueOrder(t, queue, "default"); strings.Join(got, ",") != "starving-low,fresh-medium" {
+		t.Errorf("dequeue order = %v, want aged job first", got)
+	}
+}
+
+func TestProcessingJobsAndRequeue(t *testing.T) {
+	queue := NewFilesystemQueue(t.TempDir())
+
+	enqueuedAt := time.Now().Add(-5 * time.Minute).Truncate(time.Second)
+	for _, job := range []*Job{
+		{ID: "job-1", TaskID: "task-1", PoolID: "default", EnqueuedAt: enqueuedAt},
+		{ID: "job-2", TaskID: "task-2", PoolID: "codegen"},
+	} {
+		if err := queue.Enqueue(job); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+		if _, err := queue.Dequeue(job.PoolID); err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+	}
+
+	jobs, err := queue.ProcessingJobs()
+	if err != nil {
+		t.Fatalf("ProcessingJobs failed: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 processing jobs, got %d", len(jobs))
+	}
+
+	// Requeue はキューに戻し、投入時刻（エージング）を引き継ぐ
+	if err := queue.Requeue("job-1", "default"); err != nil {
+		t.Fatalf("Requeue failed: %v", err)
+	}
+	job, err := queue.Dequeue("default")
+	if err != nil || job == nil {
+		t.Fatalf("Dequeue after requeue failed: %v", err)
+	}
+	if job.TaskID != "task-1" || !job.EnqueuedAt.Equal(enqueuedAt) {
+		t.Errorf("unexpected requeued job: %+v", job)
+	}
+
+	if err := queue.Requeue("job-missing", "default"); err == nil {
+		t.Error("expected error when requeueing a missing job")
+	}
+}
+
+func TestEnqueueLeavesNoTemporaryFiles(t *testing.T) {
+	queue := NewFilesystemQueue(t.TempDir())
+	for _, id := range []string{"job-1", "job-2"} {
+		if err := queue.Enqueue(&Job{ID: id, TaskID: "task", PoolID: "default"}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(queue.GetQueueDir("default"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != "job-1.json,job-2.json" {
+		t.Errorf("unexpected queue directory contents: %v", names)
+	}
+}
+
+func TestDequeueLeaseAndRenew(t *testing.T) {
+	queue := NewFilesystemQueue(t.TempDir())
+	queue.LeaseDuration = time.Minute
+	if err := queue.Enqueue(&Job{ID: "job-1", TaskID: "task-1", PoolID: "default"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	job, err := queue.Dequeue("default")
+	if err != nil || job == nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if job.Lease == nil || job.Lease.Owner != queue.OwnerID {
+		t.Fatalf("expected lease owned by %s, got %+v", queue.OwnerID, job.Lease)
+	}
+	firstExpiry := job.Lease.ExpiresAt
+
+	// リースはファイルにも記録される
+	jobs, err := queue.ProcessingJobs()
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("ProcessingJobs failed: %v (%d jobs)", err, len(jobs))
+	}
+	if jobs[0].Lease.Owner != queue.OwnerID || !jobs[0].Lease.ExpiresAt.Equal(firstExpiry) {
+		t.Errorf("unexpected persisted lease: %+v", jobs[0].Lease)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := queue.RenewLease(job); err != nil {
+		t.Fatalf("RenewLease failed: %v", err)
+	}
+	if !job.Lease.ExpiresAt.After(firstExpiry) {
+		t.Errorf("expected lease to be extended, got %v (was %v)", job.Lease.ExpiresAt, firstExpiry)
+	}
+
+	if err := queue.Complete(job.ID, job.PoolID); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if err := queue.RenewLease(job); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost after completion, got %v", err)
+	}
+}
+
+func TestLeaseProtectsJobFromOtherOwner(t *testing.T) {
+	dir := t.TempDir()
+	owner := NewFilesystemQueue(dir)
+	other := NewFilesystemQueue(dir)
+	if owner.OwnerID == other.OwnerID {
+		t.Fatal("expected distinct owner IDs")
+	}
+
+	if err := owner.Enqueue(&Job{ID: "job-1", TaskID: "task-1", PoolID: "default"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	job, err := owner.Dequeue("default")
+	if err != nil || job == nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if again, err := other.Dequeue("default"); err != nil || again != nil {
+		t.Fatalf("expected no job for the other owner, got %+v (%v)", again, err)
+	}
+
+	if !other.LeasedByOther(job) || owner.LeasedByOther(job) {
+		t.Error("unexpected LeasedByOther result")
+	}
+	if err := other.Complete(job.ID, job.PoolID); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost from other owner's Complete, got %v", err)
+	}
+	if err := other.Requeue(job.ID, job.PoolID); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost from other owner's Requeue, got %v", err)
+	}
+	if err := other.RenewLease(&Job{ID: job.ID, PoolID: job.PoolID}); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost from other owner's RenewLease, got %v", err)
+	}
+	if err := owner.Complete(job.ID, job.PoolID); err != nil {
+		t.Errorf("Complete by owner failed: %v", err)
+	}
+}
+
+func TestRequeueExpired(t *testing.T) {
+	dir := t.TempDir()
+	crashed := NewFilesystemQueue(dir)
+	crashed.LeaseDuration = 20 * time.Millisecond
+	alive := NewFilesystemQueue(dir)
+
+	for _, id := range []string{"job-expired", "job-live"} {
+		if err := crashed.Enqueue(&Job{ID: id, TaskID: "task-" + id, PoolID: "default"}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	expired, err := crashed.Dequeue("default")
+	if err != nil || expired == nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	live, err := alive.Dequeue("default")
+	if err != nil || live == nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+
+	requeued, err := alive.RequeueExpired()
+	if err != nil {
+		t.Fatalf("RequeueExpired failed: %v", err)
+	}
+	if len(requeued) != 1 || requeued[0].ID != expired.ID {
+		t.Fatalf("expected only %s to be requeued, got %+v", expired.ID, requeued)
+	}
+
+	// 期限切れのリースの所有者はもう延長も完了もできない
+	if err := crashed.RenewLease(expired); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost, got %v", err)
+	}
+	ids, err := alive.ListJobs("default")
+	if err != nil || len(ids) != 1 || ids[0] != expired.ID {
+		t.Errorf("expected %s back in the queue, got %v (%v)", expired.ID, ids, err)
+	}
+	if err := alive.RenewLease(live); err != nil {
+		t.Errorf("live lease should still be renewable: %v", err)
+	}
+}
+
+func TestRenewLeaseIsAtomicWithRequeue(t *testing.T) {
+	dir := t.TempDir()
+	queue := NewFilesystemQueue(dir)
+	if err := queue.Enqueue(&Job{ID: "job-1", TaskID: "task-1", PoolID: "default"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	job, err := queue.Dequeue("default")
+	if err != nil || job == nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+
+	// 他プロセスの RequeueExpired がリースのロックを保持している状態を再現する
+	lock, err := filelock.Acquire(filepath.Join(dir, "ipc", "leases.lock"))
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- queue.RenewLease(job) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("RenewLease should wait for the lease lock, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// ロック保持中にジョブが pending に戻された
+	procPath := filepath.Join(queue.GetProcessingDir("default"), "job-1.json")
+	if err := os.Rename(procPath, filepath.Join(queue.GetQueueDir("default"), "job-1.json")); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	if err := <-done; !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost after requeue, got %v", err)
+	}
+	// 更新によって processing にジョブが再作成されてはならない（二重実行の防止）
+	if _, err := os.Stat(procPath); !os.IsNotExist(err) {
+		t.Errorf("renew must not recreate the processing file, stat err = %v", err)
+	}
+}
@@ -66,11 +66,13 @@ const (
 	NodeRuntimeStatusVerified    NodeRuntimeStatus = "verified"
 	NodeRuntimeStatusBlocked     NodeRuntimeStatus = "blocked"
 	NodeRuntimeStatusObsolete    NodeRuntimeStatus = "obsolete"
+	// NodeRuntimeStatusSkipped はタスクのスキップにより依存を満たしたとみなすノード
+	NodeRuntimeStatusSkipped NodeRuntimeStatus = "skipped"
 )
 
 // IsCompleted は依存解決に使える完了状態かどうかを返す
 func (s NodeRuntimeStatus) IsCompleted() bool {
-	return s == NodeRuntimeStatusImplemented || s == NodeRuntimeStatusVerified
+	return s == NodeRuntimeStatusImplemented || s == NodeRuntimeStatusVerified || s == NodeRuntimeStatusSkipped
 }
 
 type NodesRuntime struct {
@@ -146,6 +148,60 @@ type AgentState struct {
 	Capabilities []string `json:"capabilities"`
 }
 
+// RetryPoliciesConfig is state/retry-policies.json.
+type RetryPoliciesConfig struct {
+	Policies []RetryPolicyConfig `json:"policies"`
+}
+
+// RetryPolicyConfig はタスク種別・プールごとのリトライポリシー。
+// task_kinds / pools が空のポリシーはすべてのタスクに一致する。
+type RetryPolicyConfig struct {
+	Name          string                  `json:"name"`
+	TaskKinds     []string                `json:"task_kinds,omitempty"`
+	Pools         []string                `json:"pools,omitempty"`
+	MaxAttempts   int                     `json:"max_attempts,omitempty"`
+	BackoffBase   string                  `json:"backoff_base,omitempty"` // time.ParseDuration 形式（例: "5s"）
+	BackoffMax    string                  `json:"backoff_max,omitempty"`
+	BackoffFactor float64                 `json:"backoff_factor,omitempty"`
+	RequireHuman  *bool                   `json:"require_human,omitempty"`
+	Classifiers   []ErrorClassifierConfig `json:"classifiers,omitempty"`
+	Escalations   []RetryEscalationConfig `json:"escalations,omitempty"`
+}
+
+// ErrorClassifierConfig maps failures to an error class (transient,
+// permanent, needs_human). 指定した条件はすべて満たす必要があり、各リストはいずれかに一致すればよい。
+type ErrorClassifierConfig struct {
+	Class        string   `json:"class"`
+	ExitCodes    []int    `json:"exit_codes,omitempty"`
+	ResultStates []string `json:"result_states,omitempty"`
+	Patterns     []string `json:"patterns,omitempty"` // エラーメッセージ・出力に対する正規表現
+}
+
+// RetryEscalationConfig changes how a task runs from the given attempt on.
+type RetryEscalationConfig struct {
+	FromAttempt     int    `json:"from_attempt"`
+	WorkerKind      string `json:"worker_kind,omitempty"`
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	MaxLoops        int    `json:"max_loops,omitempty"`
+}
+
+// RunnerSettingsConfig is state/runner.json: workspace-wide agent-runner
+// settings passed to every task's YAML.
+type RunnerSettingsConfig struct {
+	// Reviewer は完了判定の独立レビュー（runner.reviewer）。nil なら無効
+	Reviewer *ReviewerConfig `json:"reviewer,omitempty"`
+	// WorkerFallback は Worker kind が利用できない場合に順に試す kind（runner.worker.fallback）
+	WorkerFallback []string `json:"worker_fallback,omitempty"`
+}
+
+// ReviewerConfig mirrors runner.reviewer of the task YAML.
+type ReviewerConfig struct {
+	Kind         string `json:"kind"` // Meta プロバイダ kind または "worker"
+	Model        string `json:"model,omitempty"`
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	WorkerType   string `json:"worker_type,omitempty"`
+}
+
 // --- History Models ---
 
 type Action struct {
@@ -26,6 +26,10 @@ type StateRepository interface {
 	SaveTasks(state *TasksState) error
 	LoadAgents() (*AgentsState, error)
 	SaveAgents(state *AgentsState) error
+	LoadRetryPolicies() (*RetryPoliciesConfig, error)
+	SaveRetryPolicies(cfg *RetryPoliciesConfig) error
+	LoadRunnerSettings() (*RunnerSettingsConfig, error)
+	SaveRunnerSettings(cfg *RunnerSettingsConfig) error
 }
 
 type HistoryRepository interface {
@@ -178,6 +182,40 @@ func (r *stateRepoImpl) SaveAgents(state *AgentsState) error {
 	return writeJSON(path, state)
 }
 
+func (r *stateRepoImpl) LoadRetryPolicies() (*RetryPoliciesConfig, error) {
+	path := filepath.Join(r.baseDir, "retry-policies.json")
+	var cfg RetryPoliciesConfig
+	if err := readJSON(path, &cfg); err != nil {
+		if os.IsNotExist(err) {
+			return &RetryPoliciesConfig{Policies: []RetryPolicyConfig{}}, nil
+		}
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (r *stateRepoImpl) SaveRetryPolicies(cfg *RetryPoliciesConfig) error {
+	path := filepath.Join(r.baseDir, "retry-policies.json")
+	return writeJSON(path, cfg)
+}
+
+func (r *stateRepoImpl) LoadRunnerSettings() (*RunnerSettingsConfig, error) {
+	path := filepath.Join(r.baseDir, "runner.json")
+	var cfg RunnerSettingsConfig
+	if err := readJSON(path, &cfg); err != nil {
+		if os.IsNotExist(err) {
+			return &RunnerSettingsConfig{}, nil
+		}
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (r *stateRepoImpl) SaveRunnerSettings(cfg *RunnerSettingsConfig) error {
+	path := filepath.Join(r.baseDir, "runner.json")
+	return writeJSON(path, cfg)
+}
+
 // --- History Repo ---
 
 type historyRepoImpl struct {
@@ -0,0 +1,130 @@
+package orchestrator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+)
+
+// Task priorities. TaskState.Priority が 0 の場合は NodeDesign.Priority
+// （"low" / "medium" / "high" / "critical"）をこの値に変換して使う。
+const (
+	TaskPriorityLow      = 50
+	TaskPriorityMedium   = 100
+	TaskPriorityHigh     = 200
+	TaskPriorityCritical = 300
+)
+
+// nodePriorityValue converts a NodeDesign priority label (or a number) to a
+// task priority. 不明な値は medium として扱う。
+func nodePriorityValue(label string) int {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "low":
+		return TaskPriorityLow
+	case "high":
+		return TaskPriorityHigh
+	case "critical", "urgent":
+		return TaskPriorityCritical
+	case "", "medium", "normal":
+		return TaskPriorityMedium
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(label)); err == nil {
+		return n
+	}
+	return TaskPriorityMedium
+}
+
+// taskRanking holds what is needed to order tasks for scheduling.
+type taskRanking struct {
+	nodes map[string]*persistence.NodeDesign
+	// dependents はノードごとの、未完了タスクを持つ推移的な後続ノード数
+	dependents map[string]int
+}
+
+// rankTasks loads the node designs of tasks and counts, for each node, how
+// many not-yet-finished nodes (transitively) depend on it.
+func (s *Scheduler) rankTasks(tasks []persistence.TaskState) *taskRanking {
+	r := &taskRanking{
+		nodes:      make(map[string]*persistence.NodeDesign),
+		dependents: make(map[string]int),
+	}
+	unfinished := make(map[string]bool)
+	for i := range tasks {
+		t := &tasks[i]
+		if _, ok := r.nodes[t.NodeID]; !ok {
+			if node, err := s.Repo.Design().GetNode(t.NodeID); err == nil {
+				r.nodes[t.NodeID] = node
+			} else {
+				r.nodes[t.NodeID] = nil
+			}
+		}
+		if !isTaskFinished(TaskStatus(t.Status)) {
+			unfinished[t.NodeID] = true
+		}
+	}
+
+	// 逆向きの依存グラフ（ノード → それに依存するノード）
+	children := make(map[string][]string)
+	for id, node := range r.nodes {
+		if node == nil {
+			continue
+		}
+		for _, dep := range node.Dependencies {
+			children[dep] = append(children[dep], id)
+		}
+	}
+	for id := range r.nodes {
+		seen := map[string]bool{id: true}
+		stack := append([]string(nil), children[id]...)
+		count := 0
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			if unfinished[n] {
+				count++
+			}
+			stack = append(stack, children[n]...)
+		}
+		r.dependents[id] = count
+	}
+	return r
+}
+
+// priority returns the scheduling priority of task.
+func (r *taskRanking) priority(task *persistence.TaskState) int {
+	if task.Priority != 0 {
+		return task.Priority
+	}
+	if node := r.nodes[task.NodeID]; node != nil {
+		return nodePriorityValue(node.Priority)
+	}
+	return TaskPriorityMedium
+}
+
+// sortTasks orders tasks by priority, then dependents, then age (oldest first).
+func (r *taskRanking) sortTasks(tasks []*persistence.TaskState) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if pa, pb := r.priority(a), r.priority(b); pa != pb {
+			return pa > pb
+		}
+		if da, db := r.dependents[a.NodeID], r.dependents[b.NodeID]; da != db {
+			return da > db
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+}
+
+func isTaskFinished(status TaskStatus) bool {
+	switch status {
+	case TaskStatusSucceeded, TaskStatusCompleted, TaskStatusCanceled, TaskStatusSkipped:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/ipc"
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+)
+
+// ContainerCleaner stops the worker containers left behind by a task
+// (worker.SandboxManager implements it).
+type ContainerCleaner interface {
+	StopTaskContainers(ctx context.Context, taskID string) (int, error)
+}
+
+// containerCleanupTimeout は 1 タスク分のコンテナ停止に待つ最大時間
+const containerCleanupTimeout = 30 * time.Second
+
+// errInterrupted is recorded as the failure of an attempt that was running
+// when the orchestrator process died.
+var errInterrupted = errors.New("attempt interrupted: orchestrator stopped while the task was running")
+
+// RecoveryAction is what the recovery pass did with an orphaned job or task.
+type RecoveryAction string
+
+const (
+	RecoveryActionRequeued RecoveryAction = "requeued" // 実行前のジョブをキューに戻した
+	RecoveryActionRetry    RecoveryAction = "retry"    // 中断された試行を失敗として RETRY_WAIT にした
+	RecoveryActionBacklog  RecoveryAction = "backlog"  // リトライ上限のためバックログに回した
+	RecoveryActionFailed   RecoveryAction = "failed"   // 恒久的な失敗にした
+	RecoveryActionDropped  RecoveryAction = "dropped"  // タスクが無い・終了済みのジョブを破棄した
+)
+
+// RecoveredTask describes one orphaned job or RUNNING task handled by RecoverOrphans.
+type RecoveredTask struct {
+	TaskID            string         `json:"taskId"`
+	JobID             string         `json:"jobId,omitempty"`
+	PoolID            string         `json:"poolId,omitempty"`
+	Action            RecoveryAction `json:"action"`
+	ContainersStopped int            `json:"containersStopped,omitempty"`
+}
+
+// RecoverOrphans handles jobs left in ipc/processing and RUNNING tasks that
+// have no live owner, e.g. after the orchestrator crashed.
+// 実行前のジョブはキューに戻し、中断された試行は失敗として RetryPolicy に従って
+// リトライ・バックログ・失敗のいずれかにする（コンテナは停止する）。
+// 他のプロセスが有効なリースを持つジョブとそのタスクは対象外。
+func (e *ExecutionOrchestrator) RecoverOrphans(ctx context.Context) ([]RecoveredTask, error) {
+	if e.Repo == nil || e.Queue == nil {
+		return nil, nil
+	}
+
+	// このプロセスが実行中（取り出し済み）のジョブとタスクは対象外
+	e.cancelMu.Lock()
+	ownedJobs := make(map[string]bool, len(e.claimed))
+	ownedTasks := make(map[string]bool, len(e.running)+len(e.claimed))
+	for jobID, taskID := range e.claimed {
+		ownedJobs[jobID] = true
+		ownedTasks[taskID] = true
+	}
+	for taskID := range e.running {
+		ownedTasks[taskID] = true
+	}
+	e.cancelMu.Unlock()
+
+	jobs, err := e.Queue.ProcessingJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	var recovered []RecoveredTask
+	var interrupted []persistence.TaskState
+	interruptedJobs := make(map[string]*ipc.Job)
+
+	mu := tasksStateLock(e.Repo)
+	mu.Lock()
+	tasksState, err := e.Repo.State().LoadTasks()
+	if err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to load tasks state: %w", err)
+	}
+
+	// 同じワークスペースを実行中の他プロセスのジョブ（リースが有効なもの）も除外する
+	for _, job := range jobs {
+		if e.Queue.LeasedByOther(job) {
+			ownedJobs[job.ID] = true
+			ownedTasks[job.TaskID] = true
+		}
+	}
+
+	for _, job := range jobs {
+		if ownedJobs[job.ID] || ownedTasks[job.TaskID] {
+			continue
+		}
+		task := findTaskState(tasksState, job.TaskID)
+		switch {
+		case task == nil || isTaskFinished(TaskStatus(task.Status)):
+			if err := e.Queue.Complete(job.ID, job.PoolID); err != nil {
+				e.logger.Warn("failed to drop orphaned job", slog.String("job_id", job.ID), slog.Any("error", err))
+				continue
+			}
+			recovered = append(recovered, RecoveredTask{TaskID: job.TaskID, JobID: job.ID, PoolID: job.PoolID, Action: RecoveryActionDropped})
+		case TaskStatus(task.Status) == TaskStatusRunning:
+			// 中断された試行として下で処理する。リトライは Scheduler が改めてキューに入れる
+			if err := e.Queue.Complete(job.ID, job.PoolID); err != nil {
+				e.logger.Warn("failed to remove interrupted job", slog.String("job_id", job.ID), slog.Any("error", err))
+			}
+			interruptedJobs[job.TaskID] = job
+		default:
+			// 取り出された直後（RUNNING にする前）に中断されたジョブ
+			if err := e.Queue.Requeue(job.ID, job.PoolID); err != nil {
+				e.logger.Warn("failed to requeue orphaned job", slog.String("job_id", job.ID), slog.Any("error", err))
+				continue
+			}
+			recovered = append(recovered, RecoveredTask{TaskID: job.TaskID, JobID: job.ID, PoolID: job.PoolID, Action: RecoveryActionRequeued})
+		}
+	}
+
+	for i := range tasksState.Tasks {
+		task := &tasksState.Tasks[i]
+		if TaskStatus(task.Status) != TaskStatusRunning || ownedTasks[task.TaskID] {
+			continue
+		}
+		task.Status = string(TaskStatusFailed)
+		task.UpdatedAt = time.Now()
+		if task.Inputs == nil {
+			task.Inputs = make(map[string]interface{})
+		}
+		task.Inputs[InputKeyLastError] = errInterrupted.Error()
+		interrupted = append(interrupted, *task)
+	}
+	if len(interrupted) > 0 {
+		if err := e.Repo.State().SaveTasks(tasksState); err != nil {
+			mu.Unlock()
+			return recovered, fmt.Errorf("failed to save interrupted tasks: %w", err)
+		}
+		for _, task := range interrupted {
+			e.emitTaskStateChange(task.TaskID, TaskStatusRunning, TaskStatusFailed)
+		}
+	}
+	mu.Unlock()
+
+	for i := range interrupted {
+		task := &interrupted[i]
+		rt := RecoveredTask{TaskID: task.TaskID, Action: RecoveryActionFailed}
+		if job := interruptedJobs[task.TaskID]; job != nil {
+			rt.JobID, rt.PoolID = job.ID, job.PoolID
+		}
+		rt.ContainersStopped = e.stopTaskContainers(ctx, task.TaskID)
+
+		attempts := inputAttemptCount(task.Inputs)
+		switch _, _, action := e.failureAction(task, errInterrupted, attempts); action {
+		case NextActionRetry:
+			rt.Action = RecoveryActionRetry
+		case NextActionBacklog:
+			rt.Action = RecoveryActionBacklog
+		}
+		e.updateLegacyTask(task.TaskID, func(t *Task) {
+			t.Status = TaskStatusFailed
+		})
+		if err := e.HandleFailure(task, errInterrupted, attempts); err != nil {
+			e.logger.Error("failed to handle interrupted task", slog.String("task_id", task.TaskID), slog.Any("error", err))
+		}
+		recovered = append(recovered, rt)
+	}
+
+	if len(recovered) == 0 {
+		return nil, nil
+	}
+	for _, rt := range recovered {
+		e.logger.Warn("recovered orphaned task",
+			slog.String("task_id", rt.TaskID),
+			slog.String("job_id", rt.JobID),
+			slog.String("action", string(rt.Action)),
+			slog.Int("containers_stopped", rt.ContainersStopped),
+		)
+	}
+	if e.EventEmitter != nil {
+		e.EventEmitter.Emit(EventExecutionRecovery, ExecutionRecoveryEvent{
+			Recovered: recovered,
+			Timestamp: time.Now(),
+		})
+	}
+	return recovered, nil
+}
+
+// stopTaskContainers stops the containers left behind by taskID, returning
+// how many were stopped. 失敗はログに残して続行する。
+func (e *ExecutionOrchestrator) stopTaskContainers(ctx context.Context, taskID string) int {
+	if e.Containers == nil {
+		return 0
+	}
+	cleanupCtx, cancel := context.WithTimeout(ctx, containerCleanupTimeout)
+	defer cancel()
+	stopped, err := e.Containers.StopTaskContainers(cleanupCtx, taskID)
+	if err != nil {
+		e.logger.Warn("failed to stop containers of interrupted task",
+			slog.String("task_id", taskID),
+			slog.Any("error", err),
+		)
+	}
+	return stopped
+}
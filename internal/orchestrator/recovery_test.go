@@ -0,0 +1,160 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/ipc"
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeContainerCleaner struct {
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (f *fakeContainerCleaner) StopTaskContainers(ctx context.Context, taskID string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = append(f.stopped, taskID)
+	return 1, nil
+}
+
+func claimJob(t *testing.T, queue *ipc.FilesystemQueue, job *ipc.Job) {
+	t.Helper()
+	require.NoError(t, queue.Enqueue(job))
+	claimed, err := queue.Dequeue(job.PoolID)
+	require.NoError(t, err)
+	require.Equal(t, job.ID, claimed.ID)
+}
+
+func TestExecutionOrchestrator_RecoverOrphans(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	now := time.Now()
+	saveDesign(t, repo, []persistence.NodeDesign{
+		{NodeID: "node-run"}, {NodeID: "node-ready"}, {NodeID: "node-done"}, {NodeID: "node-exhausted"},
+	})
+	saveState(t, repo, []persistence.TaskState{
+		{TaskID: "task-run", NodeID: "node-run", Status: string(TaskStatusRunning), CreatedAt: now,
+			Inputs: map[string]interface{}{InputKeyAttemptCount: 1}},
+		{TaskID: "task-ready", NodeID: "node-ready", Status: string(TaskStatusReady), CreatedAt: now},
+		{TaskID: "task-done", NodeID: "node-done", Status: string(TaskStatusSucceeded), CreatedAt: now},
+		{TaskID: "task-exhausted", NodeID: "node-exhausted", Status: string(TaskStatusRunning), CreatedAt: now,
+			Inputs: map[string]interface{}{InputKeyAttemptCount: 3}},
+	}, nil)
+	claimJob(t, queue, &ipc.Job{ID: "job-run", TaskID: "task-run", PoolID: "default"})
+	claimJob(t, queue, &ipc.Job{ID: "job-ready", TaskID: "task-ready", PoolID: "default"})
+	claimJob(t, queue, &ipc.Job{ID: "job-done", TaskID: "task-done", PoolID: "codegen"})
+
+	emitter := new(MockEventEmitter)
+	emitter.On("Emit", mock.Anything, mock.Anything).Return()
+	cleaner := &fakeContainerCleaner{}
+	orch := NewExecutionOrchestrator(NewScheduler(repo, queue, emitter), nil, repo, queue, emitter, nil, []string{"default"})
+	orch.Containers = cleaner
+
+	recovered, err := orch.RecoverOrphans(context.Background())
+	require.NoError(t, err)
+
+	actions := map[string]RecoveryAction{}
+	for _, rt := range recovered {
+		actions[rt.TaskID] = rt.Action
+	}
+	assert.Equal(t, map[string]RecoveryAction{
+		"task-run":       RecoveryActionRetry,
+		"task-ready":     RecoveryActionRequeued,
+		"task-done":      RecoveryActionDropped,
+		"task-exhausted": RecoveryActionBacklog,
+	}, actions)
+	assert.ElementsMatch(t, []string{"task-run", "task-exhausted"}, cleaner.stopped)
+
+	tasks := loadStatuses(t, repo)
+	assert.Equal(t, string(TaskStatusRetryWait), tasks["task-run"].Status)
+	assert.Equal(t, errInterrupted.Error(), tasks["task-run"].Inputs[InputKeyLastError])
+	assert.Equal(t, string(TaskStatusReady), tasks["task-ready"].Status)
+	assert.Equal(t, string(TaskStatusFailed), tasks["task-exhausted"].Status)
+	assert.Equal(t, true, tasks["task-exhausted"].Inputs[InputKeyFailedPermanently])
+
+	// 処理中ディレクトリは空になり、未実行のジョブだけがキューに戻る
+	processing, err := queue.ProcessingJobs()
+	require.NoError(t, err)
+	assert.Empty(t, processing)
+	queued, err := queue.ListJobs("default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"job-ready"}, queued)
+
+	assertStateChange(t, emitter, "task-run", TaskStatusRunning, TaskStatusFailed)
+	emitter.AssertCalled(t, "Emit", EventExecutionRecovery, mock.AnythingOfType("ExecutionRecoveryEvent"))
+}
+
+func TestExecutionOrchestrator_RecoverOrphans_SkipsOwnedJobs(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	setupParallelTasks(t, repo, queue, "task-1")
+
+	exec := newBlockingExecutor()
+	orch := NewExecutionOrchestrator(nil, exec, repo, queue, nil, nil, []string{"default"})
+	orch.dispatchJobs(context.Background(), "default")
+	waitStarted(t, exec, 1)
+
+	// 実行中のジョブはこのプロセスが所有しているので回収しない
+	recovered, err := orch.RecoverOrphans(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, recovered)
+	assert.Equal(t, string(TaskStatusRunning), loadStatuses(t, repo)["task-1"].Status)
+
+	close(exec.release)
+	orch.jobs.Wait()
+	assert.Equal(t, string(TaskStatusSucceeded), loadStatuses(t, repo)["task-1"].Status)
+}
+
+func TestExecutionOrchestrator_RecoverOrphans_SkipsJobsLeasedByOtherProcess(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	setupParallelTasks(t, repo, queue, "task-1")
+
+	// 同じワークスペースを実行中の別プロセスが取り出して RUNNING にしたタスク
+	other := ipc.NewFilesystemQueue(queue.WorkspaceDir)
+	job, err := other.Dequeue("default")
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	state, err := repo.State().LoadTasks()
+	require.NoError(t, err)
+	state.Tasks[0].Status = string(TaskStatusRunning)
+	require.NoError(t, repo.State().SaveTasks(state))
+
+	orch := NewExecutionOrchestrator(nil, nil, repo, queue, nil, nil, []string{"default"})
+	recovered, err := orch.RecoverOrphans(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, recovered)
+	assert.Equal(t, string(TaskStatusRunning), loadStatuses(t, repo)["task-1"].Status)
+
+	processing, err := queue.ProcessingJobs()
+	require.NoError(t, err)
+	require.Len(t, processing, 1)
+	assert.Equal(t, other.OwnerID, processing[0].Lease.Owner)
+}
+
+func TestExecutionOrchestrator_LeaseLostCancelsJob(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	queue.LeaseDuration = 30 * time.Millisecond
+	setupParallelTasks(t, repo, queue, "task-1")
+
+	exec := newBlockingExecutor()
+	orch := NewExecutionOrchestrator(nil, exec, repo, queue, nil, nil, []string{"default"})
+	orch.dispatchJobs(context.Background(), "default")
+	waitStarted(t, exec, 1)
+
+	// 他のプロセスがジョブを取り上げた（処理中ファイルが消えた）とみなす
+	require.NoError(t, os.Remove(filepath.Join(queue.GetProcessingDir("default"), "job-task-1.json")))
+	orch.jobs.Wait()
+
+	assert.Equal(t, []string{"task-1"}, exec.canceled)
+	// 結果は書き込まず、次の所有者に任せる
+	assert.Equal(t, string(TaskStatusRunning), loadStatuses(t, repo)["task-1"].Status)
+	assert.Empty(t, orch.RunningTasks())
+}
@@ -1,17 +1,29 @@
 package orchestrator
 
 import (
+	"errors"
 	"math"
+	"regexp"
+	"slices"
 	"time"
 )
 
 // RetryPolicy はタスク失敗時のリトライポリシーを定義する
 type RetryPolicy struct {
+	Name          string
 	MaxAttempts   int           // 最大試行回数（デフォルト: 3）
 	BackoffBase   time.Duration // バックオフ基準時間（デフォルト: 5秒）
 	BackoffMax    time.Duration // バックオフ最大時間（デフォルト: 5分）
 	BackoffFactor float64       // バックオフ乗数（デフォルト: 2.0）
 	RequireHuman  bool          // 最大試行後に人間判断を要求するか
+
+	// TaskKinds / Pools はポリシーを適用するタスク種別とプール。空なら限定しない
+	TaskKinds []string
+	Pools     []string
+	// Classifiers は失敗の分類ルール。組み込みのルール（DefaultErrorClassifiers）より先に評価する
+	Classifiers []ErrorClassifier
+	// Escalations はリトライ時に実行方法を変える設定（FromAttempt の昇順でなくてもよい）
+	Escalations []RetryEscalation
 }
 
 // DefaultRetryPolicy はデフォルトのリトライポリシーを返す
@@ -70,3 +82,206 @@ func (p *RetryPolicy) DetermineNextAction(attemptNumber int) NextAction {
 
 	return NextActionFail
 }
+
+// ErrorClass is the category of a task failure that decides how it is retried.
+type ErrorClass string
+
+const (
+	ErrorClassTransient  ErrorClass = "transient"   // 一時的な失敗: ポリシーに従ってリトライする
+	ErrorClassPermanent  ErrorClass = "permanent"   // リトライしても直らない: 即座に失敗とする
+	ErrorClassNeedsHuman ErrorClass = "needs_human" // 人間の対応が必要: 即座にバックログへ
+)
+
+// Valid reports whether c is a known error class.
+func (c ErrorClass) Valid() bool {
+	switch c {
+	case ErrorClassTransient, ErrorClassPermanent, ErrorClassNeedsHuman:
+		return true
+	}
+	return false
+}
+
+// Failure describes a failed attempt for error classification.
+type Failure struct {
+	ExitCode    *int   // agent-runner の終了コード（不明なら nil）
+	ResultState string // agent-runner の結果ファイルに記録されたタスクの状態（例: FAILED）
+	Message     string // エラーメッセージと出力
+}
+
+// failureFromError extracts the classification inputs from an execution error.
+func failureFromError(err error) Failure {
+	if err == nil {
+		return Failure{}
+	}
+	f := Failure{Message: err.Error()}
+	var execErr *ExecutionError
+	if errors.As(err, &execErr) {
+		if execErr.ExitCode >= 0 {
+			code := execErr.ExitCode
+			f.ExitCode = &code
+		}
+		f.ResultState = execErr.ResultState
+		if execErr.Output != "" {
+			f.Message += "\n" + execErr.Output
+		}
+	}
+	return f
+}
+
+// ErrorClassifier assigns Class to failures matching all of its non-empty
+// conditions. 各条件のリストはいずれかに一致すればよい。条件が空の分類ルールは何にも一致しない。
+type ErrorClassifier struct {
+	Class        ErrorClass
+	ExitCodes    []int
+	ResultStates []string
+	Patterns     []*regexp.Regexp // Failure.Message に対する正規表現
+}
+
+// Matches reports whether f satisfies the classifier.
+func (c ErrorClassifier) Matches(f Failure) bool {
+	if !c.hasConditions() {
+		return false
+	}
+	if len(c.ExitCodes) > 0 && (f.ExitCode == nil || !slices.Contains(c.ExitCodes, *f.ExitCode)) {
+		return false
+	}
+	if len(c.ResultStates) > 0 && !slices.Contains(c.ResultStates, f.ResultState) {
+		return false
+	}
+	if len(c.Patterns) > 0 && !slices.ContainsFunc(c.Patterns, func(re *regexp.Regexp) bool {
+		return re.MatchString(f.Message)
+	}) {
+		return false
+	}
+	return true
+}
+
+func (c ErrorClassifier) hasConditions() bool {
+	return len(c.ExitCodes) > 0 || len(c.ResultStates) > 0 || len(c.Patterns) > 0
+}
+
+// DefaultErrorClassifiers returns the built-in rules evaluated after the
+// policy's own classifiers.
+func DefaultErrorClassifiers() []ErrorClassifier {
+	return []ErrorClassifier{
+		{
+			// Docker イメージが無い・取得できない
+			Class:    ErrorClassPermanent,
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)no such image|pull access denied|manifest unknown|repository does not exist`)},
+		},
+		{
+			// agent-runner や worker CLI が見つからない
+			Class:     ErrorClassPermanent,
+			ExitCodes: []int{126, 127},
+		},
+		{
+			Class:    ErrorClassPermanent,
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)executable file not found|failed to marshal task config`)},
+		},
+		{
+			// 認証情報の不足・期限切れは人間が直す必要がある
+			Class:    ErrorClassNeedsHuman,
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)invalid api key|unauthorized|authentication (failed|required)|not logged in`)},
+		},
+	}
+}
+
+// Classify returns the class of f. ポリシーの分類ルール、組み込みのルールの順に評価し、
+// どれにも一致しなければ transient とする。
+func (p *RetryPolicy) Classify(f Failure) ErrorClass {
+	for _, c := range p.Classifiers {
+		if c.Matches(f) {
+			return c.Class
+		}
+	}
+	for _, c := range DefaultErrorClassifiers() {
+		if c.Matches(f) {
+			return c.Class
+		}
+	}
+	return ErrorClassTransient
+}
+
+// NextActionFor decides the next action from the error class and the attempt
+// number. transient 以外はリトライしない。
+func (p *RetryPolicy) NextActionFor(class ErrorClass, attemptNumber int) NextAction {
+	switch class {
+	case ErrorClassPermanent:
+		return NextActionFail
+	case ErrorClassNeedsHuman:
+		return NextActionBacklog
+	default:
+		return p.DetermineNextAction(attemptNumber)
+	}
+}
+
+// RetryEscalation changes how a task runs from FromAttempt on (the attempt
+// number of the retry, starting at 2). 空のフィールドは変更しない。
+type RetryEscalation struct {
+	FromAttempt     int
+	WorkerKind      string
+	ReasoningEffort string
+	MaxLoops        int
+}
+
+// EscalationFor merges the escalations that apply to attempt, later
+// (higher FromAttempt) ones taking precedence. 該当するものが無ければ nil を返す。
+func (p *RetryPolicy) EscalationFor(attempt int) *RetryEscalation {
+	applicable := make([]RetryEscalation, 0, len(p.Escalations))
+	for _, esc := range p.Escalations {
+		if esc.FromAttempt <= attempt {
+			applicable = append(applicable, esc)
+		}
+	}
+	if len(applicable) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(applicable, func(a, b RetryEscalation) int { return a.FromAttempt - b.FromAttempt })
+
+	merged := &RetryEscalation{}
+	for _, esc := range applicable {
+		merged.FromAttempt = esc.FromAttempt
+		if esc.WorkerKind != "" {
+			merged.WorkerKind = esc.WorkerKind
+		}
+		if esc.ReasoningEffort != "" {
+			merged.ReasoningEffort = esc.ReasoningEffort
+		}
+		if esc.MaxLoops > 0 {
+			merged.MaxLoops = esc.MaxLoops
+		}
+	}
+	return merged
+}
+
+// specificity returns how specifically p targets a task of kind in pool:
+// -1 なら対象外、種別とプールの両方で限定 > 種別のみ > プールのみ > 限定なし の順に大きい。
+func (p *RetryPolicy) specificity(kind, pool string) int {
+	score := 0
+	if len(p.TaskKinds) > 0 {
+		if !slices.Contains(p.TaskKinds, kind) {
+			return -1
+		}
+		score += 2
+	}
+	if len(p.Pools) > 0 {
+		if !slices.Contains(p.Pools, pool) {
+			return -1
+		}
+		score++
+	}
+	return score
+}
+
+// SelectRetryPolicy returns the most specific policy for a task of kind in
+// pool (the first one on ties), or nil when none applies.
+func SelectRetryPolicy(policies []*RetryPolicy, kind, pool string) *RetryPolicy {
+	var best *RetryPolicy
+	bestScore := -1
+	for _, p := range policies {
+		if score := p.specificity(kind, pool); score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
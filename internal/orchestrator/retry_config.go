@@ -0,0 +1,102 @@
+package orchestrator
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+)
+
+// LoadRetryPolicies reads the workspace retry policies
+// (state/retry-policies.json). ファイルが無ければ空のリストを返す。
+func LoadRetryPolicies(repo persistence.WorkspaceRepository) ([]*RetryPolicy, error) {
+	cfg, err := repo.State().LoadRetryPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load retry policies: %w", err)
+	}
+	policies := make([]*RetryPolicy, 0, len(cfg.Policies))
+	for i, pc := range cfg.Policies {
+		p, err := RetryPolicyFromConfig(pc)
+		if err != nil {
+			name := pc.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			return nil, fmt.Errorf("invalid retry policy %s: %w", name, err)
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// RetryPolicyFromConfig converts a retry-policies.json entry. 未指定の項目は
+// DefaultRetryPolicy の値を使う。
+func RetryPolicyFromConfig(cfg persistence.RetryPolicyConfig) (*RetryPolicy, error) {
+	p := DefaultRetryPolicy()
+	p.Name = cfg.Name
+	p.TaskKinds = cfg.TaskKinds
+	p.Pools = cfg.Pools
+	if cfg.MaxAttempts > 0 {
+		p.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.BackoffFactor > 0 {
+		p.BackoffFactor = cfg.BackoffFactor
+	}
+	if cfg.RequireHuman != nil {
+		p.RequireHuman = *cfg.RequireHuman
+	}
+	var err error
+	if p.BackoffBase, err = parseDurationOr(cfg.BackoffBase, p.BackoffBase); err != nil {
+		return nil, fmt.Errorf("invalid backoff_base: %w", err)
+	}
+	if p.BackoffMax, err = parseDurationOr(cfg.BackoffMax, p.BackoffMax); err != nil {
+		return nil, fmt.Errorf("invalid backoff_max: %w", err)
+	}
+
+	for i, cc := range cfg.Classifiers {
+		class := ErrorClass(cc.Class)
+		if !class.Valid() {
+			return nil, fmt.Errorf("classifier %d: unknown class %q", i, cc.Class)
+		}
+		c := ErrorClassifier{Class: class, ExitCodes: cc.ExitCodes, ResultStates: cc.ResultStates}
+		for _, pattern := range cc.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("classifier %d: invalid pattern %q: %w", i, pattern, err)
+			}
+			c.Patterns = append(c.Patterns, re)
+		}
+		if !c.hasConditions() {
+			return nil, fmt.Errorf("classifier %d: no exit_codes, result_states or patterns", i)
+		}
+		p.Classifiers = append(p.Classifiers, c)
+	}
+
+	for i, ec := range cfg.Escalations {
+		if ec.FromAttempt < 2 {
+			return nil, fmt.Errorf("escalation %d: from_attempt must be at least 2 (the first retry)", i)
+		}
+		p.Escalations = append(p.Escalations, RetryEscalation{
+			FromAttempt:     ec.FromAttempt,
+			WorkerKind:      ec.WorkerKind,
+			ReasoningEffort: ec.ReasoningEffort,
+			MaxLoops:        ec.MaxLoops,
+		})
+	}
+	return p, nil
+}
+
+func parseDurationOr(s string, fallback time.Duration) (time.Duration, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive: %s", s)
+	}
+	return d, nil
+}
@@ -1,10 +1,14 @@
 package orchestrator
 
 import (
+	"errors"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaultRetryPolicy(t *testing.T) {
@@ -147,3 +151,114 @@ func TestRetryPolicy_DetermineNextAction(t *testing.T) {
 		assert.Equal(t, NextActionFail, policy.DetermineNextAction(4))
 	})
 }
+
+func TestRetryPolicy_Classify(t *testing.T) {
+	exitCode := func(n int) *int { return &n }
+	policy := DefaultRetryPolicy()
+	policy.Classifiers = []ErrorClassifier{
+		{Class: ErrorClassNeedsHuman, ExitCodes: []int{3}, ResultStates: []string{"FAILED"}},
+		{Class: ErrorClassPermanent, Patterns: []*regexp.Regexp{regexp.MustCompile(`compile error`)}},
+	}
+
+	tests := []struct {
+		name    string
+		failure Failure
+		want    ErrorClass
+	}{
+		{"policy rule with all conditions", Failure{ExitCode: exitCode(3), ResultState: "FAILED"}, ErrorClassNeedsHuman},
+		{"partial match falls through", Failure{ExitCode: exitCode(3), ResultState: "PLANNING"}, ErrorClassTransient},
+		{"policy pattern", Failure{Message: "go build: compile error"}, ErrorClassPermanent},
+		{"built-in missing image", Failure{Message: "Error: No such image: worker:latest"}, ErrorClassPermanent},
+		{"built-in command not found", Failure{ExitCode: exitCode(127)}, ErrorClassPermanent},
+		{"built-in auth", Failure{Message: "401 Unauthorized"}, ErrorClassNeedsHuman},
+		{"unmatched is transient", Failure{Message: "LLM request timed out"}, ErrorClassTransient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Classify(tt.failure))
+		})
+	}
+}
+
+func TestRetryPolicy_NextActionFor(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, RequireHuman: true}
+
+	assert.Equal(t, NextActionRetry, policy.NextActionFor(ErrorClassTransient, 1))
+	assert.Equal(t, NextActionBacklog, policy.NextActionFor(ErrorClassTransient, 3))
+	assert.Equal(t, NextActionFail, policy.NextActionFor(ErrorClassPermanent, 1))
+	assert.Equal(t, NextActionBacklog, policy.NextActionFor(ErrorClassNeedsHuman, 1))
+}
+
+func TestFailureFromError(t *testing.T) {
+	assert.Equal(t, Failure{Message: "boom"}, failureFromError(errors.New("boom")))
+
+	f := failureFromError(&ExecutionError{Err: errors.New("exit status 2"), ExitCode: 2, ResultState: "FAILED", Output: "details"})
+	require.NotNil(t, f.ExitCode)
+	assert.Equal(t, 2, *f.ExitCode)
+	assert.Equal(t, "FAILED", f.ResultState)
+	assert.Equal(t, "exit status 2\ndetails", f.Message)
+
+	// 起動できなかった場合は終了コードが無い
+	assert.Nil(t, failureFromError(&ExecutionError{Err: errors.New("not found"), ExitCode: -1}).ExitCode)
+}
+
+func TestRetryPolicy_EscalationFor(t *testing.T) {
+	policy := &RetryPolicy{Escalations: []RetryEscalation{
+		{FromAttempt: 3, WorkerKind: "claude-code"},
+		{FromAttempt: 2, ReasoningEffort: "high", MaxLoops: 8},
+	}}
+
+	assert.Nil(t, policy.EscalationFor(1))
+	assert.Equal(t, &RetryEscalation{FromAttempt: 2, ReasoningEffort: "high", MaxLoops: 8}, policy.EscalationFor(2))
+	assert.Equal(t, &RetryEscalation{FromAttempt: 3, WorkerKind: "claude-code", ReasoningEffort: "high", MaxLoops: 8}, policy.EscalationFor(4))
+}
+
+func TestSelectRetryPolicy(t *testing.T) {
+	all := &RetryPolicy{Name: "all"}
+	pool := &RetryPolicy{Name: "pool", Pools: []string{"gpu"}}
+	kind := &RetryPolicy{Name: "kind", TaskKinds: []string{"test"}}
+	both := &RetryPolicy{Name: "both", TaskKinds: []string{"test"}, Pools: []string{"gpu"}}
+	policies := []*RetryPolicy{all, pool, kind, both}
+
+	assert.Equal(t, both, SelectRetryPolicy(policies, "test", "gpu"))
+	assert.Equal(t, kind, SelectRetryPolicy(policies, "test", "default"))
+	assert.Equal(t, pool, SelectRetryPolicy(policies, "implementation", "gpu"))
+	assert.Equal(t, all, SelectRetryPolicy(policies, "implementation", "default"))
+	assert.Nil(t, SelectRetryPolicy([]*RetryPolicy{kind}, "implementation", "default"))
+}
+
+func TestRetryPolicyFromConfig(t *testing.T) {
+	requireHuman := false
+	policy, err := RetryPolicyFromConfig(persistence.RetryPolicyConfig{
+		Name:         "tests",
+		TaskKinds:    []string{"test"},
+		MaxAttempts:  5,
+		BackoffBase:  "1s",
+		RequireHuman: &requireHuman,
+		Classifiers: []persistence.ErrorClassifierConfig{
+			{Class: "permanent", ExitCodes: []int{2}, Patterns: []string{`(?i)syntax error`}},
+		},
+		Escalations: []persistence.RetryEscalationConfig{{FromAttempt: 2, ReasoningEffort: "high"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "tests", policy.Name)
+	assert.Equal(t, 5, policy.MaxAttempts)
+	assert.Equal(t, time.Second, policy.BackoffBase)
+	assert.Equal(t, 5*time.Minute, policy.BackoffMax, "unset fields use the default policy")
+	assert.False(t, policy.RequireHuman)
+	require.Len(t, policy.Classifiers, 1)
+	assert.Equal(t, ErrorClassPermanent, policy.Classifiers[0].Class)
+	assert.Equal(t, []RetryEscalation{{FromAttempt: 2, ReasoningEffort: "high"}}, policy.Escalations)
+
+	invalid := []persistence.RetryPolicyConfig{
+		{BackoffBase: "soon"},
+		{Classifiers: []persistence.ErrorClassifierConfig{{Class: "flaky", ExitCodes: []int{1}}}},
+		{Classifiers: []persistence.ErrorClassifierConfig{{Class: "permanent"}}},
+		{Classifiers: []persistence.ErrorClassifierConfig{{Class: "permanent", Patterns: []string{"("}}}},
+		{Escalations: []persistence.RetryEscalationConfig{{FromAttempt: 1, WorkerKind: "claude-code"}}},
+	}
+	for _, cfg := range invalid {
+		_, err := RetryPolicyFromConfig(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
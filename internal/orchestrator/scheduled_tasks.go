@@ -0,0 +1,327 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+)
+
+// TaskSchedule is when a scheduled task runs: once at RunAt, or repeatedly
+// at the times matched by the cron expression Cron. どちらか一方だけを指定する。
+type TaskSchedule struct {
+	RunAt *time.Time `json:"runAt,omitempty"`
+	Cron  string     `json:"cron,omitempty"`
+}
+
+// ScheduleRun is one triggered run of a scheduled task, kept in
+// inputs.schedule_runs (newest last, at most maxScheduleRunHistory entries).
+type ScheduleRun struct {
+	Run         int        `json:"run"`
+	TriggeredAt time.Time  `json:"triggeredAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	Status      TaskStatus `json:"status,omitempty"`
+	Attempts    int        `json:"attempts,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+// maxScheduleRunHistory は tasks.json に残す実行履歴の件数
+const maxScheduleRunHistory = 20
+
+// next returns the first run time of the schedule after after.
+// RunAt が過去の場合はそのまま返す（次の周期で即座に実行される）。
+func (sch TaskSchedule) next(after time.Time) (time.Time, error) {
+	if sch.Cron != "" {
+		cron, err := ParseCron(sch.Cron)
+		if err != nil {
+			return time.Time{}, err
+		}
+		next := cron.Next(after)
+		if next.IsZero() {
+			return time.Time{}, fmt.Errorf("cron expression %q never fires", sch.Cron)
+		}
+		return next, nil
+	}
+	return *sch.RunAt, nil
+}
+
+func (sch TaskSchedule) validate() error {
+	hasRunAt := sch.RunAt != nil && !sch.RunAt.IsZero()
+	switch {
+	case hasRunAt && sch.Cron != "":
+		return fmt.Errorf("schedule must have either runAt or cron, not both")
+	case !hasRunAt && sch.Cron == "":
+		return fmt.Errorf("schedule must have runAt or cron")
+	}
+	return nil
+}
+
+// TaskScheduleFromInputs returns the schedule stored in task inputs, or nil.
+func TaskScheduleFromInputs(inputs map[string]interface{}) *TaskSchedule {
+	if cron, _ := inputs[InputKeyScheduleCron].(string); cron != "" {
+		return &TaskSchedule{Cron: cron}
+	}
+	if runAt := inputTime(inputs, InputKeyScheduleRunAt); !runAt.IsZero() {
+		return &TaskSchedule{RunAt: &runAt}
+	}
+	return nil
+}
+
+// inputTime returns the RFC3339 time stored at inputs[key], or the zero time.
+func inputTime(inputs map[string]interface{}, key string) time.Time {
+	val, _ := inputs[key].(string)
+	if val == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// isDue reports whether the time stored at inputs[key] has been reached.
+// 時刻が無い（または読めない）場合も到達済みとみなす。
+func isDue(inputs map[string]interface{}, key string, now time.Time) bool {
+	at := inputTime(inputs, key)
+	return at.IsZero() || !now.Before(at)
+}
+
+// ScheduleRunHistory returns the run history stored in task inputs.
+func ScheduleRunHistory(inputs map[string]interface{}) []ScheduleRun {
+	raw, ok := inputs[InputKeyScheduleRuns]
+	if !ok {
+		return nil
+	}
+	// JSON から読み込んだ後は []interface{} になっているため、JSON を経由して変換する
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var runs []ScheduleRun
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil
+	}
+	return runs
+}
+
+func setScheduleRunHistory(inputs map[string]interface{}, runs []ScheduleRun) {
+	if len(runs) > maxScheduleRunHistory {
+		runs = runs[len(runs)-maxScheduleRunHistory:]
+	}
+	inputs[InputKeyScheduleRuns] = runs
+}
+
+// isScheduleRunFinished reports whether a triggered run of task has ended:
+// it succeeded, was skipped or canceled, or failed permanently.
+func isScheduleRunFinished(task *persistence.TaskState) bool {
+	switch TaskStatus(task.Status) {
+	case TaskStatusSucceeded, TaskStatusCompleted, TaskStatusSkipped, TaskStatusCanceled:
+		return true
+	}
+	return isPermanentlyFailed(task)
+}
+
+// SetTaskSchedule attaches schedule to taskID and moves it to SCHEDULED until
+// the first run time, which is returned. 実行中・キュー投入済みのタスクには設定できない。
+func (s *Scheduler) SetTaskSchedule(taskID string, schedule TaskSchedule) (time.Time, error) {
+	if err := schedule.validate(); err != nil {
+		return time.Time{}, err
+	}
+	nextRunAt, err := schedule.next(time.Now())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	mu := tasksStateLock(s.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+
+	tasksState, err := s.Repo.State().LoadTasks()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load tasks state: %w", err)
+	}
+	task := findTaskState(tasksState, taskID)
+	if task == nil {
+		return time.Time{}, fmt.Errorf("task not found: %s", taskID)
+	}
+	oldStatus := TaskStatus(task.Status)
+	switch oldStatus {
+	case TaskStatusRunning, TaskStatusReady:
+		return time.Time{}, fmt.Errorf("task %s is %s and cannot be scheduled", taskID, oldStatus)
+	}
+
+	if task.Inputs == nil {
+		task.Inputs = make(map[string]interface{})
+	}
+	clearTaskControlInputs(task)
+	delete(task.Inputs, InputKeyScheduleRunAt)
+	delete(task.Inputs, InputKeyScheduleCron)
+	if schedule.Cron != "" {
+		task.Inputs[InputKeyScheduleCron] = schedule.Cron
+	} else {
+		task.Inputs[InputKeyScheduleRunAt] = schedule.RunAt.Format(time.RFC3339)
+	}
+	task.Inputs[InputKeyNextRunAt] = nextRunAt.Format(time.RFC3339)
+	task.Status = string(TaskStatusScheduled)
+	task.UpdatedAt = time.Now()
+	if err := s.Repo.State().SaveTasks(tasksState); err != nil {
+		return time.Time{}, fmt.Errorf("failed to save task schedule: %w", err)
+	}
+	if oldStatus != TaskStatusScheduled {
+		s.emitStateChange(taskID, oldStatus, TaskStatusScheduled)
+	}
+	s.logger.Info("task scheduled for later",
+		slog.String("task_id", taskID),
+		slog.String("cron", schedule.Cron),
+		slog.Time("next_run_at", nextRunAt),
+	)
+	return nextRunAt, nil
+}
+
+// ClearTaskSchedule removes the schedule of taskID. SCHEDULED のタスクは PENDING に
+// 戻る。実行履歴は残す。
+func (s *Scheduler) ClearTaskSchedule(taskID string) error {
+	mu := tasksStateLock(s.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+
+	tasksState, err := s.Repo.State().LoadTasks()
+	if err != nil {
+		return fmt.Errorf("failed to load tasks state: %w", err)
+	}
+	task := findTaskState(tasksState, taskID)
+	if task == nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	if task.Inputs == nil || TaskScheduleFromInputs(task.Inputs) == nil {
+		return fmt.Errorf("task %s has no schedule", taskID)
+	}
+
+	delete(task.Inputs, InputKeyScheduleRunAt)
+	delete(task.Inputs, InputKeyScheduleCron)
+	delete(task.Inputs, InputKeyNextRunAt)
+	oldStatus := TaskStatus(task.Status)
+	if oldStatus == TaskStatusScheduled {
+		task.Status = string(TaskStatusPending)
+	}
+	task.UpdatedAt = time.Now()
+	if err := s.Repo.State().SaveTasks(tasksState); err != nil {
+		return fmt.Errorf("failed to save task schedule: %w", err)
+	}
+	if oldStatus == TaskStatusScheduled {
+		s.emitStateChange(taskID, oldStatus, TaskStatusPending)
+	}
+	s.logger.Info("task schedule cleared", slog.String("task_id", taskID))
+	return nil
+}
+
+// TriggerScheduledTasks is the time-based trigger of scheduled tasks:
+//   - SCHEDULED tasks whose next_run_at has passed start a new run: they move
+//     to PENDING with a fresh attempt count and a new run history entry.
+//   - Runs that have finished are recorded in the run history, and recurring
+//     (cron) tasks go back to SCHEDULED at their next run time.
+//
+// 停止中に過ぎた実行時刻はまとめて 1 回だけ実行する。キャンセルされた実行の後は
+// 繰り返さない。状態が変わったタスク ID を返す。
+func (s *Scheduler) TriggerScheduledTasks() ([]string, error) {
+	mu := tasksStateLock(s.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+
+	tasksState, err := s.Repo.State().LoadTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks state: %w", err)
+	}
+
+	type transition struct {
+		taskID    string
+		oldStatus TaskStatus
+		newStatus TaskStatus
+	}
+	var transitions []transition
+	dirty := false
+	now := time.Now()
+
+	for i := range tasksState.Tasks {
+		task := &tasksState.Tasks[i]
+		if task.Inputs == nil {
+			continue
+		}
+		schedule := TaskScheduleFromInputs(task.Inputs)
+		if schedule == nil {
+			continue
+		}
+		status := TaskStatus(task.Status)
+		runs := ScheduleRunHistory(task.Inputs)
+
+		if status == TaskStatusScheduled {
+			if !isDue(task.Inputs, InputKeyNextRunAt, now) {
+				continue
+			}
+			run := inputInt(task.Inputs, InputKeyScheduleRun) + 1
+			task.Inputs[InputKeyScheduleRun] = run
+			clearTaskControlInputs(task)
+			delete(task.Inputs, InputKeyNextRunAt)
+			delete(task.Inputs, InputKeyAttemptCount)
+			delete(task.Inputs, InputKeyLastError)
+			setScheduleRunHistory(task.Inputs, append(runs, ScheduleRun{Run: run, TriggeredAt: now}))
+			task.Status = string(TaskStatusPending)
+			task.UpdatedAt = now
+			transitions = append(transitions, transition{task.TaskID, status, TaskStatusPending})
+			s.logger.Info("scheduled task triggered", slog.String("task_id", task.TaskID), slog.Int("run", run))
+			continue
+		}
+
+		// 実行中の回（履歴の最後が未完了）が終わったかを確認する
+		if len(runs) == 0 || runs[len(runs)-1].FinishedAt != nil || !isScheduleRunFinished(task) {
+			continue
+		}
+		last := &runs[len(runs)-1]
+		finishedAt := now
+		last.FinishedAt = &finishedAt
+		last.Status = status
+		last.Attempts = inputAttemptCount(task.Inputs)
+		last.LastError, _ = task.Inputs[InputKeyLastError].(string)
+		setScheduleRunHistory(task.Inputs, runs)
+		dirty = true
+		s.logger.Info("scheduled task run finished",
+			slog.String("task_id", task.TaskID),
+			slog.Int("run", last.Run),
+			slog.String("status", string(status)),
+		)
+
+		if schedule.Cron == "" || status == TaskStatusCanceled {
+			continue
+		}
+		nextRunAt, err := schedule.next(now)
+		if err != nil {
+			s.logger.Warn("failed to compute next run of recurring task",
+				slog.String("task_id", task.TaskID),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		// 失敗した回も次の回は改めて実行する（後続タスクの DEPENDENCY_FAILED も解除される）
+		delete(task.Inputs, InputKeyFailedPermanently)
+		task.Inputs[InputKeyNextRunAt] = nextRunAt.Format(time.RFC3339)
+		task.Status = string(TaskStatusScheduled)
+		task.UpdatedAt = now
+		transitions = append(transitions, transition{task.TaskID, status, TaskStatusScheduled})
+	}
+	if len(transitions) == 0 && !dirty {
+		return nil, nil
+	}
+
+	if err := s.Repo.State().SaveTasks(tasksState); err != nil {
+		return nil, fmt.Errorf("failed to save scheduled tasks: %w", err)
+	}
+	changed := make([]string, 0, len(transitions))
+	for _, t := range transitions {
+		s.emitStateChange(t.taskID, t.oldStatus, t.newStatus)
+		changed = append(changed, t.taskID)
+	}
+	return changed, nil
+}
@@ -0,0 +1,182 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupScheduledTask(t *testing.T, status TaskStatus, inputs map[string]interface{}) (*Scheduler, persistence.WorkspaceRepository, *MockEventEmitter) {
+	t.Helper()
+	repo, queue := setupTestRepo(t)
+	saveDesign(t, repo, []persistence.NodeDesign{{NodeID: "node-1"}})
+	saveState(t, repo, []persistence.TaskState{
+		{TaskID: "task-1", NodeID: "node-1", Status: string(status), CreatedAt: time.Now(), Inputs: inputs},
+	}, nil)
+	emitter := new(MockEventEmitter)
+	emitter.On("Emit", mock.Anything, mock.Anything).Return()
+	return NewScheduler(repo, queue, emitter), repo, emitter
+}
+
+func TestScheduler_SetTaskSchedule(t *testing.T) {
+	scheduler, repo, emitter := setupScheduledTask(t, TaskStatusPending, nil)
+
+	next, err := scheduler.SetTaskSchedule("task-1", TaskSchedule{Cron: "0 3 * * *"})
+	require.NoError(t, err)
+	assert.True(t, next.After(time.Now()))
+	assert.Equal(t, 3, next.Hour())
+
+	task := loadStatuses(t, repo)["task-1"]
+	assert.Equal(t, string(TaskStatusScheduled), task.Status)
+	assert.Equal(t, "0 3 * * *", task.Inputs[InputKeyScheduleCron])
+	assert.Equal(t, next.Format(time.RFC3339), task.Inputs[InputKeyNextRunAt])
+	assertStateChange(t, emitter, "task-1", TaskStatusPending, TaskStatusScheduled)
+
+	runAt := time.Now().Add(time.Hour)
+	_, err = scheduler.SetTaskSchedule("task-1", TaskSchedule{RunAt: &runAt, Cron: "@daily"})
+	assert.Error(t, err, "both runAt and cron")
+	_, err = scheduler.SetTaskSchedule("task-1", TaskSchedule{})
+	assert.Error(t, err, "neither runAt nor cron")
+	_, err = scheduler.SetTaskSchedule("task-1", TaskSchedule{Cron: "0 0 30 2 *"})
+	assert.Error(t, err, "never fires")
+	_, err = scheduler.SetTaskSchedule("task-unknown", TaskSchedule{Cron: "@daily"})
+	assert.Error(t, err)
+
+	// 1 回だけの実行に切り替えると cron は消える
+	next, err = scheduler.SetTaskSchedule("task-1", TaskSchedule{RunAt: &runAt})
+	require.NoError(t, err)
+	assert.Equal(t, runAt.Unix(), next.Unix())
+	task = loadStatuses(t, repo)["task-1"]
+	assert.Nil(t, task.Inputs[InputKeyScheduleCron])
+	assert.Equal(t, runAt.Format(time.RFC3339), task.Inputs[InputKeyScheduleRunAt])
+
+	require.NoError(t, scheduler.ClearTaskSchedule("task-1"))
+	task = loadStatuses(t, repo)["task-1"]
+	assert.Equal(t, string(TaskStatusPending), task.Status)
+	assert.Nil(t, task.Inputs[InputKeyScheduleRunAt])
+	assert.Nil(t, task.Inputs[InputKeyNextRunAt])
+	assert.Error(t, scheduler.ClearTaskSchedule("task-1"), "no schedule")
+}
+
+func TestScheduler_SetTaskSchedule_RejectsQueuedTask(t *testing.T) {
+	scheduler, _, _ := setupScheduledTask(t, TaskStatusReady, nil)
+	_, err := scheduler.SetTaskSchedule("task-1", TaskSchedule{Cron: "@hourly"})
+	assert.Error(t, err)
+}
+
+func TestScheduler_TriggerScheduledTasks_RecurringRun(t *testing.T) {
+	scheduler, repo, emitter := setupScheduledTask(t, TaskStatusScheduled, map[string]interface{}{
+		InputKeyScheduleCron: "*/5 * * * *",
+		InputKeyNextRunAt:    time.Now().Add(-time.Minute).Format(time.RFC3339),
+		InputKeyAttemptCount: 2,
+		InputKeyLastError:    "previous run failed",
+	})
+
+	// 実行時刻を過ぎたので新しい回として PENDING になる
+	changed, err := scheduler.TriggerScheduledTasks()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task-1"}, changed)
+	task := loadStatuses(t, repo)["task-1"]
+	assert.Equal(t, string(TaskStatusPending), task.Status)
+	assert.EqualValues(t, 1, task.Inputs[InputKeyScheduleRun])
+	assert.Nil(t, task.Inputs[InputKeyAttemptCount])
+	assert.Nil(t, task.Inputs[InputKeyLastError])
+	assert.Nil(t, task.Inputs[InputKeyNextRunAt])
+	assertStateChange(t, emitter, "task-1", TaskStatusScheduled, TaskStatusPending)
+
+	runs := ScheduleRunHistory(task.Inputs)
+	require.Len(t, runs, 1)
+	assert.Equal(t, 1, runs[0].Run)
+	assert.Nil(t, runs[0].FinishedAt)
+
+	// 実行中は何もしない
+	changed, err = scheduler.TriggerScheduledTasks()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// 成功した回を履歴に記録し、次の実行時刻で SCHEDULED に戻る
+	state, err := repo.State().LoadTasks()
+	require.NoError(t, err)
+	state.Tasks[0].Status = string(TaskStatusSucceeded)
+	state.Tasks[0].Inputs[InputKeyAttemptCount] = 1
+	require.NoError(t, repo.State().SaveTasks(state))
+
+	changed, err = scheduler.TriggerScheduledTasks()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task-1"}, changed)
+	task = loadStatuses(t, repo)["task-1"]
+	assert.Equal(t, string(TaskStatusScheduled), task.Status)
+	nextRunAt := inputTime(task.Inputs, InputKeyNextRunAt)
+	assert.True(t, nextRunAt.After(time.Now()))
+	assert.Zero(t, nextRunAt.Minute()%5)
+	assertStateChange(t, emitter, "task-1", TaskStatusSucceeded, TaskStatusScheduled)
+
+	runs = ScheduleRunHistory(task.Inputs)
+	require.Len(t, runs, 1)
+	require.NotNil(t, runs[0].FinishedAt)
+	assert.Equal(t, TaskStatusSucceeded, runs[0].Status)
+	assert.Equal(t, 1, runs[0].Attempts)
+}
+
+func TestScheduler_TriggerScheduledTasks_OneShotAndCanceled(t *testing.T) {
+	runAt := time.Now().Add(-time.Second)
+	scheduler, repo, _ := setupScheduledTask(t, TaskStatusScheduled, map[string]interface{}{
+		InputKeyScheduleRunAt: runAt.Format(time.RFC3339),
+		InputKeyNextRunAt:     runAt.Format(time.RFC3339),
+	})
+	_, err := scheduler.TriggerScheduledTasks()
+	require.NoError(t, err)
+	require.Equal(t, string(TaskStatusPending), loadStatuses(t, repo)["task-1"].Status)
+
+	state, err := repo.State().LoadTasks()
+	require.NoError(t, err)
+	state.Tasks[0].Status = string(TaskStatusFailed)
+	state.Tasks[0].Inputs[InputKeyFailedPermanently] = true
+	state.Tasks[0].Inputs[InputKeyLastError] = "boom"
+	require.NoError(t, repo.State().SaveTasks(state))
+
+	// 1 回だけのスケジュールは結果を記録するだけで、再実行しない
+	changed, err := scheduler.TriggerScheduledTasks()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	task := loadStatuses(t, repo)["task-1"]
+	assert.Equal(t, string(TaskStatusFailed), task.Status)
+	runs := ScheduleRunHistory(task.Inputs)
+	require.Len(t, runs, 1)
+	assert.Equal(t, TaskStatusFailed, runs[0].Status)
+	assert.Equal(t, "boom", runs[0].LastError)
+}
+
+func TestScheduler_TriggerScheduledTasks_CanceledRunStopsRecurrence(t *testing.T) {
+	scheduler, repo, _ := setupScheduledTask(t, TaskStatusCanceled, map[string]interface{}{
+		InputKeyScheduleCron: "@hourly",
+		InputKeyScheduleRun:  3,
+		InputKeyScheduleRuns: []ScheduleRun{{Run: 3, TriggeredAt: time.Now().Add(-time.Minute)}},
+	})
+
+	changed, err := scheduler.TriggerScheduledTasks()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	task := loadStatuses(t, repo)["task-1"]
+	assert.Equal(t, string(TaskStatusCanceled), task.Status)
+	runs := ScheduleRunHistory(task.Inputs)
+	require.Len(t, runs, 1)
+	assert.Equal(t, TaskStatusCanceled, runs[0].Status)
+}
+
+func TestSetScheduleRunHistory_KeepsNewest(t *testing.T) {
+	inputs := map[string]interface{}{}
+	var runs []ScheduleRun
+	for i := 1; i <= maxScheduleRunHistory+5; i++ {
+		runs = append(runs, ScheduleRun{Run: i})
+	}
+	setScheduleRunHistory(inputs, runs)
+	kept := ScheduleRunHistory(inputs)
+	require.Len(t, kept, maxScheduleRunHistory)
+	assert.Equal(t, 6, kept[0].Run)
+	assert.Equal(t, maxScheduleRunHistory+5, kept[len(kept)-1].Run)
+}
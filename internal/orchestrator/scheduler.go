@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/biwakonbu/agent-runner/internal/logging"
@@ -30,6 +31,15 @@ func NewScheduler(repo persistence.WorkspaceRepository, q *ipc.FilesystemQueue,
 
 // ScheduleTask schedules a task for execution.
 func (s *Scheduler) ScheduleTask(taskID string) error {
+	mu := tasksStateLock(s.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+	return s.scheduleTaskLocked(taskID, nil)
+}
+
+// scheduleTaskLocked は tasks.json のロックを保持した状態で呼び出す。
+// ranking が nil の場合はここで計算する。
+func (s *Scheduler) scheduleTaskLocked(taskID string, ranking *taskRanking) error {
 	tasksState, err := s.Repo.State().LoadTasks()
 	if err != nil {
 		return fmt.Errorf("failed to load tasks: %w", err)
@@ -49,15 +59,17 @@ func (s *Scheduler) ScheduleTask(taskID string) error {
 
 	// 依存関係をチェック
 	if !s.allDependenciesSatisfied(task) {
-		if TaskStatus(task.Status) != TaskStatusBlocked {
-			oldStatus := TaskStatus(task.Status)
-			task.Status = string(TaskStatusBlocked)
-			if err := s.Repo.State().SaveTasks(tasksState); err != nil {
-				return fmt.Errorf("failed to update task status: %w", err)
-			}
+		path, reason := s.blockingPath(task)
+		oldStatus := TaskStatus(task.Status)
+		task.Status = string(TaskStatusBlocked)
+		setBlockedReason(task, path, reason)
+		if err := s.Repo.State().SaveTasks(tasksState); err != nil {
+			return fmt.Errorf("failed to update task status: %w", err)
+		}
+		if oldStatus != TaskStatusBlocked {
 			s.emitStateChange(task.TaskID, oldStatus, TaskStatusBlocked)
 		}
-		return fmt.Errorf("task has unsatisfied dependencies")
+		return fmt.Errorf("task has unsatisfied dependencies: %s", reason)
 	}
 
 	// Update to READY
@@ -69,11 +81,16 @@ func (s *Scheduler) ScheduleTask(taskID string) error {
 	s.emitStateChange(task.TaskID, oldStatus, TaskStatusReady)
 
 	// Create a job for the queue
+	if ranking == nil {
+		ranking = s.rankTasks(tasksState.Tasks)
+	}
 	job := &ipc.Job{
-		ID:      fmt.Sprintf("job-%s-%d", task.TaskID, time.Now().UnixNano()),
-		TaskID:  task.TaskID,
-		PoolID:  "default", // taskState.PoolID missing? Assuming default.
-		Payload: map[string]string{"action": "run_task"},
+		ID:         fmt.Sprintf("job-%s-%d", task.TaskID, time.Now().UnixNano()),
+		TaskID:     task.TaskID,
+		PoolID:     "default", // taskState.PoolID missing? Assuming default.
+		Payload:    map[string]string{"action": "run_task"},
+		Priority:   ranking.priority(task),
+		Dependents: ranking.dependents[task.NodeID],
 	}
 
 	if err := s.Queue.Enqueue(job); err != nil {
@@ -122,22 +139,105 @@ func (s *Scheduler) allDependenciesSatisfied(task *persistence.TaskState) bool {
 	return true
 }
 
-// ScheduleReadyTasks schedules all pending tasks that have satisfied dependencies.
+// blockingPath explains why task is blocked: the dependency path from the
+// task's node to the node that blocks it, and a human-readable reason
+// (waiting on an unfinished node, a missing node, or a cycle).
+func (s *Scheduler) blockingPath(task *persistence.TaskState) ([]string, string) {
+	if _, err := s.Repo.Design().GetNode(task.NodeID); err != nil {
+		return []string{task.NodeID}, fmt.Sprintf("node design not found: %s", task.NodeID)
+	}
+	graph, err := LoadDependencyGraph(s.Repo)
+	if err != nil {
+		return []string{task.NodeID}, "dependency graph unavailable"
+	}
+	completed := make(map[string]string)
+	if nodesRuntime, err := s.Repo.State().LoadNodesRuntime(); err == nil {
+		for _, nr := range nodesRuntime.Nodes {
+			completed[nr.NodeID] = nr.Status
+		}
+	}
+	isDone := func(id string) bool {
+		return persistence.NodeRuntimeStatus(completed[id]).IsCompleted()
+	}
+
+	path := []string{task.NodeID}
+	onPath := map[string]int{task.NodeID: 0}
+	current := task.NodeID
+	for {
+		next := ""
+		for _, dep := range graph[current] {
+			if !isDone(dep) {
+				next = dep
+				break
+			}
+		}
+		if next == "" {
+			// current の依存はすべて満たされている（current 自体が未完了）
+			status := completed[current]
+			if status == "" {
+				status = "not started"
+			}
+			return path, fmt.Sprintf("waiting for %s (%s)", current, status)
+		}
+		if i, ok := onPath[next]; ok {
+			cycle := append(append([]string(nil), path[i:]...), next)
+			return append(path, next), "dependency cycle: " + strings.Join(cycle, " -> ")
+		}
+		path = append(path, next)
+		if _, known := graph[next]; !known {
+			if _, err := s.Repo.Design().GetNode(next); err != nil {
+				return path, fmt.Sprintf("depends on unknown node %s", next)
+			}
+		}
+		onPath[next] = len(path) - 1
+		current = next
+	}
+}
+
+// setBlockedReason records (or clears, when path is nil) why task is blocked.
+func setBlockedReason(task *persistence.TaskState, path []string, reason string) {
+	if path == nil {
+		if task.Inputs != nil {
+			delete(task.Inputs, InputKeyBlockedBy)
+			delete(task.Inputs, InputKeyBlockedReason)
+		}
+		return
+	}
+	if task.Inputs == nil {
+		task.Inputs = make(map[string]interface{})
+	}
+	task.Inputs[InputKeyBlockedBy] = path
+	task.Inputs[InputKeyBlockedReason] = reason
+}
+
+// ScheduleReadyTasks schedules all pending tasks that have satisfied dependencies,
+// in priority order.
 func (s *Scheduler) ScheduleReadyTasks() ([]string, error) {
+	mu := tasksStateLock(s.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+
 	tasksState, err := s.Repo.State().LoadTasks()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load tasks state: %w", err)
 	}
 
-	scheduled := []string{}
+	var ready []*persistence.TaskState
 	for i := range tasksState.Tasks {
 		task := &tasksState.Tasks[i]
-		if TaskStatus(task.Status) == TaskStatusPending {
-			if s.allDependenciesSatisfied(task) {
-				if err := s.ScheduleTask(task.TaskID); err == nil {
-					scheduled = append(scheduled, task.TaskID)
-				}
-			}
+		if TaskStatus(task.Status) == TaskStatusPending && s.allDependenciesSatisfied(task) {
+			ready = append(ready, task)
+		}
+	}
+
+	// 優先度 → 解放する後続ノード数 → 作成日時の順に投入する
+	ranking := s.rankTasks(tasksState.Tasks)
+	ranking.sortTasks(ready)
+
+	scheduled := []string{}
+	for _, task := range ready {
+		if err := s.scheduleTaskLocked(task.TaskID, ranking); err == nil {
+			scheduled = append(scheduled, task.TaskID)
 		}
 	}
 
@@ -146,6 +246,10 @@ func (s *Scheduler) ScheduleReadyTasks() ([]string, error) {
 
 // UpdateBlockedTasks は BLOCKED 状態のタスクで依存が満たされたものを PENDING に戻す
 func (s *Scheduler) UpdateBlockedTasks() ([]string, error) {
+	mu := tasksStateLock(s.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+
 	tasksState, err := s.Repo.State().LoadTasks()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load tasks state: %w", err)
@@ -158,6 +262,7 @@ func (s *Scheduler) UpdateBlockedTasks() ([]string, error) {
 			if s.allDependenciesSatisfied(task) {
 				oldStatus := TaskStatus(task.Status)
 				task.Status = string(TaskStatusPending)
+				setBlockedReason(task, nil, "")
 				if err := s.Repo.State().SaveTasks(tasksState); err != nil {
 					s.logger.Warn("failed to unblock task",
 						slog.String("task_id", task.TaskID),
@@ -179,6 +284,10 @@ func (s *Scheduler) UpdateBlockedTasks() ([]string, error) {
 
 // SetBlockedStatusForPendingWithUnsatisfiedDeps は依存が満たされていない PENDING タスクを BLOCKED に設定する
 func (s *Scheduler) SetBlockedStatusForPendingWithUnsatisfiedDeps() ([]string, error) {
+	mu := tasksStateLock(s.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+
 	tasksState, err := s.Repo.State().LoadTasks()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load tasks state: %w", err)
@@ -189,8 +298,10 @@ func (s *Scheduler) SetBlockedStatusForPendingWithUnsatisfiedDeps() ([]string, e
 		task := &tasksState.Tasks[i]
 		if TaskStatus(task.Status) == TaskStatusPending {
 			if !s.allDependenciesSatisfied(task) {
+				path, reason := s.blockingPath(task)
 				oldStatus := TaskStatus(task.Status)
 				task.Status = string(TaskStatusBlocked)
+				setBlockedReason(task, path, reason)
 				if err := s.Repo.State().SaveTasks(tasksState); err != nil {
 					s.logger.Warn("failed to set task to blocked",
 						slog.String("task_id", task.TaskID),
@@ -202,6 +313,7 @@ func (s *Scheduler) SetBlockedStatusForPendingWithUnsatisfiedDeps() ([]string, e
 				blocked = append(blocked, task.TaskID)
 				s.logger.Info("task set to blocked",
 					slog.String("task_id", task.TaskID),
+					slog.String("reason", reason),
 				)
 			}
 		}
@@ -213,6 +325,10 @@ func (s *Scheduler) SetBlockedStatusForPendingWithUnsatisfiedDeps() ([]string, e
 // ResetRetryTasks checks for tasks in RETRY_WAIT status that are ready to be retried
 // (NextRetryAt <= now) and resets them to PENDING.
 func (s *Scheduler) ResetRetryTasks() ([]string, error) {
+	mu := tasksStateLock(s.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+
 	tasksState, err := s.Repo.State().LoadTasks()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load tasks state: %w", err)
@@ -224,20 +340,8 @@ func (s *Scheduler) ResetRetryTasks() ([]string, error) {
 	for i := range tasksState.Tasks {
 		task := &tasksState.Tasks[i]
 		if TaskStatus(task.Status) == TaskStatusRetryWait {
-			// Check retry time from Inputs (Workaround as models.go lacks NextRetryAt)
-			// Or just assume if it's in RETRY_WAIT for a while?
-			// For now, let's look for next retry timestamp in Inputs if exists.
-			var nextRetryAt time.Time
-			if task.Inputs != nil {
-				if val, ok := task.Inputs[InputKeyNextRetryAt].(string); ok {
-					if t, err := time.Parse(time.RFC3339, val); err == nil {
-						nextRetryAt = t
-					}
-				}
-			}
-
-			// If nextRetryAt is zero (not set) or before now, reset it.
-			if nextRetryAt.IsZero() || !now.Before(nextRetryAt) {
+			// If next_retry_at is not set or has passed, reset it.
+			if isDue(task.Inputs, InputKeyNextRetryAt, now) {
 				oldStatus := TaskStatus(task.Status)
 				task.Status = string(TaskStatusPending)
 				// Clear next_retry_at
@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -277,3 +278,241 @@ func TestScheduler_SetBlockedStatusForPendingWithUnsatisfiedDeps(t *testing.T) {
 		}
 	}
 }
+
+func TestScheduler_ScheduleReadyTasks_PriorityOrder(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	scheduler := NewScheduler(repo, queue, nil)
+
+	now := time.Now()
+	saveDesign(t, repo, []persistence.NodeDesign{
+		{NodeID: "node-low", Priority: "low"},
+		{NodeID: "node-high", Priority: "high"},
+		{NodeID: "node-medium"},
+		// node-core は 2 つの未完了ノードに（推移的に）依存されている
+		{NodeID: "node-core", Priority: "medium"},
+		{NodeID: "node-api", Dependencies: []string{"node-core"}},
+		{NodeID: "node-ui", Dependencies: []string{"node-api"}},
+	})
+	saveState(t, repo, []persistence.TaskState{
+		{TaskID: "task-low", NodeID: "node-low", Status: string(TaskStatusPending), CreatedAt: now.Add(-time.Hour)},
+		{TaskID: "task-medium", NodeID: "node-medium", Status: string(TaskStatusPending), CreatedAt: now.Add(-time.Minute)},
+		{TaskID: "task-core", NodeID: "node-core", Status: string(TaskStatusPending), CreatedAt: now},
+		{TaskID: "task-high", NodeID: "node-high", Status: string(TaskStatusPending), CreatedAt: now},
+		{TaskID: "task-override", NodeID: "node-low", Status: string(TaskStatusPending), CreatedAt: now, Priority: 150},
+		{TaskID: "task-api", NodeID: "node-api", Status: string(TaskStatusBlocked), CreatedAt: now},
+		{TaskID: "task-ui", NodeID: "node-ui", Status: string(TaskStatusBlocked), CreatedAt: now},
+	}, nil)
+
+	scheduled, err := scheduler.ScheduleReadyTasks()
+	if err != nil {
+		t.Fatalf("ScheduleReadyTasks failed: %v", err)
+	}
+	want := "task-high,task-override,task-core,task-medium,task-low"
+	if got := strings.Join(scheduled, ","); got != want {
+		t.Errorf("scheduled = %s, want %s", got, want)
+	}
+
+	var dequeued []string
+	for {
+		job, err := queue.Dequeue("default")
+		if err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+		if job == nil {
+			break
+		}
+		if job.TaskID == "task-core" && job.Dependents != 2 {
+			t.Errorf("task-core dependents = %d, want 2", job.Dependents)
+		}
+		dequeued = append(dequeued, job.TaskID)
+	}
+	if got := strings.Join(dequeued, ","); got != want {
+		t.Errorf("dequeued = %s, want %s", got, want)
+	}
+}
+
+func TestNodePriorityValue(t *testing.T) {
+	cases := map[string]int{
+		"low": TaskPriorityLow, "Medium": TaskPriorityMedium, "": TaskPriorityMedium,
+		"high": TaskPriorityHigh, "critical": TaskPriorityCritical, "250": 250, "unknown": TaskPriorityMedium,
+	}
+	for label, want := range cases {
+		if got := nodePriorityValue(label); got != want {
+			t.Errorf("nodePriorityValue(%q) = %d, want %d", label, got, want)
+		}
+	}
+}
+
+func TestScheduler_SetBlockedStatus_RecordsBlockingPath(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	scheduler := NewScheduler(repo, queue, nil)
+
+	now := time.Now()
+	saveDesign(t, repo, []persistence.NodeDesign{
+		{NodeID: "node-core"},
+		{NodeID: "node-api", Dependencies: []string{"node-core"}},
+		{NodeID: "node-ui", Dependencies: []string{"node-api"}},
+		{NodeID: "node-x", Dependencies: []string{"node-y"}},
+		{NodeID: "node-y", Dependencies: []string{"node-x"}},
+	})
+	saveState(t, repo, []persistence.TaskState{
+		{TaskID: "task-core", NodeID: "node-core", Status: string(TaskStatusRunning), CreatedAt: now},
+		{TaskID: "task-api", NodeID: "node-api", Status: string(TaskStatusPending), CreatedAt: now},
+		{TaskID: "task-ui", NodeID: "node-ui", Status: string(TaskStatusPending), CreatedAt: now},
+		{TaskID: "task-x", NodeID: "node-x", Status: string(TaskStatusPending), CreatedAt: now},
+		{TaskID: "task-y", NodeID: "node-y", Status: string(TaskStatusPending), CreatedAt: now},
+	}, []persistence.NodeRuntime{
+		{NodeID: "node-core", Status: "in_progress"},
+	})
+
+	if _, err := scheduler.SetBlockedStatusForPendingWithUnsatisfiedDeps(); err != nil {
+		t.Fatalf("SetBlockedStatusForPendingWithUnsatisfiedDeps failed: %v", err)
+	}
+
+	state, _ := repo.State().LoadTasks()
+	byID := map[string]persistence.TaskState{}
+	for _, ts := range state.Tasks {
+		byID[ts.TaskID] = ts
+	}
+
+	ui := byID["task-ui"]
+	if ui.Status != string(TaskStatusBlocked) {
+		t.Fatalf("task-ui status = %s, want BLOCKED", ui.Status)
+	}
+	if got := ui.Inputs[InputKeyBlockedBy]; !equalPath(got, "node-ui", "node-api", "node-core") {
+		t.Errorf("blocked_by = %v", got)
+	}
+	if got := ui.Inputs[InputKeyBlockedReason]; got != "waiting for node-core (in_progress)" {
+		t.Errorf("blocked_reason = %v", got)
+	}
+
+	reason, _ := byID["task-x"].Inputs[InputKeyBlockedReason].(string)
+	if reason != "dependency cycle: node-x -> node-y -> node-x" {
+		t.Errorf("task-x blocked_reason = %q", reason)
+	}
+
+	// 依存が満たされたら BLOCKED 理由は消える
+	saveState(t, repo, state.Tasks, []persistence.NodeRuntime{
+		{NodeID: "node-core", Status: "implemented"},
+	})
+	if _, err := scheduler.UpdateBlockedTasks(); err != nil {
+		t.Fatalf("UpdateBlockedTasks failed: %v", err)
+	}
+	state, _ = repo.State().LoadTasks()
+	for _, ts := range state.Tasks {
+		if ts.TaskID == "task-api" {
+			if ts.Status != string(TaskStatusPending) || ts.Inputs[InputKeyBlockedBy] != nil {
+				t.Errorf("task-api should be unblocked without reason: %+v", ts)
+			}
+		}
+	}
+}
+
+func equalPath(v interface{}, want ...string) bool {
+	var got []string
+	switch p := v.(type) {
+	case []string:
+		got = p
+	case []interface{}:
+		for _, x := range p {
+			s, _ := x.(string)
+			got = append(got, s)
+		}
+	}
+	return strings.Join(got, ",") == strings.Join(want, ",")
+}
+
+func TestScheduler_PropagateDependencyFailures(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	scheduler := NewScheduler(repo, queue, nil)
+
+	now := time.Now()
+	saveDesign(t, repo, []persistence.NodeDesign{
+		{NodeID: "node-core"},
+		{NodeID: "node-api", Dependencies: []string{"node-core"}},
+		{NodeID: "node-ui", Dependencies: []string{"node-api"}},
+		{NodeID: "node-docs"},
+	})
+	saveState(t, repo, []persistence.TaskState{
+		{TaskID: "task-core", NodeID: "node-core", Status: string(TaskStatusFailed), CreatedAt: now,
+			Inputs: map[string]interface{}{InputKeyFailedPermanently: true}},
+		{TaskID: "task-api", NodeID: "node-api", Status: string(TaskStatusBlocked), CreatedAt: now},
+		{TaskID: "task-ui", NodeID: "node-ui", Status: string(TaskStatusPending), CreatedAt: now},
+		{TaskID: "task-docs", NodeID: "node-docs", Status: string(TaskStatusPending), CreatedAt: now},
+	}, nil)
+
+	changed, err := scheduler.PropagateDependencyFailures()
+	if err != nil {
+		t.Fatalf("PropagateDependencyFailures failed: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("changed = %v, want task-api and task-ui", changed)
+	}
+
+	state, _ := repo.State().LoadTasks()
+	byID := map[string]persistence.TaskState{}
+	for _, ts := range state.Tasks {
+		byID[ts.TaskID] = ts
+	}
+	ui := byID["task-ui"]
+	if ui.Status != string(TaskStatusDependencyFailed) {
+		t.Fatalf("task-ui status = %s, want DEPENDENCY_FAILED", ui.Status)
+	}
+	if got := ui.Inputs[InputKeyFailedAncestor]; got != "node-core" {
+		t.Errorf("failed_ancestor = %v", got)
+	}
+	if got := ui.Inputs[InputKeyBlockedBy]; !equalPath(got, "node-ui", "node-api", "node-core") {
+		t.Errorf("blocked_by = %v", got)
+	}
+	if got := byID["task-docs"].Status; got != string(TaskStatusPending) {
+		t.Errorf("task-docs status = %s, want PENDING", got)
+	}
+
+	// 祖先がリトライされたら PENDING に戻る
+	for i := range state.Tasks {
+		if state.Tasks[i].TaskID == "task-core" {
+			state.Tasks[i].Status = string(TaskStatusPending)
+		}
+	}
+	saveState(t, repo, state.Tasks, nil)
+	if _, err := scheduler.PropagateDependencyFailures(); err != nil {
+		t.Fatalf("PropagateDependencyFailures failed: %v", err)
+	}
+	state, _ = repo.State().LoadTasks()
+	for _, ts := range state.Tasks {
+		if ts.TaskID == "task-api" || ts.TaskID == "task-ui" {
+			if ts.Status != string(TaskStatusPending) || ts.Inputs[InputKeyFailedAncestor] != nil {
+				t.Errorf("%s should return to PENDING without reason: %+v", ts.TaskID, ts)
+			}
+		}
+	}
+}
+
+func TestScheduler_PropagateDependencyFailures_DependencyRemoved(t *testing.T) {
+	repo, queue := setupTestRepo(t)
+	scheduler := NewScheduler(repo, queue, nil)
+
+	now := time.Now()
+	saveDesign(t, repo, []persistence.NodeDesign{
+		{NodeID: "node-core"},
+		{NodeID: "node-api", Dependencies: []string{"node-core"}},
+	})
+	saveState(t, repo, []persistence.TaskState{
+		{TaskID: "task-core", NodeID: "node-core", Status: string(TaskStatusFailed), CreatedAt: now,
+			Inputs: map[string]interface{}{InputKeyFailedPermanently: true}},
+		{TaskID: "task-api", NodeID: "node-api", Status: string(TaskStatusPending), CreatedAt: now},
+	}, nil)
+	if _, err := scheduler.PropagateDependencyFailures(); err != nil {
+		t.Fatalf("PropagateDependencyFailures failed: %v", err)
+	}
+
+	// 依存を外すと DEPENDENCY_FAILED から復帰する
+	saveDesign(t, repo, []persistence.NodeDesign{{NodeID: "node-api"}})
+	changed, err := scheduler.PropagateDependencyFailures()
+	if err != nil {
+		t.Fatalf("PropagateDependencyFailures failed: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "task-api" {
+		t.Fatalf("changed = %v, want [task-api]", changed)
+	}
+}
@@ -0,0 +1,71 @@
+package orchestrator
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sync"
+
+	"github.com/biwakonbu/agent-runner/internal/filelock"
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+)
+
+// tasksStateLocks はワークスペースごとの tasks.json 更新ロック。
+// Scheduler と ExecutionOrchestrator（並列ジョブ）の Load → 変更 → Save を直列化する。
+var tasksStateLocks sync.Map // baseDir -> *stateLock
+
+// stateLock serializes tasks.json updates within this process with a mutex
+// and across orchestrator processes with an advisory lock on
+// state/tasks.json.lock. プロセス内の競合は mutex で先に直列化し、
+// ファイルロックを取るのはプロセスごとに 1 つのゴルーチンだけにする。
+type stateLock struct {
+	mu   sync.Mutex
+	path string // 空の場合はプロセス内のみ排他する
+	held *filelock.Lock
+}
+
+// Lock acquires the in-process mutex and then the cross-process file lock.
+// ファイルロックを取得できない場合はプロセス内の排他のみで続行する。
+func (l *stateLock) Lock() {
+	l.mu.Lock()
+	if l.path == "" {
+		return
+	}
+	held, err := filelock.Acquire(l.path)
+	if err != nil {
+		slog.Default().Warn("failed to acquire tasks state file lock; continuing with in-process lock only",
+			slog.String("path", l.path),
+			slog.Any("error", err),
+		)
+		return
+	}
+	l.held = held
+}
+
+// Unlock releases the file lock and then the mutex.
+func (l *stateLock) Unlock() {
+	if l.held != nil {
+		if err := l.held.Release(); err != nil {
+			slog.Default().Warn("failed to release tasks state file lock",
+				slog.String("path", l.path),
+				slog.Any("error", err),
+			)
+		}
+		l.held = nil
+	}
+	l.mu.Unlock()
+}
+
+// tasksStateLock returns the lock guarding read-modify-write cycles of
+// tasks.json in repo's workspace.
+func tasksStateLock(repo persistence.WorkspaceRepository) sync.Locker {
+	key := ""
+	if repo != nil {
+		key = repo.BaseDir()
+	}
+	lock := &stateLock{}
+	if key != "" {
+		lock.path = filepath.Join(key, "state", "tasks.json.lock")
+	}
+	actual, _ := tasksStateLocks.LoadOrStore(key, lock)
+	return actual.(*stateLock)
+}
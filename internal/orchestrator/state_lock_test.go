@@ -0,0 +1,39 @@
+package orchestrator
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/filelock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTasksStateLock_HoldsFileLockForOtherProcesses(t *testing.T) {
+	repo, _ := setupTestRepo(t)
+	mu := tasksStateLock(repo)
+	mu.Lock()
+
+	// 他プロセスは state/tasks.json.lock の flock で待たされる
+	acquired := make(chan *filelock.Lock, 1)
+	go func() {
+		lock, err := filelock.Acquire(filepath.Join(repo.BaseDir(), "state", "tasks.json.lock"))
+		if err != nil {
+			t.Errorf("Acquire failed: %v", err)
+		}
+		acquired <- lock
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("file lock should be held while tasksStateLock is locked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mu.Unlock()
+	select {
+	case lock := <-acquired:
+		require.NoError(t, lock.Release())
+	case <-time.After(2 * time.Second):
+		t.Fatal("file lock was not released by Unlock")
+	}
+}
@@ -0,0 +1,139 @@
+package orchestrator
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/biwakonbu/agent-runner/internal/prompt"
+	"github.com/biwakonbu/agent-runner/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// maxPreviousErrorLen は再試行プロンプトに含める直前エラーの最大長
+const maxPreviousErrorLen = 2000
+
+// taskPromptData は Task をプロンプトテンプレート用のデータに変換する
+func taskPromptData(task *Task) prompt.TaskData {
+	data := prompt.TaskData{
+		ID:                 task.ID,
+		Title:              task.Title,
+		Kind:               task.Kind,
+		Description:        task.Description,
+		AcceptanceCriteria: task.AcceptanceCriteria,
+		Dependencies:       task.Dependencies,
+		Attempt:            task.AttemptCount,
+		PreviousError:      truncateTail(task.LastError, maxPreviousErrorLen),
+	}
+	if task.SuggestedImpl != nil {
+		data.SuggestedImpl = &prompt.SuggestedImpl{
+			Language:    task.SuggestedImpl.Language,
+			FilePaths:   task.SuggestedImpl.FilePaths,
+			Constraints: task.SuggestedImpl.Constraints,
+		}
+	}
+	return data
+}
+
+// taskStatePromptData は persistence.TaskState をプロンプトテンプレート用のデータに変換する
+func taskStatePromptData(task persistence.TaskState) prompt.TaskData {
+	data := prompt.TaskData{
+		ID:    task.TaskID,
+		Title: task.TaskID,
+		Kind:  task.Kind,
+	}
+	if goal, ok := task.Inputs["goal"].(string); ok {
+		data.Goal = goal
+	}
+	if constraints, ok := task.Inputs["constraints"].([]interface{}); ok {
+		for _, c := range constraints {
+			data.Constraints = append(data.Constraints, fmt.Sprintf("%v", c))
+		}
+	}
+	switch v := task.Inputs[InputKeyAttemptCount].(type) {
+	case float64:
+		data.Attempt = int(v)
+	case int:
+		data.Attempt = v
+	}
+	if lastErr, ok := task.Inputs[InputKeyLastError].(string); ok {
+		data.PreviousError = truncateTail(lastErr, maxPreviousErrorLen)
+	}
+	return data
+}
+
+// buildTaskConfig はテンプレートでプロンプトを生成し、agent-runner 向けの TaskConfig を組み立てる
+func buildTaskConfig(renderer *prompt.Renderer, data prompt.TaskData, details config.TaskDetails, runner *RunnerSpec) (config.TaskConfig, error) {
+	if renderer == nil {
+		renderer = prompt.Default()
+	}
+
+	prdText, err := renderer.TaskPrompt(data)
+	if err != nil {
+		return config.TaskConfig{}, err
+	}
+	metaSystemPrompt, err := renderer.MetaSystemPrompt(data)
+	if err != nil {
+		return config.TaskConfig{}, err
+	}
+
+	maxLoops := DefaultRunnerMaxLoops
+	workerKind := DefaultWorkerKind
+	reasoningEffort := ""
+	var workerFallback []string
+	var reviewer *config.ReviewerConfig
+	if runner != nil {
+		reasoningEffort = runner.ReasoningEffort
+		workerFallback = runner.WorkerFallback
+		if rc := runner.Reviewer; rc != nil && rc.Kind != "" {
+			reviewer = &config.ReviewerConfig{
+				Kind:         rc.Kind,
+				Model:        rc.Model,
+				SystemPrompt: rc.SystemPrompt,
+				WorkerType:   rc.WorkerType,
+			}
+		}
+		if runner.MaxLoops > 0 {
+			maxLoops = runner.MaxLoops
+		}
+		if runner.WorkerKind != "" {
+			workerKind = runner.WorkerKind
+		}
+	}
+
+	details.ID = data.ID
+	details.Repo = "."
+	details.PRD = config.PRDDetails{Text: prdText}
+
+	return config.TaskConfig{
+		Version: 1,
+		Task:    details,
+		Runner: config.RunnerConfig{
+			Meta:     config.MetaConfig{SystemPrompt: metaSystemPrompt},
+			Worker:   config.WorkerConfig{Kind: workerKind, ReasoningEffort: reasoningEffort, Fallback: workerFallback},
+			MaxLoops: maxLoops,
+			Reviewer: reviewer,
+		},
+	}, nil
+}
+
+// marshalTaskConfig は TaskConfig を agent-runner の stdin 用 YAML に変換する
+func marshalTaskConfig(cfg config.TaskConfig) (string, error) {
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal task config: %w", err)
+	}
+	return string(out), nil
+}
+
+// truncateTail は s が max を超える場合に末尾 max 文字を残す（エラーの原因は末尾に出やすい）
+func truncateTail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	start := len(s) - max
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return "..." + s[start:]
+}
@@ -0,0 +1,266 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/google/uuid"
+)
+
+// History action kinds of per-task operations (history/*.jsonl).
+const (
+	ActionKindTaskCanceled  = "task.canceled"
+	ActionKindTaskRetried   = "task.retried"
+	ActionKindTaskSkipped   = "task.skipped"
+	ActionKindTaskEscalated = "task.escalated" // リトライポリシーによるエスカレーション
+)
+
+// CancelTask cancels taskID. 実行中ならそのジョブのコンテキスト（agent-runner と
+// コンテナ）だけをキャンセルし、ジョブ終了時に CANCELED にする。実行中でなければ
+// 直接 CANCELED にする。キャンセルしたタスクの後続は DEPENDENCY_FAILED になる。
+func (e *ExecutionOrchestrator) CancelTask(taskID string) error {
+	e.cancelMu.Lock()
+	cancel, running := e.running[taskID]
+	if running {
+		e.canceled[taskID] = true
+		cancel()
+	}
+	e.cancelMu.Unlock()
+
+	if running {
+		e.logger.Info("canceling running task", slog.String("task_id", taskID))
+		e.recordTaskAction(ActionKindTaskCanceled, taskID, map[string]interface{}{
+			"previous_status": string(TaskStatusRunning),
+		})
+		return nil
+	}
+
+	oldStatus, err := e.updateTaskStatus(taskID, TaskStatusCanceled, func(task *persistence.TaskState) error {
+		if isTaskFinished(TaskStatus(task.Status)) {
+			return fmt.Errorf("task %s is already %s", taskID, task.Status)
+		}
+		clearTaskControlInputs(task)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	e.logger.Info("task canceled", slog.String("task_id", taskID), slog.String("previous_status", string(oldStatus)))
+	e.recordTaskAction(ActionKindTaskCanceled, taskID, map[string]interface{}{
+		"previous_status": string(oldStatus),
+	})
+	e.afterTaskControl(false)
+	return nil
+}
+
+// RetryTask immediately returns a FAILED, RETRY_WAIT or CANCELED task to
+// PENDING, ignoring the retry backoff. resetAttempts が true なら attempt_count を
+// 0 に戻す（リトライ上限の判定もやり直しになる）。
+func (e *ExecutionOrchestrator) RetryTask(taskID string, resetAttempts bool) error {
+	if e.isRunning(taskID) {
+		return fmt.Errorf("task %s is running", taskID)
+	}
+
+	oldStatus, err := e.updateTaskStatus(taskID, TaskStatusPending, func(task *persistence.TaskState) error {
+		switch TaskStatus(task.Status) {
+		case TaskStatusFailed, TaskStatusRetryWait, TaskStatusCanceled:
+		default:
+			return fmt.Errorf("task %s cannot be retried in status %s", taskID, task.Status)
+		}
+		clearTaskControlInputs(task)
+		if resetAttempts {
+			delete(task.Inputs, InputKeyAttemptCount)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	e.logger.Info("task retried",
+		slog.String("task_id", taskID),
+		slog.String("previous_status", string(oldStatus)),
+		slog.Bool("reset_attempts", resetAttempts),
+	)
+	e.updateLegacyTask(taskID, func(t *Task) {
+		t.NextRetryAt = nil
+		if resetAttempts {
+			t.AttemptCount = 0
+		}
+	})
+	e.recordTaskAction(ActionKindTaskRetried, taskID, map[string]interface{}{
+		"previous_status": string(oldStatus),
+		"reset_attempts":  resetAttempts,
+	})
+	e.afterTaskControl(true)
+	return nil
+}
+
+// SkipTask marks a task that is not running as SKIPPED and its node as
+// skipped, so that dependents treat it as satisfied.
+func (e *ExecutionOrchestrator) SkipTask(taskID string) error {
+	if e.isRunning(taskID) {
+		return fmt.Errorf("task %s is running; cancel it before skipping", taskID)
+	}
+
+	var nodeID string
+	oldStatus, err := e.updateTaskStatus(taskID, TaskStatusSkipped, func(task *persistence.TaskState) error {
+		switch TaskStatus(task.Status) {
+		case TaskStatusSucceeded, TaskStatusCompleted:
+			return fmt.Errorf("task %s is already %s", taskID, task.Status)
+		case TaskStatusSkipped:
+			// 前回のスキップでノードの更新に失敗していた場合は、ノードの更新だけをやり直す
+			status, err := e.nodeRuntimeStatus(task.NodeID)
+			if err != nil {
+				return err
+			}
+			if status == persistence.NodeRuntimeStatusSkipped {
+				return fmt.Errorf("task %s is already %s", taskID, task.Status)
+			}
+			nodeID = task.NodeID
+			return errSkipNodeMarkPending
+		}
+		clearTaskControlInputs(task)
+		nodeID = task.NodeID
+		return nil
+	})
+	reapply := errors.Is(err, errSkipNodeMarkPending)
+	if err != nil && !reapply {
+		return err
+	}
+
+	// タスク状態の保存に成功してから、後続タスクの依存解決のためノードをスキップ済み（完了扱い）にする
+	mu := tasksStateLock(e.Repo)
+	mu.Lock()
+	err = e.markNodeStatus(nodeID, persistence.NodeRuntimeStatusSkipped, "marked skipped by user")
+	mu.Unlock()
+	if err != nil {
+		e.logger.Error("task skipped but failed to update node runtime",
+			slog.String("task_id", taskID),
+			slog.String("node_id", nodeID),
+			slog.Any("error", err),
+		)
+		return fmt.Errorf("failed to mark node %s skipped: %w", nodeID, err)
+	}
+	if reapply {
+		e.logger.Info("node of skipped task marked skipped", slog.String("task_id", taskID), slog.String("node_id", nodeID))
+		e.afterTaskControl(true)
+		return nil
+	}
+	e.logger.Info("task skipped", slog.String("task_id", taskID), slog.String("previous_status", string(oldStatus)))
+	e.recordTaskAction(ActionKindTaskSkipped, taskID, map[string]interface{}{
+		"previous_status": string(oldStatus),
+		"node_id":         nodeID,
+	})
+	e.afterTaskControl(true)
+	return nil
+}
+
+// errSkipNodeMarkPending tells SkipTask that the task is already SKIPPED but
+// its node was not marked skipped, so only the node update must be redone.
+var errSkipNodeMarkPending = errors.New("skipped task node not yet marked skipped")
+
+// nodeRuntimeStatus returns the runtime status of nodeID ("" if unknown).
+func (e *ExecutionOrchestrator) nodeRuntimeStatus(nodeID string) (persistence.NodeRuntimeStatus, error) {
+	if nodeID == "" || e.Repo == nil {
+		return persistence.NodeRuntimeStatusSkipped, nil
+	}
+	nodesRuntime, err := e.Repo.State().LoadNodesRuntime()
+	if err != nil {
+		return "", fmt.Errorf("failed to load nodes runtime: %w", err)
+	}
+	for _, n := range nodesRuntime.Nodes {
+		if n.NodeID == nodeID {
+			return persistence.NodeRuntimeStatus(n.Status), nil
+		}
+	}
+	return "", nil
+}
+
+// updateTaskStatus sets taskID to newStatus after check accepts the current
+// state, and emits task:stateChange. 返り値は変更前のステータス。
+func (e *ExecutionOrchestrator) updateTaskStatus(taskID string, newStatus TaskStatus, check func(task *persistence.TaskState) error) (TaskStatus, error) {
+	mu := tasksStateLock(e.Repo)
+	mu.Lock()
+	defer mu.Unlock()
+
+	tasksState, err := e.Repo.State().LoadTasks()
+	if err != nil {
+		return "", fmt.Errorf("failed to load tasks state: %w", err)
+	}
+	task := findTaskState(tasksState, taskID)
+	if task == nil {
+		return "", fmt.Errorf("task not found: %s", taskID)
+	}
+	oldStatus := TaskStatus(task.Status)
+	if task.Inputs == nil {
+		task.Inputs = make(map[string]interface{})
+	}
+	if err := check(task); err != nil {
+		return oldStatus, err
+	}
+	task.Status = string(newStatus)
+	task.UpdatedAt = time.Now()
+	if err := e.Repo.State().SaveTasks(tasksState); err != nil {
+		return oldStatus, fmt.Errorf("failed to save task state: %w", err)
+	}
+	e.emitTaskStateChange(taskID, oldStatus, newStatus)
+	e.updateLegacyTask(taskID, func(t *Task) {
+		t.Status = newStatus
+	})
+	return oldStatus, nil
+}
+
+// clearTaskControlInputs removes the retry, failure and blocking bookkeeping
+// of a task whose status is changed manually.
+func clearTaskControlInputs(task *persistence.TaskState) {
+	delete(task.Inputs, InputKeyNextRetryAt)
+	delete(task.Inputs, InputKeyFailedPermanently)
+	setDependencyFailure(task, nil)
+}
+
+// afterTaskControl re-evaluates dependents after a manual status change.
+// schedule が true でループ実行中なら、実行可能になったタスクを即座にキューに入れる。
+func (e *ExecutionOrchestrator) afterTaskControl(schedule bool) {
+	if e.Scheduler == nil {
+		return
+	}
+	if _, err := e.Scheduler.PropagateDependencyFailures(); err != nil {
+		e.logger.Warn("failed to propagate dependency failures", slog.Any("error", err))
+	}
+	if !schedule || e.State() != ExecutionStateRunning {
+		return
+	}
+	e.triggerDependencyResolution()
+}
+
+func (e *ExecutionOrchestrator) isRunning(taskID string) bool {
+	e.cancelMu.Lock()
+	defer e.cancelMu.Unlock()
+	_, ok := e.running[taskID]
+	return ok
+}
+
+// recordTaskAction appends a per-task operation to the workspace history.
+func (e *ExecutionOrchestrator) recordTaskAction(kind, taskID string, payload map[string]interface{}) {
+	if e.Repo == nil || e.Repo.History() == nil {
+		return
+	}
+	payload["task_id"] = taskID
+	action := &persistence.Action{
+		ID:          uuid.New().String(),
+		At:          time.Now(),
+		Kind:        kind,
+		WorkspaceID: e.WorkspaceID,
+		Payload:     payload,
+	}
+	if err := e.Repo.History().AppendAction(action); err != nil {
+		e.logger.Warn("failed to append history action",
+			slog.String("kind", kind),
+			slog.String("task_id", taskID),
+			slog.Any("error", err),
+		)
+	}
+}
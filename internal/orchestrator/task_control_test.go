@@ -0,0 +1,169 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// setupControlTasks creates node-a <- node-b (b depends on a) with task-a in
+// statusA and task-b PENDING.
+func setupControlTasks(t *testing.T, statusA TaskStatus, inputsA map[string]interface{}) (*ExecutionOrchestrator, persistence.WorkspaceRepository, *MockEventEmitter) {
+	t.Helper()
+	repo, queue := setupTestRepo(t)
+	saveDesign(t, repo, []persistence.NodeDesign{
+		{NodeID: "node-a"},
+		{NodeID: "node-b", Dependencies: []string{"node-a"}},
+	})
+	now := time.Now()
+	saveState(t, repo, []persistence.TaskState{
+		{TaskID: "task-a", NodeID: "node-a", Status: string(statusA), CreatedAt: now, Inputs: inputsA},
+		{TaskID: "task-b", NodeID: "node-b", Status: string(TaskStatusPending), CreatedAt: now},
+	}, nil)
+
+	emitter := new(MockEventEmitter)
+	emitter.On("Emit", mock.Anything, mock.Anything).Return()
+	scheduler := NewScheduler(repo, queue, emitter)
+	orch := NewExecutionOrchestrator(scheduler, nil, repo, queue, emitter, nil, []string{"default"})
+	orch.WorkspaceID = "ws-test"
+	return orch, repo, emitter
+}
+
+func loadStatuses(t *testing.T, repo persistence.WorkspaceRepository) map[string]persistence.TaskState {
+	t.Helper()
+	state, err := repo.State().LoadTasks()
+	require.NoError(t, err)
+	byID := map[string]persistence.TaskState{}
+	for _, ts := range state.Tasks {
+		byID[ts.TaskID] = ts
+	}
+	return byID
+}
+
+func assertStateChange(t *testing.T, emitter *MockEventEmitter, taskID string, from, to TaskStatus) {
+	t.Helper()
+	emitter.AssertCalled(t, "Emit", EventTaskStateChange, mock.MatchedBy(func(ev TaskStateChangeEvent) bool {
+		return ev.TaskID == taskID && ev.OldStatus == from && ev.NewStatus == to
+	}))
+}
+
+func assertHistoryAction(t *testing.T, repo persistence.WorkspaceRepository, kind, taskID string) {
+	t.Helper()
+	actions, err := repo.History().ListActions(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	for _, a := range actions {
+		if a.Kind == kind && a.Payload["task_id"] == taskID {
+			assert.Equal(t, "ws-test", a.WorkspaceID)
+			return
+		}
+	}
+	t.Errorf("history action %s for %s not found in %+v", kind, taskID, actions)
+}
+
+func TestExecutionOrchestrator_CancelTask_NotRunning(t *testing.T) {
+	orch, repo, emitter := setupControlTasks(t, TaskStatusPending, nil)
+
+	require.NoError(t, orch.CancelTask("task-a"))
+
+	tasks := loadStatuses(t, repo)
+	assert.Equal(t, string(TaskStatusCanceled), tasks["task-a"].Status)
+	// キャンセルされたタスクの後続は DEPENDENCY_FAILED になる
+	assert.Equal(t, string(TaskStatusDependencyFailed), tasks["task-b"].Status)
+	assertStateChange(t, emitter, "task-a", TaskStatusPending, TaskStatusCanceled)
+	assertHistoryAction(t, repo, ActionKindTaskCanceled, "task-a")
+
+	assert.Error(t, orch.CancelTask("task-a"), "already canceled")
+}
+
+func TestExecutionOrchestrator_RetryTask(t *testing.T) {
+	orch, repo, emitter := setupControlTasks(t, TaskStatusFailed, map[string]interface{}{
+		InputKeyAttemptCount:      3,
+		InputKeyFailedPermanently: true,
+	})
+	_, err := orch.Scheduler.PropagateDependencyFailures()
+	require.NoError(t, err)
+	require.Equal(t, string(TaskStatusDependencyFailed), loadStatuses(t, repo)["task-b"].Status)
+
+	require.NoError(t, orch.RetryTask("task-a", true))
+
+	tasks := loadStatuses(t, repo)
+	assert.Equal(t, string(TaskStatusPending), tasks["task-a"].Status)
+	assert.Nil(t, tasks["task-a"].Inputs[InputKeyAttemptCount])
+	assert.Nil(t, tasks["task-a"].Inputs[InputKeyFailedPermanently])
+	assert.Equal(t, string(TaskStatusPending), tasks["task-b"].Status)
+	assertStateChange(t, emitter, "task-a", TaskStatusFailed, TaskStatusPending)
+	assertStateChange(t, emitter, "task-b", TaskStatusDependencyFailed, TaskStatusPending)
+	assertHistoryAction(t, repo, ActionKindTaskRetried, "task-a")
+
+	assert.Error(t, orch.RetryTask("task-a", false), "pending task cannot be retried")
+}
+
+func TestExecutionOrchestrator_RetryTask_KeepsAttempts(t *testing.T) {
+	orch, repo, _ := setupControlTasks(t, TaskStatusRetryWait, map[string]interface{}{
+		InputKeyAttemptCount: 2,
+		InputKeyNextRetryAt:  time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+
+	require.NoError(t, orch.RetryTask("task-a", false))
+
+	task := loadStatuses(t, repo)["task-a"]
+	assert.Equal(t, string(TaskStatusPending), task.Status)
+	assert.EqualValues(t, 2, task.Inputs[InputKeyAttemptCount])
+	assert.Nil(t, task.Inputs[InputKeyNextRetryAt])
+}
+
+func TestExecutionOrchestrator_SkipTask(t *testing.T) {
+	orch, repo, emitter := setupControlTasks(t, TaskStatusFailed, map[string]interface{}{
+		InputKeyFailedPermanently: true,
+	})
+	_, err := orch.Scheduler.PropagateDependencyFailures()
+	require.NoError(t, err)
+
+	require.NoError(t, orch.SkipTask("task-a"))
+
+	tasks := loadStatuses(t, repo)
+	assert.Equal(t, string(TaskStatusSkipped), tasks["task-a"].Status)
+	assert.Equal(t, string(TaskStatusPending), tasks["task-b"].Status)
+	assertStateChange(t, emitter, "task-a", TaskStatusFailed, TaskStatusSkipped)
+	assertHistoryAction(t, repo, ActionKindTaskSkipped, "task-a")
+
+	// スキップしたノードは後続から完了扱いになる
+	taskB := tasks["task-b"]
+	assert.True(t, orch.Scheduler.allDependenciesSatisfied(&taskB))
+
+	assert.Error(t, orch.SkipTask("task-a"), "already skipped")
+	assert.Error(t, orch.SkipTask("task-unknown"))
+}
+
+func TestExecutionOrchestrator_SkipTask_RejectedLeavesNodeUntouched(t *testing.T) {
+	orch, repo, _ := setupControlTasks(t, TaskStatusSucceeded, nil)
+
+	assert.Error(t, orch.SkipTask("task-a"), "already succeeded")
+
+	// 拒否されたスキップはノードの実行時ステータスを変更しない
+	nodes, err := repo.State().LoadNodesRuntime()
+	require.NoError(t, err)
+	for _, n := range nodes.Nodes {
+		assert.NotEqual(t, string(persistence.NodeRuntimeStatusSkipped), n.Status, "node %s", n.NodeID)
+	}
+	assert.Equal(t, string(TaskStatusSucceeded), loadStatuses(t, repo)["task-a"].Status)
+}
+
+func TestExecutionOrchestrator_SkipTask_ReappliesNodeMark(t *testing.T) {
+	// 前回のスキップでタスクだけが SKIPPED になり、ノードの更新に失敗した状態
+	orch, repo, _ := setupControlTasks(t, TaskStatusSkipped, nil)
+
+	taskB := loadStatuses(t, repo)["task-b"]
+	require.False(t, orch.Scheduler.allDependenciesSatisfied(&taskB))
+
+	require.NoError(t, orch.SkipTask("task-a"))
+
+	assert.Equal(t, string(TaskStatusSkipped), loadStatuses(t, repo)["task-a"].Status)
+	assert.True(t, orch.Scheduler.allDependenciesSatisfied(&taskB))
+
+	assert.Error(t, orch.SkipTask("task-a"), "already skipped and node marked")
+}
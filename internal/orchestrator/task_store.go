@@ -8,6 +8,9 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/orchestrator/persistence"
+	"github.com/biwakonbu/agent-runner/internal/usage"
 )
 
 // TaskStatus represents the status of a task.
@@ -23,6 +26,12 @@ const (
 	TaskStatusCanceled  TaskStatus = "CANCELED"
 	TaskStatusBlocked   TaskStatus = "BLOCKED"
 	TaskStatusRetryWait TaskStatus = "RETRY_WAIT"
+	// TaskStatusDependencyFailed は祖先ノードが恒久的に失敗したため実行できないタスク
+	TaskStatusDependencyFailed TaskStatus = "DEPENDENCY_FAILED"
+	// TaskStatusSkipped は手動でスキップされたタスク（後続タスクからは完了扱い）
+	TaskStatusSkipped TaskStatus = "SKIPPED"
+	// TaskStatusScheduled は実行予定時刻（inputs.next_run_at）を待っているスケジュール付きタスク
+	TaskStatusScheduled TaskStatus = "SCHEDULED"
 )
 
 // Default runner settings for AgentRunner tasks.
@@ -37,6 +46,25 @@ const (
 	InputKeyNextRetryAt      = "next_retry_at"
 	InputKeyRunnerMaxLoops   = "runner_max_loops"
 	InputKeyRunnerWorkerKind = "runner_worker_kind"
+	InputKeyLastError        = "last_error"
+	InputKeyPoolID           = "pool_id"
+	// リトライポリシー: 直前の失敗の分類と、エスカレーションで上書きした思考の深さ
+	InputKeyLastErrorClass        = "last_error_class"
+	InputKeyRunnerReasoningEffort = "runner_reasoning_effort"
+	// Worker kind が利用できない場合に順に試す worker kind のリスト（runner.worker.fallback）
+	InputKeyRunnerWorkerFallback = "runner_worker_fallback"
+	// BLOCKED の理由: ブロックしている依存の経路（ノード ID 列）と説明
+	InputKeyBlockedBy     = "blocked_by"
+	InputKeyBlockedReason = "blocked_reason"
+	// 恒久的な失敗（リトライ上限到達・バックログ行き）の印と、DEPENDENCY_FAILED の原因ノード
+	InputKeyFailedPermanently = "failed_permanently"
+	InputKeyFailedAncestor    = "failed_ancestor"
+	// スケジュール（実行時刻 1 回、または cron 式による繰り返し）と次回実行時刻、実行回数と実行履歴
+	InputKeyScheduleRunAt = "schedule_run_at"
+	InputKeyScheduleCron  = "schedule_cron"
+	InputKeyNextRunAt     = "next_run_at"
+	InputKeyScheduleRun   = "schedule_run"
+	InputKeyScheduleRuns  = "schedule_runs"
 )
 
 // Task represents a unit of work.
@@ -44,6 +72,7 @@ type Task struct {
 	// 基本フィールド
 	ID        string     `json:"id"`
 	Title     string     `json:"title"`
+	Kind      string     `json:"kind,omitempty"` // タスク種別（プロンプトテンプレートの選択に使用）
 	Status    TaskStatus `json:"status"`
 	PoolID    string     `json:"poolId"`
 	CreatedAt time.Time  `json:"createdAt"`
@@ -64,6 +93,11 @@ type Task struct {
 	// リトライ管理用 (v2.0 Extension)
 	AttemptCount int        `json:"attemptCount,omitempty"` // 試行回数
 	NextRetryAt  *time.Time `json:"nextRetryAt,omitempty"`  // 次回リトライ予定時刻
+	LastError    string     `json:"lastError,omitempty"`    // 直前の試行のエラー要約（再試行プロンプト用）
+
+	// スケジュール実行
+	Schedule  *TaskSchedule `json:"schedule,omitempty"`  // 実行時刻または cron 式
+	NextRunAt *time.Time    `json:"nextRunAt,omitempty"` // 次回実行予定時刻（SCHEDULED のとき）
 
 	// Phase 1: Data Model Enhancements
 	SuggestedImpl *SuggestedImpl `json:"suggestedImpl,omitempty"` // 実装のヒント（ファイルパス、言語等）
@@ -75,8 +109,13 @@ type Task struct {
 
 // RunnerSpec holds execution hints for AgentRunner.
 type RunnerSpec struct {
-	MaxLoops   int    `json:"maxLoops,omitempty"`
-	WorkerKind string `json:"workerKind,omitempty"`
+	MaxLoops        int    `json:"maxLoops,omitempty"`
+	WorkerKind      string `json:"workerKind,omitempty"`
+	ReasoningEffort string `json:"reasoningEffort,omitempty"`
+	// WorkerFallback は runner.worker.fallback（Worker kind が利用できない場合の代替 kind）
+	WorkerFallback []string `json:"workerFallback,omitempty"`
+	// Reviewer は runner.reviewer（独立レビュー）。nil なら無効
+	Reviewer *persistence.ReviewerConfig `json:"reviewer,omitempty"`
 }
 
 // SuggestedImpl represents the suggested implementation details from the Planner.
@@ -106,12 +145,13 @@ const (
 
 // Attempt represents a single execution attempt of a task.
 type Attempt struct {
-	ID           string        `json:"id"`
-	TaskID       string        `json:"taskId"`
-	Status       AttemptStatus `json:"status"`
-	StartedAt    time.Time     `json:"startedAt"`
-	FinishedAt   *time.Time    `json:"finishedAt,omitempty"`
-	ErrorSummary string        `json:"errorSummary,omitempty"`
+	ID           string         `json:"id"`
+	TaskID       string         `json:"taskId"`
+	Status       AttemptStatus  `json:"status"`
+	StartedAt    time.Time      `json:"startedAt"`
+	FinishedAt   *time.Time     `json:"finishedAt,omitempty"`
+	ErrorSummary string         `json:"errorSummary,omitempty"`
+	Usage        *usage.Summary `json:"usage,omitempty"` // agent-runner が報告したトークン使用量
 }
 
 // TaskStore handles task and attempt persistence.
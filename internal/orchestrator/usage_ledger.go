@@ -0,0 +1,221 @@
+package orchestrator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biwakonbu/agent-runner/internal/logging"
+	"github.com/biwakonbu/agent-runner/internal/usage"
+)
+
+// 使用量の発生元。空の Source は UsageSourceTask として扱う（旧形式との互換）
+const (
+	UsageSourceTask = "task"
+	UsageSourceChat = "chat"
+)
+
+// UsageRecord は 1 試行（またはタスクに紐付かない 1 回の Meta 呼び出し）分のトークン使用量を表す
+type UsageRecord struct {
+	Source     string        `json:"source,omitempty"`
+	TaskID     string        `json:"taskId,omitempty"`
+	AttemptID  string        `json:"attemptId,omitempty"`
+	Attempt    int           `json:"attempt,omitempty"`   // 1 始まりの試行番号
+	SessionID  string        `json:"sessionId,omitempty"` // Source=chat の場合のチャットセッション ID
+	RecordedAt time.Time     `json:"recordedAt"`
+	Usage      usage.Summary `json:"usage"`
+}
+
+// isTask はタスク試行のレコードかどうかを返す
+func (r UsageRecord) isTask() bool {
+	return r.Source == "" || r.Source == UsageSourceTask
+}
+
+// TaskUsage はタスク単位の使用量集計を表す
+type TaskUsage struct {
+	TaskID   string        `json:"taskId"`
+	Total    usage.Summary `json:"total"`
+	Attempts []UsageRecord `json:"attempts"`
+}
+
+// UsageReport はワークスペース全体の使用量集計を表す
+type UsageReport struct {
+	Total usage.Summary `json:"total"`
+	Tasks []TaskUsage   `json:"tasks"`
+	// Sources はタスクに紐付かない使用量（チャットの Meta 呼び出し等）の発生元別集計
+	Sources map[string]usage.Summary `json:"sources,omitempty"`
+}
+
+// UsageLedger は試行ごとの使用量を <workspace>/usage/usage.jsonl に追記保存する
+type UsageLedger struct {
+	workspaceDir string
+	mu           sync.Mutex
+	logger       *slog.Logger
+}
+
+// NewUsageLedger は UsageLedger を作成する
+func NewUsageLedger(workspaceDir string) *UsageLedger {
+	return &UsageLedger{
+		workspaceDir: workspaceDir,
+		logger:       logging.WithComponent(slog.Default(), "usage-ledger"),
+	}
+}
+
+// ledgerPath は台帳ファイルのパスを返す
+func (l *UsageLedger) ledgerPath() string {
+	return filepath.Join(l.workspaceDir, "usage", "usage.jsonl")
+}
+
+// Append は使用量レコードを追記する
+func (l *UsageLedger) Append(rec UsageRecord) error {
+	if rec.RecordedAt.IsZero() {
+		rec.RecordedAt = time.Now()
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.ledgerPath()), 0755); err != nil {
+		return fmt.Errorf("failed to create usage dir: %w", err)
+	}
+	f, err := os.OpenFile(l.ledgerPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage record: %w", err)
+	}
+
+	l.logger.Debug("usage recorded",
+		slog.String("source", rec.Source),
+		slog.String("task_id", rec.TaskID),
+		slog.String("attempt_id", rec.AttemptID),
+		slog.Int("total_tokens", rec.Usage.TotalTokens()),
+		slog.Float64("cost_usd", rec.Usage.CostUSD),
+	)
+	return nil
+}
+
+// List は記録済みの全レコードを記録順に返す
+func (l *UsageLedger) List() ([]UsageRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.ledgerPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []UsageRecord{}, nil
+		}
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	records := []UsageRecord{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec UsageRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			// 壊れた行はスキップ（追記途中のクラッシュ等）
+			l.logger.Warn("skipping malformed usage record", slog.Any("error", err))
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return records, nil
+}
+
+// Report はワークスペース全体・タスク単位・試行単位・発生元別の集計を返す
+func (l *UsageLedger) Report() (*UsageReport, error) {
+	records, err := l.List()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UsageReport{Tasks: []TaskUsage{}}
+	byTask := make(map[string]*TaskUsage)
+	var order []string
+	for _, rec := range records {
+		report.Total.Merge(rec.Usage)
+		if !rec.isTask() {
+			if report.Sources == nil {
+				report.Sources = make(map[string]usage.Summary)
+			}
+			sum := report.Sources[rec.Source]
+			sum.Merge(rec.Usage)
+			report.Sources[rec.Source] = sum
+			continue
+		}
+		tu, ok := byTask[rec.TaskID]
+		if !ok {
+			tu = &TaskUsage{TaskID: rec.TaskID}
+			byTask[rec.TaskID] = tu
+			order = append(order, rec.TaskID)
+		}
+		tu.Total.Merge(rec.Usage)
+		tu.Attempts = append(tu.Attempts, rec)
+	}
+
+	for _, id := range order {
+		tu := byTask[id]
+		sort.SliceStable(tu.Attempts, func(i, j int) bool {
+			return tu.Attempts[i].Attempt < tu.Attempts[j].Attempt
+		})
+		report.Tasks = append(report.Tasks, *tu)
+	}
+	return report, nil
+}
+
+// parseUsageEvent は agent-runner 出力から task:usage イベントを探して集計を返す。
+// 複数ある場合は最後のものを採用する。見つからなければ nil。
+func parseUsageEvent(output string) *usage.Summary {
+	var found *usage.Summary
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") || !strings.Contains(line, "task:usage") {
+			continue
+		}
+		var entry struct {
+			EventType         string                   `json:"event_type"`
+			InputTokens       int                      `json:"input_tokens"`
+			CachedInputTokens int                      `json:"cached_input_tokens"`
+			OutputTokens      int                      `json:"output_tokens"`
+			Calls             int                      `json:"calls"`
+			CostUSD           float64                  `json:"cost_usd"`
+			ByModel           map[string]usage.Summary `json:"by_model"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.EventType != "task:usage" {
+			continue
+		}
+		found = &usage.Summary{
+			InputTokens:       entry.InputTokens,
+			CachedInputTokens: entry.CachedInputTokens,
+			OutputTokens:      entry.OutputTokens,
+			CostUSD:           entry.CostUSD,
+			Calls:             entry.Calls,
+			ByModel:           entry.ByModel,
+		}
+	}
+	return found
+}
@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/biwakonbu/agent-runner/internal/usage"
+)
+
+func TestUsageLedger_AppendAndReport(t *testing.T) {
+	ledger := NewUsageLedger(t.TempDir())
+
+	records := []UsageRecord{
+		{TaskID: "task-1", AttemptID: "a2", Attempt: 2, Usage: usage.Summary{InputTokens: 20, OutputTokens: 2, CostUSD: 0.2, Calls: 1}},
+		{TaskID: "task-1", AttemptID: "a1", Attempt: 1, Usage: usage.Summary{InputTokens: 10, OutputTokens: 1, CostUSD: 0.1, Calls: 1}},
+		{TaskID: "task-2", AttemptID: "b1", Attempt: 1, Usage: usage.Summary{InputTokens: 5, OutputTokens: 5, CostUSD: 0.05, Calls: 2}},
+	}
+	for _, rec := range records {
+		if err := ledger.Append(rec); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	report, err := ledger.Report()
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if report.Total.InputTokens != 35 || report.Total.OutputTokens != 8 || report.Total.Calls != 4 {
+		t.Errorf("unexpected workspace total: %+v", report.Total)
+	}
+	if len(report.Tasks) != 2 || report.Tasks[0].TaskID != "task-1" {
+		t.Fatalf("unexpected tasks: %+v", report.Tasks)
+	}
+	task1 := report.Tasks[0]
+	if task1.Total.InputTokens != 30 || len(task1.Attempts) != 2 || task1.Attempts[0].AttemptID != "a1" {
+		t.Errorf("unexpected task-1 usage: %+v", task1)
+	}
+}
+
+func TestUsageLedger_ReportEmpty(t *testing.T) {
+	report, err := NewUsageLedger(t.TempDir()).Report()
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if report.Total.Calls != 0 || len(report.Tasks) != 0 {
+		t.Errorf("expected empty report, got %+v", report)
+	}
+}
+
+func TestUsageLedger_ReportSeparatesChatUsage(t *testing.T) {
+	ledger := NewUsageLedger(t.TempDir())
+
+	records := []UsageRecord{
+		{Source: UsageSourceTask, TaskID: "task-1", AttemptID: "a1", Attempt: 1, Usage: usage.Summary{InputTokens: 10, OutputTokens: 1, Calls: 1}},
+		{Source: UsageSourceChat, SessionID: "s1", Usage: usage.Summary{InputTokens: 100, OutputTokens: 10, CostUSD: 0.5, Calls: 1}},
+		{Source: UsageSourceChat, SessionID: "s2", Usage: usage.Summary{InputTokens: 50, OutputTokens: 5, CostUSD: 0.25, Calls: 1}},
+	}
+	for _, rec := range records {
+		if err := ledger.Append(rec); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	report, err := ledger.Report()
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if report.Total.InputTokens != 160 || report.Total.Calls != 3 {
+		t.Errorf("workspace total should include chat usage: %+v", report.Total)
+	}
+	if len(report.Tasks) != 1 || report.Tasks[0].TaskID != "task-1" {
+		t.Fatalf("chat usage should not appear as a task: %+v", report.Tasks)
+	}
+	chat := report.Sources[UsageSourceChat]
+	if chat.InputTokens != 150 || chat.Calls != 2 || chat.CostUSD != 0.75 {
+		t.Errorf("unexpected chat usage: %+v", chat)
+	}
+}
+
+func TestParseUsageEvent(t *testing.T) {
+	output := `{"level":"INFO","msg":"starting task"}
+not json
+{"level":"INFO","msg":"task usage","event_type":"task:usage","input_tokens":120,"cached_input_tokens":20,"output_tokens":30,"calls":3,"cost_usd":0.01,"by_model":{"gpt-5.2":{"inputTokens":120,"cachedInputTokens":20,"outputTokens":30,"costUsd":0.01,"calls":3}}}`
+
+	s := parseUsageEvent(output)
+	if s == nil {
+		t.Fatal("expected usage summary")
+	}
+	if s.InputTokens != 120 || s.CachedInputTokens != 20 || s.OutputTokens != 30 || s.Calls != 3 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+	if s.ByModel["gpt-5.2"].Calls != 3 {
+		t.Errorf("unexpected by-model: %+v", s.ByModel)
+	}
+
+	if parseUsageEvent("plain output") != nil {
+		t.Error("expected nil when no usage event")
+	}
+}
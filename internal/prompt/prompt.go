@@ -0,0 +1,206 @@
+// Package prompt は text/template ベースのプロンプト生成を提供する。
+//
+// 既定テンプレート（templates/*.tmpl）を埋め込み、~/.multiverse/prompts と
+// <project>/.multiverse/prompts に置いた同名ファイルで上書きできる。
+// タスク種別ごとの上書きは "<name>.<kind>.tmpl"（例: task.implementation.tmpl）で指定する。
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Template names.
+const (
+	TemplateTask       = "task"        // Worker に渡すタスクプロンプト（PRD テキスト）
+	TemplateRetry      = "retry"       // 再試行時にタスクプロンプトへ追記する
+	TemplateMetaSystem = "meta_system" // Meta エージェントのシステムプロンプト
+)
+
+// metaCallTemplatePrefix prefixes the per-call Meta system prompt templates
+// ("meta_<type>", e.g. meta_next_action).
+const metaCallTemplatePrefix = "meta_"
+
+// Response formats of Meta calls (MetaCallData.Format).
+const (
+	MetaFormatJSON = "json" // HTTP プロバイダ
+	MetaFormatYAML = "yaml" // CLI プロバイダ
+)
+
+// OverridesDir is the directory (relative to a workspace or ~/.multiverse)
+// that holds template overrides.
+const OverridesDir = "prompts"
+
+const templateExt = ".tmpl"
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// SuggestedImpl mirrors the planner's implementation hints.
+type SuggestedImpl struct {
+	Language    string
+	FilePaths   []string
+	Constraints []string
+}
+
+// TaskData is the data available to task, retry and meta_system templates.
+type TaskData struct {
+	ID                 string
+	Title              string
+	Kind               string // planning, implementation, test, ...
+	Description        string
+	Goal               string
+	AcceptanceCriteria []string
+	Constraints        []string
+	Dependencies       []string
+	SuggestedImpl      *SuggestedImpl
+	Attempt            int    // 1 始まりの試行番号（0 は不明）
+	PreviousError      string // 直前の試行のエラー要約
+}
+
+// MetaCallData is the data available to the per-call Meta system prompt templates.
+type MetaCallData struct {
+	Type   string // Meta メッセージ種別（plan_task 等）
+	Format string // MetaFormatJSON / MetaFormatYAML
+}
+
+// Renderer renders prompts from the default templates and overrides.
+type Renderer struct {
+	sources map[string]string // テンプレート名（拡張子なし）-> 本文
+}
+
+// Default returns a renderer with only the built-in templates.
+func Default() *Renderer {
+	r := &Renderer{sources: make(map[string]string)}
+	entries, _ := fs.ReadDir(defaultTemplates, "templates")
+	for _, entry := range entries {
+		data, err := defaultTemplates.ReadFile(path.Join("templates", entry.Name()))
+		if err != nil {
+			continue
+		}
+		r.sources[strings.TrimSuffix(entry.Name(), templateExt)] = string(data)
+	}
+	return r
+}
+
+// NewRenderer returns a renderer with the built-in templates overlaid by
+// every *.tmpl file in dirs (later directories win). Missing directories are skipped.
+func NewRenderer(dirs ...string) (*Renderer, error) {
+	r := Default()
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read prompt dir %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != templateExt {
+				continue
+			}
+			p := filepath.Join(dir, entry.Name())
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read prompt template %s: %w", p, err)
+			}
+			name := strings.TrimSuffix(entry.Name(), templateExt)
+			if _, err := parse(name, string(data)); err != nil {
+				return nil, fmt.Errorf("%s: %w", p, err)
+			}
+			r.sources[name] = string(data)
+		}
+	}
+	return r, nil
+}
+
+// Load returns a renderer using ~/.multiverse/prompts followed by
+// <projectRoot>/.multiverse/prompts.
+func Load(projectRoot string) (*Renderer, error) {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".multiverse", OverridesDir))
+	}
+	if projectRoot != "" {
+		dirs = append(dirs, filepath.Join(projectRoot, ".multiverse", OverridesDir))
+	}
+	return NewRenderer(dirs...)
+}
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+	"trim": strings.TrimSpace,
+}
+
+func parse(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// Render renders the template name for the task kind. "<name>.<kind>" is
+// preferred over "<name>" when present. The result is trimmed.
+func (r *Renderer) Render(name, kind string, data any) (string, error) {
+	src, ok := "", false
+	if kind != "" {
+		src, ok = r.sources[name+"."+kind]
+	}
+	if !ok {
+		src, ok = r.sources[name]
+	}
+	if !ok {
+		return "", fmt.Errorf("prompt template not found: %s", name)
+	}
+	t, err := parse(name, src)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// TaskPrompt renders the worker task prompt. From the second attempt on,
+// the retry template is appended.
+func (r *Renderer) TaskPrompt(data TaskData) (string, error) {
+	text, err := r.Render(TemplateTask, data.Kind, data)
+	if err != nil {
+		return "", err
+	}
+	if data.Attempt > 1 {
+		retry, err := r.Render(TemplateRetry, data.Kind, data)
+		if err != nil {
+			return "", err
+		}
+		if retry != "" {
+			text += "\n\n" + retry
+		}
+	}
+	return text, nil
+}
+
+// MetaSystemPrompt renders the Meta system prompt. An empty result means
+// the provider's built-in prompts are used.
+func (r *Renderer) MetaSystemPrompt(data TaskData) (string, error) {
+	return r.Render(TemplateMetaSystem, data.Kind, data)
+}
+
+// MetaCallPrompt renders the Meta system prompt for the call kind msgType
+// (template "meta_<msgType>") in the given response format.
+func (r *Renderer) MetaCallPrompt(msgType, format string) (string, error) {
+	return r.Render(metaCallTemplatePrefix+msgType, "", MetaCallData{Type: msgType, Format: format})
+}
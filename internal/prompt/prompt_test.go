@@ -0,0 +1,144 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefault_TaskPrompt(t *testing.T) {
+	r := Default()
+	data := TaskData{
+		Title:              "Add login",
+		Description:        "Implement login form",
+		AcceptanceCriteria: []string{"form renders", "submit works"},
+		SuggestedImpl: &SuggestedImpl{
+			Language:  "ts",
+			FilePaths: []string{"src/login.ts"},
+		},
+	}
+
+	got, err := r.TaskPrompt(data)
+	if err != nil {
+		t.Fatalf("TaskPrompt() error = %v", err)
+	}
+	want := `Execute task: Add login
+
+Description:
+Implement login form
+
+Acceptance Criteria:
+- form renders
+- submit works
+
+Suggested Implementation:
+Language: ts
+Target Files:
+- src/login.ts`
+	if got != want {
+		t.Errorf("TaskPrompt() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDefault_TaskPromptRetry(t *testing.T) {
+	r := Default()
+	got, err := r.TaskPrompt(TaskData{Title: "t", Attempt: 3, PreviousError: "exit status 1"})
+	if err != nil {
+		t.Fatalf("TaskPrompt() error = %v", err)
+	}
+	if !strings.Contains(got, "attempt 3") || !strings.Contains(got, "exit status 1") {
+		t.Errorf("retry section missing: %s", got)
+	}
+
+	first, _ := r.TaskPrompt(TaskData{Title: "t", Attempt: 1, PreviousError: "ignored"})
+	if strings.Contains(first, "ignored") {
+		t.Errorf("first attempt should not include retry section: %s", first)
+	}
+}
+
+func TestDefault_MetaSystemPromptEmpty(t *testing.T) {
+	got, err := Default().MetaSystemPrompt(TaskData{Title: "t"})
+	if err != nil {
+		t.Fatalf("MetaSystemPrompt() error = %v", err)
+	}
+	if got != "" {
+		t.Errorf("default meta system prompt should be empty, got %q", got)
+	}
+}
+
+func TestDefault_MetaCallPrompts(t *testing.T) {
+	r := Default()
+	got, err := r.MetaCallPrompt("next_action", MetaFormatJSON)
+	if err != nil {
+		t.Fatalf("MetaCallPrompt() error = %v", err)
+	}
+	want := "You are a Meta-agent that orchestrates a coding task.\nOutput MUST be a JSON block."
+	if got != want {
+		t.Errorf("MetaCallPrompt(next_action, json) = %q, want %q", got, want)
+	}
+
+	for _, msgType := range []string{"plan_task", "next_action", "completion_assessment"} {
+		for _, format := range []string{MetaFormatJSON, MetaFormatYAML} {
+			got, err := r.MetaCallPrompt(msgType, format)
+			if err != nil || !strings.HasPrefix(got, "You are a Meta-agent") {
+				t.Errorf("MetaCallPrompt(%s, %s) = %q, %v", msgType, format, got, err)
+			}
+		}
+		if got, _ := r.MetaCallPrompt(msgType, MetaFormatYAML); !strings.Contains(got, "YAML block") {
+			t.Errorf("yaml prompt for %s should ask for YAML: %q", msgType, got)
+		}
+	}
+
+	if _, err := r.MetaCallPrompt("unknown", MetaFormatJSON); err == nil {
+		t.Error("expected error for unknown call kind")
+	}
+}
+
+func TestNewRenderer_Overrides(t *testing.T) {
+	global := t.TempDir()
+	workspace := t.TempDir()
+	write := func(dir, name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(global, "task.tmpl", "global {{.Title}}")
+	write(workspace, "task.tmpl", "workspace {{.Title}}")
+	write(workspace, "task.test.tmpl", "test kind {{.Title}}")
+	write(workspace, "meta_system.tmpl", "You plan {{.Kind}} tasks.")
+	write(workspace, "meta_next_action.tmpl", "Decide ({{.Type}}, {{.Format}}).")
+	write(workspace, "notes.txt", "ignored")
+
+	r, err := NewRenderer(global, filepath.Join(t.TempDir(), "missing"), workspace)
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+
+	if got, _ := r.TaskPrompt(TaskData{Title: "a", Kind: "implementation"}); got != "workspace a" {
+		t.Errorf("workspace override not applied: %q", got)
+	}
+	if got, _ := r.TaskPrompt(TaskData{Title: "a", Kind: "test"}); got != "test kind a" {
+		t.Errorf("kind override not applied: %q", got)
+	}
+	if got, _ := r.MetaSystemPrompt(TaskData{Kind: "test"}); got != "You plan test tasks." {
+		t.Errorf("meta system override not applied: %q", got)
+	}
+	if got, _ := r.MetaCallPrompt("next_action", MetaFormatYAML); got != "Decide (next_action, yaml)." {
+		t.Errorf("meta call override not applied: %q", got)
+	}
+	if got, _ := r.MetaCallPrompt("plan_task", MetaFormatJSON); !strings.Contains(got, "plans software development tasks") {
+		t.Errorf("other call kinds should keep the default: %q", got)
+	}
+}
+
+func TestNewRenderer_InvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "task.tmpl"), []byte("{{.Title"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRenderer(dir); err == nil {
+		t.Error("expected parse error for invalid template")
+	}
+}
@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName はワークスペース / ~/.multiverse/config 配下の設定ファイル名
+const ConfigFileName = "ratelimits.yaml"
+
+const (
+	providerKeyPrefix = "provider:"
+	modelKeyPrefix    = "model:"
+
+	defaultBackoffBase = 10 * time.Second
+	defaultBackoffMax  = 5 * time.Minute
+)
+
+// ProviderKey returns the limiter key for an agenttools provider kind.
+func ProviderKey(kind string) string {
+	return providerKeyPrefix + kind
+}
+
+// ModelKey returns the limiter key for a Meta model ID.
+func ModelKey(model string) string {
+	return modelKeyPrefix + model
+}
+
+// Limits は 1 キーあたりの制限値。0 は無制限を表す。
+type Limits struct {
+	MaxConcurrent     int `yaml:"max_concurrent,omitempty" json:"maxConcurrent"`
+	RequestsPerMinute int `yaml:"requests_per_minute,omitempty" json:"requestsPerMinute"`
+}
+
+// IsZero reports whether no limit is configured.
+func (l Limits) IsZero() bool {
+	return l.MaxConcurrent <= 0 && l.RequestsPerMinute <= 0
+}
+
+// Config はワークスペース単位のレート制限設定
+//
+//	providers:
+//	  codex-cli: { max_concurrent: 2, requests_per_minute: 20 }
+//	models:
+//	  gpt-5.2: { requests_per_minute: 60 }
+//	backoff_base: 10s
+//	backoff_max: 5m
+type Config struct {
+	Providers   map[string]Limits `yaml:"providers,omitempty" json:"providers,omitempty"`
+	Models      map[string]Limits `yaml:"models,omitempty" json:"models,omitempty"`
+	BackoffBase time.Duration     `yaml:"backoff_base,omitempty" json:"backoffBase,omitempty"`
+	BackoffMax  time.Duration     `yaml:"backoff_max,omitempty" json:"backoffMax,omitempty"`
+}
+
+// LimitsFor returns the configured limits for key.
+func (c Config) LimitsFor(key string) Limits {
+	switch {
+	case strings.HasPrefix(key, providerKeyPrefix):
+		return c.Providers[strings.TrimPrefix(key, providerKeyPrefix)]
+	case strings.HasPrefix(key, modelKeyPrefix):
+		return c.Models[strings.TrimPrefix(key, modelKeyPrefix)]
+	}
+	return Limits{}
+}
+
+// Keys returns every key that has configured limits.
+func (c Config) Keys() []string {
+	keys := make([]string, 0, len(c.Providers)+len(c.Models))
+	for kind := range c.Providers {
+		keys = append(keys, ProviderKey(kind))
+	}
+	for model := range c.Models {
+		keys = append(keys, ModelKey(model))
+	}
+	return keys
+}
+
+// backoff returns the backoff duration after n consecutive throttles (n >= 1).
+func (c Config) backoff(n int) time.Duration {
+	base := c.BackoffBase
+	if base <= 0 {
+		base = defaultBackoffBase
+	}
+	maxBackoff := c.BackoffMax
+	if maxBackoff <= 0 {
+		maxBackoff = defaultBackoffMax
+	}
+	d := base
+	for i := 1; i < n && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// ConfigPaths returns ~/.multiverse/config/ratelimits.yaml followed by
+// <projectRoot>/.multiverse/ratelimits.yaml (the workspace setting wins).
+func ConfigPaths(projectRoot string) []string {
+	var paths []string
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".multiverse", "config", ConfigFileName))
+	}
+	if projectRoot != "" {
+		paths = append(paths, filepath.Join(projectRoot, ".multiverse", ConfigFileName))
+	}
+	return paths
+}
+
+// StateDir returns the directory that holds the shared limiter state.
+// 同じ CLI アカウントを使う全ワークスペースで共有するため、ホーム配下に置く。
+func StateDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home dir: %w", err)
+	}
+	return filepath.Join(home, ".multiverse", "ratelimit"), nil
+}
+
+// LoadShared loads the configuration for projectRoot and returns a limiter
+// sharing its state through StateDir.
+func LoadShared(projectRoot string) (*Limiter, error) {
+	cfg, err := LoadConfig(ConfigPaths(projectRoot)...)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewShared(dir, cfg), nil
+}
+
+// LoadConfig reads each existing file in paths and overlays them in order
+// (later files win per key). Missing files are skipped.
+func LoadConfig(paths ...string) (Config, error) {
+	cfg := Config{
+		Providers: map[string]Limits{},
+		Models:    map[string]Limits{},
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return Config{}, fmt.Errorf("failed to read rate limit config %s: %w", path, err)
+		}
+		var overlay Config
+		if err := yaml.Unmarshal(data, &overlay); err != nil {
+			return Config{}, fmt.Errorf("failed to parse rate limit config %s: %w", path, err)
+		}
+		for kind, l := range overlay.Providers {
+			cfg.Providers[kind] = l
+		}
+		for model, l := range overlay.Models {
+			cfg.Models[model] = l
+		}
+		if overlay.BackoffBase > 0 {
+			cfg.BackoffBase = overlay.BackoffBase
+		}
+		if overlay.BackoffMax > 0 {
+			cfg.BackoffMax = overlay.BackoffMax
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,271 @@
+// Package ratelimit はプロバイダ kind / Meta モデル単位の共有レート制限を提供する。
+//
+// 同時実行数（max_concurrent）と 1 分あたりのリクエスト数（requests_per_minute）を
+// キーごとに制限し、いずれかの呼び出し元が 429 やクォータエラーを観測した場合は
+// 同じキーを使う全呼び出し元に共有バックオフを適用する。
+// NewShared で作成した Limiter は状態をディレクトリに保存するため、
+// Orchestrator が並列起動した複数の agent-runner プロセス間で制限が共有される。
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLeaseTTL     = 2 * time.Minute
+	defaultPollInterval = 500 * time.Millisecond
+)
+
+// KeyState は IDE 向けに公開する 1 キー分の現在状態
+type KeyState struct {
+	Key                  string     `json:"key"`
+	Limits               Limits     `json:"limits"`
+	InFlight             int        `json:"inFlight"`
+	RequestsLastMinute   int        `json:"requestsLastMinute"`
+	BackoffUntil         *time.Time `json:"backoffUntil,omitempty"`
+	ConsecutiveThrottles int        `json:"consecutiveThrottles"`
+}
+
+// Limiter はキー単位の同時実行数・リクエストレート・共有バックオフを管理する。
+// nil の *Limiter は何も制限しない。
+type Limiter struct {
+	cfg          Config
+	store        store
+	leaseTTL     time.Duration
+	pollInterval time.Duration
+	now          func() time.Time
+}
+
+// New creates a limiter whose state is shared within the current process.
+func New(cfg Config) *Limiter {
+	return newLimiter(cfg, newMemoryStore())
+}
+
+// NewShared creates a limiter whose state is stored under dir and shared
+// with every process using the same directory.
+func NewShared(dir string, cfg Config) *Limiter {
+	return newLimiter(cfg, newFileStore(dir))
+}
+
+func newLimiter(cfg Config, st store) *Limiter {
+	return &Limiter{
+		cfg:          cfg,
+		store:        st,
+		leaseTTL:     defaultLeaseTTL,
+		pollInterval: defaultPollInterval,
+		now:          time.Now,
+	}
+}
+
+// Config returns the limiter configuration.
+func (l *Limiter) Config() Config {
+	if l == nil {
+		return Config{}
+	}
+	return l.cfg
+}
+
+// Acquire blocks until a slot is available for every key, or ctx is done.
+// The returned release function must be called when the request finishes.
+// キーはソート順に取得し、途中で失敗した場合は取得済みのスロットを解放する。
+func (l *Limiter) Acquire(ctx context.Context, keys ...string) (func(), error) {
+	if l == nil || len(keys) == 0 {
+		return func() {}, nil
+	}
+
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+
+	var releases []func()
+	releaseAll := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+	seen := make(map[string]bool, len(sorted))
+	for _, key := range sorted {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		release, err := l.acquireOne(ctx, key)
+		if err != nil {
+			releaseAll()
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+	var once sync.Once
+	return func() { once.Do(releaseAll) }, nil
+}
+
+func (l *Limiter) acquireOne(ctx context.Context, key string) (func(), error) {
+	limits := l.cfg.LimitsFor(key)
+	leaseID := uuid.New().String()
+
+	for {
+		var wait time.Duration
+		err := l.store.update(key, func(st *keyState) error {
+			now := l.now()
+			st.prune(now)
+			switch {
+			case now.Before(st.BackoffUntil):
+				wait = st.BackoffUntil.Sub(now)
+			case limits.MaxConcurrent > 0 && len(st.Leases) >= limits.MaxConcurrent:
+				wait = l.pollInterval
+			case limits.RequestsPerMinute > 0 && len(st.Requests) >= limits.RequestsPerMinute:
+				wait = st.Requests[0].Add(time.Minute).Sub(now)
+			default:
+				wait = 0
+				if limits.MaxConcurrent > 0 {
+					st.Leases[leaseID] = now.Add(l.leaseTTL)
+				}
+				if limits.RequestsPerMinute > 0 {
+					st.Requests = append(st.Requests, now)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("rate limiter %s: %w", key, err)
+		}
+		if wait <= 0 {
+			break
+		}
+		if wait > l.pollInterval {
+			wait = l.pollInterval
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("rate limiter %s: %w", key, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	if limits.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+
+	// リースを定期的に延長する（プロセスが落ちた場合は leaseTTL 後に自動解放される）
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(l.leaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_ = l.store.update(key, func(st *keyState) error {
+					if _, ok := st.Leases[leaseID]; ok {
+						st.Leases[leaseID] = l.now().Add(l.leaseTTL)
+					}
+					return nil
+				})
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		_ = l.store.update(key, func(st *keyState) error {
+			delete(st.Leases, leaseID)
+			return nil
+		})
+	}, nil
+}
+
+// Throttle records a 429 / quota error observed for key and applies a shared
+// backoff to every caller. retryAfter (e.g. from a Retry-After header) is
+// honoured when it is longer than the exponential backoff.
+func (l *Limiter) Throttle(key string, retryAfter time.Duration) {
+	if l == nil || key == "" {
+		return
+	}
+	_ = l.store.update(key, func(st *keyState) error {
+		now := l.now()
+		st.Throttles++
+		d := l.cfg.backoff(st.Throttles)
+		if retryAfter > d {
+			d = retryAfter
+		}
+		if until := now.Add(d); until.After(st.BackoffUntil) {
+			st.BackoffUntil = until
+		}
+		return nil
+	})
+}
+
+// Succeeded resets the consecutive throttle counter for key.
+func (l *Limiter) Succeeded(key string) {
+	if l == nil || key == "" {
+		return
+	}
+	_ = l.store.update(key, func(st *keyState) error {
+		st.Throttles = 0
+		return nil
+	})
+}
+
+// BackoffRemaining returns how long key is still in shared backoff.
+func (l *Limiter) BackoffRemaining(key string) time.Duration {
+	if l == nil || key == "" {
+		return 0
+	}
+	var remaining time.Duration
+	_ = l.store.update(key, func(st *keyState) error {
+		if d := st.BackoffUntil.Sub(l.now()); d > 0 {
+			remaining = d
+		}
+		return nil
+	})
+	return remaining
+}
+
+// Snapshot returns the current state of every configured or used key.
+func (l *Limiter) Snapshot() ([]KeyState, error) {
+	if l == nil {
+		return []KeyState{}, nil
+	}
+	used, err := l.store.keys()
+	if err != nil {
+		return nil, err
+	}
+	keySet := make(map[string]bool)
+	for _, k := range append(used, l.cfg.Keys()...) {
+		keySet[k] = true
+	}
+	keys := make([]string, 0, len(keySet))
+	for k := range keySet {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	states := make([]KeyState, 0, len(keys))
+	for _, key := range keys {
+		ks := KeyState{Key: key, Limits: l.cfg.LimitsFor(key)}
+		err := l.store.update(key, func(st *keyState) error {
+			now := l.now()
+			st.prune(now)
+			ks.InFlight = len(st.Leases)
+			ks.RequestsLastMinute = len(st.Requests)
+			ks.ConsecutiveThrottles = st.Throttles
+			if now.Before(st.BackoffUntil) {
+				until := st.BackoffUntil
+				ks.BackoffUntil = &until
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, ks)
+	}
+	return states, nil
+}
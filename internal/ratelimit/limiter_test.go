@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastLimiter(l *Limiter) *Limiter {
+	l.pollInterval = 5 * time.Millisecond
+	return l
+}
+
+func TestLimiter_MaxConcurrent(t *testing.T) {
+	for name, l := range map[string]*Limiter{
+		"memory": New(Config{Providers: map[string]Limits{"codex-cli": {MaxConcurrent: 2}}}),
+		"file":   NewShared(t.TempDir(), Config{Providers: map[string]Limits{"codex-cli": {MaxConcurrent: 2}}}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			l = fastLimiter(l)
+			var inFlight, peak int32
+			var wg sync.WaitGroup
+			for i := 0; i < 6; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					release, err := l.Acquire(context.Background(), ProviderKey("codex-cli"))
+					if err != nil {
+						t.Errorf("Acquire() error = %v", err)
+						return
+					}
+					n := atomic.AddInt32(&inFlight, 1)
+					for {
+						p := atomic.LoadInt32(&peak)
+						if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+							break
+						}
+					}
+					time.Sleep(20 * time.Millisecond)
+					atomic.AddInt32(&inFlight, -1)
+					release()
+				}()
+			}
+			wg.Wait()
+			if peak > 2 {
+				t.Errorf("peak concurrency = %d, want <= 2", peak)
+			}
+		})
+	}
+}
+
+func TestLimiter_RequestsPerMinute(t *testing.T) {
+	l := fastLimiter(New(Config{Models: map[string]Limits{"gpt-5.2": {RequestsPerMinute: 2}}}))
+	key := ModelKey("gpt-5.2")
+
+	for i := 0; i < 2; i++ {
+		release, err := l.Acquire(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		release()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, key); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected third request to wait, got err = %v", err)
+	}
+
+	// 1 分経過後は再び取得できる
+	base := time.Now()
+	l.now = func() time.Time { return base.Add(61 * time.Second) }
+	release, err := l.Acquire(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Acquire() after window error = %v", err)
+	}
+	release()
+}
+
+func TestLimiter_SharedBackoff(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{BackoffBase: time.Second, BackoffMax: 4 * time.Second}
+	a := fastLimiter(NewShared(dir, cfg))
+	b := fastLimiter(NewShared(dir, cfg)) // 別プロセス相当
+	key := ProviderKey("codex-cli")
+
+	a.Throttle(key, 0)
+	if d := b.BackoffRemaining(key); d <= 0 || d > time.Second {
+		t.Fatalf("BackoffRemaining() = %v, want (0, 1s]", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := b.Acquire(ctx, key); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Acquire to wait during backoff, got %v", err)
+	}
+
+	// 連続スロットルで指数的に延び、上限で頭打ちになる
+	a.Throttle(key, 0)
+	a.Throttle(key, 0)
+	a.Throttle(key, 0)
+	if d := a.BackoffRemaining(key); d <= 2*time.Second || d > 4*time.Second {
+		t.Errorf("BackoffRemaining() after 4 throttles = %v, want (2s, 4s]", d)
+	}
+
+	// Retry-After が長い場合はそちらを優先
+	a.Throttle(key, time.Minute)
+	if d := a.BackoffRemaining(key); d <= 4*time.Second {
+		t.Errorf("BackoffRemaining() with retry-after = %v, want > 4s", d)
+	}
+
+	a.Succeeded(key)
+	states, err := b.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if len(states) != 1 || states[0].Key != key || states[0].ConsecutiveThrottles != 0 || states[0].BackoffUntil == nil {
+		t.Errorf("unexpected snapshot: %+v", states)
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	var l *Limiter
+	release, err := l.Acquire(context.Background(), ProviderKey("codex-cli"))
+	if err != nil {
+		t.Fatalf("nil limiter Acquire() error = %v", err)
+	}
+	release()
+	l.Throttle("x", time.Second)
+	if l.BackoffRemaining("x") != 0 {
+		t.Error("nil limiter should never back off")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	workspace := filepath.Join(dir, "workspace.yaml")
+	if err := os.WriteFile(global, []byte(`
+providers:
+  codex-cli: { max_concurrent: 4 }
+  claude-code: { max_concurrent: 1 }
+backoff_base: 5s
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(workspace, []byte(`
+providers:
+  codex-cli: { max_concurrent: 2, requests_per_minute: 10 }
+models:
+  gpt-5.2: { requests_per_minute: 60 }
+backoff_max: 1m
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(global, filepath.Join(dir, "missing.yaml"), workspace)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got := cfg.LimitsFor(ProviderKey("codex-cli")); got.MaxConcurrent != 2 || got.RequestsPerMinute != 10 {
+		t.Errorf("codex-cli limits = %+v", got)
+	}
+	if got := cfg.LimitsFor(ProviderKey("claude-code")); got.MaxConcurrent != 1 {
+		t.Errorf("claude-code limits = %+v", got)
+	}
+	if got := cfg.LimitsFor(ModelKey("gpt-5.2")); got.RequestsPerMinute != 60 {
+		t.Errorf("gpt-5.2 limits = %+v", got)
+	}
+	if cfg.BackoffBase != 5*time.Second || cfg.BackoffMax != time.Minute {
+		t.Errorf("backoff = %v / %v", cfg.BackoffBase, cfg.BackoffMax)
+	}
+}
@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyState は 1 キー分の共有状態（ファイルストアではそのまま JSON 化される）
+type keyState struct {
+	Key          string               `json:"key"`
+	Leases       map[string]time.Time `json:"leases,omitempty"` // lease ID -> 有効期限
+	Requests     []time.Time          `json:"requests,omitempty"`
+	BackoffUntil time.Time            `json:"backoffUntil,omitempty"`
+	Throttles    int                  `json:"throttles,omitempty"` // 連続スロットル回数
+}
+
+// prune は期限切れのリースと 1 分より古いリクエスト記録を削除する
+func (s *keyState) prune(now time.Time) {
+	for id, exp := range s.Leases {
+		if !exp.After(now) {
+			delete(s.Leases, id)
+		}
+	}
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(s.Requests) && !s.Requests[i].After(cutoff) {
+		i++
+	}
+	s.Requests = s.Requests[i:]
+}
+
+// store は keyState をアトミックに読み書きするバックエンド
+type store interface {
+	update(key string, fn func(*keyState) error) error
+	keys() ([]string, error)
+}
+
+// memoryStore はプロセス内で共有する状態
+type memoryStore struct {
+	mu     sync.Mutex
+	states map[string]*keyState
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{states: make(map[string]*keyState)}
+}
+
+func (m *memoryStore) update(key string, fn func(*keyState) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.states[key]
+	if !ok {
+		st = &keyState{Key: key, Leases: map[string]time.Time{}}
+		m.states[key] = st
+	}
+	return fn(st)
+}
+
+func (m *memoryStore) keys() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.states))
+	for k := range m.states {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+const (
+	lockRetryInterval = 10 * time.Millisecond
+	lockTimeout       = 5 * time.Second
+	staleLockAge      = 10 * time.Second
+)
+
+// fileStore は複数の agent-runner プロセス間で状態を共有する。
+// キーごとに <dir>/<key>.json を持ち、<key>.lock（O_EXCL で作成）で排他する。
+type fileStore struct {
+	dir string
+	mu  sync.Mutex // 同一プロセス内の競合を先に直列化する
+}
+
+func newFileStore(dir string) *fileStore {
+	return &fileStore{dir: dir}
+}
+
+// fileName はキーをファイル名に使える形へ変換する
+func fileName(key string) string {
+	return strings.NewReplacer(":", "__", "/", "_", "\\", "_").Replace(key)
+}
+
+func (f *fileStore) lock(key string) (func(), error) {
+	path := filepath.Join(f.dir, fileName(key)+".lock")
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		lf, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_ = lf.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+		// クラッシュしたプロセスが残したロックは一定時間後に破棄する
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for rate limit lock %s", path)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+func (f *fileStore) update(key string, fn func(*keyState) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create rate limit dir: %w", err)
+	}
+	unlock, err := f.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := filepath.Join(f.dir, fileName(key)+".json")
+	st := &keyState{Key: key}
+	if data, err := os.ReadFile(path); err == nil {
+		// 壊れたファイルは空状態として扱う
+		_ = json.Unmarshal(data, st)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read rate limit state: %w", err)
+	}
+	if st.Leases == nil {
+		st.Leases = map[string]time.Time{}
+	}
+	st.Key = key
+
+	if err := fn(st); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rate limit state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write rate limit state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write rate limit state: %w", err)
+	}
+	return nil
+}
+
+func (f *fileStore) keys() ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read rate limit dir: %w", err)
+	}
+	var keys []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(f.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var st keyState
+		if err := json.Unmarshal(data, &st); err != nil || st.Key == "" {
+			continue
+		}
+		keys = append(keys, st.Key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
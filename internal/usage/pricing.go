@@ -0,0 +1,102 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PricingFileName はワークスペース / ~/.multiverse/config 配下の価格表ファイル名
+const PricingFileName = "pricing.json"
+
+// Price is the USD price per one million tokens.
+type Price struct {
+	InputPerMTok       float64 `json:"inputPerMTok"`
+	CachedInputPerMTok float64 `json:"cachedInputPerMTok,omitempty"`
+	OutputPerMTok      float64 `json:"outputPerMTok"`
+}
+
+// PriceTable maps a model ID (or model ID prefix) to its price.
+type PriceTable map[string]Price
+
+// DefaultPriceTable returns built-in prices for the default models.
+// NOTE: 参考値。実際の価格は pricing.json で上書きする。
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		"gpt-5.2":                   {InputPerMTok: 1.75, CachedInputPerMTok: 0.175, OutputPerMTok: 14.00},
+		"gpt-5.1-codex":             {InputPerMTok: 1.25, CachedInputPerMTok: 0.125, OutputPerMTok: 10.00},
+		"gpt-5.1":                   {InputPerMTok: 1.25, CachedInputPerMTok: 0.125, OutputPerMTok: 10.00},
+		"gpt-4o-mini":               {InputPerMTok: 0.15, CachedInputPerMTok: 0.075, OutputPerMTok: 0.60},
+		"gpt-4o":                    {InputPerMTok: 2.50, CachedInputPerMTok: 1.25, OutputPerMTok: 10.00},
+		"claude-3-5-haiku-20241022": {InputPerMTok: 0.80, CachedInputPerMTok: 0.08, OutputPerMTok: 4.00},
+		"claude-sonnet-4":           {InputPerMTok: 3.00, CachedInputPerMTok: 0.30, OutputPerMTok: 15.00},
+		"claude-opus-4":             {InputPerMTok: 15.00, CachedInputPerMTok: 1.50, OutputPerMTok: 75.00},
+		"gemini-2.5-pro":            {InputPerMTok: 1.25, CachedInputPerMTok: 0.31, OutputPerMTok: 10.00},
+	}
+}
+
+// Lookup returns the price for model. An exact match wins; otherwise the
+// longest key that is a prefix of model is used.
+func (t PriceTable) Lookup(model string) (Price, bool) {
+	if p, ok := t[model]; ok {
+		return p, true
+	}
+	best := ""
+	for key := range t {
+		if strings.HasPrefix(model, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t[best], true
+}
+
+// Cost returns the USD cost of u. Unknown models cost 0.
+// キャッシュ済み入力は InputTokens に含まれる前提で、差分のみ割引価格を適用する。
+func (t PriceTable) Cost(u Usage) float64 {
+	p, ok := t.Lookup(u.Model)
+	if !ok {
+		return 0
+	}
+	cached := u.CachedInputTokens
+	if cached > u.InputTokens {
+		cached = u.InputTokens
+	}
+	cachedPrice := p.CachedInputPerMTok
+	if cachedPrice == 0 {
+		cachedPrice = p.InputPerMTok
+	}
+	uncached := u.InputTokens - cached
+	return (float64(uncached)*p.InputPerMTok +
+		float64(cached)*cachedPrice +
+		float64(u.OutputTokens)*p.OutputPerMTok) / 1_000_000
+}
+
+// LoadPriceTable returns the default table overlaid with each existing file
+// in paths (later files win). Missing files are skipped.
+func LoadPriceTable(paths ...string) (PriceTable, error) {
+	table := DefaultPriceTable()
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read price table %s: %w", path, err)
+		}
+		var overrides PriceTable
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return nil, fmt.Errorf("failed to parse price table %s: %w", path, err)
+		}
+		for model, price := range overrides {
+			table[model] = price
+		}
+	}
+	return table, nil
+}
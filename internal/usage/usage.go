@@ -0,0 +1,178 @@
+// Package usage はトークン使用量とコストの集計を扱う。
+//
+// Meta 呼び出し（HTTP / CLI）と Worker 実行の双方から使用量を収集し、
+// タスク・試行・ワークスペース単位で価格表に基づいて集計する。
+package usage
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Usage is the token usage of a single LLM call or worker run.
+type Usage struct {
+	Model             string `json:"model,omitempty" yaml:"model,omitempty"`
+	InputTokens       int    `json:"inputTokens" yaml:"input_tokens"`
+	CachedInputTokens int    `json:"cachedInputTokens,omitempty" yaml:"cached_input_tokens,omitempty"`
+	OutputTokens      int    `json:"outputTokens" yaml:"output_tokens"`
+}
+
+// IsZero reports whether no tokens were recorded.
+func (u Usage) IsZero() bool {
+	return u.InputTokens == 0 && u.CachedInputTokens == 0 && u.OutputTokens == 0
+}
+
+// TotalTokens returns input + output tokens.
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Add returns the sum of u and other. The model is kept only when both agree.
+func (u Usage) Add(other Usage) Usage {
+	model := u.Model
+	if model == "" {
+		model = other.Model
+	} else if other.Model != "" && other.Model != model {
+		model = ""
+	}
+	return Usage{
+		Model:             model,
+		InputTokens:       u.InputTokens + other.InputTokens,
+		CachedInputTokens: u.CachedInputTokens + other.CachedInputTokens,
+		OutputTokens:      u.OutputTokens + other.OutputTokens,
+	}
+}
+
+// Summary is a rolled-up usage total with cost.
+type Summary struct {
+	InputTokens       int                `json:"inputTokens" yaml:"input_tokens"`
+	CachedInputTokens int                `json:"cachedInputTokens" yaml:"cached_input_tokens"`
+	OutputTokens      int                `json:"outputTokens" yaml:"output_tokens"`
+	CostUSD           float64            `json:"costUsd" yaml:"cost_usd"`
+	Calls             int                `json:"calls" yaml:"calls"`
+	ByModel           map[string]Summary `json:"byModel,omitempty" yaml:"by_model,omitempty"`
+}
+
+// TotalTokens returns input + output tokens.
+func (s Summary) TotalTokens() int {
+	return s.InputTokens + s.OutputTokens
+}
+
+// AddUsage adds a single usage record priced with prices.
+func (s *Summary) AddUsage(u Usage, prices PriceTable) {
+	cost := prices.Cost(u)
+	s.InputTokens += u.InputTokens
+	s.CachedInputTokens += u.CachedInputTokens
+	s.OutputTokens += u.OutputTokens
+	s.CostUSD += cost
+	s.Calls++
+
+	model := u.Model
+	if model == "" {
+		model = "unknown"
+	}
+	if s.ByModel == nil {
+		s.ByModel = make(map[string]Summary)
+	}
+	m := s.ByModel[model]
+	m.InputTokens += u.InputTokens
+	m.CachedInputTokens += u.CachedInputTokens
+	m.OutputTokens += u.OutputTokens
+	m.CostUSD += cost
+	m.Calls++
+	s.ByModel[model] = m
+}
+
+// Merge adds another summary into s.
+func (s *Summary) Merge(other Summary) {
+	s.InputTokens += other.InputTokens
+	s.CachedInputTokens += other.CachedInputTokens
+	s.OutputTokens += other.OutputTokens
+	s.CostUSD += other.CostUSD
+	s.Calls += other.Calls
+	if len(other.ByModel) > 0 && s.ByModel == nil {
+		s.ByModel = make(map[string]Summary)
+	}
+	for model, m := range other.ByModel {
+		cur := s.ByModel[model]
+		cur.InputTokens += m.InputTokens
+		cur.CachedInputTokens += m.CachedInputTokens
+		cur.OutputTokens += m.OutputTokens
+		cur.CostUSD += m.CostUSD
+		cur.Calls += m.Calls
+		s.ByModel[model] = cur
+	}
+}
+
+// Models returns the model names in ByModel sorted alphabetically.
+func (s Summary) Models() []string {
+	models := make([]string, 0, len(s.ByModel))
+	for m := range s.ByModel {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// Summarize rolls up usages with the given price table.
+func Summarize(usages []Usage, prices PriceTable) Summary {
+	var s Summary
+	for _, u := range usages {
+		if u.IsZero() {
+			continue
+		}
+		s.AddUsage(u, prices)
+	}
+	return s
+}
+
+// Recorder collects usage reported by providers during a call.
+// context 経由で Provider に渡し、呼び出し側で MetaCallLog に紐付ける。
+type Recorder struct {
+	mu     sync.Mutex
+	usages []Usage
+}
+
+// Record appends a usage record.
+func (r *Recorder) Record(u Usage) {
+	if r == nil || u.IsZero() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.usages = append(r.usages, u)
+}
+
+// Usages returns a copy of the recorded usages.
+func (r *Recorder) Usages() []Usage {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Usage(nil), r.usages...)
+}
+
+// Total returns the sum of the recorded usages.
+func (r *Recorder) Total() Usage {
+	var total Usage
+	for _, u := range r.Usages() {
+		total = total.Add(u)
+	}
+	return total
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns a context carrying rec.
+func WithRecorder(ctx context.Context, rec *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, rec)
+}
+
+// Record reports usage to the Recorder in ctx, if any.
+func Record(ctx context.Context, u Usage) {
+	if rec, ok := ctx.Value(recorderKey{}).(*Recorder); ok {
+		rec.Record(u)
+	}
+}
@@ -0,0 +1,110 @@
+package usage
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPriceTable_Cost(t *testing.T) {
+	table := PriceTable{
+		"gpt-5":       {InputPerMTok: 1.0, CachedInputPerMTok: 0.1, OutputPerMTok: 10.0},
+		"gpt-5-codex": {InputPerMTok: 2.0, OutputPerMTok: 20.0},
+	}
+
+	// 完全一致
+	cost := table.Cost(Usage{Model: "gpt-5", InputTokens: 1_000_000, CachedInputTokens: 500_000, OutputTokens: 100_000})
+	if want := 0.5*1.0 + 0.5*0.1 + 0.1*10.0; !almostEqual(cost, want) {
+		t.Errorf("Cost() = %v, want %v", cost, want)
+	}
+
+	// 最長プレフィックス一致（キャッシュ価格未設定時は通常価格）
+	cost = table.Cost(Usage{Model: "gpt-5-codex-mini", InputTokens: 1_000_000, CachedInputTokens: 1_000_000})
+	if !almostEqual(cost, 2.0) {
+		t.Errorf("Cost() = %v, want 2.0", cost)
+	}
+
+	// 未知のモデル
+	if cost := table.Cost(Usage{Model: "unknown", InputTokens: 100}); cost != 0 {
+		t.Errorf("Cost() for unknown model = %v, want 0", cost)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	table := PriceTable{"m": {InputPerMTok: 1, OutputPerMTok: 2}}
+	s := Summarize([]Usage{
+		{Model: "m", InputTokens: 1_000_000, OutputTokens: 1_000_000},
+		{},
+		{Model: "other", InputTokens: 10, OutputTokens: 5},
+	}, table)
+
+	if s.Calls != 2 || s.InputTokens != 1_000_010 || s.OutputTokens != 1_000_005 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+	if !almostEqual(s.CostUSD, 3.0) {
+		t.Errorf("CostUSD = %v, want 3.0", s.CostUSD)
+	}
+	if got := s.Models(); len(got) != 2 || got[0] != "m" || got[1] != "other" {
+		t.Errorf("Models() = %v", got)
+	}
+
+	var total Summary
+	total.Merge(s)
+	total.Merge(s)
+	if total.Calls != 4 || total.ByModel["m"].Calls != 2 || !almostEqual(total.CostUSD, 6.0) {
+		t.Errorf("unexpected merged summary: %+v", total)
+	}
+}
+
+func TestRecorder_Context(t *testing.T) {
+	rec := &Recorder{}
+	ctx := WithRecorder(context.Background(), rec)
+
+	Record(ctx, Usage{Model: "m", InputTokens: 1, OutputTokens: 2})
+	Record(ctx, Usage{Model: "m", InputTokens: 3, OutputTokens: 4})
+	Record(ctx, Usage{})                                  // ゼロは無視
+	Record(context.Background(), Usage{InputTokens: 100}) // Recorder なしは無視
+
+	total := rec.Total()
+	if total.InputTokens != 4 || total.OutputTokens != 6 || total.Model != "m" {
+		t.Errorf("unexpected total: %+v", total)
+	}
+	if len(rec.Usages()) != 2 {
+		t.Errorf("expected 2 records, got %d", len(rec.Usages()))
+	}
+}
+
+func TestLoadPriceTable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, PricingFileName)
+	if err := os.WriteFile(path, []byte(`{"gpt-5.2": {"inputPerMTok": 9, "outputPerMTok": 99}, "custom": {"inputPerMTok": 1, "outputPerMTok": 1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := LoadPriceTable(filepath.Join(dir, "missing.json"), path)
+	if err != nil {
+		t.Fatalf("LoadPriceTable() error = %v", err)
+	}
+	if table["gpt-5.2"].InputPerMTok != 9 {
+		t.Errorf("override not applied: %+v", table["gpt-5.2"])
+	}
+	if _, ok := table["custom"]; !ok {
+		t.Error("custom model not loaded")
+	}
+	if _, ok := table["gpt-5.1-codex"]; !ok {
+		t.Error("defaults should be kept")
+	}
+
+	if err := os.WriteFile(path, []byte(`{invalid`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPriceTable(path); err == nil {
+		t.Error("expected parse error")
+	}
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -15,6 +16,7 @@ import (
 	"github.com/biwakonbu/agent-runner/internal/core"
 	"github.com/biwakonbu/agent-runner/internal/logging"
 	"github.com/biwakonbu/agent-runner/internal/meta"
+	"github.com/biwakonbu/agent-runner/internal/ratelimit"
 	"github.com/biwakonbu/agent-runner/pkg/config"
 )
 
@@ -22,7 +24,9 @@ type Executor struct {
 	Config      config.WorkerConfig
 	Sandbox     SandboxProvider
 	RepoPath    string
-	containerID string // 持続的なコンテナを保持
+	TaskID      string             // コンテナのラベルに付けるタスク ID（クラッシュ後の後始末用）
+	Limiter     *ratelimit.Limiter // nil の場合は制限しない
+	containerID string             // 持続的なコンテナを保持
 	logger      *slog.Logger
 }
 
@@ -45,7 +49,10 @@ func (e *Executor) SetLogger(logger *slog.Logger) {
 	e.logger = logging.WithComponent(logger, "worker-executor")
 }
 
-// RunWorker executes a worker task
+// RunWorker executes a worker task.
+// Config.Fallback が設定されている場合、レート制限・認証エラー・CLI 不在で失敗した
+// プロバイダをスキップし、同じ WorkerCall を次のプロバイダで再実行する。
+// 失敗したプロバイダの使用量は FallbackAttempts に残し、WorkerRunResult.Usages で合算できるようにする。
 func (e *Executor) RunWorker(ctx context.Context, call meta.WorkerCall, env map[string]string) (*core.WorkerRunResult, error) {
 	logger := logging.WithTraceID(e.logger, ctx)
 
@@ -54,15 +61,78 @@ func (e *Executor) RunWorker(ctx context.Context, call meta.WorkerCall, env map[
 		return nil, fmt.Errorf("container not started: call Start() first")
 	}
 
-	// Determine worker type (fallback to config default)
-	workerType := call.WorkerType
-	if workerType == "" {
-		workerType = e.Config.Kind
-	}
-	if workerType == "" {
-		workerType = "codex-cli"
+	chain := e.Config.KindChain(call.WorkerType)
+
+	var attempts []core.ProviderAttempt
+	for i, workerType := range chain {
+		last := i == len(chain)-1
+
+		// 共有バックオフ中のプロバイダは、フォールバック先があれば待たずにスキップする
+		if !last {
+			if d := e.Limiter.BackoffRemaining(ratelimit.ProviderKey(workerType)); d > 0 {
+				logger.Warn("worker provider in shared backoff, falling back",
+					slog.String("worker_kind", workerType),
+					slog.Duration("backoff_remaining", d),
+					slog.String("next_kind", chain[i+1]),
+				)
+				attempts = append(attempts, core.ProviderAttempt{
+					WorkerKind:   workerType,
+					FailureClass: string(agenttools.FailureRateLimit),
+					Error:        fmt.Sprintf("shared backoff active for %s", d.Round(time.Second)),
+				})
+				continue
+			}
+		}
+
+		res, err := e.runProvider(ctx, logger, workerType, i == 0, call, env)
+		if err != nil {
+			// 未登録 kind はフォールバック対象
+			if !last && errors.Is(err, agenttools.ErrUnsupportedKind) {
+				logger.Warn("worker provider not available, falling back",
+					slog.String("worker_kind", workerType),
+					slog.String("next_kind", chain[i+1]),
+					slog.Any("error", err),
+				)
+				attempts = append(attempts, core.ProviderAttempt{
+					WorkerKind:   workerType,
+					FailureClass: string(agenttools.FailureUnavailable),
+					Error:        err.Error(),
+				})
+				continue
+			}
+			return nil, err
+		}
+
+		res.FallbackAttempts = attempts
+		if res.Error != nil || last {
+			return res, nil
+		}
+
+		class := agenttools.ClassifyFailure(workerType, res.ExitCode, res.RawOutput)
+		if !class.Retryable() {
+			return res, nil
+		}
+
+		logger.Warn("worker provider failed, falling back",
+			slog.String("worker_kind", workerType),
+			slog.String("failure_class", string(class)),
+			slog.Int("exit_code", res.ExitCode),
+			slog.String("next_kind", chain[i+1]),
+		)
+		attempts = append(attempts, core.ProviderAttempt{
+			WorkerKind:   workerType,
+			FailureClass: string(class),
+			ExitCode:     res.ExitCode,
+			Usage:        res.Usage,
+		})
 	}
 
+	// chain は常に 1 要素以上なのでここには到達しない
+	return nil, fmt.Errorf("no worker provider available")
+}
+
+// runProvider executes the WorkerCall with a single provider kind.
+func (e *Executor) runProvider(ctx context.Context, logger *slog.Logger, workerType string, primary bool, call meta.WorkerCall, env map[string]string) (*core.WorkerRunResult, error) {
 	// Build provider config and request
 	reqEnv := mergeEnvMaps(e.Config.Env, call.Env, env)
 	providerCfg := agenttools.ProviderConfig{
@@ -74,18 +144,47 @@ func (e *Executor) RunWorker(ctx context.Context, call meta.WorkerCall, env map[
 		ToolSpecific: call.ToolSpecific,
 	}
 
+	// フォールバック先ではモデル・CLI パス指定がプロバイダ固有のため引き継がない
+	if !primary {
+		providerCfg.CLIPath = ""
+		providerCfg.Model = ""
+	}
+
+	// セッション継続: 主プロバイダでのみ resume する（フォールバック先には別セッションしかない）
+	mode := call.Mode
+	sessionID := ""
+	if primary && (call.Session == meta.WorkerSessionContinue || mode == agenttools.ModeResume) {
+		mode = agenttools.ModeResume
+		sessionID = call.SessionID
+	} else if mode == agenttools.ModeResume {
+		mode = ""
+	}
+
+	// セッション管理を要求された場合は構造化出力を有効化してセッション ID を取得する
+	toolSpecific := call.ToolSpecific
+	if call.Session != "" {
+		if _, ok := toolSpecific["json_output"]; !ok {
+			toolSpecific = make(map[string]interface{}, len(call.ToolSpecific)+1)
+			for k, v := range call.ToolSpecific {
+				toolSpecific[k] = v
+			}
+			toolSpecific["json_output"] = true
+		}
+	}
+
 	req := agenttools.Request{
 		Prompt:          call.Prompt,
-		Mode:            call.Mode,
-		Model:           call.Model,
+		Mode:            mode,
+		SessionID:       sessionID,
+		Model:           providerCfg.Model,
 		Temperature:     call.Temperature,
 		MaxTokens:       call.MaxTokens,
-		ReasoningEffort: call.ReasoningEffort,
+		ReasoningEffort: atLeastReasoningEffort(call.ReasoningEffort, e.Config.ReasoningEffort),
 		Workdir:         call.Workdir,
 		Timeout:         0,
 		ExtraEnv:        reqEnv,
 		Flags:           call.Flags,
-		ToolSpecific:    call.ToolSpecific,
+		ToolSpecific:    toolSpecific,
 		UseStdin:        call.UseStdin,
 	}
 
@@ -95,10 +194,18 @@ func (e *Executor) RunWorker(ctx context.Context, call meta.WorkerCall, env map[
 		timeout = 30 * time.Minute
 	}
 
-	plan, err := agenttools.Build(ctx, workerType, providerCfg, req)
+	provider, err := agenttools.New(workerType, providerCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build agent tool plan: %w", err)
 	}
+	plan, err := provider.Build(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build agent tool plan: %w", err)
+	}
+	model := req.Model
+	if model == "" {
+		model = provider.Capabilities().DefaultModel
+	}
 
 	if plan.Timeout > 0 {
 		timeout = plan.Timeout
@@ -106,6 +213,14 @@ func (e *Executor) RunWorker(ctx context.Context, call meta.WorkerCall, env map[
 		timeout = req.Timeout
 	}
 
+	// 共有レート制限（待ち時間は実行タイムアウトに含めない）
+	limitKey := ratelimit.ProviderKey(workerType)
+	release, err := e.Limiter.Acquire(ctx, limitKey)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -130,6 +245,7 @@ func (e *Executor) RunWorker(ctx context.Context, call meta.WorkerCall, env map[
 
 	logger.Info("executing worker command",
 		slog.String("container_id", containerLabel),
+		slog.String("worker_kind", workerType),
 		slog.Int("prompt_length", len(call.Prompt)),
 		slog.Float64("timeout_sec", timeout.Seconds()),
 	)
@@ -150,6 +266,18 @@ func (e *Executor) RunWorker(ctx context.Context, call meta.WorkerCall, env map[
 		RawOutput:  output,
 		Summary:    "Worker executed",
 		Error:      execErr,
+		WorkerKind: workerType,
+		SessionID:  agenttools.ExtractSessionID(workerType, output),
+		Usage:      agenttools.ExtractUsage(workerType, model, output),
+	}
+
+	if execErr == nil {
+		switch agenttools.ClassifyFailure(workerType, exitCode, output) {
+		case agenttools.FailureRateLimit:
+			e.Limiter.Throttle(limitKey, 0)
+		case agenttools.FailureNone:
+			e.Limiter.Succeeded(limitKey)
+		}
 	}
 
 	durationMs := float64(finish.Sub(start).Milliseconds())
@@ -187,7 +315,10 @@ func (e *Executor) Start(ctx context.Context) error {
 				slog.Any("error", err),
 				slog.String("hint", "claude login で認証してください"),
 			)
-			return fmt.Errorf("Claude Code セッションがありません: %w", err)
+			if len(e.Config.Fallback) == 0 {
+				return fmt.Errorf("Claude Code セッションがありません: %w", err)
+			}
+			logger.Warn("continuing with fallback worker providers", slog.Any("fallback", e.Config.Fallback))
 		}
 	} else if e.Config.Kind == "codex-cli" || e.Config.Kind == "" {
 		if err := e.verifyCodexSession(ctx); err != nil {
@@ -195,7 +326,10 @@ func (e *Executor) Start(ctx context.Context) error {
 				slog.Any("error", err),
 				slog.String("hint", "codex login で認証するか ~/.codex/auth.json を用意してください"),
 			)
-			return fmt.Errorf("Codex CLI セッションがありません: %w", err)
+			if len(e.Config.Fallback) == 0 {
+				return fmt.Errorf("Codex CLI セッションがありません: %w", err)
+			}
+			logger.Warn("continuing with fallback worker providers", slog.Any("fallback", e.Config.Fallback))
 		}
 	}
 
@@ -231,6 +365,17 @@ func (e *Executor) Start(ctx context.Context) error {
 	if e.Config.AuthPath != "" {
 		startEnv["__INTERNAL_CLAUDE_AUTH_PATH"] = e.Config.AuthPath
 	}
+	// 宣言的プロバイダの認証情報マウントを渡す（フォールバック先を含む）
+	var mounts []agenttools.CredentialMount
+	for _, kind := range e.Config.KindChain("") {
+		mounts = append(mounts, agenttools.CredentialMountsFor(kind, agenttools.ProviderConfig{Kind: kind})...)
+	}
+	if len(mounts) > 0 {
+		startEnv[internalExtraMountsEnv] = encodeCredentialMounts(mounts)
+	}
+	if e.TaskID != "" {
+		startEnv[internalTaskIDEnv] = e.TaskID
+	}
 
 	containerID, err := e.Sandbox.StartContainer(ctx, image, repoPath, startEnv)
 	if err != nil {
@@ -377,6 +522,29 @@ func mergeEnvMaps(envs ...map[string]string) map[string]string {
 	return result
 }
 
+// reasoningEffortRank orders reasoning effort levels from lowest to highest.
+var reasoningEffortRank = map[string]int{"none": 0, "minimal": 1, "low": 2, "medium": 3, "high": 4, "xhigh": 5}
+
+// atLeastReasoningEffort returns requested, raised to floor when floor is a
+// higher known level. 未知の値は比較できないため requested を優先する。
+func atLeastReasoningEffort(requested, floor string) string {
+	floor = strings.ToLower(strings.TrimSpace(floor))
+	if floor == "" {
+		return requested
+	}
+	if requested == "" {
+		return floor
+	}
+	want, ok := reasoningEffortRank[floor]
+	if !ok {
+		return requested
+	}
+	if have, ok := reasoningEffortRank[strings.ToLower(strings.TrimSpace(requested))]; ok && have < want {
+		return floor
+	}
+	return requested
+}
+
 // buildEnvPrefix renders env vars as ["env", "KEY=VAL", ...] for container exec.
 func buildEnvPrefix(env map[string]string) []string {
 	if len(env) == 0 {
@@ -9,6 +9,7 @@ import (
 	"io"
 
 	"github.com/biwakonbu/agent-runner/internal/meta"
+	"github.com/biwakonbu/agent-runner/internal/ratelimit"
 	"github.com/biwakonbu/agent-runner/pkg/config"
 )
 
@@ -444,3 +445,250 @@ func TestExecutor_RunWorker_MultipleExecutionsInSameContainer(t *testing.T) {
 		t.Errorf("Exec should have been called at least once")
 	}
 }
+
+// scriptedSandbox returns a predefined result per Exec call and records commands
+type scriptedSandbox struct {
+	MockSandboxManager
+	results []struct {
+		exitCode int
+		output   string
+	}
+	commands [][]string
+}
+
+func (s *scriptedSandbox) Exec(ctx context.Context, containerID string, cmd []string, stdin io.Reader) (int, string, error) {
+	idx := len(s.commands)
+	s.commands = append(s.commands, cmd)
+	if idx >= len(s.results) {
+		return 0, "ok", nil
+	}
+	return s.results[idx].exitCode, s.results[idx].output, nil
+}
+
+func TestExecutor_RunWorker_FallbackKeepsFailedAttemptUsage(t *testing.T) {
+	sandbox := &scriptedSandbox{
+		results: []struct {
+			exitCode int
+			output   string
+		}{
+			{1, `{"type":"turn.completed","usage":{"input_tokens":100,"cached_input_tokens":40,"output_tokens":10}}
+ERROR: You've hit your usage limit. Try again later.`},
+			{0, `{"type":"result","usage":{"input_tokens":50,"output_tokens":5}}`},
+		},
+	}
+	executor := &Executor{
+		Config: config.WorkerConfig{
+			Kind:     "codex-cli",
+			Fallback: []string{"claude-code"},
+		},
+		Sandbox:     sandbox,
+		containerID: "container-123",
+	}
+
+	result, err := executor.RunWorker(context.Background(), meta.WorkerCall{Prompt: "do it"}, nil)
+	if err != nil {
+		t.Fatalf("RunWorker() error = %v", err)
+	}
+	if len(result.FallbackAttempts) != 1 || result.FallbackAttempts[0].Usage.InputTokens != 100 {
+		t.Fatalf("failed attempt should keep its usage: %+v", result.FallbackAttempts)
+	}
+
+	// 失敗したプロバイダの消費分も合算される
+	var input, output int
+	for _, u := range result.Usages() {
+		input += u.InputTokens
+		output += u.OutputTokens
+	}
+	if input != 150 || output != 15 {
+		t.Errorf("summed usage = %d/%d, want 150/15", input, output)
+	}
+}
+
+func TestExecutor_RunWorker_FallbackOnRateLimit(t *testing.T) {
+	sandbox := &scriptedSandbox{
+		results: []struct {
+			exitCode int
+			output   string
+		}{
+			{1, "ERROR: You've hit your usage limit. Try again later."},
+			{127, "sh: gemini: command not found"},
+			{0, "done"},
+		},
+	}
+	executor := &Executor{
+		Config: config.WorkerConfig{
+			Kind:     "codex-cli",
+			Fallback: []string{"gemini-cli", "claude-code"},
+		},
+		Sandbox:     sandbox,
+		containerID: "container-123",
+	}
+
+	result, err := executor.RunWorker(context.Background(), meta.WorkerCall{Prompt: "do it", Model: "gpt-5.1-codex"}, nil)
+	if err != nil {
+		t.Fatalf("RunWorker() error = %v", err)
+	}
+	if result.WorkerKind != "claude-code" {
+		t.Errorf("WorkerKind = %s, want claude-code", result.WorkerKind)
+	}
+	if len(result.FallbackAttempts) != 2 {
+		t.Fatalf("FallbackAttempts = %+v, want 2 entries", result.FallbackAttempts)
+	}
+	if result.FallbackAttempts[0].WorkerKind != "codex-cli" || result.FallbackAttempts[0].FailureClass != "rate_limit" {
+		t.Errorf("unexpected first attempt: %+v", result.FallbackAttempts[0])
+	}
+	if result.FallbackAttempts[1].WorkerKind != "gemini-cli" || result.FallbackAttempts[1].FailureClass != "unavailable" {
+		t.Errorf("unexpected second attempt: %+v", result.FallbackAttempts[1])
+	}
+	// フォールバック先には Codex 向けのモデル指定を引き継がない
+	lastCmd := strings.Join(sandbox.commands[2], " ")
+	if strings.Contains(lastCmd, "gpt-5.1-codex") {
+		t.Errorf("fallback command should not carry primary model: %s", lastCmd)
+	}
+}
+
+func TestExecutor_RunWorker_NoFallbackOnOrdinaryFailure(t *testing.T) {
+	sandbox := &scriptedSandbox{
+		results: []struct {
+			exitCode int
+			output   string
+		}{
+			{1, "test failed: expected 2, got 3"},
+		},
+	}
+	executor := &Executor{
+		Config: config.WorkerConfig{
+			Kind:     "codex-cli",
+			Fallback: []string{"claude-code"},
+		},
+		Sandbox:     sandbox,
+		containerID: "container-123",
+	}
+
+	result, err := executor.RunWorker(context.Background(), meta.WorkerCall{Prompt: "do it"}, nil)
+	if err != nil {
+		t.Fatalf("RunWorker() error = %v", err)
+	}
+	if result.WorkerKind != "codex-cli" || result.ExitCode != 1 {
+		t.Errorf("expected codex-cli failure to be returned as-is, got %+v", result)
+	}
+	if len(sandbox.commands) != 1 {
+		t.Errorf("expected a single Exec call, got %d", len(sandbox.commands))
+	}
+}
+
+func TestExecutor_RunWorker_SessionContinue(t *testing.T) {
+	sandbox := &scriptedSandbox{
+		results: []struct {
+			exitCode int
+			output   string
+		}{
+			{0, "{\"type\":\"thread.started\",\"thread_id\":\"th-42\"}\n{\"type\":\"turn.completed\"}"},
+		},
+	}
+	executor := &Executor{
+		Config:      config.WorkerConfig{Kind: "codex-cli"},
+		Sandbox:     sandbox,
+		containerID: "container-123",
+	}
+
+	result, err := executor.RunWorker(context.Background(), meta.WorkerCall{
+		Prompt:    "keep going",
+		Session:   meta.WorkerSessionContinue,
+		SessionID: "th-41",
+	}, nil)
+	if err != nil {
+		t.Fatalf("RunWorker() error = %v", err)
+	}
+	if result.SessionID != "th-42" {
+		t.Errorf("SessionID = %q, want th-42", result.SessionID)
+	}
+	cmd := strings.Join(sandbox.commands[0], " ")
+	if !strings.Contains(cmd, "resume th-41 keep going") {
+		t.Errorf("expected resume command, got %s", cmd)
+	}
+}
+
+func TestExecutor_RunWorker_ReasoningEffortFloor(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		floor     string
+		want      string
+	}{
+		{"floor when unspecified", "", "high", "reasoning_effort=high"},
+		{"raise lower request", "low", "high", "reasoning_effort=high"},
+		{"keep higher request", "high", "low", "reasoning_effort=high"},
+		{"no floor", "low", "", "reasoning_effort=low"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sandbox := &scriptedSandbox{
+				results: []struct {
+					exitCode int
+					output   string
+				}{
+					{0, "done"},
+				},
+			}
+			executor := &Executor{
+				Config:      config.WorkerConfig{Kind: "codex-cli", ReasoningEffort: tt.floor},
+				Sandbox:     sandbox,
+				containerID: "container-123",
+			}
+			if _, err := executor.RunWorker(context.Background(), meta.WorkerCall{Prompt: "do it", ReasoningEffort: tt.requested}, nil); err != nil {
+				t.Fatalf("RunWorker() error = %v", err)
+			}
+			cmd := strings.Join(sandbox.commands[0], " ")
+			if !strings.Contains(cmd, tt.want) {
+				t.Errorf("expected %s in command, got %s", tt.want, cmd)
+			}
+		})
+	}
+}
+
+func TestExecutor_RunWorker_SharedRateLimitBackoff(t *testing.T) {
+	sandbox := &scriptedSandbox{
+		results: []struct {
+			exitCode int
+			output   string
+		}{
+			{1, "ERROR: You've hit your usage limit. Try again later."},
+			{0, "done"},
+			{0, "done again"},
+		},
+	}
+	limiter := ratelimit.New(ratelimit.Config{})
+	executor := &Executor{
+		Config: config.WorkerConfig{
+			Kind:     "codex-cli",
+			Fallback: []string{"claude-code"},
+		},
+		Sandbox:     sandbox,
+		Limiter:     limiter,
+		containerID: "container-123",
+	}
+
+	// 1 回目: codex-cli が 429 → 共有バックオフを設定して claude-code へ
+	if _, err := executor.RunWorker(context.Background(), meta.WorkerCall{Prompt: "do it"}, nil); err != nil {
+		t.Fatalf("RunWorker() error = %v", err)
+	}
+	if limiter.BackoffRemaining(ratelimit.ProviderKey("codex-cli")) <= 0 {
+		t.Fatal("expected shared backoff for codex-cli")
+	}
+
+	// 2 回目: バックオフ中の codex-cli は実行せずにスキップする
+	result, err := executor.RunWorker(context.Background(), meta.WorkerCall{Prompt: "do it again"}, nil)
+	if err != nil {
+		t.Fatalf("RunWorker() error = %v", err)
+	}
+	if result.WorkerKind != "claude-code" {
+		t.Errorf("WorkerKind = %s, want claude-code", result.WorkerKind)
+	}
+	if len(sandbox.commands) != 3 {
+		t.Errorf("expected 3 sandbox executions, got %d", len(sandbox.commands))
+	}
+	if len(result.FallbackAttempts) != 1 || result.FallbackAttempts[0].FailureClass != "rate_limit" {
+		t.Errorf("unexpected fallback attempts: %+v", result.FallbackAttempts)
+	}
+}
@@ -3,13 +3,17 @@ package worker
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/biwakonbu/agent-runner/internal/agenttools"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
@@ -54,12 +58,22 @@ func (s *SandboxManager) StartContainer(ctx context.Context, image string, repoP
 
 	var envSlice []string
 	var customAuthPath string
+	var extraMounts []agenttools.CredentialMount
+	labels := map[string]string{ContainerLabelManaged: "true"}
 
 	for k, v := range env {
 		if k == "__INTERNAL_CLAUDE_AUTH_PATH" {
 			customAuthPath = v
 			continue
 		}
+		if k == internalExtraMountsEnv {
+			extraMounts = decodeCredentialMounts(v)
+			continue
+		}
+		if k == internalTaskIDEnv {
+			labels[ContainerLabelTaskID] = v
+			continue
+		}
 		val := v
 		if len(v) > 4 && v[:4] == "env:" {
 			val = os.Getenv(v[4:])
@@ -114,6 +128,20 @@ func (s *SandboxManager) StartContainer(ctx context.Context, image string, repoP
 		}
 	}
 
+	// 宣言的プロバイダが要求する認証情報をマウント（存在するもののみ）
+	for _, m := range extraMounts {
+		source := resolveHostPath(m.Source, homeDir)
+		if _, err := os.Stat(source); err != nil {
+			continue
+		}
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		})
+	}
+
 	// If auth.json doesn't exist, check for CODEX_API_KEY env var
 	if codexAPIKey := os.Getenv("CODEX_API_KEY"); codexAPIKey != "" {
 		envSlice = append(envSlice, fmt.Sprintf("CODEX_API_KEY=%s", codexAPIKey))
@@ -125,6 +153,7 @@ func (s *SandboxManager) StartContainer(ctx context.Context, image string, repoP
 		Env:        envSlice,
 		Cmd:        []string{"tail", "-f", "/dev/null"}, // Keep alive
 		WorkingDir: "/workspace/project",
+		Labels:     labels,
 	}, &container.HostConfig{
 		Mounts: mounts,
 	}, nil, nil, "")
@@ -198,3 +227,75 @@ func (s *SandboxManager) StopContainer(ctx context.Context, containerID string)
 	timeout := 0 // Force kill
 	return s.cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout})
 }
+
+// StopTaskContainers stops the running containers started for taskID
+// (labelled ContainerLabelTaskID), e.g. those left behind by a crashed
+// orchestrator. 停止したコンテナ数を返す。
+func (s *SandboxManager) StopTaskContainers(ctx context.Context, taskID string) (int, error) {
+	args := filters.NewArgs(
+		filters.Arg("label", ContainerLabelManaged+"=true"),
+		filters.Arg("label", ContainerLabelTaskID+"="+taskID),
+	)
+	containers, err := s.cli.ContainerList(ctx, types.ContainerListOptions{Filters: args})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list containers of task %s: %w", taskID, err)
+	}
+	stopped := 0
+	for _, c := range containers {
+		if err := s.StopContainer(ctx, c.ID); err != nil {
+			return stopped, fmt.Errorf("failed to stop container %s: %w", c.ID, err)
+		}
+		stopped++
+	}
+	return stopped, nil
+}
+
+// Container labels set on every container started by SandboxManager.
+const (
+	ContainerLabelManaged = "dev.agent-runner.managed"
+	ContainerLabelTaskID  = "dev.agent-runner.task-id"
+)
+
+// internalTaskIDEnv は StartContainer にタスク ID（コンテナラベル）を渡すための内部キー。
+const internalTaskIDEnv = "__INTERNAL_TASK_ID"
+
+// internalExtraMountsEnv は StartContainer に追加マウントを渡すための内部キー。
+// 値は []agenttools.CredentialMount の JSON（パスに ':' や ';' を含んでもよい）。
+const internalExtraMountsEnv = "__INTERNAL_EXTRA_MOUNTS"
+
+func encodeCredentialMounts(mounts []agenttools.CredentialMount) string {
+	data, err := json.Marshal(mounts)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func decodeCredentialMounts(value string) []agenttools.CredentialMount {
+	var decoded []agenttools.CredentialMount
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return nil
+	}
+	mounts := decoded[:0]
+	for _, m := range decoded {
+		if m.Source == "" || m.Target == "" {
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// resolveHostPath は ~ 展開と、相対パスをホームディレクトリ基準で解決する。
+func resolveHostPath(path, homeDir string) string {
+	if path == "~" {
+		return homeDir
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir, path[2:])
+	}
+	if !filepath.IsAbs(path) && homeDir != "" {
+		return filepath.Join(homeDir, path)
+	}
+	return path
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/biwakonbu/agent-runner/internal/agenttools"
 )
 
 // TestNewSandboxManager_Success tests successful SandboxManager creation
@@ -285,3 +287,29 @@ func TestExec_CommandConstruction(t *testing.T) {
 		t.Errorf("First command should be 'codex', got: %s", cmd[0])
 	}
 }
+
+func TestCredentialMountsEncoding(t *testing.T) {
+	mounts := []agenttools.CredentialMount{
+		{Source: "~/.tool", Target: "/root/.tool", ReadOnly: true},
+		{Source: "/etc/tool", Target: "/etc/tool"},
+		// ':' と ';' を含むパスも壊れない
+		{Source: "/mnt/c:/Users/me;work/.tool", Target: "/root/.tool:cfg"},
+	}
+	decoded := decodeCredentialMounts(encodeCredentialMounts(mounts))
+	if len(decoded) != 3 || decoded[0] != mounts[0] || decoded[1] != mounts[1] || decoded[2] != mounts[2] {
+		t.Errorf("round trip mismatch: %+v", decoded)
+	}
+	if got := decodeCredentialMounts("not json"); got != nil {
+		t.Errorf("invalid value should decode to nil, got %+v", got)
+	}
+
+	if got := resolveHostPath("~/.tool", "/home/u"); got != "/home/u/.tool" {
+		t.Errorf("resolveHostPath = %q", got)
+	}
+	if got := resolveHostPath(".tool", "/home/u"); got != "/home/u/.tool" {
+		t.Errorf("resolveHostPath = %q", got)
+	}
+	if got := resolveHostPath("/abs", "/home/u"); got != "/abs" {
+		t.Errorf("resolveHostPath = %q", got)
+	}
+}
@@ -47,6 +47,25 @@ type RunnerConfig struct {
 	Meta     MetaConfig   `yaml:"meta"`
 	Worker   WorkerConfig `yaml:"worker"`
 	MaxLoops int          `yaml:"max_loops"`
+
+	// Reviewer は完了判定のセカンドオピニオン。nil の場合は Meta の判定のみで完了とする
+	Reviewer *ReviewerConfig `yaml:"reviewer,omitempty"`
+}
+
+// ReviewerKindWorker is the ReviewerConfig.Kind that runs a read-only
+// reviewer worker in the sandbox instead of a Meta model.
+const ReviewerKindWorker = "worker"
+
+// ReviewerConfig configures the independent reviewer that re-assesses the
+// acceptance criteria against the diff and test output.
+type ReviewerConfig struct {
+	// Kind は Meta プロバイダ kind（openai-chat / anthropic-messages / codex-cli 等）または "worker"
+	Kind         string `yaml:"kind"`
+	Model        string `yaml:"model,omitempty"`
+	SystemPrompt string `yaml:"system_prompt,omitempty"`
+
+	// WorkerType は kind: worker のときの Worker 種別（省略時は runner.worker.kind）
+	WorkerType string `yaml:"worker_type,omitempty"`
 }
 
 // MetaConfig holds Meta agent configuration
@@ -54,6 +73,30 @@ type MetaConfig struct {
 	Kind         string `yaml:"kind"`
 	Model        string `yaml:"model"`
 	SystemPrompt string `yaml:"system_prompt"`
+
+	// HTTP providers (openai-chat, anthropic-messages) only
+	BaseURL           string            `yaml:"base_url,omitempty"`
+	Headers           map[string]string `yaml:"headers,omitempty"`
+	TimeoutSec        int               `yaml:"timeout_sec,omitempty"`
+	StructuredOutputs *bool             `yaml:"structured_outputs,omitempty"`
+	// ToolCalls は next_action / plan_patch を関数呼び出し（tools）で受け取るか（未指定は有効）。
+	// エンドポイントが未対応の場合は自動的に YAML/JSON テキストへ戻る。
+	ToolCalls *bool `yaml:"tool_calls,omitempty"`
+
+	// TranscriptMaxTokens はタスク内の会話履歴を再送する際の推定トークン上限。
+	// 超えると古いターンを要約する。0 は既定値、負の値は会話履歴を再送しない。
+	TranscriptMaxTokens int `yaml:"transcript_max_tokens,omitempty"`
+
+	// Routes はメッセージ種別（plan_task / next_action / completion_assessment /
+	// decompose / plan_patch）ごとのプロバイダとモデル。未指定の種別は kind / model を使う。
+	Routes map[string]MetaRoute `yaml:"routes,omitempty"`
+}
+
+// MetaRoute selects the provider kind and model for one Meta message type.
+// 空のフィールドは既定の kind / model を引き継ぐ。
+type MetaRoute struct {
+	Kind  string `yaml:"kind,omitempty"`
+	Model string `yaml:"model,omitempty"`
 }
 
 // WorkerConfig holds Worker agent configuration
@@ -63,4 +106,38 @@ type WorkerConfig struct {
 	MaxRunTimeSec int               `yaml:"max_run_time_sec"`
 	AuthPath      string            `yaml:"auth_path"`
 	Env           map[string]string `yaml:"env"`
+
+	// ReasoningEffort は Worker 呼び出しの最低限の思考の深さ（low / medium / high）。
+	// Meta の指定がこれより低い、または未指定の場合に使う
+	ReasoningEffort string `yaml:"reasoning_effort,omitempty"`
+
+	// Fallback は Kind が利用できない（レート制限・認証エラー・CLI 不在）場合に
+	// 順に試す worker kind のリスト（例: [claude-code, gemini-cli]）
+	Fallback []string `yaml:"fallback"`
+}
+
+// KindChain returns the ordered worker kinds to try, starting with primary
+// (or Kind / "codex-cli" when primary is empty) followed by Kind and Fallback.
+// Without Fallback the chain is just the primary kind. Duplicates are removed
+// while preserving order.
+func (w WorkerConfig) KindChain(primary string) []string {
+	if primary == "" {
+		primary = w.Kind
+	}
+	if primary == "" {
+		primary = "codex-cli"
+	}
+	chain := []string{primary}
+	if len(w.Fallback) == 0 {
+		return chain
+	}
+	seen := map[string]bool{primary: true}
+	for _, k := range append([]string{w.Kind}, w.Fallback...) {
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		chain = append(chain, k)
+	}
+	return chain
 }
@@ -318,3 +318,31 @@ runner:
 		t.Errorf("Worker.Env should be nil when not provided, got %v", cfg.Runner.Worker.Env)
 	}
 }
+
+func TestWorkerConfig_KindChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     WorkerConfig
+		primary string
+		want    []string
+	}{
+		{"default", WorkerConfig{}, "", []string{"codex-cli"}},
+		{"kind only", WorkerConfig{Kind: "claude-code"}, "", []string{"claude-code"}},
+		{"with fallback", WorkerConfig{Kind: "codex-cli", Fallback: []string{"claude-code", "gemini-cli"}}, "", []string{"codex-cli", "claude-code", "gemini-cli"}},
+		{"call override keeps config kind as fallback", WorkerConfig{Kind: "codex-cli", Fallback: []string{"claude-code"}}, "gemini-cli", []string{"gemini-cli", "codex-cli", "claude-code"}},
+		{"dedup", WorkerConfig{Kind: "codex-cli", Fallback: []string{"codex-cli", "claude-code", "claude-code"}}, "claude-code", []string{"claude-code", "codex-cli"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.KindChain(tt.primary)
+			if len(got) != len(tt.want) {
+				t.Fatalf("KindChain() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("KindChain() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}